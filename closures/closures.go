@@ -0,0 +1,150 @@
+package closures
+
+import (
+	"cloudproject/utils"
+	"errors"
+	"io"
+	"log"
+	"net/http"
+	"os"
+	"strings"
+	"sync"
+	"time"
+)
+
+/**
+ * Class closures.go
+ * Road closures and convoy driving, such as on the Norwegian mountain passes in winter
+ * The closures are read from a DATEX II feed, or a local file standing in for it, and are matched to the points of
+ * a route. The feed is read again when the closures are older than RefreshInterval.
+ */
+
+// Types of closures
+const (
+	Closed = "closed" // The road can not be used
+	Convoy = "convoy" // The road can only be driven in a convoy behind a snowplough, at set times
+)
+
+// MatchDistance How far, in meters, the points of a closure can be from a route and still be on it
+const MatchDistance = 300
+
+// Closure A closed stretch of road, or a single closed point if the stretch is not known
+type Closure struct {
+	ID          string
+	Type        string
+	Winter      bool // Closed for the winter
+	Road        string
+	Description string
+	Start       time.Time // Zero if it has started
+	End         time.Time // Zero if the end is not known
+	Points      []utils.Coordinate
+}
+
+var (
+	// Source The DATEX II feed, either an http(s) url or a file. ROAD_CLOSURES sets it, no closures are known without it
+	Source = os.Getenv("ROAD_CLOSURES")
+	// RefreshInterval How long the closures read from the source are used before it is read again
+	RefreshInterval = 5 * time.Minute
+
+	mutex      sync.Mutex
+	cached     []Closure
+	cachedFrom string
+	cachedAt   time.Time
+)
+
+// Current The closures of the source, read again if they are older than the refresh interval or the source has changed
+func Current() ([]Closure, error) {
+	mutex.Lock()
+	defer mutex.Unlock()
+	if Source == "" {
+		return []Closure{}, nil
+	}
+	if cachedFrom == Source && time.Since(cachedAt) < RefreshInterval {
+		return cached, nil
+	}
+
+	closures, err := Load(Source)
+	if err != nil {
+		// The closures read last are better than none while the feed is unavailable
+		if cachedFrom == Source {
+			log.Println("Unable to refresh the road closures, using the closures from " + cachedAt.Format(time.RFC3339) + "\n" + err.Error())
+			return cached, nil
+		}
+		return nil, err
+	}
+	cached, cachedFrom, cachedAt = closures, Source, time.Now()
+	return closures, nil
+}
+
+// Load Reads the closures from a DATEX II feed at an http(s) url, or from a file
+func Load(source string) ([]Closure, error) {
+	var reader io.ReadCloser
+	if strings.HasPrefix(source, "http://") || strings.HasPrefix(source, "https://") {
+		response, err := http.Get(source)
+		if err != nil {
+			return nil, errors.New("Unable to reach the road closure feed\n" + err.Error())
+		}
+		if response.StatusCode != http.StatusOK {
+			response.Body.Close()
+			return nil, errors.New("The road closure feed answered with status: " + response.Status)
+		}
+		reader = response.Body
+	} else {
+		file, err := os.Open(source)
+		if err != nil {
+			return nil, errors.New("Unable to open the road closures\n" + err.Error())
+		}
+		reader = file
+	}
+	defer reader.Close()
+	return Parse(reader)
+}
+
+// Active Checks if the closure is in effect at some time from the start to the end of a period, such as a trip
+func (closure Closure) Active(from time.Time, to time.Time) bool {
+	return (closure.Start.IsZero() || !to.Before(closure.Start)) && (closure.End.IsZero() || from.Before(closure.End))
+}
+
+// Match Returns the closures in effect during the period with all their points on the path
+func Match(closures []Closure, path []utils.Coordinate, from time.Time, to time.Time) []Closure {
+	matched := []Closure{}
+	for _, closure := range closures {
+		if !closure.Active(from, to) || len(closure.Points) == 0 {
+			continue
+		}
+		onPath := true
+		for _, point := range closure.Points {
+			if utils.DistanceToPath(point, path) > MatchDistance {
+				onPath = false
+			}
+		}
+		if onPath {
+			matched = append(matched, closure)
+		}
+	}
+	return matched
+}
+
+// Area The rectangle around the closure to avoid, padded by the match distance
+func (closure Closure) Area() (southWest utils.Coordinate, northEast utils.Coordinate) {
+	southWest, northEast = closure.Points[0], closure.Points[0]
+	for _, point := range closure.Points[1:] {
+		if point.Latitude < southWest.Latitude {
+			southWest.Latitude = point.Latitude
+		}
+		if point.Longitude < southWest.Longitude {
+			southWest.Longitude = point.Longitude
+		}
+		if point.Latitude > northEast.Latitude {
+			northEast.Latitude = point.Latitude
+		}
+		if point.Longitude > northEast.Longitude {
+			northEast.Longitude = point.Longitude
+		}
+	}
+	// About the match distance in degrees, a degree of longitude is shorter in the north, which only widens the area
+	padding := MatchDistance / 111000.0
+	southWest.Latitude, southWest.Longitude = southWest.Latitude-padding, southWest.Longitude-2*padding
+	northEast.Latitude, northEast.Longitude = northEast.Latitude+padding, northEast.Longitude+2*padding
+	return southWest, northEast
+}
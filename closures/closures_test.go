@@ -0,0 +1,96 @@
+package closures
+
+import (
+	"cloudproject/utils"
+	"os"
+	"reflect"
+	"testing"
+	"time"
+)
+
+// route The points of the route from Gjøvik to Lillehammer in the harness fixtures
+var route = path(60.7953, 10.6917, 60.80712, 10.68321, 60.8442, 10.6601, 60.88931, 10.62577, 60.93102, 10.60014,
+	60.97355, 10.57342, 61.01983, 10.54003, 61.0624, 10.50128, 61.09871, 10.47412, 61.1153, 10.4662)
+
+// path Makes coordinates of pairs of latitudes and longitudes
+func path(degrees ...float64) []utils.Coordinate {
+	var coordinates []utils.Coordinate
+	for i := 0; i+1 < len(degrees); i += 2 {
+		coordinates = append(coordinates, utils.Coordinate{Latitude: degrees[i], Longitude: degrees[i+1]})
+	}
+	return coordinates
+}
+
+func TestParse(t *testing.T) {
+	file, err := os.Open("testdata/datex.xml")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer file.Close()
+	closures, err := Parse(file)
+	if err != nil {
+		t.Fatalf("Could not parse the publication: %v", err)
+	}
+
+	// The lane closure and the suspended closure are left out
+	var ids []string
+	for _, closure := range closures {
+		ids = append(ids, closure.ID)
+	}
+	if expected := []string{"NPRA_HBT_0101_1", "NPRA_HBT_0202_1", "NPRA_HBT_0303_1", "NPRA_HBT_0606_1"}; !reflect.DeepEqual(ids, expected) {
+		t.Fatalf("Expected the closures %v; got %v", expected, ids)
+	}
+
+	closed, convoy, winter := closures[0], closures[1], closures[2]
+	if closed.Type != Closed || closed.Road != "Rv4 Mjøsvegen" || len(closed.Points) != 2 || closed.Description == "" {
+		t.Errorf("Expected a closed stretch of Rv4 Mjøsvegen; got %+v", closed)
+	}
+	if convoy.Type != Convoy || !convoy.Start.IsZero() || convoy.Road != "E134 Haukelivegen" {
+		t.Errorf("Expected convoy driving on E134 which has started; got %+v", convoy)
+	}
+	if !winter.Winter || winter.End.IsZero() || winter.Active(time.Date(2021, 6, 1, 0, 0, 0, 0, time.UTC), time.Date(2021, 6, 2, 0, 0, 0, 0, time.UTC)) {
+		t.Errorf("Expected a winter closure which has ended by June; got %+v", winter)
+	}
+	if len(closures[3].Points) != 1 {
+		t.Errorf("Expected the location for display of a closure without a stretch; got %v", closures[3].Points)
+	}
+}
+
+func TestMatch(t *testing.T) {
+	closures, err := Load("testdata/datex.xml")
+	if err != nil {
+		t.Fatal(err)
+	}
+	departure := time.Date(2021, 5, 10, 8, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		name     string
+		path     []utils.Coordinate
+		from, to time.Time
+		expected []string
+	}{
+		{"on the route", route, departure, departure.Add(time.Hour), []string{"NPRA_HBT_0101_1", "NPRA_HBT_0606_1"}},
+		{"before the closures", route, departure.Add(-240 * time.Hour), departure.Add(-239 * time.Hour), []string{}},
+		{"ending before the convoy", route, departure.Add(-9 * time.Hour), departure.Add(-7 * time.Hour), []string{"NPRA_HBT_0101_1"}},
+		{"starting during the trip", route, departure.Add(-7 * time.Hour), departure.Add(-5 * time.Hour),
+			[]string{"NPRA_HBT_0101_1", "NPRA_HBT_0606_1"}},
+		{"part of the stretch", route[:5], departure, departure.Add(time.Hour), []string{}},
+	}
+	for _, test := range tests {
+		ids := []string{}
+		for _, closure := range Match(closures, test.path, test.from, test.to) {
+			ids = append(ids, closure.ID)
+		}
+		if !reflect.DeepEqual(ids, test.expected) {
+			t.Errorf("%v: expected %v; got %v", test.name, test.expected, ids)
+		}
+	}
+}
+
+func TestArea(t *testing.T) {
+	closure := Closure{Points: path(60.97355, 10.57342, 60.93102, 10.60014)}
+	southWest, northEast := closure.Area()
+	if southWest.Latitude >= 60.93102 || southWest.Longitude >= 10.57342 || northEast.Latitude <= 60.97355 || northEast.Longitude <= 10.60014 {
+		t.Errorf("Expected the area to contain the closure; got %v to %v", southWest, northEast)
+	}
+}
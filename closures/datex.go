@@ -0,0 +1,120 @@
+package closures
+
+import (
+	"cloudproject/utils"
+	"encoding/xml"
+	"errors"
+	"io"
+	"strings"
+	"time"
+)
+
+/**
+ * Class datex.go
+ * Reads road closures from DATEX II (version 2) situation publications, the format of the Norwegian Public Roads
+ * Administration's traffic information feed. Only the records closing the road or requiring convoy driving are kept,
+ * other records, such as lane closures and roadworks, are left out.
+ */
+
+// managementTypes The DATEX II road management types which are closures, and the closure type they are given
+var managementTypes = map[string]string{
+	"roadClosed":                    Closed,
+	"carriagewayClosures":           Closed,
+	"closedPermanentlyForTheWinter": Closed,
+	"convoyService":                 Convoy,
+}
+
+// datexModel The parts of a DATEX II publication which are used
+type datexModel struct {
+	XMLName    xml.Name `xml:"d2LogicalModel"`
+	Situations []struct {
+		Records []datexRecord `xml:"situationRecord"`
+	} `xml:"payloadPublication>situation"`
+}
+
+// datexRecord A situation record of a DATEX II publication
+type datexRecord struct {
+	ID             string      `xml:"id,attr"`
+	Type           string      `xml:"http://www.w3.org/2001/XMLSchema-instance type,attr"`
+	ValidityStatus string      `xml:"validity>validityStatus"`
+	Start          string      `xml:"validity>validityTimeSpecification>overallStartTime"`
+	End            string      `xml:"validity>validityTimeSpecification>overallEndTime"`
+	Comments       []string    `xml:"generalPublicComment>comment>values>value"`
+	RoadName       string      `xml:"groupOfLocations>supplementaryPositionalDescription>roadInformation>roadName"`
+	RoadNumber     string      `xml:"groupOfLocations>supplementaryPositionalDescription>roadInformation>roadNumber"`
+	StartPoint     *datexPoint `xml:"groupOfLocations>linearExtension>linearByCoordinatesExtension>linearCoordinatesStartPoint>pointCoordinates"`
+	EndPoint       *datexPoint `xml:"groupOfLocations>linearExtension>linearByCoordinatesExtension>linearCoordinatesEndPoint>pointCoordinates"`
+	Display        *datexPoint `xml:"groupOfLocations>locationForDisplay"`
+	ManagementType string      `xml:"roadOrCarriagewayOrLaneManagementType"`
+}
+
+// datexPoint Coordinates in a DATEX II publication
+type datexPoint struct {
+	Latitude  float64 `xml:"latitude"`
+	Longitude float64 `xml:"longitude"`
+}
+
+// Parse Reads the closures of a DATEX II situation publication
+func Parse(reader io.Reader) ([]Closure, error) {
+	var model datexModel
+	if err := xml.NewDecoder(reader).Decode(&model); err != nil {
+		return nil, errors.New("Unable to read the DATEX II publication\n" + err.Error())
+	}
+
+	closures := []Closure{}
+	for _, situation := range model.Situations {
+		for _, record := range situation.Records {
+			closureType, isClosure := managementTypes[record.ManagementType]
+			if !isClosure || record.ValidityStatus == "suspended" {
+				continue
+			}
+			closure := Closure{ID: record.ID, Type: closureType, Road: road(record.RoadNumber, record.RoadName),
+				Description: strings.TrimSpace(strings.Join(record.Comments, " "))}
+			if record.ManagementType == "closedPermanentlyForTheWinter" {
+				closure.Winter = true
+			}
+
+			// The closed stretch, or the location for display if the stretch is not given
+			if record.StartPoint != nil && record.EndPoint != nil {
+				closure.Points = []utils.Coordinate{record.StartPoint.coordinate(), record.EndPoint.coordinate()}
+			} else if record.Display != nil {
+				closure.Points = []utils.Coordinate{record.Display.coordinate()}
+			} else {
+				continue
+			}
+
+			// Records which are active regardless of the validity time are only limited by the time they end
+			var err error
+			if closure.Start, err = parseTime(record.Start); err != nil {
+				return nil, errors.New("Invalid start time of the situation record " + record.ID + "\n" + err.Error())
+			}
+			if closure.End, err = parseTime(record.End); err != nil {
+				return nil, errors.New("Invalid end time of the situation record " + record.ID + "\n" + err.Error())
+			}
+			if record.ValidityStatus == "active" {
+				closure.Start = time.Time{}
+			}
+			closures = append(closures, closure)
+		}
+	}
+	return closures, nil
+}
+
+// coordinate Converts the DATEX II point to a coordinate
+func (point datexPoint) coordinate() utils.Coordinate {
+	return utils.Coordinate{Latitude: point.Latitude, Longitude: point.Longitude}
+}
+
+// road Names the road by its number and name, such as "E134 Haukelivegen"
+func road(number string, name string) string {
+	return strings.TrimSpace(strings.TrimSpace(number) + " " + strings.TrimSpace(name))
+}
+
+// parseTime Parses a DATEX II time, an empty time is the zero time
+func parseTime(value string) (time.Time, error) {
+	value = strings.TrimSpace(value)
+	if value == "" {
+		return time.Time{}, nil
+	}
+	return time.Parse(time.RFC3339, value)
+}
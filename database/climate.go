@@ -0,0 +1,154 @@
+package database
+
+import (
+	"cloudproject/structs"
+	"cloudproject/utils"
+	"encoding/csv"
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+	"runtime"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+/**
+ * Class climate.go
+ * Climate normals of weather stations, imported from a CSV file into the database
+ * The file has a header row and one row per station and month, with the columns
+ * station, name, latitude, longitude, elevation, month, temp_mean, temp_min, temp_max, precipitation,
+ * precipitation_days, snow_days, humidity and wind_speed. Temperatures are in degrees Celsius, precipitation in mm
+ * for the month, and wind speed in m/s. Each station is stored as one document with its twelve months.
+ */
+
+// ClimateCollection Name of the collection containing the climate normals
+var ClimateCollection = "climate"
+
+// ClimateFile The climate normals imported at startup, CLIMATE_NORMALS overrides the file next to this one
+var ClimateFile = climateFile()
+
+// climateColumns The columns of the climate normals, in order
+var climateColumns = []string{"station", "name", "latitude", "longitude", "elevation", "month", "temp_mean", "temp_min",
+	"temp_max", "precipitation", "precipitation_days", "snow_days", "humidity", "wind_speed"}
+
+// climateFile Finds the climate normals, independent of the directory the application or the tests run in
+func climateFile() string {
+	if file := os.Getenv("CLIMATE_NORMALS"); file != "" {
+		return file
+	}
+	_, source, _, _ := runtime.Caller(0)
+	return filepath.Join(filepath.Dir(source), "climate", "normals.csv")
+}
+
+// ImportClimateFile Imports the climate normals of a CSV file, and returns the number of stations imported
+func ImportClimateFile(path string) (int, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return 0, err
+	}
+	defer file.Close()
+	return ImportClimate(file)
+}
+
+// ImportClimate Reads climate normals in CSV format and stores every station, replacing the stations already stored.
+// Nothing is stored if a row is invalid, or a station does not have all twelve months
+func ImportClimate(reader io.Reader) (int, error) {
+	rows, err := csv.NewReader(reader).ReadAll()
+	if err != nil {
+		return 0, errors.New("Unable to read the climate normals\n" + err.Error())
+	}
+	if len(rows) == 0 || strings.Join(rows[0], ",") != strings.Join(climateColumns, ",") {
+		return 0, errors.New("The climate normals must start with the header: " + strings.Join(climateColumns, ","))
+	}
+
+	stations := map[string]*structs.ClimateStation{}
+	for i, row := range rows[1:] {
+		line := strconv.Itoa(i + 2)
+		station, month, err := parseClimateRow(row)
+		if err != nil {
+			return 0, errors.New("Invalid climate normals on line " + line + ": " + err.Error())
+		}
+		if stations[station.ID] == nil {
+			stations[station.ID] = &station
+		}
+		stations[station.ID].Months = append(stations[station.ID].Months, month)
+	}
+
+	var ids []string
+	for id, station := range stations {
+		sort.Slice(station.Months, func(i, j int) bool { return station.Months[i].Month < station.Months[j].Month })
+		for i, month := range station.Months {
+			if month.Month != i+1 || len(station.Months) != 12 {
+				return 0, errors.New("The station " + id + " must have exactly one row for each of the twelve months")
+			}
+		}
+		ids = append(ids, id)
+	}
+	sort.Strings(ids)
+
+	for _, id := range ids {
+		data, err := ToData(stations[id])
+		if err != nil {
+			return 0, err
+		}
+		if err = Client.Set(ClimateCollection, id, data); err != nil {
+			return 0, errors.New("Error while storing the climate normals of station: " + id + "\n" + err.Error())
+		}
+	}
+	return len(ids), nil
+}
+
+// parseClimateRow Parses a row of the climate normals into the station and the month
+func parseClimateRow(row []string) (structs.ClimateStation, structs.ClimateMonth, error) {
+	if len(row) != len(climateColumns) {
+		return structs.ClimateStation{}, structs.ClimateMonth{}, errors.New("expected " + strconv.Itoa(len(climateColumns)) + " columns")
+	}
+	numbers := make([]float64, len(row))
+	for i := 2; i < len(row); i++ {
+		number, err := strconv.ParseFloat(strings.TrimSpace(row[i]), 64)
+		if err != nil {
+			return structs.ClimateStation{}, structs.ClimateMonth{}, errors.New("the " + climateColumns[i] + " must be a number")
+		}
+		numbers[i] = number
+	}
+	if strings.TrimSpace(row[0]) == "" || numbers[5] < 1 || numbers[5] > 12 {
+		return structs.ClimateStation{}, structs.ClimateMonth{}, errors.New("the station must be given and the month must be from 1 to 12")
+	}
+
+	station := structs.ClimateStation{ID: strings.TrimSpace(row[0]), Name: strings.TrimSpace(row[1]),
+		Latitude: numbers[2], Longitude: numbers[3], Elevation: int(numbers[4])}
+	month := structs.ClimateMonth{Month: int(numbers[5]), TempMean: numbers[6], TempMin: numbers[7], TempMax: numbers[8],
+		Precipitation: numbers[9], PrecipitationDays: numbers[10], SnowDays: numbers[11], Humidity: int(numbers[12]),
+		WindSpeed: numbers[13]}
+	return station, month, nil
+}
+
+// NearestClimateStation Finds the stored station closest to the coordinates, and its distance in meters
+func NearestClimateStation(latitude float64, longitude float64) (structs.ClimateStation, float64, error) {
+	docs, err := Client.GetAll(ClimateCollection)
+	if err != nil {
+		return structs.ClimateStation{}, 0, err
+	}
+
+	var nearest structs.ClimateStation
+	shortest := -1.0
+	for _, doc := range docs {
+		var station structs.ClimateStation
+		if err := doc.DataTo(&station); err != nil {
+			return structs.ClimateStation{}, 0, err
+		}
+		if len(station.Months) != 12 {
+			continue
+		}
+		distance := utils.Haversine(latitude, longitude, station.Latitude, station.Longitude)
+		if shortest < 0 || distance < shortest {
+			nearest, shortest = station, distance
+		}
+	}
+	if shortest < 0 {
+		return structs.ClimateStation{}, 0, ErrNotFound
+	}
+	return nearest, shortest, nil
+}
@@ -1,14 +1,12 @@
 package database
 
 import (
-	"cloud.google.com/go/firestore"
 	"cloudproject/structs"
 	"cloudproject/utils"
 	"context"
 	"encoding/json"
 	"errors"
 	"fmt"
-	"google.golang.org/api/iterator"
 	"io/ioutil"
 	"log"
 	"net/http"
@@ -32,8 +30,8 @@ import (
 // Ctx Initializing the context to be used with firebase
 var Ctx context.Context
 
-// Client Initializing the firebase client
-var Client *firestore.Client
+// Client The store the application uses, Firestore in production
+var Client Store
 
 // LocationCollection Name of the collection containing locations in firebase
 var LocationCollection = "location"
@@ -47,7 +45,7 @@ func Delete(id string) (string, error) {
 	if err != nil {
 		return "", errors.New("Error occurred when trying to delete entry. Entry ID: " + id)
 	}
-	_, err = Client.Collection(Collection).Doc(id).Delete(Ctx) //Deletes from the database
+	err = Client.Delete(Collection, id) //Deletes from the database
 	if err != nil {
 		return "", errors.New("Error occurred when trying to delete entry. Entry ID: " + id)
 	}
@@ -58,41 +56,33 @@ func Delete(id string) (string, error) {
 // Get Used for retrieving a specific database entry and its data
 func Get(id string) ([]byte, error) {
 
-	dbSnapShot, err := Client.Collection(Collection).Doc(id).Get(Ctx)
+	doc, err := Client.Get(Collection, id)
 	if err != nil {
 		return nil, fmt.Errorf("error occurred: There is no document in the db with the id: %v", id)
 	}
 
-	m := dbSnapShot.Data()
-	jsonString, err := json.Marshal(m)
+	jsonString, err := json.Marshal(doc.Data)
+	if err != nil {
+		return nil, err
+	}
 
 	return jsonString, nil
 }
 
 // GetAll Retrieves all entries in a database
-// Source: https://stackoverflow.com/a/61429531
-// 		- Decided to use this because it is a quick and easy way to retrieve all entries from the database
-// Returns an object containing document snapshots of the entries in the database
-func GetAll() ([]*firestore.DocumentSnapshot, error) {
-	var docs []*firestore.DocumentSnapshot               //Defining object to be returned
-	iter := Client.Collection(Collection).Documents(Ctx) //Gets all entries in the database
-	for {                                                //Iterates through the database
-		doc, err := iter.Next()
-		if err == iterator.Done {
-			break
-		}
-		if err != nil {
-			fmt.Println(err)
-			return nil, err
-		}
-		docs = append(docs, doc)
+// Returns a list of the documents in the database
+func GetAll() ([]Document, error) {
+	docs, err := Client.GetAll(Collection) //Gets all entries in the database
+	if err != nil {
+		log.Println(err)
+		return nil, err
 	}
 	return docs, nil //Returns a list of entries
 }
 
-// Update Updates information of an entry in the database
-func Update(id string, data interface{}) error {
-	_, err := Client.Collection(Collection).Doc(id).Set(Ctx, data)
+// Update Updates the given fields of an entry in the database
+func Update(id string, data map[string]interface{}) error {
+	err := Client.Merge(Collection, id, data)
 	if err != nil {
 		return errors.New("Error while updating information for entry: " + id + " in the database: " + err.Error())
 	}
@@ -105,13 +95,15 @@ func GetLocation(address string) (string, string, error) {
 	address = strings.Replace(address, " ", "+", -1) //Replaces the spaces in location with +, which will please the url-condition
 
 	// Asks the API for the location data
-	response, err := http.Get("https://www.mapquestapi.com/geocoding/v1/address?key=" + utils.MapQuestKey + "&inFormat=kvp&outFormat=json&location=" + address)
+	response, err := http.Get(utils.MapQuestURL + "/geocoding/v1/address?key=" + utils.MapQuestKey + "&inFormat=kvp&outFormat=json&location=" + address)
+	if err != nil {
+		return "", "", errors.New("Internal Error, unable to reach the location API\n" + err.Error())
+	}
+	defer response.Body.Close()
 	if response.StatusCode == http.StatusBadRequest {
 		return "", "", errors.New("Syntax Error, Bad request, Status code: " + strconv.Itoa(response.StatusCode) + "\nPlease ensure you have entered an existing location")
 	} else if response.StatusCode == http.StatusInternalServerError || response.StatusCode == http.StatusForbidden {
 		return "", "", errors.New("Internal Error, Status code: " + strconv.Itoa(response.StatusCode) + "\nPlease try again later")
-	} else if err != nil {
-		return "", "", errors.New("Internal Error, Status code: " + strconv.Itoa(response.StatusCode) + "\n" + err.Error())
 	}
 
 	body, err := ioutil.ReadAll(response.Body)
@@ -124,6 +116,10 @@ func GetLocation(address string) (string, string, error) {
 		return "", "", errors.New("internal error\n" + err.Error())
 	}
 
+	if len(location.Results) == 0 || len(location.Results[0].Locations) == 0 {
+		return "-1", "-1", errors.New("the location you attempted to find was unreachable")
+	}
+
 	var latitude float64
 	var longitude float64
 
@@ -155,7 +151,7 @@ func LocationPresent(address string) (string, string, error) {
 	}
 
 	// Tries to retrieve the given document from the database
-	loc, errRetrieve := Client.Collection(LocationCollection).Doc(addressUnescaped).Get(Ctx)
+	loc, errRetrieve := Client.Get(LocationCollection, addressUnescaped)
 	if errRetrieve != nil {
 		log.Println("Address: " + addressUnescaped + " is not present in the location database. It will be added.")
 	}
@@ -175,7 +171,7 @@ func LocationPresent(address string) (string, string, error) {
 		locLat, locLon, err = GetLocation(address)
 		if locLat != "-1" && locLon != "-1" && err == nil {
 			// Add the new location instance to the database to be easily access next time
-			_, errSetLoc := Client.Collection(LocationCollection).Doc(addressUnescaped).Set(Ctx, map[string]interface{}{
+			errSetLoc := Client.Set(LocationCollection, addressUnescaped, map[string]interface{}{
 				"Latitude":  locLat,
 				"Longitude": locLon,
 			})
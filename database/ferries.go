@@ -0,0 +1,326 @@
+package database
+
+import (
+	"archive/zip"
+	"cloudproject/structs"
+	"cloudproject/utils"
+	"encoding/csv"
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+	"runtime"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+/**
+ * Class ferries.go
+ * Ferry timetables, imported from a GTFS feed into the database
+ * The feed is a directory or a zip file with the GTFS files agency.txt, stops.txt, routes.txt, trips.txt,
+ * stop_times.txt, and calendar.txt and/or calendar_dates.txt. Only the ferry routes are kept, each is stored as one
+ * document with its quays, trips and services.
+ * The fares for vehicles are not part of GTFS, they can be given in the extra file vehicle_fares.txt with the columns
+ * route_id, vehicle_class and price.
+ */
+
+// FerryCollection Name of the collection containing the ferry timetables
+var FerryCollection = "ferries"
+
+// FerryTimetable The GTFS feed imported at startup, FERRY_TIMETABLE overrides the feed next to this file
+var FerryTimetable = ferryTimetable()
+
+// ferryRouteTypes The GTFS route types of ferries, the basic type and the extended water transport types
+var ferryRouteTypes = map[string]bool{"4": true, "1000": true, "1200": true}
+
+// ferryTimetable Finds the ferry timetable, independent of the directory the application or the tests run in
+func ferryTimetable() string {
+	if path := os.Getenv("FERRY_TIMETABLE"); path != "" {
+		return path
+	}
+	_, source, _, _ := runtime.Caller(0)
+	return filepath.Join(filepath.Dir(source), "ferries")
+}
+
+// gtfsFeed The tables of a GTFS feed, each row maps the column names to the values
+type gtfsFeed map[string][]map[string]string
+
+// ImportTimetableFile Imports the ferry routes of a GTFS feed in a directory or a zip file, and returns the number
+// of routes imported
+func ImportTimetableFile(path string) (int, error) {
+	feed := gtfsFeed{}
+	names := []string{"agency.txt", "stops.txt", "routes.txt", "trips.txt", "stop_times.txt", "calendar.txt", "calendar_dates.txt",
+		"vehicle_fares.txt"}
+
+	if strings.HasSuffix(strings.ToLower(path), ".zip") {
+		archive, err := zip.OpenReader(path)
+		if err != nil {
+			return 0, err
+		}
+		defer archive.Close()
+		for _, file := range archive.File {
+			if !utils.Contains(names, file.Name) {
+				continue
+			}
+			reader, err := file.Open()
+			if err != nil {
+				return 0, err
+			}
+			feed[file.Name], err = readGTFSTable(file.Name, reader)
+			reader.Close()
+			if err != nil {
+				return 0, err
+			}
+		}
+	} else {
+		for _, name := range names {
+			file, err := os.Open(filepath.Join(path, name))
+			if os.IsNotExist(err) {
+				continue
+			} else if err != nil {
+				return 0, err
+			}
+			feed[name], err = readGTFSTable(name, file)
+			file.Close()
+			if err != nil {
+				return 0, err
+			}
+		}
+	}
+	return importTimetable(feed)
+}
+
+// readGTFSTable Reads a GTFS file into rows mapping the column names to the values
+func readGTFSTable(name string, reader io.Reader) ([]map[string]string, error) {
+	csvReader := csv.NewReader(reader)
+	csvReader.FieldsPerRecord = -1
+	records, err := csvReader.ReadAll()
+	if err != nil {
+		return nil, errors.New("Unable to read " + name + "\n" + err.Error())
+	}
+	if len(records) == 0 {
+		return nil, nil
+	}
+	header := records[0]
+	// Some feeds start with a byte order mark
+	header[0] = strings.TrimPrefix(header[0], "\ufeff")
+
+	rows := make([]map[string]string, 0, len(records)-1)
+	for _, record := range records[1:] {
+		row := map[string]string{}
+		for i, column := range header {
+			if i < len(record) {
+				row[strings.TrimSpace(column)] = strings.TrimSpace(record[i])
+			}
+		}
+		rows = append(rows, row)
+	}
+	return rows, nil
+}
+
+// importTimetable Stores the ferry routes of the tables of a GTFS feed, keyed by their file names, replacing the routes
+// already stored. Nothing is stored if a table is missing or a value is invalid
+func importTimetable(feed gtfsFeed) (int, error) {
+	for _, name := range []string{"agency.txt", "stops.txt", "routes.txt", "trips.txt", "stop_times.txt"} {
+		if feed[name] == nil {
+			return 0, errors.New("The timetable is missing " + name)
+		}
+	}
+	if feed["calendar.txt"] == nil && feed["calendar_dates.txt"] == nil {
+		return 0, errors.New("The timetable is missing calendar.txt and calendar_dates.txt")
+	}
+
+	// The time zones of the agencies, a feed with a single agency can leave out the agency of the routes
+	timezones := map[string]string{}
+	for _, agency := range feed["agency.txt"] {
+		timezones[agency["agency_id"]] = agency["agency_timezone"]
+	}
+
+	routes := map[string]*structs.FerryRoute{}
+	for _, row := range feed["routes.txt"] {
+		if !ferryRouteTypes[row["route_type"]] {
+			continue
+		}
+		name := row["route_long_name"]
+		if name == "" {
+			name = row["route_short_name"]
+		}
+		timezone := timezones[row["agency_id"]]
+		if timezone == "" && len(feed["agency.txt"]) == 1 {
+			timezone = feed["agency.txt"][0]["agency_timezone"]
+		}
+		routes[row["route_id"]] = &structs.FerryRoute{ID: row["route_id"], Name: name, Agency: row["agency_id"], Timezone: timezone,
+			Fares: map[string]float64{}}
+	}
+
+	for _, row := range feed["vehicle_fares.txt"] {
+		price, err := strconv.ParseFloat(row["price"], 64)
+		if err != nil || price < 0 {
+			return 0, errors.New("The fare for " + row["vehicle_class"] + " on the route " + row["route_id"] + " must be a number, and not negative")
+		}
+		if route := routes[row["route_id"]]; route != nil {
+			route.Fares[row["vehicle_class"]] = price
+		}
+	}
+
+	stops := map[string]structs.FerryStop{}
+	for _, row := range feed["stops.txt"] {
+		latitude, errLat := strconv.ParseFloat(row["stop_lat"], 64)
+		longitude, errLon := strconv.ParseFloat(row["stop_lon"], 64)
+		if errLat != nil || errLon != nil {
+			return 0, errors.New("The stop " + row["stop_id"] + " must have a latitude and a longitude")
+		}
+		stops[row["stop_id"]] = structs.FerryStop{ID: row["stop_id"], Name: row["stop_name"], Latitude: latitude, Longitude: longitude}
+	}
+
+	// The calls of the trips, sorted by their sequence below
+	type call struct {
+		sequence int
+		call     structs.FerryCall
+	}
+	calls := map[string][]call{}
+	for _, row := range feed["stop_times.txt"] {
+		sequence, err := strconv.Atoi(row["stop_sequence"])
+		if err != nil {
+			return 0, errors.New("The stop sequence of the trip " + row["trip_id"] + " must be a number")
+		}
+		arrival, errArrival := gtfsSeconds(row["arrival_time"])
+		departure, errDeparture := gtfsSeconds(row["departure_time"])
+		if errArrival != nil || errDeparture != nil {
+			return 0, errors.New("The times of the trip " + row["trip_id"] + " must be given as HH:MM:SS")
+		}
+		if _, found := stops[row["stop_id"]]; !found {
+			return 0, errors.New("The trip " + row["trip_id"] + " calls at the unknown stop " + row["stop_id"])
+		}
+		calls[row["trip_id"]] = append(calls[row["trip_id"]], call{sequence, structs.FerryCall{Stop: row["stop_id"], Arrival: arrival, Departure: departure}})
+	}
+
+	services := map[string]*structs.FerryService{}
+	service := func(id string) *structs.FerryService {
+		if services[id] == nil {
+			services[id] = &structs.FerryService{ID: id, Days: make([]bool, 7)}
+		}
+		return services[id]
+	}
+	weekdays := []string{"sunday", "monday", "tuesday", "wednesday", "thursday", "friday", "saturday"}
+	for _, row := range feed["calendar.txt"] {
+		days := service(row["service_id"])
+		for i, weekday := range weekdays {
+			days.Days[i] = row[weekday] == "1"
+		}
+		days.Start, days.End = row["start_date"], row["end_date"]
+	}
+	for _, row := range feed["calendar_dates.txt"] {
+		days := service(row["service_id"])
+		switch row["exception_type"] {
+		case "1":
+			days.Added = append(days.Added, row["date"])
+		case "2":
+			days.Removed = append(days.Removed, row["date"])
+		default:
+			return 0, errors.New("The exception type of the service " + row["service_id"] + " must be 1 or 2")
+		}
+	}
+
+	// Adds the trips to their routes, with the stops and services they use
+	for _, row := range feed["trips.txt"] {
+		route := routes[row["route_id"]]
+		if route == nil {
+			continue
+		}
+		tripCalls := calls[row["trip_id"]]
+		sort.Slice(tripCalls, func(i, j int) bool { return tripCalls[i].sequence < tripCalls[j].sequence })
+		trip := structs.FerryTrip{Service: row["service_id"]}
+		for _, c := range tripCalls {
+			trip.Calls = append(trip.Calls, c.call)
+			if !hasStop(route.Stops, c.call.Stop) {
+				route.Stops = append(route.Stops, stops[c.call.Stop])
+			}
+		}
+		if len(trip.Calls) < 2 {
+			continue
+		}
+		route.Trips = append(route.Trips, trip)
+		if !hasService(route.Services, trip.Service) {
+			if services[trip.Service] == nil {
+				return 0, errors.New("The trip " + row["trip_id"] + " has the unknown service " + trip.Service)
+			}
+			route.Services = append(route.Services, *services[trip.Service])
+		}
+	}
+
+	var ids []string
+	for id, route := range routes {
+		if len(route.Trips) != 0 {
+			ids = append(ids, id)
+		}
+	}
+	sort.Strings(ids)
+
+	for _, id := range ids {
+		data, err := ToData(routes[id])
+		if err != nil {
+			return 0, err
+		}
+		// Document ids can not contain slashes
+		if err = Client.Set(FerryCollection, strings.ReplaceAll(id, "/", "_"), data); err != nil {
+			return 0, errors.New("Error while storing the timetable of the ferry route: " + id + "\n" + err.Error())
+		}
+	}
+	return len(ids), nil
+}
+
+// FerryRoutes Gets the stored ferry routes
+func FerryRoutes() ([]structs.FerryRoute, error) {
+	docs, err := Client.GetAll(FerryCollection)
+	if err != nil {
+		return nil, err
+	}
+	routes := make([]structs.FerryRoute, 0, len(docs))
+	for _, doc := range docs {
+		var route structs.FerryRoute
+		if err := doc.DataTo(&route); err != nil {
+			return nil, err
+		}
+		routes = append(routes, route)
+	}
+	return routes, nil
+}
+
+// gtfsSeconds Parses a GTFS time, HH:MM:SS where the hours can pass 24, into seconds after midnight
+func gtfsSeconds(value string) (int, error) {
+	parts := strings.Split(value, ":")
+	if len(parts) != 3 {
+		return 0, errors.New("invalid time: " + value)
+	}
+	seconds := 0
+	for _, part := range parts {
+		number, err := strconv.Atoi(part)
+		if err != nil || number < 0 {
+			return 0, errors.New("invalid time: " + value)
+		}
+		seconds = seconds*60 + number
+	}
+	return seconds, nil
+}
+
+// hasStop Checks if the stop is among the stops
+func hasStop(stops []structs.FerryStop, id string) bool {
+	for _, stop := range stops {
+		if stop.ID == id {
+			return true
+		}
+	}
+	return false
+}
+
+// hasService Checks if the service is among the services
+func hasService(services []structs.FerryService, id string) bool {
+	for _, service := range services {
+		if service.ID == id {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,79 @@
+package database
+
+import (
+	"cloud.google.com/go/firestore"
+	"google.golang.org/api/iterator"
+)
+
+// firestoreStore Store backed by a Firestore client
+type firestoreStore struct {
+	client *firestore.Client
+}
+
+// NewFirestore Creates a Store using the Firestore client, requests are made with Ctx
+func NewFirestore(client *firestore.Client) Store {
+	return &firestoreStore{client: client}
+}
+
+// Get Retrieves a single document from Firestore
+func (store *firestoreStore) Get(collection string, id string) (Document, error) {
+	snapshot, err := store.client.Collection(collection).Doc(id).Get(Ctx)
+	if snapshot != nil && !snapshot.Exists() {
+		return Document{}, ErrNotFound
+	}
+	if err != nil {
+		return Document{}, err
+	}
+	return Document{ID: snapshot.Ref.ID, Data: snapshot.Data()}, nil
+}
+
+// GetAll Retrieves all documents of a Firestore collection
+// Source: https://stackoverflow.com/a/61429531
+func (store *firestoreStore) GetAll(collection string) ([]Document, error) {
+	var docs []Document
+	iter := store.client.Collection(collection).Documents(Ctx)
+	defer iter.Stop()
+	for {
+		snapshot, err := iter.Next()
+		if err == iterator.Done {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+		docs = append(docs, Document{ID: snapshot.Ref.ID, Data: snapshot.Data()})
+	}
+	return docs, nil
+}
+
+// Add Adds a document to a Firestore collection with an id generated by Firestore
+func (store *firestoreStore) Add(collection string, data map[string]interface{}) (string, error) {
+	ref, _, err := store.client.Collection(collection).Add(Ctx, data)
+	if err != nil {
+		return "", err
+	}
+	return ref.ID, nil
+}
+
+// Set Creates or replaces a Firestore document
+func (store *firestoreStore) Set(collection string, id string, data map[string]interface{}) error {
+	_, err := store.client.Collection(collection).Doc(id).Set(Ctx, data)
+	return err
+}
+
+// Merge Sets the given fields of a Firestore document
+func (store *firestoreStore) Merge(collection string, id string, data map[string]interface{}) error {
+	_, err := store.client.Collection(collection).Doc(id).Set(Ctx, data, firestore.MergeAll)
+	return err
+}
+
+// Delete Deletes a Firestore document
+func (store *firestoreStore) Delete(collection string, id string) error {
+	_, err := store.client.Collection(collection).Doc(id).Delete(Ctx)
+	return err
+}
+
+// Close Closes the Firestore client
+func (store *firestoreStore) Close() error {
+	return store.client.Close()
+}
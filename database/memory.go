@@ -0,0 +1,109 @@
+package database
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"sort"
+	"sync"
+)
+
+// memoryStore Store keeping the documents in memory, the data is lost when the application stops
+type memoryStore struct {
+	mutex       sync.RWMutex
+	collections map[string]map[string]map[string]interface{}
+}
+
+// NewMemory Creates an empty in-memory Store
+func NewMemory() Store {
+	return &memoryStore{collections: map[string]map[string]map[string]interface{}{}}
+}
+
+// Get Retrieves a copy of a single document
+func (store *memoryStore) Get(collection string, id string) (Document, error) {
+	store.mutex.RLock()
+	defer store.mutex.RUnlock()
+	data, found := store.collections[collection][id]
+	if !found {
+		return Document{}, ErrNotFound
+	}
+	return Document{ID: id, Data: clone(data)}, nil
+}
+
+// GetAll Retrieves copies of all documents of a collection, ordered by id
+func (store *memoryStore) GetAll(collection string) ([]Document, error) {
+	store.mutex.RLock()
+	defer store.mutex.RUnlock()
+	var ids []string
+	for id := range store.collections[collection] {
+		ids = append(ids, id)
+	}
+	sort.Strings(ids)
+
+	var docs []Document
+	for _, id := range ids {
+		docs = append(docs, Document{ID: id, Data: clone(store.collections[collection][id])})
+	}
+	return docs, nil
+}
+
+// Add Adds a document with a random id of the same form as the ids Firestore generates
+func (store *memoryStore) Add(collection string, data map[string]interface{}) (string, error) {
+	random := make([]byte, 10)
+	if _, err := rand.Read(random); err != nil {
+		return "", err
+	}
+	id := hex.EncodeToString(random)
+	return id, store.Set(collection, id, data)
+}
+
+// Set Creates or replaces a document
+func (store *memoryStore) Set(collection string, id string, data map[string]interface{}) error {
+	store.mutex.Lock()
+	defer store.mutex.Unlock()
+	if store.collections[collection] == nil {
+		store.collections[collection] = map[string]map[string]interface{}{}
+	}
+	store.collections[collection][id] = clone(data)
+	return nil
+}
+
+// Merge Sets the given fields of a document, creating it if it does not exist
+func (store *memoryStore) Merge(collection string, id string, data map[string]interface{}) error {
+	store.mutex.Lock()
+	defer store.mutex.Unlock()
+	if store.collections[collection] == nil {
+		store.collections[collection] = map[string]map[string]interface{}{}
+	}
+	existing := store.collections[collection][id]
+	if existing == nil {
+		existing = map[string]interface{}{}
+	}
+	for key, value := range clone(data) {
+		existing[key] = value
+	}
+	store.collections[collection][id] = existing
+	return nil
+}
+
+// Delete Deletes a document
+func (store *memoryStore) Delete(collection string, id string) error {
+	store.mutex.Lock()
+	defer store.mutex.Unlock()
+	delete(store.collections[collection], id)
+	return nil
+}
+
+// Close Nothing to release for the in-memory store
+func (store *memoryStore) Close() error {
+	return nil
+}
+
+// clone Copies the data, so callers can not change the stored documents, values are normalised to the
+// types a JSON round trip gives, as Firestore normalises the values it stores
+func clone(data map[string]interface{}) map[string]interface{} {
+	copied, err := ToData(data)
+	if err != nil || copied == nil {
+		return map[string]interface{}{}
+	}
+	return copied
+}
@@ -0,0 +1,38 @@
+package database
+
+import "cloudproject/structs"
+
+// ProfileCollection Name of the collection containing the vehicle profiles
+var ProfileCollection = "profiles"
+
+// GetProfile Gets the stored vehicle profile with the id, returns ErrNotFound if there is none
+func GetProfile(id string) (structs.VehicleProfile, error) {
+	doc, err := Client.Get(ProfileCollection, id)
+	if err != nil {
+		return structs.VehicleProfile{}, err
+	}
+	var profile structs.VehicleProfile
+	if err = doc.DataTo(&profile); err != nil {
+		return structs.VehicleProfile{}, err
+	}
+	profile.ID = doc.ID
+	return profile, nil
+}
+
+// GetProfiles Gets all stored vehicle profiles
+func GetProfiles() ([]structs.VehicleProfile, error) {
+	docs, err := Client.GetAll(ProfileCollection)
+	if err != nil {
+		return nil, err
+	}
+	profiles := make([]structs.VehicleProfile, 0, len(docs))
+	for _, doc := range docs {
+		var profile structs.VehicleProfile
+		if err := doc.DataTo(&profile); err != nil {
+			return nil, err
+		}
+		profile.ID = doc.ID
+		profiles = append(profiles, profile)
+	}
+	return profiles, nil
+}
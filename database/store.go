@@ -0,0 +1,57 @@
+package database
+
+import (
+	"encoding/json"
+	"errors"
+)
+
+// ErrNotFound Returned by a Store when there is no document with the requested id
+var ErrNotFound = errors.New("document not found")
+
+// Store The operations the application needs from the database, implemented by Firestore and by an
+// in-memory store (for tests and for running without Firebase credentials)
+type Store interface {
+	// Get Retrieves a single document, returns ErrNotFound if there is no document with the id
+	Get(collection string, id string) (Document, error)
+	// GetAll Retrieves all documents of a collection
+	GetAll(collection string) ([]Document, error)
+	// Add Adds a document with a generated id, and returns the id
+	Add(collection string, data map[string]interface{}) (string, error)
+	// Set Creates or replaces the document with the id
+	Set(collection string, id string, data map[string]interface{}) error
+	// Merge Sets the given fields of the document with the id, leaving the other fields as they are
+	Merge(collection string, id string, data map[string]interface{}) error
+	// Delete Deletes the document with the id
+	Delete(collection string, id string) error
+	// Close Releases the connection to the database
+	Close() error
+}
+
+// Document A stored entry and its id
+type Document struct {
+	ID   string
+	Data map[string]interface{}
+}
+
+// DataTo Populates the struct pointed to by v with the data of the document, fields are matched by their
+// json name, or case-insensitively by their field name
+func (doc Document) DataTo(v interface{}) error {
+	data, err := json.Marshal(doc.Data)
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(data, v)
+}
+
+// ToData Converts a struct to the map of fields stored in the database, the inverse of Document.DataTo
+func ToData(v interface{}) (map[string]interface{}, error) {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return nil, err
+	}
+	var fields map[string]interface{}
+	if err = json.Unmarshal(data, &fields); err != nil {
+		return nil, err
+	}
+	return fields, nil
+}
@@ -0,0 +1,129 @@
+package database
+
+import (
+	"cloudproject/structs"
+	"encoding/csv"
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+	"runtime"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+/**
+ * Class tolls.go
+ * Toll points, such as the AutoPASS toll stations, imported from a CSV file into the database
+ * The file has a header row and one row per toll point, with the columns id, name, road, latitude and longitude,
+ * followed by a column with the price of passing for each vehicle class, such as car, ev and trailer. Each toll
+ * point is stored as one document.
+ */
+
+// TollCollection Name of the collection containing the toll points
+var TollCollection = "tolls"
+
+// TollFile The toll points imported at startup, TOLL_POINTS overrides the file next to this one
+var TollFile = tollFile()
+
+// tollColumns The columns every toll point has, the prices of the vehicle classes follow them
+var tollColumns = []string{"id", "name", "road", "latitude", "longitude"}
+
+// tollFile Finds the toll points, independent of the directory the application or the tests run in
+func tollFile() string {
+	if file := os.Getenv("TOLL_POINTS"); file != "" {
+		return file
+	}
+	_, source, _, _ := runtime.Caller(0)
+	return filepath.Join(filepath.Dir(source), "tolls", "tollpoints.csv")
+}
+
+// ImportTollsFile Imports the toll points of a CSV file, and returns the number of toll points imported
+func ImportTollsFile(path string) (int, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return 0, err
+	}
+	defer file.Close()
+	return ImportTolls(file)
+}
+
+// ImportTolls Reads toll points in CSV format and stores every toll point, replacing the toll points already stored.
+// Nothing is stored if a row is invalid
+func ImportTolls(reader io.Reader) (int, error) {
+	rows, err := csv.NewReader(reader).ReadAll()
+	if err != nil {
+		return 0, errors.New("Unable to read the toll points\n" + err.Error())
+	}
+	if len(rows) == 0 || len(rows[0]) <= len(tollColumns) ||
+		strings.Join(rows[0][:len(tollColumns)], ",") != strings.Join(tollColumns, ",") {
+		return 0, errors.New("The toll points must start with the header: " + strings.Join(tollColumns, ",") +
+			", followed by the vehicle classes")
+	}
+	classes := rows[0][len(tollColumns):]
+
+	var points []structs.TollPoint
+	for i, row := range rows[1:] {
+		point, err := parseTollRow(row, classes)
+		if err != nil {
+			return 0, errors.New("Invalid toll point on line " + strconv.Itoa(i+2) + ": " + err.Error())
+		}
+		points = append(points, point)
+	}
+	sort.Slice(points, func(i, j int) bool { return points[i].ID < points[j].ID })
+
+	for _, point := range points {
+		data, err := ToData(point)
+		if err != nil {
+			return 0, err
+		}
+		if err = Client.Set(TollCollection, point.ID, data); err != nil {
+			return 0, errors.New("Error while storing the toll point: " + point.ID + "\n" + err.Error())
+		}
+	}
+	return len(points), nil
+}
+
+// parseTollRow Parses a row of the toll points, with the prices of the vehicle classes
+func parseTollRow(row []string, classes []string) (structs.TollPoint, error) {
+	if len(row) != len(tollColumns)+len(classes) {
+		return structs.TollPoint{}, errors.New("expected " + strconv.Itoa(len(tollColumns)+len(classes)) + " columns")
+	}
+	point := structs.TollPoint{ID: strings.TrimSpace(row[0]), Name: strings.TrimSpace(row[1]), Road: strings.TrimSpace(row[2]),
+		Prices: map[string]float64{}}
+	if point.ID == "" {
+		return structs.TollPoint{}, errors.New("the id must be given")
+	}
+	var errLat, errLon error
+	point.Latitude, errLat = strconv.ParseFloat(strings.TrimSpace(row[3]), 64)
+	point.Longitude, errLon = strconv.ParseFloat(strings.TrimSpace(row[4]), 64)
+	if errLat != nil || errLon != nil {
+		return structs.TollPoint{}, errors.New("the latitude and longitude must be numbers")
+	}
+	for i, class := range classes {
+		price, err := strconv.ParseFloat(strings.TrimSpace(row[len(tollColumns)+i]), 64)
+		if err != nil || price < 0 {
+			return structs.TollPoint{}, errors.New("the price for " + class + " must be a number, and not negative")
+		}
+		point.Prices[strings.TrimSpace(class)] = price
+	}
+	return point, nil
+}
+
+// TollPoints Gets the stored toll points
+func TollPoints() ([]structs.TollPoint, error) {
+	docs, err := Client.GetAll(TollCollection)
+	if err != nil {
+		return nil, err
+	}
+	points := make([]structs.TollPoint, 0, len(docs))
+	for _, doc := range docs {
+		var point structs.TollPoint
+		if err := doc.DataTo(&point); err != nil {
+			return nil, err
+		}
+		points = append(points, point)
+	}
+	return points, nil
+}
@@ -0,0 +1,63 @@
+package database
+
+import "cloudproject/structs"
+
+// TripCollection Name of the collection containing the saved trips
+var TripCollection = "trips"
+
+// GetTrip Gets the saved trip with the id, returns ErrNotFound if there is none
+func GetTrip(id string) (structs.Trip, error) {
+	doc, err := Client.Get(TripCollection, id)
+	if err != nil {
+		return structs.Trip{}, err
+	}
+	var trip structs.Trip
+	if err = doc.DataTo(&trip); err != nil {
+		return structs.Trip{}, err
+	}
+	trip.ID = doc.ID
+	return trip, nil
+}
+
+// GetTrips Gets all saved trips
+func GetTrips() ([]structs.Trip, error) {
+	docs, err := Client.GetAll(TripCollection)
+	if err != nil {
+		return nil, err
+	}
+	trips := make([]structs.Trip, 0, len(docs))
+	for _, doc := range docs {
+		var trip structs.Trip
+		if err := doc.DataTo(&trip); err != nil {
+			return nil, err
+		}
+		trip.ID = doc.ID
+		trips = append(trips, trip)
+	}
+	return trips, nil
+}
+
+// AddTrip Saves a new trip, and returns its id
+func AddTrip(trip structs.Trip) (string, error) {
+	data, err := ToData(trip)
+	if err != nil {
+		return "", err
+	}
+	delete(data, "id")
+	return Client.Add(TripCollection, data)
+}
+
+// DeleteTrip Deletes the saved trip with the id
+func DeleteTrip(id string) error {
+	return Client.Delete(TripCollection, id)
+}
+
+// SetTrip Replaces the saved trip with the id
+func SetTrip(id string, trip structs.Trip) error {
+	data, err := ToData(trip)
+	if err != nil {
+		return err
+	}
+	delete(data, "id")
+	return Client.Set(TripCollection, id, data)
+}
@@ -0,0 +1,63 @@
+package endpoints
+
+import (
+	"cloudproject/database"
+	"cloudproject/structs"
+	"cloudproject/utils"
+	"log"
+	"strings"
+	"time"
+)
+
+/**
+ * Class briefing.go
+ * The briefing of a trip, which webhooks send the evening before it
+ * The trip is planned for the departure, and the briefing has the forecast at the start when departing and at the
+ * destination when arriving, the closures still on the route, and where to charge an electric or hybrid vehicle.
+ */
+
+// TripBriefing The briefing of the trip departing at the departure, with the forecasts in the locale
+func TripBriefing(trip structs.Trip, departure time.Time, locale string) (structs.TripBriefing, error) {
+	trip.Departure, trip.Arrival = departure.Format(time.RFC3339), ""
+	planned, _, err := planTrip(trip, utils.Query{})
+	if err != nil {
+		return structs.TripBriefing{}, err
+	}
+	briefing := structs.TripBriefing{Departure: planned.departure.Format(time.RFC3339), Arrival: planned.arrival.Format(time.RFC3339),
+		Closures: []structs.RouteClosure{}, ChargingStops: []structs.ChargingStop{}}
+	for _, closure := range planned.closures {
+		if !closure.Avoided {
+			briefing.Closures = append(briefing.Closures, closure)
+		}
+	}
+
+	forecasts := endForecasts(planned.roads, locale)
+	advisories := []*string{&briefing.DepartureWeather, &briefing.ArrivalWeather}
+	for i, at := range []time.Time{planned.departure, planned.arrival} {
+		if forecasts[i] == nil {
+			continue
+		}
+		if entry := forecastAt(*forecasts[i], at); entry != nil {
+			*advisories[i] = entry.Weather.Main.Message
+		}
+	}
+
+	// Charging is planned as by TripCharge with its default radius, the briefing is sent without it if the stations
+	// are unavailable
+	if trip.Profile != "" {
+		profile, err := database.GetProfile(trip.Profile)
+		if err == nil && (profile.Fuel == FuelElectric || profile.Fuel == FuelHybrid) && profile.Range != 0 {
+			filters := "&radius=" + TripChargeQuery[0].Default
+			if len(profile.Connectors) != 0 {
+				filters += "&connectorSet=" + strings.Join(profile.Connectors, ",")
+			}
+			stops, _, err := chargingStops(planned, profile, filters, utils.Metric)
+			if err != nil {
+				log.Println("Unable to plan the charging for the briefing of the trip with ID: " + trip.ID + "\n" + err.Error())
+			} else {
+				briefing.ChargingStops = stops
+			}
+		}
+	}
+	return briefing, nil
+}
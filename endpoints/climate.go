@@ -0,0 +1,216 @@
+package endpoints
+
+import (
+	"cloudproject/database"
+	"cloudproject/i18n"
+	"cloudproject/router"
+	"cloudproject/structs"
+	"cloudproject/utils"
+	"encoding/json"
+	"fmt"
+	"log"
+	"math"
+	"net/http"
+	"net/url"
+	"strconv"
+	"time"
+)
+
+// Limits of the climate statistics
+const (
+	maxClimateDays        = 366    // Longest date range
+	maxStationDistance    = 100000 // Meters from the place to the nearest station
+	precipitationDayLimit = 0.5    // Chance of precipitation from which the typical day has precipitation
+	cloudyDayLimit        = 0.3    // Chance of precipitation from which the typical day is cloudy
+)
+
+// ClimateQuery The query parameters accepted by Climate
+var ClimateQuery = utils.QuerySchema{
+	{Name: "from", Type: utils.TypeDate, Description: "First day of the range, the year is only used to count the days",
+		Required: true},
+	{Name: "to", Type: utils.TypeDate, Description: "Last day of the range, the same as from by default"},
+	i18n.LangParam,
+	utils.UnitsParam,
+}
+
+// dailyNormals The climate normals of a single day, in metric units
+type dailyNormals struct {
+	tempMean, tempMin, tempMax float64
+	precipitation              float64 // mm
+	precipitationChance        float64
+	snowChance                 float64
+	humidity                   float64
+	windSpeed                  float64
+}
+
+// Climate Gives the usual weather at a place in a date range, from the climate normals of the nearest station
+func Climate(w http.ResponseWriter, request *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	presentation := PresentationOf(request)
+	w.Header().Set("Content-Language", presentation.Locale)
+
+	// Gets the name of the place from the path
+	address := router.Param(request, "place")
+
+	query := router.Query(request)
+	from, to := query.Date("from"), query.Date("to")
+	if to.IsZero() {
+		to = from
+	}
+	if to.Before(from) {
+		http.Error(w, "The last day of the range must not be before the first", http.StatusBadRequest)
+		return
+	}
+	if to.Sub(from) >= maxClimateDays*24*time.Hour {
+		http.Error(w, "The date range can be at most "+strconv.Itoa(maxClimateDays)+" days", http.StatusBadRequest)
+		return
+	}
+
+	//Receives the latitude and longitude of the place passed in the url
+	latitude, longitude, err := database.LocationPresent(url.QueryEscape(address))
+	if err != nil {
+		log.Println("Error: No entries in the database gave the wanted result.\n" + err.Error())
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	lat, errLat := strconv.ParseFloat(latitude, 64)
+	lon, errLon := strconv.ParseFloat(longitude, 64)
+	if errLat != nil || errLon != nil {
+		http.Error(w, "Check formatting of latitude and longitude.", http.StatusBadRequest)
+		return
+	}
+
+	station, distance, err := database.NearestClimateStation(lat, lon)
+	if err == database.ErrNotFound || (err == nil && distance > maxStationDistance) {
+		http.Error(w, "There are no climate normals for a station within "+strconv.Itoa(maxStationDistance/1000)+
+			" km of "+address, http.StatusNotFound)
+		return
+	} else if err != nil {
+		log.Println("Unable to retrieve the climate normals.\n" + err.Error())
+		http.Error(w, "Unable to retrieve the climate normals", http.StatusInternalServerError)
+		return
+	}
+
+	climate := ClimateSummary(station, from, to, presentation)
+	climate.Station.Distance = utils.Distance(distance, presentation.Units)
+
+	result, err := json.Marshal(climate)
+	if err != nil {
+		log.Println("There was an error while marshalling the data.\n" + err.Error())
+		jsonError := utils.JsonMarshalErrorHandling(err)
+		http.Error(w, jsonError.Error(), http.StatusInternalServerError)
+		return
+	}
+	_, err = fmt.Fprintf(w, "%v", string(result))
+	if err != nil {
+		log.Println("There was an error while displaying the output to the user.")
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+	}
+}
+
+// ClimateSummary Summarises the climate normals of the station from the first to the last day, and advises on the
+// typical day with the same messages and road conditions as the current weather, in the presentation
+func ClimateSummary(station structs.ClimateStation, from time.Time, to time.Time, p Presentation) structs.OutputClimate {
+	var sum dailyNormals
+	days := 0
+	for day := from; !day.After(to); day = day.AddDate(0, 0, 1) {
+		normals := normalsOn(station.Months, day)
+		sum.tempMean += normals.tempMean
+		sum.tempMin += normals.tempMin
+		sum.tempMax += normals.tempMax
+		sum.precipitation += normals.precipitation
+		sum.precipitationChance += normals.precipitationChance
+		sum.snowChance += normals.snowChance
+		sum.humidity += normals.humidity
+		sum.windSpeed += normals.windSpeed
+		days++
+	}
+	n := float64(days)
+	chance, snowChance := sum.precipitationChance/n, sum.snowChance/n
+
+	// The typical day, precipitation falls as snow if it snows on at least half of the days with precipitation
+	weather := structs.OutputWeather{
+		Main:       structs.MainStruct{Main: "Clear"},
+		Temp:       structs.TempStruct{Temp: utils.Round(sum.tempMean/n, 1)},
+		FeelsLike:  structs.FeelsLikeStruct{FeelsLike: utils.Round(sum.tempMean/n, 1)},
+		TempMin:    structs.TempMinStruct{TempMin: utils.Round(sum.tempMin/n, 1)},
+		TempMax:    structs.TempMaxStruct{TempMax: utils.Round(sum.tempMax/n, 1)},
+		Humidity:   structs.HumidityStruct{Humidity: int(math.Round(sum.humidity / n))},
+		Visibility: structs.VisibilityStruct{Visibility: unknownVisibility},
+		WindSpeed:  structs.WindSpeedStruct{WindSpeed: utils.Round(sum.windSpeed/n, 1)},
+	}
+	switch {
+	case chance >= precipitationDayLimit:
+		// The amount per hour on a day with precipitation
+		perHour := utils.Round(sum.precipitation/(chance*n)/24, 2)
+		if snowChance >= chance/2 {
+			weather.Main.Main, weather.Snow1h = "Snow", perHour
+		} else {
+			weather.Main.Main, weather.Rain1h = "Rain", perHour
+		}
+	case chance >= cloudyDayLimit:
+		weather.Main.Main = "Clouds"
+	}
+
+	return structs.OutputClimate{
+		Station: structs.ClimateStationInfo{ID: station.ID, Name: station.Name, Latitude: station.Latitude,
+			Longitude: station.Longitude, Elevation: station.Elevation, DistanceUnit: unitsOf(p.Units).Distance},
+		From:                     from.Format(utils.DateLayout),
+		To:                       to.Format(utils.DateLayout),
+		TempMean:                 utils.Temperature(weather.Temp.Temp, p.Units),
+		TempMin:                  utils.Temperature(weather.TempMin.TempMin, p.Units),
+		TempMax:                  utils.Temperature(weather.TempMax.TempMax, p.Units),
+		PrecipitationProbability: utils.Round(chance, 2),
+		Precipitation:            utils.Precipitation(utils.Round(sum.precipitation, 1), p.Units),
+		SnowDays:                 utils.Round(sum.snowChance, 1),
+		Weather:                  advise(weather, from.Add(12*time.Hour), p),
+	}
+}
+
+// normalsOn The climate normals of a day. The normals of a month are for the middle of the month, the days in between
+// are interpolated from the two closest months
+func normalsOn(months []structs.ClimateMonth, day time.Time) dailyNormals {
+	year, month := day.Year(), day.Month()
+	before, after := month-1, month
+	if !day.Before(middleOf(year, month)) {
+		before, after = month, month+1
+	}
+	start, end := middleOf(year, before), middleOf(year, after)
+	weight := float64(day.Sub(start)) / float64(end.Sub(start))
+
+	a, b := monthNormals(months, year, before), monthNormals(months, year, after)
+	mix := func(x float64, y float64) float64 { return x*(1-weight) + y*weight }
+	return dailyNormals{
+		tempMean:            mix(a.tempMean, b.tempMean),
+		tempMin:             mix(a.tempMin, b.tempMin),
+		tempMax:             mix(a.tempMax, b.tempMax),
+		precipitation:       mix(a.precipitation, b.precipitation),
+		precipitationChance: mix(a.precipitationChance, b.precipitationChance),
+		snowChance:          mix(a.snowChance, b.snowChance),
+		humidity:            mix(a.humidity, b.humidity),
+		windSpeed:           mix(a.windSpeed, b.windSpeed),
+	}
+}
+
+// monthNormals The normals of a day in the middle of the month, months outside of the year wrap around
+func monthNormals(months []structs.ClimateMonth, year int, month time.Month) dailyNormals {
+	first := time.Date(year, month, 1, 0, 0, 0, 0, time.UTC)
+	days := float64(first.AddDate(0, 1, -1).Day())
+	normals := months[first.Month()-1]
+	return dailyNormals{
+		tempMean:            normals.TempMean,
+		tempMin:             normals.TempMin,
+		tempMax:             normals.TempMax,
+		precipitation:       normals.Precipitation / days,
+		precipitationChance: normals.PrecipitationDays / days,
+		snowChance:          normals.SnowDays / days,
+		humidity:            float64(normals.Humidity),
+		windSpeed:           normals.WindSpeed,
+	}
+}
+
+// middleOf The middle of the month, months outside of the year wrap around
+func middleOf(year int, month time.Month) time.Time {
+	first := time.Date(year, month, 1, 0, 0, 0, 0, time.UTC)
+	return first.Add(first.AddDate(0, 1, 0).Sub(first) / 2)
+}
@@ -0,0 +1,61 @@
+package endpoints
+
+import (
+	"cloudproject/structs"
+	"math"
+	"testing"
+	"time"
+)
+
+// mountainPass Climate normals where the mean temperature of a month is its number, and it snows every other day
+func mountainPass() structs.ClimateStation {
+	station := structs.ClimateStation{ID: "SN1", Name: "Pass"}
+	for month := 1; month <= 12; month++ {
+		station.Months = append(station.Months, structs.ClimateMonth{Month: month, TempMean: float64(month),
+			TempMin: float64(month) - 5, TempMax: float64(month) + 5, Precipitation: 60, PrecipitationDays: 20,
+			SnowDays: 15, Humidity: 85, WindSpeed: 6})
+	}
+	return station
+}
+
+func TestNormalsOn(t *testing.T) {
+	months := mountainPass().Months
+	tests := []struct {
+		day      time.Time
+		expected float64
+	}{
+		{middleOf(2021, time.April), 4},
+		{middleOf(2021, time.December), 12},
+		{time.Date(2021, 4, 1, 0, 0, 0, 0, time.UTC), 3 + 15.5/30.5},
+		{time.Date(2021, 1, 1, 0, 0, 0, 0, time.UTC), 12 - 11*15.5/31},
+	}
+	for _, test := range tests {
+		if normals := normalsOn(months, test.day); math.Abs(normals.tempMean-test.expected) > 1e-9 {
+			t.Errorf("%v: expected the mean temperature %v; got %v", test.day, test.expected, normals.tempMean)
+		}
+	}
+}
+
+func TestClimateSummary(t *testing.T) {
+	from := time.Date(2021, 4, 1, 0, 0, 0, 0, time.UTC)
+	climate := ClimateSummary(mountainPass(), from, from.AddDate(0, 0, 9), MetricPresentation("en"))
+
+	if climate.From != "2021-04-01" || climate.To != "2021-04-10" {
+		t.Errorf("Expected the range 2021-04-01 to 2021-04-10; got %v to %v", climate.From, climate.To)
+	}
+	if climate.Weather.Main.Main != "Snow" || climate.Weather.Conditions.Precipitation != PrecipitationSnow {
+		t.Errorf("Expected snow on the typical day; got %v", climate.Weather.Main.Main)
+	}
+	if climate.Weather.Conditions.FreezingRisk != FreezingPossible || climate.Weather.Temp.Message == "" {
+		t.Errorf("Expected a possible freezing risk with advice; got %+v", climate.Weather.Conditions)
+	}
+	if climate.SnowDays < 4.5 || climate.SnowDays > 5.5 || climate.PrecipitationProbability < 0.6 || climate.PrecipitationProbability > 0.7 {
+		t.Errorf("Expected about 5 snow days and a 2 in 3 chance of precipitation; got %v and %v", climate.SnowDays,
+			climate.PrecipitationProbability)
+	}
+
+	imperial := ClimateSummary(mountainPass(), from, from, Presentation{Locale: "en", Units: "imperial"})
+	if imperial.Weather.Units.System != "imperial" || imperial.TempMean < 37 || imperial.TempMean > 39 {
+		t.Errorf("Expected a mean temperature of about 38 °F; got %v", imperial.TempMean)
+	}
+}
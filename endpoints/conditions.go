@@ -0,0 +1,185 @@
+package endpoints
+
+import (
+	"cloudproject/i18n"
+	"cloudproject/structs"
+	"time"
+)
+
+// Classes of the road condition model
+const (
+	PrecipitationNone = "none"
+	PrecipitationRain = "rain"
+	PrecipitationSnow = "snow"
+
+	IntensityNone     = "none"
+	IntensityLight    = "light"
+	IntensityModerate = "moderate"
+	IntensityHeavy    = "heavy"
+	IntensityViolent  = "violent"
+
+	FreezingNone     = "none"
+	FreezingPossible = "possible"
+	FreezingLikely   = "likely"
+
+	VisibilityGood     = "good"
+	VisibilityModerate = "moderate"
+	VisibilityPoor     = "poor"
+	VisibilityFog      = "fog"
+
+	WindLow      = "low"
+	WindModerate = "moderate"
+	WindHigh     = "high"
+	WindSevere   = "severe"
+)
+
+// riskPoints Points added to the risk score, and the extra delay factor, for each class
+var riskPoints = map[string]struct {
+	points int
+	delay  float64
+}{
+	PrecipitationRain + "/" + IntensityLight:    {5, 0},
+	PrecipitationRain + "/" + IntensityModerate: {15, 0.2},
+	PrecipitationRain + "/" + IntensityHeavy:    {30, 0.5},
+	PrecipitationRain + "/" + IntensityViolent:  {45, 0.8},
+	PrecipitationSnow + "/" + IntensityLight:    {20, 0.2},
+	PrecipitationSnow + "/" + IntensityModerate: {35, 0.6},
+	PrecipitationSnow + "/" + IntensityHeavy:    {50, 1.0},
+	"freezing/" + FreezingPossible:              {15, 0.1},
+	"freezing/" + FreezingLikely:                {30, 0.3},
+	"visibility/" + VisibilityModerate:          {5, 0},
+	"visibility/" + VisibilityPoor:              {20, 0.2},
+	"visibility/" + VisibilityFog:               {35, 0.4},
+	"wind/" + WindModerate:                      {10, 0},
+	"wind/" + WindHigh:                          {25, 0.2},
+	"wind/" + WindSevere:                        {40, 0.4},
+	"darkness":                                  {10, 0.1},
+}
+
+// AssessConditions Classifies the weather at the given time into the road condition model, and calculates the risk score
+// and the delay factor with the reasons behind them, described in the presentation. The weather is in metric units
+func AssessConditions(weather structs.OutputWeather, at time.Time, p Presentation) structs.RoadConditions {
+	locale := p.Locale
+	conditions := structs.RoadConditions{
+		Precipitation:          PrecipitationNone,
+		PrecipitationIntensity: IntensityNone,
+		FreezingRisk:           FreezingNone,
+		Visibility:             VisibilityGood,
+		WindRisk:               WindLow,
+		Daylight:               true,
+		DelayFactor:            1,
+		Reasons:                []structs.RiskReason{},
+	}
+
+	// Precipitation, the intensities are the amounts per hour used by the messages
+	switch weather.Main.Main {
+	case "Rain", "Drizzle", "Thunderstorm":
+		conditions.Precipitation = PrecipitationRain
+		switch {
+		case weather.Rain1h <= 2:
+			conditions.PrecipitationIntensity = IntensityLight
+		case weather.Rain1h <= 7:
+			conditions.PrecipitationIntensity = IntensityModerate
+		case weather.Rain1h <= 50:
+			conditions.PrecipitationIntensity = IntensityHeavy
+		default:
+			conditions.PrecipitationIntensity = IntensityViolent
+		}
+	case "Snow":
+		conditions.Precipitation = PrecipitationSnow
+		switch {
+		case weather.Snow1h <= 1:
+			conditions.PrecipitationIntensity = IntensityLight
+		case weather.Snow1h <= 3:
+			conditions.PrecipitationIntensity = IntensityModerate
+		default:
+			conditions.PrecipitationIntensity = IntensityHeavy
+		}
+	}
+	if conditions.Precipitation != PrecipitationNone {
+		addReason(&conditions, conditions.Precipitation+"/"+conditions.PrecipitationIntensity, "precipitation",
+			i18n.Text(locale, "reason."+conditions.Precipitation+"."+conditions.PrecipitationIntensity, nil))
+	}
+
+	// Freezing risk, ice forms on wet roads below zero, and may form where the temperature drops below zero during the day
+	temp := weather.Temp.Temp
+	wet := conditions.Precipitation != PrecipitationNone || weather.Humidity.Humidity > 80
+	switch {
+	case temp <= 0 && wet:
+		conditions.FreezingRisk = FreezingLikely
+	case temp <= 0, weather.TempMin.TempMin <= 0, temp <= 2 && conditions.Precipitation == PrecipitationRain:
+		conditions.FreezingRisk = FreezingPossible
+	}
+	if conditions.FreezingRisk != FreezingNone {
+		addReason(&conditions, "freezing/"+conditions.FreezingRisk, "freezing",
+			i18n.Text(locale, "reason.freezing."+conditions.FreezingRisk, p.temperature(temp)))
+	}
+
+	// Visibility, fog and mist are reported as fog even when the visibility is not given
+	visibility := weather.Visibility.Visibility
+	switch {
+	case visibility < 1000 || weather.Main.Main == "Fog":
+		conditions.Visibility = VisibilityFog
+	case visibility < 4000 || weather.Main.Main == "Mist":
+		conditions.Visibility = VisibilityPoor
+	case visibility < 10000:
+		conditions.Visibility = VisibilityModerate
+	}
+	if conditions.Visibility != VisibilityGood {
+		addReason(&conditions, "visibility/"+conditions.Visibility, "visibility",
+			i18n.Text(locale, "reason.visibility."+conditions.Visibility, p.visibility(visibility)))
+	}
+
+	// Wind risk, from the gusts if they are reported
+	wind := weather.WindGust
+	if wind < weather.WindSpeed.WindSpeed {
+		wind = weather.WindSpeed.WindSpeed
+	}
+	switch {
+	case wind >= 24:
+		conditions.WindRisk = WindSevere
+	case wind >= 17:
+		conditions.WindRisk = WindHigh
+	case wind >= 10:
+		conditions.WindRisk = WindModerate
+	}
+	if conditions.WindRisk != WindLow {
+		addReason(&conditions, "wind/"+conditions.WindRisk, "wind",
+			i18n.Text(locale, "reason.wind."+conditions.WindRisk, p.speed("gust", wind)))
+	}
+
+	// Daylight, assumed if the sunrise and sunset are not known
+	if weather.Sunrise.Sunrise != 0 && weather.Sunset.Sunset != 0 {
+		sunrise := time.Unix(int64(weather.Sunrise.Sunrise), 0)
+		sunset := time.Unix(int64(weather.Sunset.Sunset), 0)
+		conditions.Daylight = !at.Before(sunrise) && at.Before(sunset)
+	}
+	if !conditions.Daylight {
+		addReason(&conditions, "darkness", "daylight", i18n.Text(locale, "reason.darkness", nil))
+	}
+
+	if conditions.RiskScore > 100 {
+		conditions.RiskScore = 100
+	}
+	return conditions
+}
+
+// addReason Adds the points and delay of a class to the conditions, with the reason
+func addReason(conditions *structs.RoadConditions, class string, factor string, description string) {
+	risk := riskPoints[class]
+	conditions.RiskScore += risk.points
+	conditions.DelayFactor += risk.delay
+	conditions.Reasons = append(conditions.Reasons, structs.RiskReason{Factor: factor, Points: risk.points, Description: description})
+}
+
+// ConditionsSlowdown The share of the travel time lost for each step of the delay factor above 1, a delay factor of
+// 2 makes a trip a quarter longer
+const ConditionsSlowdown = 0.25
+
+// GetConditionsWeight Calculates how much time needs to be added to the travel time in the road conditions
+func GetConditionsWeight(conditions structs.RoadConditions, travel time.Duration) time.Duration {
+	if conditions.DelayFactor <= 1 {
+		return 0
+	}
+	return time.Duration(float64(travel) * (conditions.DelayFactor - 1) * ConditionsSlowdown).Round(time.Second)
+}
@@ -0,0 +1,65 @@
+package endpoints
+
+import (
+	"cloudproject/structs"
+	"testing"
+	"time"
+)
+
+func TestAssessConditions(t *testing.T) {
+	noon := time.Date(2021, 5, 10, 12, 0, 0, 0, time.UTC)
+	daylight := func(weather structs.OutputWeather) structs.OutputWeather {
+		weather.Sunrise.Sunrise = int(noon.Add(-8 * time.Hour).Unix())
+		weather.Sunset.Sunset = int(noon.Add(8 * time.Hour).Unix())
+		weather.Visibility.Visibility = 10000
+		return weather
+	}
+
+	tests := []struct {
+		name     string
+		weather  structs.OutputWeather
+		at       time.Time
+		expected structs.RoadConditions
+		weight   time.Duration
+	}{
+		{"clear day", daylight(structs.OutputWeather{Main: structs.MainStruct{Main: "Clear"},
+			Temp: structs.TempStruct{Temp: 15}, TempMin: structs.TempMinStruct{TempMin: 8}}), noon,
+			structs.RoadConditions{Precipitation: PrecipitationNone, PrecipitationIntensity: IntensityNone,
+				FreezingRisk: FreezingNone, Visibility: VisibilityGood, WindRisk: WindLow, Daylight: true, RiskScore: 0}, 0},
+		{"heavy snow below zero", daylight(structs.OutputWeather{Main: structs.MainStruct{Main: "Snow"}, Snow1h: 4,
+			Temp: structs.TempStruct{Temp: -3}, TempMin: structs.TempMinStruct{TempMin: -6}}), noon,
+			structs.RoadConditions{Precipitation: PrecipitationSnow, PrecipitationIntensity: IntensityHeavy,
+				FreezingRisk: FreezingLikely, Visibility: VisibilityGood, WindRisk: WindLow, Daylight: true, RiskScore: 80}, 19*time.Minute + 30*time.Second},
+		{"moderate rain and gusts at night", daylight(structs.OutputWeather{Main: structs.MainStruct{Main: "Rain"}, Rain1h: 5,
+			Temp: structs.TempStruct{Temp: 8}, TempMin: structs.TempMinStruct{TempMin: 4}, WindGust: 18}), noon.Add(10 * time.Hour),
+			structs.RoadConditions{Precipitation: PrecipitationRain, PrecipitationIntensity: IntensityModerate,
+				FreezingRisk: FreezingNone, Visibility: VisibilityGood, WindRisk: WindHigh, Daylight: false, RiskScore: 50}, 7*time.Minute + 30*time.Second},
+		{"fog", structs.OutputWeather{Main: structs.MainStruct{Main: "Fog"}, Visibility: structs.VisibilityStruct{Visibility: 500},
+			Temp: structs.TempStruct{Temp: 5}, TempMin: structs.TempMinStruct{TempMin: 1}}, noon,
+			structs.RoadConditions{Precipitation: PrecipitationNone, PrecipitationIntensity: IntensityNone,
+				FreezingRisk: FreezingNone, Visibility: VisibilityFog, WindRisk: WindLow, Daylight: true, RiskScore: 35}, 6 * time.Minute},
+	}
+	for _, test := range tests {
+		conditions := AssessConditions(test.weather, test.at, MetricPresentation("en"))
+		if !sameClasses(conditions, test.expected) {
+			t.Errorf("%v: expected %+v; got %+v", test.name, test.expected, conditions)
+		}
+		points := 0
+		for _, reason := range conditions.Reasons {
+			points += reason.Points
+		}
+		if points != conditions.RiskScore {
+			t.Errorf("%v: the reasons add up to %v, but the risk score is %v", test.name, points, conditions.RiskScore)
+		}
+		if weight := GetConditionsWeight(conditions, time.Hour); weight != test.weight {
+			t.Errorf("%v: expected a delay of %v on an hour of driving; got %v", test.name, test.weight, weight)
+		}
+	}
+}
+
+// sameClasses Compares the classes and risk score of the conditions
+func sameClasses(a structs.RoadConditions, b structs.RoadConditions) bool {
+	return a.Precipitation == b.Precipitation && a.PrecipitationIntensity == b.PrecipitationIntensity &&
+		a.FreezingRisk == b.FreezingRisk && a.Visibility == b.Visibility && a.WindRisk == b.WindRisk &&
+		a.Daylight == b.Daylight && a.RiskScore == b.RiskScore
+}
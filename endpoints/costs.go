@@ -0,0 +1,117 @@
+package endpoints
+
+import (
+	"cloudproject/database"
+	"cloudproject/structs"
+	"cloudproject/utils"
+	"log"
+	"math"
+	"os"
+	"strconv"
+)
+
+/**
+ * Class costs.go
+ * What a route costs: the toll stations passed, the ferry fares, and the fuel or electricity used
+ * The toll stations are the imported toll points close to the route, and the prices of the toll points and the ferries
+ * depend on the vehicle class. The energy is priced by FUEL_PRICE and ELECTRICITY_PRICE.
+ */
+
+// Vehicle classes, which the prices of tolls and ferries and the kind of energy depend on
+const (
+	VehicleCar     = "car"     // A car running on fuel
+	VehicleEV      = "ev"      // An electric car
+	VehicleTrailer = "trailer" // A car running on fuel, towing a trailer or caravan
+)
+
+// Currency The currency of the prices
+const Currency = "NOK"
+
+// TollMatchDistance How far, in meters, a toll point can be from a route and still be passed
+const TollMatchDistance = 100
+
+// DefaultConsumption The consumption of the vehicle classes, in liters or kWh per 100 km
+var DefaultConsumption = map[string]float64{VehicleCar: 6.5, VehicleEV: 18, VehicleTrailer: 9.5}
+
+var (
+	// FuelPrice The price of a liter of fuel, FUEL_PRICE sets it
+	FuelPrice = priceFromEnv("FUEL_PRICE", 21.5)
+	// ElectricityPrice The price of a kWh of electricity, ELECTRICITY_PRICE sets it
+	ElectricityPrice = priceFromEnv("ELECTRICITY_PRICE", 4.5)
+)
+
+// VehicleParams The query parameters for the vehicle a cost is calculated for
+var VehicleParams = utils.QuerySchema{
+	{Name: "vehicle", Type: utils.TypeString, Description: "Class of the vehicle, which the tolls, ferry fares and energy depend on. That of the profile, or else car, if it is not given",
+		Enum: []string{VehicleCar, VehicleEV, VehicleTrailer}},
+	{Name: "consumption", Type: utils.TypeNumber, Description: "Liters of fuel, or kWh for an ev, per 100 km. The usual consumption of the vehicle class if it is not given",
+		Minimum: utils.Bound(0), Maximum: utils.Bound(100)},
+}
+
+// priceFromEnv Reads a price from an environment variable, or uses the default if it is not a valid price
+func priceFromEnv(name string, fallback float64) float64 {
+	value := os.Getenv(name)
+	if value == "" {
+		return fallback
+	}
+	price, err := strconv.ParseFloat(value, 64)
+	if err != nil || price < 0 {
+		log.Println(name + " is not a valid price, " + strconv.FormatFloat(fallback, 'f', -1, 64) + " is used instead.")
+		return fallback
+	}
+	return price
+}
+
+// RouteCost Calculates the cost of the route for the vehicle class, with the ferries found on it. A consumption of 0
+// is the usual consumption of the vehicle class
+func RouteCost(roads structs.RouteStruct, ferries []structs.FerryLeg, vehicle string, consumption float64) structs.RouteCost {
+	cost := structs.RouteCost{Vehicle: vehicle, Currency: Currency, Tolls: []structs.CostItem{}, Ferries: []structs.CostItem{}}
+
+	tolls, err := database.TollPoints()
+	if err != nil {
+		log.Println("The toll points are unavailable, the route is not checked for tolls.\n" + err.Error())
+	}
+	path := routePath(roads)
+	for _, toll := range tolls {
+		if utils.DistanceToPath(utils.Coordinate{Latitude: toll.Latitude, Longitude: toll.Longitude}, path) <= TollMatchDistance {
+			cost.Tolls = append(cost.Tolls, structs.CostItem{Name: toll.Name, Road: toll.Road, Cost: toll.Prices[vehicle]})
+			cost.Total += toll.Prices[vehicle]
+		}
+	}
+
+	for _, ferry := range ferries {
+		if ferry.Fare > 0 {
+			cost.Ferries = append(cost.Ferries, structs.CostItem{Name: ferry.Route, Cost: ferry.Fare})
+			cost.Total += ferry.Fare
+		}
+	}
+
+	if consumption == 0 {
+		consumption = DefaultConsumption[vehicle]
+	}
+	cost.Energy = structs.EnergyCost{Consumption: consumption, Unit: "l", Price: FuelPrice}
+	if vehicle == VehicleEV {
+		cost.Energy.Unit, cost.Energy.Price = "kWh", ElectricityPrice
+	}
+	// No energy is used on the ferries
+	driven := math.Max(0, float64(roads.Routes[0].Summary.LengthInMeters)-ferryDistance(roads))
+	cost.Energy.Amount = utils.Round(driven/100000*consumption, 1)
+	cost.Energy.Cost = utils.Round(cost.Energy.Amount*cost.Energy.Price, 2)
+	cost.Total = utils.Round(cost.Total+cost.Energy.Cost, 2)
+	return cost
+}
+
+// ferryDistance The length in meters of the ferry crossings of the route, along their points
+func ferryDistance(roads structs.RouteStruct) float64 {
+	path := routePath(roads)
+	distance := 0.0
+	for _, section := range roads.Routes[0].Sections {
+		if section.SectionType != "FERRY" {
+			continue
+		}
+		for i := section.StartPointIndex + 1; i <= section.EndPointIndex && i < len(path); i++ {
+			distance += utils.Haversine(path[i-1].Latitude, path[i-1].Longitude, path[i].Latitude, path[i].Longitude)
+		}
+	}
+	return distance
+}
@@ -0,0 +1,295 @@
+package endpoints
+
+import (
+	"cloudproject/i18n"
+	"cloudproject/router"
+	"cloudproject/structs"
+	"cloudproject/utils"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"math"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+/**
+ * Class departure.go
+ * Departure planner: when to depart to arrive by a time
+ * TomTom predicts the travel time in the traffic for several departures around the one it plans for arriving in time,
+ * and the road conditions forecast at the start and at the destination add their delay to each. The departure is
+ * recommended from the prediction made closest to it, with the spread of the predictions and the weather as the
+ * uncertainty.
+ */
+
+// Defaults of the departure planner
+const (
+	DefaultCandidates = 5
+	DefaultInterval   = 15 * time.Minute
+)
+
+// departureParams The query parameters for the departures to predict the travel time for
+var departureParams = utils.QuerySchema{
+	{Name: "candidates", Type: utils.TypeInteger, Description: "Number of departures to predict the travel time for",
+		Default: "5", Minimum: utils.Bound(1), Maximum: utils.Bound(9)},
+	{Name: "interval", Type: utils.TypeInteger, Description: "Minutes between the departures",
+		Default: "15", Minimum: utils.Bound(5), Maximum: utils.Bound(60)},
+	i18n.LangParam,
+}
+
+// DepartureQuery The query parameters accepted by Departure
+var DepartureQuery = append(utils.QuerySchema{
+	{Name: "arriveBy", Type: utils.TypeDateTime, Description: "Time to arrive by", Required: true},
+	RouteTypeParam, PreferenceParams[0], ProfileParam,
+}, departureParams...)
+
+// TripDepartureQuery The query parameters accepted by TripDeparture
+var TripDepartureQuery = departureParams
+
+// DepartureRequest The route a departure is planned for, and when to arrive
+type DepartureRequest struct {
+	Coordinates string
+	Profile     *structs.VehicleProfile
+	Preferences structs.RoutePreferences // The departure, arrival and traffic are left out
+	ArriveBy    time.Time
+	Candidates  int           // Departures to predict the travel time for, DefaultCandidates if 0
+	Interval    time.Duration // Between the departures, DefaultInterval if 0
+	Locale      string        // Of the reasons of the road conditions
+}
+
+// candidate A departure with the travel time predicted for it
+type candidate struct {
+	departure  time.Time
+	arrival    time.Time     // Including the delays, in the time zone of the destination
+	travel     time.Duration // Driving in the traffic
+	traffic    time.Duration // Of the travel, caused by the traffic
+	weather    time.Duration
+	wait       time.Duration // For ferries
+	forecast   bool          // Whether the weather is forecast at both ends
+	conditions *structs.RoadConditions
+}
+
+// total The travel time of the candidate, with the delays
+func (c candidate) total() time.Duration {
+	return c.travel + c.weather + c.wait
+}
+
+// Departure Responds with when to depart from the start to arrive at the destination by the time in the query
+func Departure(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	presentation := PresentationOf(r)
+	w.Header().Set("Content-Language", presentation.Locale)
+	query := router.Query(r)
+
+	trip, status, err := parseRouteRequest(r)
+	if err != nil {
+		http.Error(w, err.Error(), status)
+		return
+	}
+	writeDeparture(w, DepartureRequest{Coordinates: trip.coordinates, Profile: trip.profile, Preferences: trip.preferences,
+		ArriveBy: query.Time("arriveBy"), Candidates: query.Int("candidates"),
+		Interval: time.Duration(query.Int("interval")) * time.Minute, Locale: presentation.Locale})
+}
+
+// TripDeparture Responds with when to depart on the trip with the id in the path to arrive by its planned arrival
+func TripDeparture(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	presentation := PresentationOf(r)
+	w.Header().Set("Content-Language", presentation.Locale)
+	query := router.Query(r)
+
+	trip, found := loadTrip(w, r)
+	if !found {
+		return
+	}
+	arriveBy, err := time.Parse(time.RFC3339, trip.Arrival)
+	if err != nil {
+		http.Error(w, "Only trips with a planned arrival have a departure to plan", http.StatusBadRequest)
+		return
+	}
+	request, status, err := tripRouteRequest(trip, query)
+	if err != nil {
+		http.Error(w, err.Error(), status)
+		return
+	}
+	writeDeparture(w, DepartureRequest{Coordinates: request.coordinates, Profile: request.profile, Preferences: request.preferences,
+		ArriveBy: arriveBy, Candidates: query.Int("candidates"), Interval: time.Duration(query.Int("interval")) * time.Minute,
+		Locale: presentation.Locale})
+}
+
+// writeDeparture Plans the departure, and answers with it
+func writeDeparture(w http.ResponseWriter, request DepartureRequest) {
+	plan, status, err := PlanDeparture(request)
+	if err != nil {
+		http.Error(w, err.Error(), status)
+		return
+	}
+	output, err := json.Marshal(plan)
+	if err != nil {
+		log.Println("Unable to marshall response: " + "\n" + err.Error())
+		http.Error(w, utils.JsonMarshalErrorHandling(err).Error(), http.StatusInternalServerError)
+		return
+	}
+	fmt.Fprintf(w, "%v", string(output))
+}
+
+// PlanDeparture Predicts the travel time in the traffic and the weather for departures around the one TomTom plans for
+// arriving in time, and recommends when to depart. Returns the status to answer with if the departure can not be planned
+func PlanDeparture(request DepartureRequest) (structs.DeparturePlan, int, error) {
+	if !request.ArriveBy.After(time.Now()) {
+		return structs.DeparturePlan{}, http.StatusBadRequest, errors.New("The time to arrive by must be in the future")
+	}
+	if request.Candidates <= 0 {
+		request.Candidates = DefaultCandidates
+	}
+	if request.Interval <= 0 {
+		request.Interval = DefaultInterval
+	}
+
+	trip := routeRequest{coordinates: request.Coordinates, profile: request.Profile, preferences: request.Preferences,
+		vehicle: VehicleClass(request.Profile)}
+	trip.preferences.Traffic = true
+	trip.preferences.DepartAt = ""
+	trip.preferences.ArriveAt = request.ArriveBy.Format(time.RFC3339)
+	roads, status, err := FetchRoute(trip.coordinates, RouteOptions(trip.profile, trip.preferences), nil)
+	if err != nil {
+		return structs.DeparturePlan{}, status, err
+	}
+	planned := trip.plan(roads)
+	forecasts := endForecasts(planned.roads, request.Locale)
+
+	// The departures are spread around the one arriving in time after waiting for the ferries
+	center := planned.departure.Add(request.ArriveBy.Sub(planned.arrival))
+	trip.preferences.ArriveAt = ""
+	var candidates []candidate
+	for _, at := range departureTimes(center, request.Candidates, request.Interval, time.Now()) {
+		trip.preferences.DepartAt = at.Format(time.RFC3339)
+		roads, status, err := FetchRoute(trip.coordinates, RouteOptions(trip.profile, trip.preferences), nil)
+		if err != nil {
+			return structs.DeparturePlan{}, status, err
+		}
+		candidates = append(candidates, predict(trip.plan(roads), forecasts))
+	}
+
+	best, departure, uncertainty := recommend(request.ArriveBy, candidates)
+	plan := structs.DeparturePlan{ArriveBy: request.ArriveBy.Format(time.RFC3339), Departure: departure.Format(time.RFC3339),
+		Earliest: departure.Add(-uncertainty).Format(time.RFC3339), Latest: departure.Add(uncertainty).Format(time.RFC3339),
+		TravelTime: minutes(candidates[best].total()), Uncertainty: minutes(uncertainty)}
+	for i, c := range candidates {
+		plan.Candidates = append(plan.Candidates, structs.DepartureCandidate{Departure: c.departure.Format(time.RFC3339),
+			Arrival: c.arrival.Format(time.RFC3339), TravelTime: minutes(c.total()), TrafficDelay: minutes(c.traffic),
+			WeatherDelay: minutes(c.weather), FerryWait: minutes(c.wait), Forecast: c.forecast, Conditions: c.conditions,
+			Recommended: i == best})
+	}
+	return plan, http.StatusOK, nil
+}
+
+// departureTimes The departures spread by the interval around the center, those which have passed are moved to the next
+// whole minute
+func departureTimes(center time.Time, count int, interval time.Duration, now time.Time) []time.Time {
+	earliest := now.Truncate(time.Minute).Add(time.Minute)
+	var times []time.Time
+	for i := 0; i < count; i++ {
+		at := center.Add(time.Duration(i-(count-1)/2) * interval).Truncate(time.Second)
+		if at.Before(earliest) {
+			at = earliest
+		}
+		if len(times) == 0 || at.After(times[len(times)-1]) {
+			times = append(times, at)
+		}
+	}
+	return times
+}
+
+// endForecasts The forecasts at the start and at the destination of the route, nil where the forecast is unavailable
+func endForecasts(roads structs.RouteStruct, locale string) []*structs.OutputForecast {
+	forecasts := make([]*structs.OutputForecast, 2)
+	path := routePath(roads)
+	if len(path) == 0 {
+		return forecasts
+	}
+	for i, point := range []utils.Coordinate{path[0], path[len(path)-1]} {
+		forecast, err := FetchForecast(forecastURL(point), MetricPresentation(locale))
+		if err != nil {
+			log.Println("Unable to get the forecast for the departure planner.\n" + err.Error())
+			continue
+		}
+		forecasts[i] = &forecast
+	}
+	return forecasts
+}
+
+// predict The travel time of the planned route with the delay of the worse of the road conditions forecast at the start
+// when departing and at the destination when arriving. There is no delay for the weather beyond the forecasts
+func predict(planned plannedRoute, forecasts []*structs.OutputForecast) candidate {
+	summary := planned.roads.Routes[0].Summary
+	c := candidate{departure: planned.departure, travel: time.Duration(summary.TravelTimeInSeconds) * time.Second,
+		traffic: time.Duration(summary.TrafficDelayInSeconds) * time.Second, wait: planned.wait, forecast: true}
+
+	for i, at := range []time.Time{c.departure, c.departure.Add(c.travel)} {
+		var entry *structs.ForecastEntry
+		if forecasts[i] != nil {
+			entry = forecastAt(*forecasts[i], at)
+		}
+		if entry == nil {
+			c.forecast = false
+			continue
+		}
+		if c.conditions == nil || entry.Weather.Conditions.DelayFactor > c.conditions.DelayFactor {
+			conditions := entry.Weather.Conditions
+			c.conditions = &conditions
+		}
+	}
+	if c.conditions != nil {
+		c.weather = GetConditionsWeight(*c.conditions, c.travel)
+	}
+	c.arrival = planned.arrival.Add(c.weather)
+	return c
+}
+
+// recommend Chooses the candidate whose travel time was predicted closest to the departure it gives for arriving by the
+// time, and returns it with the departure and its uncertainty. The uncertainty is 5% of the travel time, half the spread
+// of the predicted travel times and half the delay for the weather, or 10% of the travel time if the weather is not
+// forecast
+func recommend(arriveBy time.Time, candidates []candidate) (int, time.Time, time.Duration) {
+	best, bestOffset := 0, time.Duration(math.MaxInt64)
+	shortest, longest := candidates[0].total(), candidates[0].total()
+	for i, c := range candidates {
+		offset := c.departure.Sub(arriveBy.Add(-c.total()))
+		if offset < 0 {
+			offset = -offset
+		}
+		if offset < bestOffset {
+			best, bestOffset = i, offset
+		}
+		if c.total() < shortest {
+			shortest = c.total()
+		}
+		if c.total() > longest {
+			longest = c.total()
+		}
+	}
+
+	chosen := candidates[best]
+	uncertainty := chosen.total()/20 + (longest-shortest)/2
+	if chosen.forecast {
+		uncertainty += chosen.weather / 2
+	} else {
+		uncertainty += chosen.total() / 10
+	}
+	return best, arriveBy.Add(-chosen.total()), uncertainty.Round(time.Second)
+}
+
+// minutes The duration in whole minutes
+func minutes(duration time.Duration) int {
+	return int(duration.Minutes())
+}
+
+// forecastURL The url of the hourly and daily forecast at the point
+func forecastURL(point utils.Coordinate) string {
+	return utils.OpenWeatherMapURL + "/data/2.5/onecall?lat=" + strconv.FormatFloat(point.Latitude, 'f', 6, 64) +
+		"&lon=" + strconv.FormatFloat(point.Longitude, 'f', 6, 64) + "&exclude=current,minutely,alerts&units=metric&appid=" +
+		utils.OpenweathermapKey
+}
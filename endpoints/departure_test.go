@@ -0,0 +1,53 @@
+package endpoints
+
+import (
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestRecommend(t *testing.T) {
+	at := func(hour int, minute int) time.Time {
+		return time.Date(2021, 5, 17, hour, minute, 0, 0, time.UTC)
+	}
+	candidates := []candidate{
+		{departure: at(10, 30), travel: 80 * time.Minute},
+		{departure: at(10, 45), travel: 60 * time.Minute, weather: 10 * time.Minute, forecast: true},
+		{departure: at(11, 0), travel: 50 * time.Minute},
+	}
+
+	// The travel time predicted at 10:45 gives a departure at 10:50, closer than the others. The uncertainty is 5% of
+	// the travel time, half the spread from 50 to 80 minutes and half the weather delay
+	best, departure, uncertainty := recommend(at(12, 0), candidates)
+	if best != 1 || !departure.Equal(at(10, 50)) || uncertainty != 23*time.Minute+30*time.Second {
+		t.Errorf("Expected to depart at 10:50 give or take 23m30s; got candidate %v at %v give or take %v", best, departure, uncertainty)
+	}
+
+	// Without a forecast 10% of the travel time is added instead
+	candidates[1].forecast = false
+	if _, _, uncertainty := recommend(at(12, 0), candidates); uncertainty != 25*time.Minute+30*time.Second {
+		t.Errorf("Expected the uncertainty to be 25m30s without a forecast; got %v", uncertainty)
+	}
+}
+
+func TestDepartureTimes(t *testing.T) {
+	center := time.Date(2021, 5, 17, 12, 0, 30, 0, time.UTC)
+	at := func(minutes int, seconds int) time.Time {
+		return time.Date(2021, 5, 17, 11, 0, 0, 0, time.UTC).Add(time.Duration(minutes)*time.Minute + time.Duration(seconds)*time.Second)
+	}
+
+	tests := []struct {
+		name     string
+		now      time.Time
+		expected []time.Time
+	}{
+		{"ahead", at(40, 10), []time.Time{at(45, 30), at(60, 30), at(75, 30), at(90, 30)}},
+		{"first passed", at(50, 10), []time.Time{at(51, 0), at(60, 30), at(75, 30), at(90, 30)}},
+		{"most passed", at(80, 0), []time.Time{at(81, 0), at(90, 30)}},
+	}
+	for _, test := range tests {
+		if times := departureTimes(center, 4, 15*time.Minute, test.now); !reflect.DeepEqual(times, test.expected) {
+			t.Errorf("%v: expected %v; got %v", test.name, test.expected, times)
+		}
+	}
+}
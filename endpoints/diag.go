@@ -1,9 +1,13 @@
 package endpoints
 
 import (
+	"cloudproject/structs"
+	"cloudproject/utils"
+	"encoding/json"
 	"fmt"
 	"log"
 	"net/http"
+	"strconv"
 	"time"
 )
 
@@ -24,7 +28,7 @@ func Diag(w http.ResponseWriter, r *http.Request) {
 	var mapQuestStatusCode int
 
 	// Sends a request to the TomTom API.
-	respTomTom, err := http.Get("https://developer.tomtom.com/")
+	respTomTom, err := http.Get(utils.TomTomURL + "/")
 	// If any errors occur, log it and set the status code to StatusInternalServerError (500),
 	// otherwise set the status code to the received status code (for instance StatusOK, 200).
 	if err != nil {
@@ -36,7 +40,7 @@ func Diag(w http.ResponseWriter, r *http.Request) {
 	}
 
 	// Sends a request to the OpenRouteService API.
-	respOpenRouteService, err := http.Get("https://openrouteservice.org")
+	respOpenRouteService, err := http.Get(utils.OpenRouteServiceURL + "/")
 	// If any errors occur, log it and set the status code to StatusInternalServerError (500),
 	// otherwise set the status code to the received status code (for instance StatusOK, 200).
 	if err != nil {
@@ -48,7 +52,7 @@ func Diag(w http.ResponseWriter, r *http.Request) {
 	}
 
 	// Sends a request to the OpenWeatherMap API.
-	respOpenWeatherMap, err := http.Get("https://api.openweathermap.org/")
+	respOpenWeatherMap, err := http.Get(utils.OpenWeatherMapURL + "/")
 	// If any errors occur, log it and set the status code to StatusInternalServerError (500),
 	// otherwise set the status code to the received status code (for instance StatusOK, 200).
 	if err != nil {
@@ -60,7 +64,7 @@ func Diag(w http.ResponseWriter, r *http.Request) {
 	}
 
 	// Sends a request to the MapQuest API.
-	respMapQuest, err := http.Get("https://open.mapquestapi.com/")
+	respMapQuest, err := http.Get(utils.MapQuestURL + "/")
 	// If any errors occur, log it and set the status code to StatusInternalServerError (500),
 	// otherwise set the status code to the received status code (for instance StatusOK, 200).
 	if err != nil {
@@ -71,6 +75,23 @@ func Diag(w http.ResponseWriter, r *http.Request) {
 		defer respMapQuest.Body.Close()
 	}
 
-	fmt.Fprintf(w, `{"tomtom": "%v", "openrouteservice": "%v", "openweathermap": "%v", "mapquest": "%v", "version": "v1", "uptime": %v}`,
-		tomtomStatusCode, openRouteServiceStatusCode, openWeatherMapStatusCode, mapQuestStatusCode, int(time.Since(Uptime)/time.Second))
+	// The version is the API surface the endpoint is mounted on, for instance "v1" for /rtc/v1/diag
+	version := APIVersion(r)
+
+	output, err := json.Marshal(structs.Diagnostics{
+		TomTom:           strconv.Itoa(tomtomStatusCode),
+		OpenRouteService: strconv.Itoa(openRouteServiceStatusCode),
+		OpenWeatherMap:   strconv.Itoa(openWeatherMapStatusCode),
+		MapQuest:         strconv.Itoa(mapQuestStatusCode),
+		Version:          version,
+		Uptime:           int(time.Since(Uptime) / time.Second),
+	})
+	if err != nil {
+		jsonError := utils.JsonMarshalErrorHandling(err)
+		log.Println("Unable to marshall diagnostics.\n" + err.Error())
+		http.Error(w, jsonError.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	fmt.Fprintf(w, "%v", string(output))
 }
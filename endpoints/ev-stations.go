@@ -2,26 +2,36 @@ package endpoints
 
 import (
 	"cloudproject/database"
+	"cloudproject/router"
 	structs2 "cloudproject/structs"
 	"cloudproject/utils"
 	"encoding/json"
-	"errors"
 	"fmt"
 	"io/ioutil"
 	"log"
 	"net/http"
 	"net/url"
-	"strconv"
 	"strings"
 )
 
-// EVStations Displays all the electric-vehicle charging stations from a location, within 5 km
+// EVStationsQuery The query parameters accepted by EVStations
+var EVStationsQuery = utils.QuerySchema{
+	{Name: "radius", Type: utils.TypeInteger, Description: "Search radius in meters", Default: "5000",
+		Minimum: utils.Bound(1), Maximum: utils.Bound(50000)},
+	{Name: "connector", Type: utils.TypeString, Description: "Connector types the charging station must have",
+		Enum: outletArray, Aliases: outletsMap, Multiple: true},
+	{Name: "power", Type: utils.TypeNumber, Description: "Minimum charging power in kW", Minimum: utils.Bound(0)},
+	utils.UnitsParam,
+	ProfileParam,
+}
+
+// EVStations Displays all the electric-vehicle charging stations from a location, within 5 km by default
 func EVStations(w http.ResponseWriter, request *http.Request) {
 
 	w.Header().Set("Content-Type", "application/json")
 
 	//Getting the address/name of the place we want to look for chargers
-	address := strings.Split(request.URL.Path, `/`)[4] //Getting the address/name of the place we want to look for chargers
+	address := router.Param(request, "place")
 
 	if address == "" {
 		log.Println("There is no address provided.")
@@ -37,38 +47,70 @@ func EVStations(w http.ResponseWriter, request *http.Request) {
 		return
 	}
 
-	// If there is an optional filter, retrieve it
-	filter, err := utils.GetOptionalFilter(request.URL)
+	// Adds the optional filters, validated by the router against EVStationsQuery
+	query := router.Query(request)
+	profile, status, err := QueryProfile(query)
 	if err != nil {
-		log.Println("Unable to retrieve filter(s).\n" + err.Error())
-		http.Error(w, err.Error(), http.StatusBadRequest)
+		http.Error(w, err.Error(), status)
 		return
 	}
+	filters := "&radius=" + query.Get("radius")
+	if query.Has("connector") {
+		filters += "&connectorSet=" + strings.Join(query.All("connector"), ",")
+	} else if profile != nil && len(profile.Connectors) != 0 {
+		// The connectors of the vehicle, unless others are asked for
+		filters += "&connectorSet=" + strings.Join(profile.Connectors, ",")
+	}
+	if query.Has("power") {
+		filters += "&minPowerKW=" + query.Get("power")
+	}
 
-	var response *http.Response
+	total, status, err := fetchChargers(latitude, longitude, filters, query.Get("units"))
+	if err != nil {
+		http.Error(w, err.Error(), status)
+		return
+	}
 
-	// No filters provided
-	if len(filter) != 0 {
-		// Return the filter
-		connector, power, radius, err := checkOptional(filter)
-		if err != nil {
-			log.Println("There was an error while retrieving filters.\n" + err.Error())
-			http.Error(w, err.Error(), http.StatusBadRequest)
-			return
-		}
-		// Get method with filters
-		response, err = http.Get("https://api.tomtom.com/search/2/nearbySearch/.json?lat=" + latitude + "&lon=" + longitude + radius + "&connectorSet=" + connector + power + "&categorySet=7309&key=" + utils.TomtomKey)
-	} else {
-		// Get method without filters
-		response, err = http.Get("https://api.tomtom.com/search/2/nearbySearch/.json?lat=" + latitude + "&lon=" + longitude + "&radius=5000&categorySet=7309&key=" + utils.TomtomKey)
+	//Checking if the struct is empty
+	if total == nil {
+		log.Println("The json struct is empty.")
+		http.Error(w, "No electric charges in this area", http.StatusNoContent)
+		return
 	}
 
+	// Marshalling the array to JSON
+	output, err := json.Marshal(Versioned(request, total))
+	if err != nil {
+		log.Println("There was an error while marshalling the data.\n" + err.Error())
+		jsonError := utils.JsonMarshalErrorHandling(err)
+		http.Error(w, jsonError.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	// Display the output to the user
+	_, err = fmt.Fprintf(w, "%v", string(output))
+	if err != nil {
+		log.Println("There has been an error displaying the data to the user.")
+		http.Error(w, "There has been an error when displaying the data.", http.StatusInternalServerError)
+		return
+	}
+}
+
+// fetchChargers Gets the charging stations around the coordinates from TomTom, with the search filters. Returns the
+// status to answer with if the search fails
+func fetchChargers(latitude string, longitude string, filters string, units string) ([]structs2.OutputCharge, int, error) {
+	response, err := http.Get(utils.TomTomURL + "/search/2/nearbySearch/.json?lat=" + latitude + "&lon=" + longitude + filters + "&categorySet=7309&key=" + utils.TomtomKey)
+	if err != nil {
+		log.Println("There was an error while requesting charging stations.\n" + err.Error())
+		return nil, http.StatusInternalServerError, err
+	}
+	defer response.Body.Close()
+
 	// Read the response body
 	body, err := ioutil.ReadAll(response.Body)
 	if err != nil {
 		log.Println("There was an error while reading the response body.\n" + err.Error())
-		http.Error(w, err.Error(), http.StatusBadRequest)
-		return
+		return nil, http.StatusBadRequest, err
 
 	}
 
@@ -77,9 +119,7 @@ func EVStations(w http.ResponseWriter, request *http.Request) {
 	// Unmarshalling the body
 	if err = json.Unmarshal(body, &charge); err != nil {
 		log.Println("There was an error during unmarshalling.\n" + err.Error())
-		jsonError := utils.JsonUnmarshalErrorHandling(err)
-		http.Error(w, jsonError.Error(), http.StatusInternalServerError)
-		return
+		return nil, http.StatusInternalServerError, utils.JsonUnmarshalErrorHandling(err)
 	}
 
 	var total []structs2.OutputCharge
@@ -87,6 +127,7 @@ func EVStations(w http.ResponseWriter, request *http.Request) {
 		addressCharge := charge.Results[i].Address.FreeformAddress //Address where the ev station is located
 		chargeName := charge.Results[i].Poi.Name                   //Name of the charger
 		phone := charge.Results[i].Poi.Phone                       //Phone number to charger maintainer
+		distance := utils.Distance(charge.Results[i].Dist, units)  //Distance from the place
 
 		var connector string
 		var power float64
@@ -103,74 +144,12 @@ func EVStations(w http.ResponseWriter, request *http.Request) {
 			}
 		}
 
-		jsonStruct := structs2.OutputCharge{Charger: chargeName, Address: addressCharge, Phone: phone, Connectors: connectorStruct} //Creating a JSON object
-		total = append(total, jsonStruct)                                                                                           //Appending the json object to an array
+		jsonStruct := structs2.OutputCharge{Charger: chargeName, Address: addressCharge, Phone: phone, Connectors: connectorStruct,
+			Distance: distance, DistanceUnit: unitsOf(units).Distance} //Creating a JSON object
+		total = append(total, jsonStruct) //Appending the json object to an array
 	}
 
-	//Checking if the struct is empty
-	if total == nil {
-		log.Println("The json struct is empty.")
-		http.Error(w, "No electric charges in this area", http.StatusNoContent)
-		return
-	}
-
-	// Marshalling the array to JSON
-	output, err := json.Marshal(total)
-	if err != nil {
-		log.Println("There was an error while marshalling the data.\n" + err.Error())
-		jsonError := utils.JsonMarshalErrorHandling(err)
-		http.Error(w, jsonError.Error(), http.StatusInternalServerError)
-		return
-	}
-
-	// Display the output to the user
-	_, err = fmt.Fprintf(w, "%v", string(output))
-	if err != nil {
-		log.Println("There has been an error displaying the data to the user.")
-		http.Error(w, "There has been an error when displaying the data.", http.StatusInternalServerError)
-		return
-	}
-}
-
-// checkOptional Checks if the filter is valid and has the proper input
-func checkOptional(filter map[string]string) (string, string, string, error) {
-	_, foundCharge := filter["connector"]
-	_, foundRadius := filter["radius"]
-	_, foundPower := filter["power"]
-
-	// If statement to check if the user passed in a correct filter, and with a value
-	if !(foundCharge || foundPower || foundRadius) {
-		return "", "", "", errors.New("error, Bad Request\nNone of the filters is accepted\nAccepted filters: radius, charge, power")
-	} else if len(filter["connector"]) == 0 && len(filter["radius"]) == 0 && len(filter["power"]) == 0 {
-		return "", "", "", errors.New("error, Bad Request\nField cannot be empty")
-	}
-
-	connector := ""
-	power := ""
-	radius := "&radius=5000" //Hardcoded value, to satisfy the url, if the user has not passed in the radius
-	if len(filter["connector"]) != 0 {
-		chargingOutlet := outletSearch(filter["connector"]) //Checks if the user has passed in a correct connector outlet
-		if chargingOutlet != "" {
-			connector = "&connectorSet=" + filter["connector"] //Format the filter to support api url
-		} else {
-			return "", "", "", errors.New("Connector Not supported\nThe connector is not supported in our system")
-		}
-	}
-	if len(filter["radius"]) != 0 {
-		if _, err := strconv.Atoi(filter["radius"]); err != nil { //Checks if the user has passed in an int, and not a string
-			return "", "", "", errors.New("Value of radius must be a number\nTry again")
-		} else {
-			radius = "&radius=" + filter["radius"] //Format the filter to support api url
-		}
-	}
-	if len(filter["power"]) != 0 {
-		if _, err := strconv.Atoi(filter["power"]); err != nil { //Checks if the user has passed in an int, and not a string
-			return "", "", "", errors.New("Value of power must be a number\nTry again")
-		} else {
-			power = "&minPowerKW=" + filter["power"] //Format the filter to support api url
-		}
-	}
-	return connector, power, radius, nil
+	return total, http.StatusOK, nil
 }
 
 // outletsMap Map with alternative searches, that will map to the supported name to the API
@@ -200,20 +179,3 @@ var outletArray = []string{
 	"IEC62196Type2Outlet", "IEC62196Type2CCS", "IEC62196Type3", "Chademo", "GBT20234Part2", "GBT20234Part3",
 	"IEC60309AC3PhaseRed", "IEC60309AC1PhaseBlue", "IEC60309DCWhite", "Tesla",
 }
-
-// outletSearch Checks if the user has passed in a supported connector type
-func outletSearch(searchedOutlet string) string {
-	for i := 0; i < len(outletArray); i++ {
-		// If the user passed in a connector with the correct syntax from the API
-		if strings.ToLower(searchedOutlet) == strings.ToLower(outletArray[i]) {
-			return outletArray[i]
-		}
-	}
-	//If the user has passed in a value from the coded map
-	if outlet, found := outletsMap[searchedOutlet]; found {
-		return outlet
-	}
-
-	return ""
-
-}
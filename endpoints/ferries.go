@@ -0,0 +1,204 @@
+package endpoints
+
+import (
+	"cloudproject/database"
+	"cloudproject/structs"
+	"cloudproject/utils"
+	"log"
+	"sort"
+	"time"
+)
+
+/**
+ * Class ferries.go
+ * Ferry crossings on routes, matched to the imported timetable
+ * The crossings are the ferry sections of a TomTom route. A crossing is matched to the timetable route with quays
+ * close to both of its ends, and the wait for the first departure after the arrival at the quay is added to the trip.
+ */
+
+// FerryQuayDistance How far, in meters, a quay of the timetable can be from the end of a crossing and still be its quay
+const FerryQuayDistance = 1000
+
+// FerryDepartures The number of departures given for a crossing
+const FerryDepartures = 3
+
+// ferryDeparture A departure from the timetable, with the arrival at the other quay
+type ferryDeparture struct {
+	departure time.Time
+	arrival   time.Time
+}
+
+// FerryLegs Finds the ferry crossings of the route, for a trip starting at the departure, with the next departures after
+// the expected arrival at each quay and the fare for the vehicle class. Waiting for a ferry delays the arrival at the
+// following quays. Returns the crossings and the total wait
+func FerryLegs(roads structs.RouteStruct, departure time.Time, vehicle string) ([]structs.FerryLeg, time.Duration) {
+	legs := []structs.FerryLeg{}
+	route := roads.Routes[0]
+	var crossings []int
+	for i, section := range route.Sections {
+		if section.SectionType == "FERRY" {
+			crossings = append(crossings, i)
+		}
+	}
+	if len(crossings) == 0 {
+		return legs, 0
+	}
+
+	timetable, err := database.FerryRoutes()
+	if err != nil {
+		log.Println("The ferry timetable is unavailable, the wait for ferries is not estimated.\n" + err.Error())
+	}
+
+	path := routePath(roads)
+	var wait time.Duration
+	for _, i := range crossings {
+		section := route.Sections[i]
+		if section.StartPointIndex >= len(path) || section.EndPointIndex >= len(path) {
+			continue
+		}
+
+		// The travel time to the quay is that of the last instruction before the crossing starts
+		leg := structs.FerryLeg{Departures: []structs.FerryDeparture{}}
+		travelTime := 0
+		for _, instruction := range route.Guidance.Instructions {
+			if instruction.PointIndex > section.StartPointIndex {
+				break
+			}
+			travelTime = instruction.TravelTimeInSeconds
+			if instruction.Maneuver == "TAKE_FERRY" {
+				leg.Route = instruction.Street
+			}
+		}
+		// Whole seconds, as the arrival at the quay is shown, so the wait adds up with the times shown
+		quayArrival := departure.Add(time.Duration(travelTime)*time.Second + wait).Truncate(time.Second)
+		leg.QuayArrival = quayArrival.Format(time.RFC3339)
+
+		ferry, from, to, found := matchFerry(timetable, path[section.StartPointIndex], path[section.EndPointIndex])
+		if found {
+			location := ferryLocation(ferry)
+			quayArrival = quayArrival.In(location)
+			leg.Route, leg.From, leg.To, leg.QuayArrival = ferry.Name, from.Name, to.Name, quayArrival.Format(time.RFC3339)
+			leg.Fare = ferry.Fares[vehicle]
+			departures := nextFerryDepartures(ferry, from.ID, to.ID, quayArrival, FerryDepartures)
+			for _, next := range departures {
+				leg.Departures = append(leg.Departures, structs.FerryDeparture{Departure: next.departure.In(location).Format(time.RFC3339),
+					Arrival: next.arrival.In(location).Format(time.RFC3339)})
+			}
+			if len(departures) != 0 {
+				legWait := departures[0].departure.Sub(quayArrival)
+				leg.Wait = int(legWait.Minutes())
+				wait += legWait
+			}
+		}
+		legs = append(legs, leg)
+	}
+	return legs, wait
+}
+
+// matchFerry Finds the timetable route with quays closest to the start and end of a crossing, within the quay distance
+func matchFerry(timetable []structs.FerryRoute, start utils.Coordinate, end utils.Coordinate) (structs.FerryRoute, structs.FerryStop, structs.FerryStop, bool) {
+	var best structs.FerryRoute
+	var bestFrom, bestTo structs.FerryStop
+	shortest := -1.0
+	for _, ferry := range timetable {
+		from, fromDistance := nearestQuay(ferry.Stops, start)
+		to, toDistance := nearestQuay(ferry.Stops, end)
+		if fromDistance > FerryQuayDistance || toDistance > FerryQuayDistance || from.ID == to.ID {
+			continue
+		}
+		if shortest < 0 || fromDistance+toDistance < shortest {
+			best, bestFrom, bestTo, shortest = ferry, from, to, fromDistance+toDistance
+		}
+	}
+	return best, bestFrom, bestTo, shortest >= 0
+}
+
+// nearestQuay Finds the stop closest to the point, and its distance in meters
+func nearestQuay(stops []structs.FerryStop, point utils.Coordinate) (structs.FerryStop, float64) {
+	var nearest structs.FerryStop
+	shortest := -1.0
+	for _, stop := range stops {
+		distance := utils.Haversine(point.Latitude, point.Longitude, stop.Latitude, stop.Longitude)
+		if shortest < 0 || distance < shortest {
+			nearest, shortest = stop, distance
+		}
+	}
+	if shortest < 0 {
+		return nearest, FerryQuayDistance + 1
+	}
+	return nearest, shortest
+}
+
+// ferryLocation The time zone of the timetable of a ferry route, UTC if it is unknown
+func ferryLocation(ferry structs.FerryRoute) *time.Location {
+	location, err := time.LoadLocation(ferry.Timezone)
+	if err != nil {
+		return time.UTC
+	}
+	return location
+}
+
+// nextFerryDepartures Finds the first departures from a quay to another at or after a time, sorted by the departure
+func nextFerryDepartures(ferry structs.FerryRoute, from string, to string, after time.Time, count int) []ferryDeparture {
+	location := ferryLocation(ferry)
+	after = after.In(location)
+
+	// The trips of the previous service day can run past midnight, and the last departure of a day can be followed
+	// by the first the day after
+	var departures []ferryDeparture
+	for day := -1; day <= 2; day++ {
+		date := time.Date(after.Year(), after.Month(), after.Day()+day, 12, 0, 0, 0, location)
+		// The times of a service day are counted from noon minus 12 hours, which is midnight except when the clocks change
+		start := date.Add(-12 * time.Hour)
+		for _, trip := range ferry.Trips {
+			if !serviceRuns(ferry.Services, trip.Service, date) {
+				continue
+			}
+			for i, call := range trip.Calls {
+				if call.Stop != from {
+					continue
+				}
+				for _, next := range trip.Calls[i+1:] {
+					if next.Stop != to {
+						continue
+					}
+					departure := start.Add(time.Duration(call.Departure) * time.Second)
+					if !departure.Before(after) {
+						departures = append(departures, ferryDeparture{departure: departure,
+							arrival: start.Add(time.Duration(next.Arrival) * time.Second)})
+					}
+					break
+				}
+			}
+		}
+	}
+
+	sort.Slice(departures, func(i, j int) bool { return departures[i].departure.Before(departures[j].departure) })
+	if len(departures) > count {
+		departures = departures[:count]
+	}
+	return departures
+}
+
+// serviceRuns Checks if a service of the timetable runs on the date
+func serviceRuns(services []structs.FerryService, id string, date time.Time) bool {
+	day := date.Format("20060102")
+	for _, service := range services {
+		if service.ID != id {
+			continue
+		}
+		for _, removed := range service.Removed {
+			if removed == day {
+				return false
+			}
+		}
+		for _, added := range service.Added {
+			if added == day {
+				return true
+			}
+		}
+		return service.Start != "" && day >= service.Start && day <= service.End &&
+			len(service.Days) == 7 && service.Days[date.Weekday()]
+	}
+	return false
+}
@@ -0,0 +1,74 @@
+package endpoints
+
+import (
+	"cloudproject/structs"
+	"cloudproject/utils"
+	"reflect"
+	"testing"
+	"time"
+)
+
+// crossing A ferry route with daily departures at 06:00, 07:00, 23:00 and 00:40 after midnight, an extra departure at
+// 06:30 on weekdays and no departures on Christmas Day. The crossing takes 45 minutes
+func crossing() structs.FerryRoute {
+	trip := func(service string, departure int) structs.FerryTrip {
+		return structs.FerryTrip{Service: service, Calls: []structs.FerryCall{
+			{Stop: "HALH", Arrival: departure, Departure: departure},
+			{Stop: "SAND", Arrival: departure + 45*60, Departure: departure + 45*60}}}
+	}
+	return structs.FerryRoute{ID: "E39-HS", Name: "Halhjem - Sandvikvåg", Timezone: "Europe/Oslo",
+		Stops: []structs.FerryStop{{ID: "HALH", Name: "Halhjem", Latitude: 60.1458, Longitude: 5.4264},
+			{ID: "SAND", Name: "Sandvikvåg", Latitude: 59.8117, Longitude: 5.3972}},
+		Trips: []structs.FerryTrip{trip("DAILY", 6*3600), trip("DAILY", 7*3600), trip("DAILY", 23*3600),
+			trip("DAILY", 24*3600+40*60), trip("WEEKDAYS", 6*3600+30*60)},
+		Services: []structs.FerryService{
+			{ID: "DAILY", Days: []bool{true, true, true, true, true, true, true}, Start: "20210101", End: "20301231",
+				Removed: []string{"20261225"}},
+			{ID: "WEEKDAYS", Days: []bool{false, true, true, true, true, true, false}, Start: "20210101", End: "20301231",
+				Removed: []string{"20261225"}}}}
+}
+
+func TestNextFerryDepartures(t *testing.T) {
+	oslo, _ := time.LoadLocation("Europe/Oslo")
+	ferry := crossing()
+	tests := []struct {
+		after    time.Time
+		expected []string
+	}{
+		{time.Date(2026, 10, 19, 6, 10, 0, 0, oslo), []string{"2026-10-19T06:30:00+02:00", "2026-10-19T07:00:00+02:00", "2026-10-19T23:00:00+02:00"}},
+		{time.Date(2026, 10, 24, 6, 10, 0, 0, oslo), []string{"2026-10-24T07:00:00+02:00", "2026-10-24T23:00:00+02:00", "2026-10-25T00:40:00+02:00"}},
+		{time.Date(2026, 10, 20, 0, 10, 0, 0, oslo), []string{"2026-10-20T00:40:00+02:00", "2026-10-20T06:00:00+02:00", "2026-10-20T06:30:00+02:00"}},
+		{time.Date(2026, 12, 25, 5, 0, 0, 0, oslo), []string{"2026-12-26T06:00:00+01:00", "2026-12-26T07:00:00+01:00", "2026-12-26T23:00:00+01:00"}},
+		{time.Date(2026, 10, 19, 4, 10, 0, 0, time.UTC), []string{"2026-10-19T06:30:00+02:00", "2026-10-19T07:00:00+02:00", "2026-10-19T23:00:00+02:00"}},
+	}
+	for _, test := range tests {
+		departures := []string{}
+		for _, departure := range nextFerryDepartures(ferry, "HALH", "SAND", test.after, FerryDepartures) {
+			departures = append(departures, departure.departure.Format(time.RFC3339))
+			if departure.arrival.Sub(departure.departure) != 45*time.Minute {
+				t.Errorf("%v: expected the crossing to take 45 minutes; got %v", test.after, departure.arrival.Sub(departure.departure))
+			}
+		}
+		if !reflect.DeepEqual(departures, test.expected) {
+			t.Errorf("%v: expected the departures %v; got %v", test.after, test.expected, departures)
+		}
+	}
+
+	if departures := nextFerryDepartures(ferry, "SAND", "HALH", time.Date(2026, 10, 19, 6, 0, 0, 0, oslo), FerryDepartures); len(departures) != 0 {
+		t.Errorf("Expected no departures in the opposite direction; got %v", departures)
+	}
+}
+
+func TestMatchFerry(t *testing.T) {
+	timetable := []structs.FerryRoute{crossing()}
+	halhjem := utils.Coordinate{Latitude: 60.1461, Longitude: 5.4261}
+	sandvikvag := utils.Coordinate{Latitude: 59.8119, Longitude: 5.3976}
+
+	ferry, from, to, found := matchFerry(timetable, halhjem, sandvikvag)
+	if !found || ferry.ID != "E39-HS" || from.ID != "HALH" || to.ID != "SAND" {
+		t.Errorf("Expected the crossing from Halhjem to Sandvikvåg; got %v %v %v %v", found, ferry.ID, from.ID, to.ID)
+	}
+	if _, _, _, found := matchFerry(timetable, halhjem, utils.Coordinate{Latitude: 59.3607, Longitude: 5.4067}); found {
+		t.Errorf("Expected no crossing to a quay which is not in the timetable")
+	}
+}
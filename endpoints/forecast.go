@@ -0,0 +1,224 @@
+package endpoints
+
+import (
+	"cloudproject/database"
+	"cloudproject/i18n"
+	"cloudproject/router"
+	"cloudproject/structs"
+	"cloudproject/utils"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"log"
+	"net/http"
+	"net/url"
+	"time"
+)
+
+// Resolutions of the forecast
+const (
+	Hourly = "hourly"
+	Daily  = "daily"
+)
+
+// unknownVisibility The daily forecasts do not contain the visibility, this is used for days without hourly forecasts.
+// It is the highest visibility the API reports
+const unknownVisibility = 10000
+
+// WeatherForecastQuery The query parameters accepted by WeatherForecast
+var WeatherForecastQuery = utils.QuerySchema{
+	{Name: "resolution", Type: utils.TypeString, Description: "Forecasts to return, hourly for the next 48 hours and daily for the next 8 days",
+		Default: Hourly + "," + Daily, Enum: []string{Hourly, Daily}, Multiple: true},
+	{Name: "from", Type: utils.TypeDateTime, Description: "Start of the time window, forecasts ending before it are left out"},
+	{Name: "to", Type: utils.TypeDateTime, Description: "End of the time window, forecasts starting after it are left out"},
+	i18n.LangParam,
+	utils.UnitsParam,
+}
+
+// WeatherForecast Gets the hourly and daily forecast for a place from the openweathermap-API
+func WeatherForecast(w http.ResponseWriter, request *http.Request) {
+	w.Header().Set("Content-type", "application/json")
+	presentation := PresentationOf(request)
+	w.Header().Set("Content-Language", presentation.Locale)
+
+	// Gets the name of the place from the path
+	address := router.Param(request, "place")
+
+	query := router.Query(request)
+	from, to := query.Time("from"), query.Time("to")
+	if !from.IsZero() && !to.IsZero() && !from.Before(to) {
+		http.Error(w, "The start of the time window must be before the end", http.StatusBadRequest)
+		return
+	}
+
+	//Receives the latitude and longitude of the place passed in the url
+	latitude, longitude, err := database.LocationPresent(url.QueryEscape(address))
+	if err != nil {
+		log.Println("Error: No entries in the database gave the wanted result.\n" + err.Error())
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	if latitude == "" || longitude == "" {
+		http.Error(w, "Check formatting of latitude and longitude.", http.StatusBadRequest)
+		return
+	}
+
+	// Defines the url to the One Call API, leaving out the parts which are not used
+	urlForecast := utils.OpenWeatherMapURL + "/data/2.5/onecall?lat=" + latitude + "&lon=" + longitude +
+		"&exclude=current,minutely,alerts&units=metric&appid=" + utils.OpenweathermapKey
+	forecast, err := FetchForecast(urlForecast, presentation)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	// Leaves out the resolutions which are not requested, and the forecasts outside of the time window
+	output := structs.OutputForecast{Timezone: forecast.Timezone}
+	for _, resolution := range query.All("resolution") {
+		switch resolution {
+		case Hourly:
+			output.Hourly = window(forecast.Hourly, time.Hour, from, to)
+		case Daily:
+			output.Daily = window(forecast.Daily, 24*time.Hour, from, to)
+		}
+	}
+
+	result, err := json.Marshal(output)
+	if err != nil {
+		log.Println("There was an error while marshalling the data.\n" + err.Error())
+		jsonError := utils.JsonMarshalErrorHandling(err)
+		http.Error(w, jsonError.Error(), http.StatusInternalServerError)
+		return
+	}
+	_, err = fmt.Fprintf(w, "%v", string(result))
+	if err != nil {
+		log.Println("There was an error while displaying the output to the user.")
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+	}
+}
+
+// FetchForecast Requests the forecast in metric units from the url, and converts every hour and day to the weather output
+// with messages, in the presentation. The times are in the time zone of the location
+func FetchForecast(url string, p Presentation) (structs.OutputForecast, error) {
+	resp, err := http.Get(url)
+	if err != nil {
+		log.Println("Error: Encountered problem when requesting the url.\n" + err.Error())
+		return structs.OutputForecast{}, errors.New("Error: " + err.Error())
+	}
+	defer resp.Body.Close()
+
+	body, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		log.Println("Error while reading response body.\n" + err.Error())
+		return structs.OutputForecast{}, errors.New("Error: " + err.Error())
+	}
+	if resp.StatusCode != http.StatusOK {
+		log.Println("The forecast request failed with status: " + resp.Status + "\n" + string(body))
+		return structs.OutputForecast{}, errors.New("Error: The forecast is unavailable, status " + resp.Status)
+	}
+
+	var forecast structs.ForecastData
+	if err = json.Unmarshal(body, &forecast); err != nil {
+		log.Println("There was an error during unmarshalling.\n" + err.Error())
+		return structs.OutputForecast{}, utils.JsonUnmarshalErrorHandling(err)
+	}
+
+	location := zone(forecast.Timezone, forecast.TimezoneOffset)
+	output := structs.OutputForecast{Hourly: []structs.ForecastEntry{}, Daily: []structs.ForecastEntry{}, Timezone: location.String()}
+
+	// The daily forecasts are given at noon, a day lasts from 12 hours before to 12 hours after
+	dayOf := func(hour time.Time) int {
+		for i, day := range forecast.Daily {
+			noon := time.Unix(day.Dt, 0)
+			if !hour.Before(noon.Add(-12*time.Hour)) && hour.Before(noon.Add(12*time.Hour)) {
+				return i
+			}
+		}
+		return -1
+	}
+	lowestVisibility := map[int]int{}
+
+	for _, hour := range forecast.Hourly {
+		if len(hour.Weather) == 0 {
+			continue
+		}
+		start := time.Unix(hour.Dt, 0).In(location)
+		weather := structs.OutputWeather{
+			Main:       structs.MainStruct{Main: hour.Weather[0].Main},
+			Rain1h:     hour.Rain.OneH,
+			Snow1h:     hour.Snow.OneH,
+			Temp:       structs.TempStruct{Temp: hour.Temp},
+			FeelsLike:  structs.FeelsLikeStruct{FeelsLike: hour.FeelsLike},
+			TempMin:    structs.TempMinStruct{TempMin: hour.Temp},
+			TempMax:    structs.TempMaxStruct{TempMax: hour.Temp},
+			Humidity:   structs.HumidityStruct{Humidity: hour.Humidity},
+			Visibility: structs.VisibilityStruct{Visibility: hour.Visibility},
+			WindSpeed:  structs.WindSpeedStruct{WindSpeed: hour.WindSpeed},
+			WindDeg:    structs.WindDegStruct{WindDeg: hour.WindDeg},
+			WindGust:   hour.WindGust,
+		}
+		// The minimum and maximum temperature, sunrise and sunset are taken from the day of the hour
+		if i := dayOf(start); i != -1 {
+			day := forecast.Daily[i]
+			weather.TempMin.TempMin = day.Temp.Min
+			weather.TempMax.TempMax = day.Temp.Max
+			weather.Sunrise = structs.SunriseStruct{Sunrise: day.Sunrise, Local: time.Unix(int64(day.Sunrise), 0).In(location)}
+			weather.Sunset = structs.SunsetStruct{Sunset: day.Sunset, Local: time.Unix(int64(day.Sunset), 0).In(location)}
+			if lowest, found := lowestVisibility[i]; !found || hour.Visibility < lowest {
+				lowestVisibility[i] = hour.Visibility
+			}
+		}
+		output.Hourly = append(output.Hourly, structs.ForecastEntry{
+			Time: start, PrecipitationProbability: hour.Pop, Weather: advise(weather, start, p)})
+	}
+
+	for i, day := range forecast.Daily {
+		if len(day.Weather) == 0 {
+			continue
+		}
+		visibility, found := lowestVisibility[i]
+		if !found {
+			visibility = unknownVisibility
+		}
+		weather := structs.OutputWeather{
+			Main: structs.MainStruct{Main: day.Weather[0].Main},
+			// The advice is based on the precipitation per hour, so the daily amount is spread over the day
+			Rain1h:     day.Rain / 24,
+			Snow1h:     day.Snow / 24,
+			Temp:       structs.TempStruct{Temp: day.Temp.Day},
+			FeelsLike:  structs.FeelsLikeStruct{FeelsLike: day.FeelsLike.Day},
+			TempMin:    structs.TempMinStruct{TempMin: day.Temp.Min},
+			TempMax:    structs.TempMaxStruct{TempMax: day.Temp.Max},
+			Humidity:   structs.HumidityStruct{Humidity: day.Humidity},
+			Visibility: structs.VisibilityStruct{Visibility: visibility},
+			WindSpeed:  structs.WindSpeedStruct{WindSpeed: day.WindSpeed},
+			WindDeg:    structs.WindDegStruct{WindDeg: day.WindDeg},
+			WindGust:   day.WindGust,
+			Sunrise:    structs.SunriseStruct{Sunrise: day.Sunrise, Local: time.Unix(int64(day.Sunrise), 0).In(location)},
+			Sunset:     structs.SunsetStruct{Sunset: day.Sunset, Local: time.Unix(int64(day.Sunset), 0).In(location)},
+		}
+		noon := time.Unix(day.Dt, 0).In(location)
+		output.Daily = append(output.Daily, structs.ForecastEntry{
+			Time: noon, PrecipitationProbability: day.Pop, Weather: advise(weather, noon, p)})
+	}
+	return output, nil
+}
+
+// window Returns the forecasts overlapping the time window, a zero from or to leaves the window open in that end.
+// Hourly forecasts start at their time, daily forecasts are given at noon and last 12 hours before and after
+func window(entries []structs.ForecastEntry, length time.Duration, from time.Time, to time.Time) []structs.ForecastEntry {
+	selected := []structs.ForecastEntry{}
+	for _, entry := range entries {
+		start := entry.Time
+		if length == 24*time.Hour {
+			start = start.Add(-12 * time.Hour)
+		}
+		end := start.Add(length)
+		if (!from.IsZero() && !end.After(from)) || (!to.IsZero() && !start.Before(to)) {
+			continue
+		}
+		selected = append(selected, entry)
+	}
+	return selected
+}
@@ -2,24 +2,33 @@ package endpoints
 
 import (
 	"cloudproject/database"
+	"cloudproject/router"
 	"cloudproject/structs"
 	"cloudproject/utils"
 	"encoding/json"
-	"errors"
 	"fmt"
 	"io/ioutil"
 	"log"
 	"net/http"
 	"net/url"
-	"strconv"
-	"strings"
 )
 
-//PetrolStation Function that will display all the electric-vehicle charging stations from a location, within 1km
+// PetrolStationQuery The query parameters accepted by PetrolStation
+var PetrolStationQuery = utils.QuerySchema{
+	{Name: "radius", Type: utils.TypeInteger, Description: "Search radius in meters", Default: "5000",
+		Minimum: utils.Bound(1), Maximum: utils.Bound(50000)},
+	utils.UnitsParam,
+	ProfileParam,
+}
+
+// fuelSets The TomTom fuel types of the fuels of vehicle profiles, hybrids are filled with petrol
+var fuelSets = map[string]string{FuelPetrol: "Petrol", FuelDiesel: "Diesel", FuelHybrid: "Petrol"}
+
+// PetrolStation Function that will display all the petrol stations from a location, within 5 km by default
 func PetrolStation(w http.ResponseWriter, request *http.Request) {
 	w.Header().Set("Content-Type", "application/json")
 
-	address := strings.Split(request.URL.Path, `/`)[4] //Getting the address/name of the place we want to look for chargers
+	address := router.Param(request, "place") //Getting the address/name of the place we want to look for petrol stations
 	if address == "" {
 		http.Error(w, "Please insert a Location", http.StatusBadRequest)
 		return
@@ -32,28 +41,30 @@ func PetrolStation(w http.ResponseWriter, request *http.Request) {
 		return
 	}
 
-	filter, err := utils.GetOptionalFilter(request.URL) //Getting the optional filters
+	//Gets the stations within the radius, validated by the router against PetrolStationQuery
+	query := router.Query(request)
+	radius := query.Get("radius")
+
+	//Only the stations with the fuel of the vehicle profile are of use
+	profile, status, err := QueryProfile(query)
 	if err != nil {
-		log.Println("Unable to retrieve filters from url: " + "\n" + err.Error())
-		http.Error(w, err.Error(), http.StatusBadRequest)
+		http.Error(w, err.Error(), status)
 		return
 	}
-	var response *http.Response //Defines a response object
-
-	if len(filter) != 0 {
-		radius, err := checkFilter(filter) //Getting filters
-		if err != nil {
-			log.Println("Unable to check filters for filter " + "\n" + err.Error())
-			http.Error(w, err.Error(), http.StatusBadRequest)
-			return
-		}
-
-		//http.get with radius using the latitude and longitude
-		response, err = http.Get("https://api.tomtom.com/search/2/nearbySearch/.json?lat=" + latitude + "&lon=" + longitude + radius + "&categorySet=7311&fuelSet=&key=" + utils.TomtomKey)
-	} else {
-		//http.get without user specified radius
-		response, err = http.Get("https://api.tomtom.com/search/2/nearbySearch/.json?lat=" + latitude + "&lon=" + longitude + "&radius=5000&categorySet=7311&key=" + utils.TomtomKey)
+	filters := ""
+	if profile != nil && profile.Fuel == FuelElectric {
+		http.Error(w, "The vehicle profile "+profile.ID+" is electric, search for charging stations instead", http.StatusBadRequest)
+		return
+	} else if profile != nil {
+		filters = "&fuelSet=" + fuelSets[profile.Fuel]
 	}
+	response, err := http.Get(utils.TomTomURL + "/search/2/nearbySearch/.json?lat=" + latitude + "&lon=" + longitude + "&radius=" + radius + filters + "&categorySet=7311&key=" + utils.TomtomKey)
+	if err != nil {
+		log.Println("Unable to get petrol stations for location: " + address + "\n" + err.Error())
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	defer response.Body.Close()
 
 	body, err := ioutil.ReadAll(response.Body) //Reading body
 	if err != nil {
@@ -71,6 +82,7 @@ func PetrolStation(w http.ResponseWriter, request *http.Request) {
 		return
 	}
 
+	units := router.Query(request).Get("units")
 	var total []structs.OutputPetrol
 	for i := 0; i < len(petrol.Results); i++ { //For each of the stations
 
@@ -81,11 +93,14 @@ func PetrolStation(w http.ResponseWriter, request *http.Request) {
 		}
 		address := petrol.Results[i].Address.FreeformAddress //Getting the address to the station
 
-		jsonStruct := structs.OutputPetrol{StationName: stationName, StationBrand: stationBrand, Address: address} //Creating a JSON object
-		total = append(total, jsonStruct)                                                                          //Appending the json object to an array
+		distance := utils.Distance(petrol.Results[i].Dist, units) //Distance from the place
+
+		jsonStruct := structs.OutputPetrol{StationName: stationName, StationBrand: stationBrand, Address: address,
+			Distance: distance, DistanceUnit: unitsOf(units).Distance} //Creating a JSON object
+		total = append(total, jsonStruct) //Appending the json object to an array
 	}
 
-	output, err := json.Marshal(total) //Marshalling the array to JSON
+	output, err := json.Marshal(Versioned(request, total)) //Marshalling the array to JSON
 	if err != nil {
 		jsonError := utils.JsonUnmarshalErrorHandling(err)
 		log.Println("Unable marshall object, output: " + string(output) + "\n" + err.Error())
@@ -96,24 +111,3 @@ func PetrolStation(w http.ResponseWriter, request *http.Request) {
 	fmt.Fprintf(w, "%v", string(output)) //Outputs the chargers
 
 }
-
-//checkFilter Function to check if the filter is valid and has the proper input
-func checkFilter(filter map[string]string) (string, error) {
-	_, foundRadius := filter["radius"]
-	//If statement to check if the user passed in a correct filter, and with a value
-	if !(foundRadius) {
-		return "", errors.New("error, Bad Request\nNone of the filters is accepted\nAccepted filters: radius, charge, power")
-	} else if len(filter["radius"]) == 0 { //Checking if the user has passed in a valid filter
-		return "", errors.New("error, Bad Request\nField cannot be empty")
-	}
-	radius := ""
-	if len(filter["radius"]) != 0 {
-		//Checks if the user has passed in an int, and not a string
-		if _, err := strconv.Atoi(filter["radius"]); err != nil {
-			return "", errors.New("Value of radius must be a number\nTry again")
-		} else {
-			radius = "&radius=" + filter["radius"]
-		}
-	}
-	return radius, nil
-}
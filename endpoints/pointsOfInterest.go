@@ -2,6 +2,7 @@ package endpoints
 
 import (
 	"cloudproject/database"
+	"cloudproject/router"
 	"cloudproject/structs"
 	"cloudproject/utils"
 	"encoding/json"
@@ -10,18 +11,24 @@ import (
 	"log"
 	"net/http"
 	"net/url"
-	"strings"
 )
 
-// PointOfInterest Displays all the points of interest from a location, within 5 km radius
+// PointOfInterestQuery The query parameters accepted by PointOfInterest
+var PointOfInterestQuery = utils.QuerySchema{
+	{Name: "radius", Type: utils.TypeInteger, Description: "Search radius in meters", Default: "5000",
+		Minimum: utils.Bound(1), Maximum: utils.Bound(50000)},
+	utils.UnitsParam,
+}
+
+// PointOfInterest Displays all the points of interest from a location, within 5 km radius by default
 func PointOfInterest(w http.ResponseWriter, request *http.Request) {
 	w.Header().Set("Content-Type", "application/json")
 
 	//Getting the address/name of the place we want to look for points of interest
-	address := strings.Split(request.URL.Path, `/`)[4]
+	address := router.Param(request, "place")
 
 	//Gets the interest the user are interested in
-	poiPath := strings.Split(request.URL.Path, `/`)[5]
+	poiPath := router.Param(request, "category")
 
 	//Receives the latitude and longitude of the place passed in to the url
 	latitude, longitude, err := database.LocationPresent(url.QueryEscape(address))
@@ -32,7 +39,14 @@ func PointOfInterest(w http.ResponseWriter, request *http.Request) {
 	}
 
 	// Sends a GET request to the API and stores the response
-	response, err := http.Get("https://api.tomtom.com/search/2/poiSearch/" + poiPath + ".json?lat=" + latitude + "&lon=" + longitude + "&radius=5000&key=gcP26xVobGHjX2VVWGTskjelxX81WA1G")
+	response, err := http.Get(utils.TomTomURL + "/search/2/poiSearch/" + url.PathEscape(poiPath) + ".json?lat=" + latitude + "&lon=" + longitude +
+		"&radius=" + router.Query(request).Get("radius") + "&key=gcP26xVobGHjX2VVWGTskjelxX81WA1G")
+	if err != nil {
+		log.Println("An error occurred while requesting points of interest.\n" + err.Error())
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	defer response.Body.Close()
 
 	// Reads the response body
 	body, err := ioutil.ReadAll(response.Body)
@@ -51,6 +65,7 @@ func PointOfInterest(w http.ResponseWriter, request *http.Request) {
 		return
 	}
 
+	units := router.Query(request).Get("units")
 	var total []structs.OutputPoi
 
 	// For each point of interest
@@ -60,11 +75,14 @@ func PointOfInterest(w http.ResponseWriter, request *http.Request) {
 		poiPhoneNumber := poi.Results[i].Poi.Phone
 		poiAddress := poi.Results[i].Address.Freeformaddress
 
-		jsonStruct := structs.OutputPoi{Name: poiName, PhoneNumber: poiPhoneNumber, Address: poiAddress} //Creating a JSON object
-		total = append(total, jsonStruct)                                                                //Appending the json object to an array
+		poiDistance := utils.Distance(poi.Results[i].Dist, units) //Distance from the place
+
+		jsonStruct := structs.OutputPoi{Name: poiName, PhoneNumber: poiPhoneNumber, Address: poiAddress,
+			Distance: poiDistance, DistanceUnit: unitsOf(units).Distance} //Creating a JSON object
+		total = append(total, jsonStruct) //Appending the json object to an array
 	}
 
-	output, err := json.Marshal(total) //Marshaling the array to JSON
+	output, err := json.Marshal(Versioned(request, total)) //Marshaling the array to JSON
 	if err != nil {
 		log.Println("An error occurred during unmarshal.\n" + err.Error())
 		marshalErr := utils.JsonMarshalErrorHandling(err)
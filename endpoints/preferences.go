@@ -0,0 +1,145 @@
+package endpoints
+
+import (
+	"cloudproject/structs"
+	"cloudproject/utils"
+	"errors"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+)
+
+/**
+ * Class preferences.go
+ * Route preferences: the kinds of roads to avoid, the route type, the traffic and the time to depart at or arrive by
+ * The preferences are given as query parameters of the routes and in the body of webhooks, and are passed on to
+ * TomTom together with the vehicle of the profile. Closures and convoy driving are avoided by this service.
+ */
+
+// Values of the avoid preference
+const (
+	AvoidTolls     = "tolls"
+	AvoidFerries   = "ferries"
+	AvoidMotorways = "motorways"
+	AvoidUnpaved   = "unpaved"
+	AvoidClosures  = "closures"
+	AvoidConvoys   = "convoys"
+)
+
+// Route types, what TomTom optimizes the route for
+const (
+	RouteFastest   = "fastest"
+	RouteShortest  = "shortest"
+	RouteEco       = "eco"
+	RouteThrilling = "thrilling" // Winding and hilly roads
+)
+
+// avoidable The accepted values of the avoid preference
+var avoidable = []string{AvoidTolls, AvoidFerries, AvoidMotorways, AvoidUnpaved, AvoidClosures, AvoidConvoys}
+
+// routeTypes The accepted route types
+var routeTypes = []string{RouteFastest, RouteShortest, RouteEco, RouteThrilling}
+
+// tomTomAvoid The names TomTom uses for the kinds of roads it can avoid
+var tomTomAvoid = map[string]string{AvoidTolls: "tollRoads", AvoidFerries: "ferries", AvoidMotorways: "motorways",
+	AvoidUnpaved: "unpavedRoads"}
+
+// RouteTypeParam The query parameter for the route type
+var RouteTypeParam = utils.QueryParam{Name: "routeType", Type: utils.TypeString, Description: "What the route is optimized for",
+	Enum: routeTypes, Default: RouteFastest}
+
+// PreferenceParams The query parameters for the route preferences, other than the route type
+var PreferenceParams = utils.QuerySchema{
+	{Name: "avoid", Type: utils.TypeString, Description: "Kinds of roads to avoid. Closures and convoy driving are routed around by this service, and only when asked for",
+		Enum: avoidable, Default: AvoidUnpaved, Multiple: true},
+	{Name: "traffic", Type: utils.TypeBoolean, Description: "Plan for the current traffic, not only the usual traffic", Default: "false"},
+	{Name: "departAt", Type: utils.TypeDateTime, Description: "Time to depart at, now if neither departAt nor arriveAt is given"},
+	{Name: "arriveAt", Type: utils.TypeDateTime, Description: "Time to arrive by, the route starts in time to arrive then"},
+}
+
+// QueryPreferences Gets the route preferences of the query
+func QueryPreferences(query utils.Query) (structs.RoutePreferences, error) {
+	preferences := structs.RoutePreferences{Avoid: query.All("avoid"), RouteType: query.Get("routeType"),
+		Traffic: query.Bool("traffic"), DepartAt: query.Get("departAt"), ArriveAt: query.Get("arriveAt")}
+	return preferences, ValidatePreferences(&preferences)
+}
+
+// ValidatePreferences Checks the route preferences, and fills in the defaults of those left out. Unpaved roads are
+// avoided if avoid is left out, an empty list avoids nothing
+func ValidatePreferences(preferences *structs.RoutePreferences) error {
+	if preferences.Avoid == nil {
+		preferences.Avoid = []string{AvoidUnpaved}
+	}
+	avoid := []string{}
+	for _, kind := range preferences.Avoid {
+		kind = strings.ToLower(strings.TrimSpace(kind))
+		if !utils.Contains(avoidable, kind) {
+			return errors.New("Unable to avoid " + kind + ", supported values: " + strings.Join(avoidable, ", "))
+		}
+		if !utils.Contains(avoid, kind) {
+			avoid = append(avoid, kind)
+		}
+	}
+	preferences.Avoid = avoid
+
+	preferences.RouteType = strings.ToLower(strings.TrimSpace(preferences.RouteType))
+	if preferences.RouteType == "" {
+		preferences.RouteType = RouteFastest
+	} else if !utils.Contains(routeTypes, preferences.RouteType) {
+		return errors.New("The route type must be one of: " + strings.Join(routeTypes, ", "))
+	}
+
+	if preferences.DepartAt != "" && preferences.ArriveAt != "" {
+		return errors.New("Only one of departAt and arriveAt can be given")
+	}
+	for name, value := range map[string]string{"departAt": preferences.DepartAt, "arriveAt": preferences.ArriveAt} {
+		if value == "" {
+			continue
+		}
+		at, err := time.Parse(time.RFC3339, value)
+		if err != nil {
+			return errors.New("The " + name + " time must be in RFC3339 format, such as 2021-05-17T12:10:00+02:00")
+		}
+		if at.Before(time.Now()) {
+			return errors.New("The " + name + " time must be in the future")
+		}
+	}
+	return nil
+}
+
+// RouteOptions The TomTom routing parameters for the vehicle of a profile and the preferences, the restrictions of the
+// vehicle are left out if there is no profile
+func RouteOptions(profile *structs.VehicleProfile, preferences structs.RoutePreferences) string {
+	options := vehicleOptions(profile)
+	for _, kind := range preferences.Avoid {
+		if name, found := tomTomAvoid[kind]; found {
+			options += "&avoid=" + name
+		}
+	}
+	routeType := preferences.RouteType
+	if routeType == "" {
+		routeType = RouteFastest
+	}
+	options += "&routeType=" + routeType + "&traffic=" + strconv.FormatBool(preferences.Traffic)
+	if preferences.DepartAt != "" {
+		options += "&departAt=" + url.QueryEscape(preferences.DepartAt)
+	} else if preferences.ArriveAt != "" {
+		options += "&arriveAt=" + url.QueryEscape(preferences.ArriveAt)
+	}
+	return options
+}
+
+// tripTimes The departure and the arrival of the route planned with the preferences, before waiting for ferries. The
+// arrival is in the time zone of the destination
+func tripTimes(roads structs.RouteStruct, preferences structs.RoutePreferences) (time.Time, time.Time) {
+	summary := roads.Routes[0].Summary
+	travel := time.Duration(summary.TravelTimeInSeconds) * time.Second
+	if departAt, err := time.Parse(time.RFC3339, preferences.DepartAt); err == nil {
+		return departAt, departAt.Add(travel).In(summary.ArrivalTime.Location())
+	}
+	if arriveAt, err := time.Parse(time.RFC3339, preferences.ArriveAt); err == nil {
+		return arriveAt.Add(-travel), arriveAt.In(summary.ArrivalTime.Location())
+	}
+	return time.Now(), summary.ArrivalTime
+}
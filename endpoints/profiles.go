@@ -0,0 +1,278 @@
+package endpoints
+
+import (
+	"cloudproject/database"
+	"cloudproject/router"
+	"cloudproject/structs"
+	"cloudproject/utils"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+)
+
+/**
+ * Class profiles.go
+ * Stored vehicle profiles, which routes, searches, costs and webhooks can refer to by id
+ * A route is restricted to the dimensions and weight of the vehicle, charging stations are searched for with its
+ * connectors and petrol stations with its fuel.
+ */
+
+// Fuels of vehicle profiles
+const (
+	FuelPetrol   = "petrol"
+	FuelDiesel   = "diesel"
+	FuelElectric = "electric"
+	FuelHybrid   = "hybrid" // A plug-in hybrid, charged with its connectors and filled with petrol
+)
+
+// fuels The accepted fuels
+var fuels = []string{FuelPetrol, FuelDiesel, FuelElectric, FuelHybrid}
+
+// ProfileParam The query parameter naming the vehicle profile of a request
+var ProfileParam = utils.QueryParam{Name: "profile", Type: utils.TypeString,
+	Description: "Id of the vehicle profile to adapt the response to, the other parameters take precedence"}
+
+// AddProfile Stores a new vehicle profile, and answers with it and its id
+func AddProfile(w http.ResponseWriter, r *http.Request) {
+	profile, err := readProfile(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	data, err := database.ToData(profile)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	delete(data, "id")
+	profile.ID, err = database.Client.Add(database.ProfileCollection, data)
+	if err != nil {
+		log.Println("Unable to store the vehicle profile.\n" + err.Error())
+		http.Error(w, "Unable to store the vehicle profile, try again", http.StatusInternalServerError)
+		return
+	}
+	log.Println("Stored the vehicle profile with ID: " + profile.ID)
+	writeProfile(w, profile, http.StatusCreated)
+}
+
+// UpdateProfile Replaces the vehicle profile with the id in the path
+func UpdateProfile(w http.ResponseWriter, r *http.Request) {
+	id := router.Param(r, "id")
+	if _, err := database.GetProfile(id); err != nil {
+		profileError(w, id, err)
+		return
+	}
+	profile, err := readProfile(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	profile.ID = id
+	data, err := database.ToData(profile)
+	if err == nil {
+		delete(data, "id")
+		err = database.Client.Set(database.ProfileCollection, id, data)
+	}
+	if err != nil {
+		log.Println("Unable to update the vehicle profile with ID: " + id + "\n" + err.Error())
+		http.Error(w, "Unable to update the vehicle profile, try again", http.StatusInternalServerError)
+		return
+	}
+	writeProfile(w, profile, http.StatusOK)
+}
+
+// GetProfile Displays the vehicle profile with the id in the path
+func GetProfile(w http.ResponseWriter, r *http.Request) {
+	id := router.Param(r, "id")
+	profile, err := database.GetProfile(id)
+	if err != nil {
+		profileError(w, id, err)
+		return
+	}
+	writeProfile(w, profile, http.StatusOK)
+}
+
+// ListProfiles Displays all vehicle profiles
+func ListProfiles(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	profiles, err := database.GetProfiles()
+	if err != nil {
+		log.Println("Unable to list the vehicle profiles.\n" + err.Error())
+		http.Error(w, "Unable to list the vehicle profiles, try again", http.StatusInternalServerError)
+		return
+	}
+	output, err := json.Marshal(profiles)
+	if err != nil {
+		http.Error(w, utils.JsonMarshalErrorHandling(err).Error(), http.StatusInternalServerError)
+		return
+	}
+	fmt.Fprintf(w, "%v", string(output))
+}
+
+// DeleteProfile Deletes the vehicle profile with the id in the path
+func DeleteProfile(w http.ResponseWriter, r *http.Request) {
+	id := router.Param(r, "id")
+	if _, err := database.GetProfile(id); err != nil {
+		profileError(w, id, err)
+		return
+	}
+	if err := database.Client.Delete(database.ProfileCollection, id); err != nil {
+		log.Println("Unable to delete the vehicle profile with ID: " + id + "\n" + err.Error())
+		http.Error(w, "Unable to delete the vehicle profile, try again", http.StatusInternalServerError)
+		return
+	}
+	fmt.Fprintf(w, "Deleted the vehicle profile with ID: %v", id)
+}
+
+// readProfile Reads and validates the vehicle profile in the body of the request
+func readProfile(r *http.Request) (structs.VehicleProfile, error) {
+	body, err := ioutil.ReadAll(r.Body)
+	if err != nil {
+		return structs.VehicleProfile{}, err
+	}
+	var profile structs.VehicleProfile
+	if err = json.Unmarshal(body, &profile); err != nil {
+		return structs.VehicleProfile{}, utils.JsonUnmarshalErrorHandling(err)
+	}
+	return profile, validateProfile(&profile)
+}
+
+// validateProfile Checks the values of a vehicle profile, and gives the connectors the names used by TomTom
+func validateProfile(profile *structs.VehicleProfile) error {
+	profile.Name = strings.TrimSpace(profile.Name)
+	profile.Fuel = strings.ToLower(strings.TrimSpace(profile.Fuel))
+	if profile.Name == "" {
+		return errors.New("The vehicle profile must have a name")
+	}
+	if !utils.Contains(fuels, profile.Fuel) {
+		return errors.New("The fuel must be one of: " + strings.Join(fuels, ", "))
+	}
+
+	limits := []struct {
+		name    string
+		value   float64
+		maximum float64
+	}{
+		{"length", profile.Length, 25}, {"width", profile.Width, 3}, {"height", profile.Height, 5},
+		{"weight", float64(profile.Weight), 60000}, {"consumption", profile.Consumption, 100},
+		{"range", float64(profile.Range), 2000},
+	}
+	for _, limit := range limits {
+		if limit.value < 0 || limit.value > limit.maximum {
+			return errors.New("The " + limit.name + " must be from 0 to " + strconv.FormatFloat(limit.maximum, 'f', -1, 64))
+		}
+	}
+
+	if len(profile.Connectors) != 0 && profile.Fuel != FuelElectric && profile.Fuel != FuelHybrid {
+		return errors.New("Only electric and hybrid vehicles can have connectors")
+	}
+	connectors := []string{}
+	for _, connector := range profile.Connectors {
+		canonical, found := canonicalConnector(connector)
+		if !found {
+			return errors.New("Unknown connector " + connector + ", supported connectors: " + strings.Join(outletArray, ", "))
+		}
+		connectors = append(connectors, canonical)
+	}
+	profile.Connectors = connectors
+	return nil
+}
+
+// canonicalConnector The name TomTom uses for a connector type or one of its alternative names
+func canonicalConnector(name string) (string, bool) {
+	name = strings.TrimSpace(name)
+	for _, outlet := range outletArray {
+		if strings.EqualFold(outlet, name) {
+			return outlet, true
+		}
+	}
+	for alias, outlet := range outletsMap {
+		if strings.EqualFold(alias, name) {
+			return outlet, true
+		}
+	}
+	return "", false
+}
+
+// writeProfile Answers with the vehicle profile
+func writeProfile(w http.ResponseWriter, profile structs.VehicleProfile, status int) {
+	output, err := json.Marshal(profile)
+	if err != nil {
+		http.Error(w, utils.JsonMarshalErrorHandling(err).Error(), http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	fmt.Fprintf(w, "%v", string(output))
+}
+
+// profileError Answers that the vehicle profile could not be found, or could not be read
+func profileError(w http.ResponseWriter, id string, err error) {
+	if err == database.ErrNotFound {
+		router.Error(w, "No vehicle profile with ID: "+id, http.StatusNotFound)
+		return
+	}
+	log.Println("Unable to read the vehicle profile with ID: " + id + "\n" + err.Error())
+	http.Error(w, "Unable to read the vehicle profile, try again", http.StatusInternalServerError)
+}
+
+// QueryProfile Gets the vehicle profile named by the profile parameter, nil if no profile is named. Returns the status
+// to answer with if the profile can not be used
+func QueryProfile(query utils.Query) (*structs.VehicleProfile, int, error) {
+	if !query.Has("profile") {
+		return nil, http.StatusOK, nil
+	}
+	id := query.Get("profile")
+	profile, err := database.GetProfile(id)
+	if err == database.ErrNotFound {
+		return nil, http.StatusBadRequest, errors.New("No vehicle profile with ID: " + id)
+	} else if err != nil {
+		log.Println("Unable to read the vehicle profile with ID: " + id + "\n" + err.Error())
+		return nil, http.StatusInternalServerError, errors.New("Unable to read the vehicle profile, try again")
+	}
+	return &profile, http.StatusOK, nil
+}
+
+// VehicleClass The vehicle class of a profile, which the prices depend on. A car if there is no profile
+func VehicleClass(profile *structs.VehicleProfile) string {
+	if profile == nil {
+		return VehicleCar
+	} else if profile.Fuel == FuelElectric {
+		return VehicleEV
+	} else if profile.Trailer {
+		return VehicleTrailer
+	}
+	return VehicleCar
+}
+
+// vehicleOptions The TomTom routing parameters for the vehicle of a profile, the restrictions of the profile are left
+// out if there is no profile
+func vehicleOptions(profile *structs.VehicleProfile) string {
+	options := "&travelMode=car"
+	if profile == nil {
+		return options
+	}
+	dimensions := []struct {
+		name  string
+		value float64
+	}{
+		{"vehicleLength", profile.Length}, {"vehicleWidth", profile.Width}, {"vehicleHeight", profile.Height},
+		{"vehicleWeight", float64(profile.Weight)},
+	}
+	for _, dimension := range dimensions {
+		if dimension.value > 0 {
+			options += "&" + dimension.name + "=" + strconv.FormatFloat(dimension.value, 'f', -1, 64)
+		}
+	}
+	if profile.Fuel == FuelElectric {
+		options += "&vehicleEngineType=electric"
+	} else {
+		options += "&vehicleEngineType=combustion"
+	}
+	return options
+}
@@ -0,0 +1,122 @@
+package endpoints
+
+import (
+	"cloudproject/structs"
+	"cloudproject/utils"
+	"errors"
+	"math"
+	"strconv"
+	"time"
+)
+
+/**
+ * Class progress.go
+ * The progress of a trip being driven, from the positions the driver checks in
+ * The route given at a check-in is kept leg by leg, one leg to each stop left. The next check-in is matched to the
+ * closest leg, which tells the stops left and how far off the route the driver is, and the remaining route is planned
+ * from the position in the current traffic.
+ */
+
+// DeviationThreshold Meters from the route a position has to be to be off the route
+const DeviationThreshold = 500
+
+// ArrivalRadius Meters from the destination a position is at it
+const ArrivalRadius = 200
+
+// DriveRoute The route given to a trip being driven, with a leg to each of the stops left
+type DriveRoute struct {
+	Stops []string // The stop each leg leads to
+	Legs  [][]utils.Coordinate
+}
+
+// TripDriveRoute The route planned for the trip through all its stops, for the first check-in to be compared with
+func TripDriveRoute(trip structs.Trip) (DriveRoute, error) {
+	planned, _, err := planTrip(trip, utils.Query{})
+	if err != nil {
+		return DriveRoute{}, err
+	}
+	return driveRoute(planned.roads, trip.Stops[1:]), nil
+}
+
+// driveRoute The legs of the route to the stops, stops the route has no leg to are left out
+func driveRoute(roads structs.RouteStruct, stops []string) DriveRoute {
+	var route DriveRoute
+	for i, leg := range roads.Routes[0].Legs {
+		if i < len(stops) && len(leg.Points) != 0 {
+			route.Stops = append(route.Stops, stops[i])
+			route.Legs = append(route.Legs, leg.Points)
+		}
+	}
+	return route
+}
+
+// RemainingRoute Plans the rest of the trip from the position, through the stops of the route which are left, in the
+// current traffic. Returns the progress and the route to compare the next check-in with
+func RemainingRoute(trip structs.Trip, route DriveRoute, position utils.Coordinate) (structs.TripProgress, DriveRoute, error) {
+	if len(route.Legs) == 0 {
+		return structs.TripProgress{}, route, errors.New("the route of the trip has no points")
+	}
+	now := time.Now()
+	leg, deviation := closestLeg(route, position)
+	progress := structs.TripProgress{Trip: trip.ID, Position: position, Time: now.Format(time.RFC3339),
+		NextStop: route.Stops[leg], RemainingStops: route.Stops[leg:], Deviation: int(math.Round(deviation)),
+		OffRoute: deviation > DeviationThreshold}
+
+	// The destination is the end of the last leg
+	last := route.Legs[len(route.Legs)-1]
+	destination := last[len(last)-1]
+	if utils.Haversine(position.Latitude, position.Longitude, destination.Latitude, destination.Longitude) <= ArrivalRadius {
+		progress.Arrived = true
+		progress.NextStop, progress.RemainingStops = route.Stops[len(route.Stops)-1], []string{}
+		progress.ETA = now.Format(time.RFC3339)
+		setDelay(&progress, trip, now)
+		return progress, DriveRoute{}, nil
+	}
+
+	request, _, err := tripRouteRequest(trip, utils.Query{})
+	if err != nil {
+		return structs.TripProgress{}, route, err
+	}
+	coordinates, err := TripCoordinates(progress.RemainingStops)
+	if err != nil {
+		return structs.TripProgress{}, route, err
+	}
+	request.coordinates = strconv.FormatFloat(position.Latitude, 'f', 6, 64) + "%2C" +
+		strconv.FormatFloat(position.Longitude, 'f', 6, 64) + "%3A" + coordinates
+	request.preferences.Traffic = true
+	request.preferences.DepartAt, request.preferences.ArriveAt = "", ""
+	roads, _, err := FetchRoute(request.coordinates, RouteOptions(request.profile, request.preferences), nil)
+	if err != nil {
+		return structs.TripProgress{}, route, err
+	}
+	planned := request.plan(roads)
+
+	summary := planned.roads.Routes[0].Summary
+	progress.Distance = summary.LengthInMeters
+	progress.TravelTime = minutes(time.Duration(summary.TravelTimeInSeconds)*time.Second + planned.wait)
+	progress.TrafficDelay = minutes(time.Duration(summary.TrafficDelayInSeconds) * time.Second)
+	progress.ETA = planned.arrival.Format(time.RFC3339)
+	setDelay(&progress, trip, planned.arrival)
+	return progress, driveRoute(planned.roads, progress.RemainingStops), nil
+}
+
+// closestLeg The leg of the route closest to the position, and the distance to it in meters
+func closestLeg(route DriveRoute, position utils.Coordinate) (int, float64) {
+	closest, distance := 0, math.Inf(1)
+	for i, leg := range route.Legs {
+		if d := utils.DistanceToPath(position, leg); d < distance {
+			closest, distance = i, d
+		}
+	}
+	return closest, distance
+}
+
+// setDelay Sets how many minutes after the planned arrival of the trip the estimated arrival is, if it has one
+func setDelay(progress *structs.TripProgress, trip structs.Trip, eta time.Time) {
+	arrival, err := time.Parse(time.RFC3339, trip.Arrival)
+	if err != nil {
+		return
+	}
+	delay := int(math.Round(eta.Sub(arrival).Minutes()))
+	progress.PlannedArrival, progress.Delay = trip.Arrival, &delay
+}
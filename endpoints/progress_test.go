@@ -0,0 +1,36 @@
+package endpoints
+
+import (
+	"cloudproject/structs"
+	"cloudproject/utils"
+	"encoding/json"
+	"reflect"
+	"testing"
+)
+
+func TestDriveRoute(t *testing.T) {
+	var roads structs.RouteStruct
+	if err := json.Unmarshal([]byte(`{"routes": [{"legs": [
+		{"points": [{"latitude": 60.7953, "longitude": 10.6917}, {"latitude": 60.97355, "longitude": 10.57342}]},
+		{"points": []},
+		{"points": [{"latitude": 60.97355, "longitude": 10.57342}, {"latitude": 61.1153, "longitude": 10.4662}]}]}]}`), &roads); err != nil {
+		t.Fatal(err)
+	}
+
+	// The leg without points is left out with its stop
+	route := driveRoute(roads, []string{"biri", "moelv", "lillehammer"})
+	if !reflect.DeepEqual(route.Stops, []string{"biri", "lillehammer"}) || len(route.Legs) != 2 {
+		t.Fatalf("Expected legs to biri and lillehammer; got %+v", route)
+	}
+
+	// A position by the second leg is on the way to its stop
+	leg, deviation := closestLeg(route, utils.Coordinate{Latitude: 61.05, Longitude: 10.52})
+	if leg != 1 || deviation > DeviationThreshold {
+		t.Errorf("Expected to be on the leg to lillehammer; got leg %v %.0f meters off", leg, deviation)
+	}
+
+	// Bergen is far off the route
+	if _, deviation = closestLeg(route, utils.Coordinate{Latitude: 60.3943, Longitude: 5.3259}); deviation <= DeviationThreshold {
+		t.Errorf("Expected Bergen to be off the route; got %.0f meters off", deviation)
+	}
+}
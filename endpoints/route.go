@@ -1,75 +1,82 @@
 package endpoints
 
 import (
+	"bytes"
+	"cloudproject/closures"
 	"cloudproject/database"
+	"cloudproject/router"
 	"cloudproject/structs"
 	"cloudproject/utils"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io/ioutil"
 	"log"
 	"net/http"
 	"net/url"
-	"strings"
+	"strconv"
+	"time"
 )
 
-//Route function will respond with a route from the specified location to a destination
-func Route(w http.ResponseWriter, request *http.Request) {
-	w.Header().Set("Content-Type", "application/json")
+// RouteQuery The query parameters accepted by Route
+var RouteQuery = append(append(utils.QuerySchema{RouteTypeParam}, PreferenceParams...),
+	append(utils.QuerySchema{utils.UnitsParam, ProfileParam}, VehicleParams...)...)
 
-	if len(strings.Split(request.URL.Path, `/`)) != 6 { //Splits url in order to get startLocation and endLocation
-		http.Error(w, "error Bad Request\nExpected input: /route/{startLocation}/{endDestination}", http.StatusBadRequest)
-		return
-	}
+// CompareRoutesQuery The query parameters accepted by CompareRoutes
+var CompareRoutesQuery = append(append(utils.QuerySchema{
+	{Name: "routeTypes", Type: utils.TypeString, Description: "The route types to compare",
+		Enum: routeTypes, Default: RouteFastest + "," + RouteShortest + "," + RouteEco, Multiple: true},
+	{Name: "alternatives", Type: utils.TypeInteger, Description: "Number of alternatives to the route of each route type",
+		Default: "0", Minimum: utils.Bound(0), Maximum: utils.Bound(3)},
+}, PreferenceParams...), append(utils.QuerySchema{utils.UnitsParam, ProfileParam}, VehicleParams...)...)
 
-	StartAddress := strings.Split(request.URL.Path, `/`)[4] //Getting the address/name of the place we want to look for chargers
-	EndAddress := strings.Split(request.URL.Path, `/`)[5]   //Getting the address/name of the place we want to look for chargers
+// routeRequest The places, vehicle and preferences a route is requested for
+type routeRequest struct {
+	coordinates string
+	profile     *structs.VehicleProfile
+	preferences structs.RoutePreferences
+	vehicle     string
+	consumption float64
+}
 
-	startLat, startLong, err := database.LocationPresent(url.QueryEscape(StartAddress)) //Gets coordinates of start address
-	if err != nil {
-		log.Println("Unable to get request for address: " + StartAddress + "\n" + err.Error())
-		http.Error(w, err.Error(), http.StatusBadRequest)
-		return
-	}
+// plannedRoute A route, with what is found along it
+type plannedRoute struct {
+	roads     structs.RouteStruct
+	closures  []structs.RouteClosure
+	ferries   []structs.FerryLeg
+	wait      time.Duration // For the ferries
+	departure time.Time
+	arrival   time.Time // Including the wait for ferries
+	cost      structs.RouteCost
+}
 
-	EndLat, endLong, err := database.LocationPresent(url.QueryEscape(EndAddress)) //Gets coordinates for destination
-	if err != nil {
-		log.Println("Unable to get request for address: " + EndAddress + "\n" + err.Error())
-		http.Error(w, err.Error(), http.StatusBadRequest)
-		return
-	}
+//Route function will respond with a route from the specified location to a destination
+func Route(w http.ResponseWriter, request *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
 
-	coordinates := startLat + "%2C" + startLong + "%3A" + EndLat + "%2C" + endLong
+	units := router.Query(request).Get("units")
 
-	//Gets route using coordinates of start and end location
-	resp, err := http.Get("https://api.tomtom.com/routing/1/calculateRoute/" + coordinates + "/json?instructionsType=coded&traffic=false&avoid=unpavedRoads&travelMode=car&key=" + utils.TomtomKey)
-	errTomTom := utils.TomTomErrorHandling(resp.StatusCode)
+	trip, status, err := parseRouteRequest(request)
 	if err != nil {
-		log.Println("Unable to get response for coordinates: " + coordinates + "\n" + err.Error())
-		http.Error(w, err.Error(), resp.StatusCode)
-		return
-	} else if errTomTom != nil {
-		log.Println("TomTom error for coordinates: " + coordinates + "\n" + err.Error())
-		http.Error(w, errTomTom.Error(), resp.StatusCode)
+		http.Error(w, err.Error(), status)
 		return
 	}
+	writeRoute(w, request, trip, units)
+}
 
-	//Reads body
-	body, err := ioutil.ReadAll(resp.Body)
+// writeRoute Answers with the route of the request, with the closures, ferries and cost along it
+func writeRoute(w http.ResponseWriter, request *http.Request, trip routeRequest, units string) {
+	//Gets route using coordinates of the start, the stops and the destination
+	roads, status, err := FetchRoute(trip.coordinates, RouteOptions(trip.profile, trip.preferences), nil)
 	if err != nil {
-		log.Println("Unable to read body:" + string(body) + "\n" + err.Error())
-		http.Error(w, err.Error(), http.StatusBadRequest)
-	}
-
-	//Unmarshalls response into a roads object
-	var roads structs.RouteStruct
-	if err = json.Unmarshal(body, &roads); err != nil {
-		jsonError := utils.JsonUnmarshalErrorHandling(err)
-		log.Println("Unable to unmarshal response for body: " + string(body) + "\n" + err.Error())
-		http.Error(w, jsonError.Error(), http.StatusInternalServerError)
+		http.Error(w, err.Error(), status)
 		return
 	}
 
+	//Finds the closures, ferries and cost of the route
+	planned := trip.plan(roads)
+	roads = planned.roads
+
 	//Defines variables and structs from roads object
 	var maneuver string
 	var junctionType string
@@ -78,9 +85,8 @@ func Route(w http.ResponseWriter, request *http.Request) {
 
 	var total []structs.Route
 
-	drivingLength := roads.Routes[0].Summary.LengthInMeters / 1000
-	estimatedTime := roads.Routes[0].Summary.ArrivalTime
-	estimatedTimeString := estimatedTime.Format("2006-01-02 15:04:05")
+	drivingLength := utils.Distance(float64(roads.Routes[0].Summary.LengthInMeters), units)
+	estimatedTimeString := planned.arrival.Format(time.RFC3339) //In the time zone of the destination
 
 	//For each instruction get maneuver and roadnumber
 	for i := 0; i < len(roads.Routes[0].Guidance.Instructions); i++ {
@@ -98,9 +104,11 @@ func Route(w http.ResponseWriter, request *http.Request) {
 		total = append(total, route) //Appends information
 	}
 
-	information := structs.RoadInformation{EstimatedArrival: estimatedTimeString, LengthKM: drivingLength, Route: total}
+	information := structs.RoadInformation{Departure: planned.departure.Format(time.RFC3339), EstimatedArrival: estimatedTimeString,
+		Length: drivingLength, DistanceUnit: unitsOf(units).Distance, Route: total, Closures: planned.closures,
+		Ferries: planned.ferries, Cost: planned.cost}
 
-	output, err := json.Marshal(information) //Marshalling the array to JSON
+	output, err := json.Marshal(Versioned(request, information)) //Marshalling the array to JSON
 	if err != nil {
 		jsonError := utils.JsonUnmarshalErrorHandling(err)
 		log.Println("Unable to marshall response: " + "\n" + err.Error())
@@ -112,6 +120,244 @@ func Route(w http.ResponseWriter, request *http.Request) {
 
 }
 
+// CompareRoutes Responds with the routes of several route types between two places side by side, with their travel
+// time, length and cost
+func CompareRoutes(w http.ResponseWriter, request *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	query := router.Query(request)
+	units := query.Get("units")
+	count := query.Int("alternatives")
+
+	trip, status, err := parseRouteRequest(request)
+	if err != nil {
+		http.Error(w, err.Error(), status)
+		return
+	}
+
+	alternatives := []structs.RouteAlternative{}
+	for _, routeType := range query.All("routeTypes") {
+		trip.preferences.RouteType = routeType
+		options := RouteOptions(trip.profile, trip.preferences)
+		if count > 0 {
+			options += "&maxAlternatives=" + strconv.Itoa(count)
+		}
+		roads, status, err := FetchRoute(trip.coordinates, options, nil)
+		if err != nil {
+			http.Error(w, err.Error(), status)
+			return
+		}
+
+		// Each of the routes is planned on its own
+		for i := 0; i < len(roads.Routes) && i <= count; i++ {
+			single := roads
+			single.Routes = roads.Routes[i : i+1]
+			planned := trip.plan(single)
+			alternatives = append(alternatives, planned.alternative(routeType, i, units))
+		}
+	}
+
+	output, err := json.Marshal(alternatives)
+	if err != nil {
+		log.Println("Unable to marshall response: " + "\n" + err.Error())
+		http.Error(w, utils.JsonMarshalErrorHandling(err).Error(), http.StatusInternalServerError)
+		return
+	}
+	fmt.Fprintf(w, "%v", string(output))
+}
+
+// parseRouteRequest Finds the places of a route request, and reads the vehicle and the preferences from its query.
+// Returns the status to answer with if the route can not be planned
+func parseRouteRequest(request *http.Request) (routeRequest, int, error) {
+	query := router.Query(request)
+
+	StartAddress := router.Param(request, "start")     //Getting the address/name of the place the route starts
+	EndAddress := router.Param(request, "destination") //Getting the address/name of the destination
+
+	startLat, startLong, err := database.LocationPresent(url.QueryEscape(StartAddress)) //Gets coordinates of start address
+	if err != nil {
+		log.Println("Unable to get request for address: " + StartAddress + "\n" + err.Error())
+		return routeRequest{}, http.StatusBadRequest, err
+	}
+
+	EndLat, endLong, err := database.LocationPresent(url.QueryEscape(EndAddress)) //Gets coordinates for destination
+	if err != nil {
+		log.Println("Unable to get request for address: " + EndAddress + "\n" + err.Error())
+		return routeRequest{}, http.StatusBadRequest, err
+	}
+
+	trip := routeRequest{coordinates: startLat + "%2C" + startLong + "%3A" + EndLat + "%2C" + endLong}
+
+	//The route is restricted to the vehicle of the profile, and its class and consumption are used for the cost
+	var status int
+	trip.profile, status, err = QueryProfile(query)
+	if err != nil {
+		return routeRequest{}, status, err
+	}
+	trip.vehicle, trip.consumption = vehicleOf(trip.profile, query)
+
+	if trip.preferences, err = QueryPreferences(query); err != nil {
+		return routeRequest{}, http.StatusBadRequest, err
+	}
+	return trip, http.StatusOK, nil
+}
+
+// vehicleOf The vehicle class and consumption a route is priced for, those of the profile unless the query gives them
+func vehicleOf(profile *structs.VehicleProfile, query utils.Query) (string, float64) {
+	vehicle := VehicleClass(profile)
+	if query.Has("vehicle") {
+		vehicle = query.Get("vehicle")
+	}
+	consumption := query.Float("consumption")
+	if !query.Has("consumption") && profile != nil {
+		consumption = profile.Consumption
+	}
+	return vehicle, consumption
+}
+
+// plan Routes around the closures to avoid, and finds the ferries and the cost of the route
+func (trip routeRequest) plan(roads structs.RouteStruct) plannedRoute {
+	options := RouteOptions(trip.profile, trip.preferences)
+	departure, arrival := tripTimes(roads, trip.preferences)
+	roads, routeClosures := RouteAroundClosures(trip.coordinates, options, roads, trip.preferences.Avoid, departure, arrival)
+
+	// The route around the closures may take longer
+	departure, arrival = tripTimes(roads, trip.preferences)
+	ferries, wait := FerryLegs(roads, departure, trip.vehicle)
+	return plannedRoute{roads: roads, closures: routeClosures, ferries: ferries, wait: wait, departure: departure,
+		arrival: arrival.Add(wait), cost: RouteCost(roads, ferries, trip.vehicle, trip.consumption)}
+}
+
+// alternative Summarizes the planned route for the comparison of routes
+func (planned plannedRoute) alternative(routeType string, index int, units string) structs.RouteAlternative {
+	summary := planned.roads.Routes[0].Summary
+	closuresOnRoute := 0
+	for _, closure := range planned.closures {
+		if !closure.Avoided {
+			closuresOnRoute++
+		}
+	}
+	return structs.RouteAlternative{RouteType: routeType, Alternative: index,
+		Departure: planned.departure.Format(time.RFC3339), EstimatedArrival: planned.arrival.Format(time.RFC3339),
+		TravelTime: int((time.Duration(summary.TravelTimeInSeconds)*time.Second + planned.wait).Minutes()),
+		Length:     utils.Distance(float64(summary.LengthInMeters), units), DistanceUnit: unitsOf(units).Distance,
+		Tolls: len(planned.cost.Tolls), Ferries: len(planned.ferries), Closures: closuresOnRoute,
+		Cost: planned.cost.Total, Currency: planned.cost.Currency}
+}
+
+// FetchRoute Gets the route between the coordinates from TomTom with the routing options, such as those of RouteOptions,
+// routing around the areas of the closures. Returns the status to answer with if the route is unavailable
+func FetchRoute(coordinates string, options string, avoid []closures.Closure) (structs.RouteStruct, int, error) {
+	routeURL := utils.TomTomURL + "/routing/1/calculateRoute/" + coordinates + "/json?instructionsType=coded&sectionType=ferry" + options + "&key=" + utils.TomtomKey
+
+	var resp *http.Response
+	var err error
+	if len(avoid) == 0 {
+		resp, err = http.Get(routeURL)
+	} else {
+		// The areas to avoid are posted, at most 10 rectangles are accepted
+		var rectangles []map[string]utils.Coordinate
+		for i := 0; i < len(avoid) && i < 10; i++ {
+			southWest, northEast := avoid[i].Area()
+			rectangles = append(rectangles, map[string]utils.Coordinate{"southWestCorner": southWest, "northEastCorner": northEast})
+		}
+		body, _ := json.Marshal(map[string]interface{}{"avoidAreas": map[string]interface{}{"rectangles": rectangles}})
+		resp, err = http.Post(routeURL, "application/json", bytes.NewReader(body))
+	}
+	if err != nil {
+		log.Println("Unable to get response for coordinates: " + coordinates + "\n" + err.Error())
+		return structs.RouteStruct{}, http.StatusBadGateway, err
+	}
+	defer resp.Body.Close()
+	if errTomTom := utils.TomTomErrorHandling(resp.StatusCode); errTomTom != nil {
+		log.Println("TomTom error for coordinates: " + coordinates + "\n" + errTomTom.Error())
+		return structs.RouteStruct{}, resp.StatusCode, errTomTom
+	}
+
+	//Reads body
+	body, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		log.Println("Unable to read body:" + string(body) + "\n" + err.Error())
+		return structs.RouteStruct{}, http.StatusBadRequest, err
+	}
+
+	//Unmarshalls response into a roads object
+	var roads structs.RouteStruct
+	if err = json.Unmarshal(body, &roads); err != nil {
+		log.Println("Unable to unmarshal response for body: " + string(body) + "\n" + err.Error())
+		return structs.RouteStruct{}, http.StatusInternalServerError, utils.JsonUnmarshalErrorHandling(err)
+	}
+	if len(roads.Routes) == 0 {
+		return structs.RouteStruct{}, http.StatusNotFound, errors.New("No route was found between the places")
+	}
+	return roads, http.StatusOK, nil
+}
+
+// RouteAroundClosures Finds the closures on the route in effect between the departure and the arrival. If any of them
+// are of the kinds to avoid, a route around them is requested, and is used if it is found. Returns the route with the
+// closures on the original route, where those the route no longer passes are marked as avoided
+func RouteAroundClosures(coordinates string, options string, roads structs.RouteStruct, avoid []string, departure time.Time,
+	arrival time.Time) (structs.RouteStruct, []structs.RouteClosure) {
+	current, err := closures.Current()
+	if err != nil {
+		log.Println("The road closures are unavailable, the route is not checked for closures.\n" + err.Error())
+		return roads, []structs.RouteClosure{}
+	}
+	if arrival.Before(departure) {
+		arrival = departure
+	}
+	matched := closures.Match(current, routePath(roads), departure, arrival)
+
+	var toAvoid []closures.Closure
+	for _, closure := range matched {
+		for _, kind := range avoid {
+			if (kind == AvoidClosures && closure.Type == closures.Closed) || (kind == AvoidConvoys && closure.Type == closures.Convoy) {
+				toAvoid = append(toAvoid, closure)
+			}
+		}
+	}
+
+	stillOnRoute := matched
+	if len(toAvoid) != 0 {
+		alternative, _, err := FetchRoute(coordinates, options, toAvoid)
+		if err != nil {
+			log.Println("No route around the closures was found, the original route is used.\n" + err.Error())
+		} else {
+			roads = alternative
+			stillOnRoute = closures.Match(matched, routePath(roads), departure, arrival)
+		}
+	}
+
+	routeClosures := []structs.RouteClosure{}
+	for _, closure := range matched {
+		avoided := true
+		for _, onRoute := range stillOnRoute {
+			if onRoute.ID == closure.ID {
+				avoided = false
+			}
+		}
+		routeClosure := structs.RouteClosure{ID: closure.ID, Type: closure.Type, Winter: closure.Winter, Road: closure.Road,
+			Description: closure.Description, Avoided: avoided}
+		if closure.Start.After(departure) {
+			routeClosure.Start = closure.Start.Format(time.RFC3339)
+		}
+		if !closure.End.IsZero() {
+			routeClosure.End = closure.End.Format(time.RFC3339)
+		}
+		routeClosures = append(routeClosures, routeClosure)
+	}
+	return roads, routeClosures
+}
+
+// routePath The points of the first route, from all its legs
+func routePath(roads structs.RouteStruct) []utils.Coordinate {
+	var path []utils.Coordinate
+	for _, leg := range roads.Routes[0].Legs {
+		path = append(path, leg.Points...)
+	}
+	return path
+}
+
 //Maneuvers that map to a more detailed description
 var maneuvers = map[string]string{
 	"ARRIVE":               "You have arrived.",
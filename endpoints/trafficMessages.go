@@ -1,134 +1,378 @@
 package endpoints
 
 import (
-	"cloudproject/database"
+	"cloudproject/router"
 	"cloudproject/structs"
 	"cloudproject/utils"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io/ioutil"
 	"log"
+	"math"
 	"net/http"
 	"net/url"
+	"sort"
 	"strconv"
 	"strings"
 	"time"
 )
 
-//Messages for getting traffic messages for an area
+// MessagesQuery The query parameters accepted by Messages
+var MessagesQuery = append(append(utils.QuerySchema{IncidentRadiusParam}, IncidentFilterParams...), RouteQuery...)
+
+// TripMessagesQuery The query parameters accepted by TripMessages
+var TripMessagesQuery = append(utils.QuerySchema{IncidentRadiusParam, utils.UnitsParam}, IncidentFilterParams...)
+
+// Statuses of incidents, active now or planned to start later
+const (
+	IncidentActive  = "active"
+	IncidentPlanned = "planned"
+)
+
+// MagnitudeUndefined The magnitude of delay of closures and other incidents without a known end to the delay
+const MagnitudeUndefined = 4
+
+// severities The names of the magnitudes of delay of incidents, by magnitude
+var severities = []string{"unknown", "minor", "moderate", "major", "undefined"}
+
+// incidentCategories The names of the icon categories of incidents, by category
+var incidentCategories = map[int]string{0: "unknown", 1: "accident", 2: "fog", 3: "dangerousConditions", 4: "rain", 5: "ice",
+	6: "jam", 7: "laneClosed", 8: "roadClosed", 9: "roadWorks", 10: "wind", 11: "flooding", 14: "brokenDownVehicle"}
+
+// IncidentFilterParams The query parameters filtering traffic incidents
+var IncidentFilterParams = utils.QuerySchema{
+	{Name: "category", Type: utils.TypeString, Description: "Categories of the incidents to list", Multiple: true,
+		Enum: []string{"unknown", "accident", "fog", "dangerousConditions", "rain", "ice", "jam", "laneClosed", "roadClosed",
+			"roadWorks", "wind", "flooding", "brokenDownVehicle"}},
+	{Name: "severity", Type: utils.TypeString, Description: "The least magnitude of delay of the incidents to list. Closures and other incidents of undefined delay are always listed",
+		Enum: severities[1:4]},
+	{Name: "road", Type: utils.TypeString, Description: "Road numbers of the incidents to list, such as E6", Multiple: true},
+	{Name: "status", Type: utils.TypeString, Description: "Only the incidents active now, or only those planned to start later",
+		Enum: []string{IncidentActive, IncidentPlanned}},
+}
+
+// IncidentRadiusParam The query parameter for how far from a route incidents are on it
+var IncidentRadiusParam = utils.QueryParam{Name: "radius", Type: utils.TypeInteger, Description: "Meters from the route an incident can be to be on it",
+	Default: strconv.Itoa(IncidentRadius), Minimum: utils.Bound(10), Maximum: utils.Bound(5000)}
+
+// IncidentRadius Meters from a route an incident is on it by default
+const IncidentRadius = 200
+
+// PassageMargin The time before and after a route passes an incident, the incident counts as during the passage if it
+// is there at some point in between
+const PassageMargin = 30 * time.Minute
+
+// magnitudeDelays Minutes of delay estimated from the magnitude of an incident when TomTom gives no delay. Unknown and
+// undefined magnitudes, the latter used for closures, are not estimated
+var magnitudeDelays = map[int]int{1: 2, 2: 5, 3: 15}
+
+// Messages Responds with the traffic incidents on the route between two places, in the order they are passed
 func Messages(w http.ResponseWriter, request *http.Request) {
 	w.Header().Set("Content-Type", "application/json")
-	if len(strings.Split(request.URL.Path, `/`)) != 6 {
-		log.Println("Unable to get response for request")
-		http.Error(w, "error Bad Request\nExpected input: /rtc/v1/messages/{startLocation}/{endDestination}", http.StatusBadRequest)
+
+	trip, status, err := parseRouteRequest(request)
+	if err != nil {
+		http.Error(w, err.Error(), status)
+		return
+	}
+	roads, status, err := FetchRoute(trip.coordinates, RouteOptions(trip.profile, trip.preferences), nil)
+	if err != nil {
+		http.Error(w, err.Error(), status)
 		return
 	}
-	StartAddress := strings.Split(request.URL.Path, `/`)[4] //Getting the address/name of the place we want to look for chargers
-	EndAddress := strings.Split(request.URL.Path, `/`)[5]   //Getting the address/name of the place we want to look for chargers
+	departure, _ := tripTimes(roads, trip.preferences)
 
-	bodyBox, err := getBBox(StartAddress, EndAddress) //Get BBox object for traffic messages
+	incidents, status, err := routeIncidents(roads, departure, router.Query(request))
+	if err != nil {
+		http.Error(w, err.Error(), status)
+		return
+	}
+	writeIncidents(w, request, incidents)
+}
+
+// writeIncidents Answers with the incidents
+func writeIncidents(w http.ResponseWriter, request *http.Request, incidents []structs.OutIncident) {
+	if incidents == nil {
+		incidents = []structs.OutIncident{}
+	}
+	output, err := json.Marshal(Versioned(request, incidents)) //Marshalling the array to JSON
 	if err != nil {
-		log.Println("Unable to get response for getBBox method provided: " + StartAddress + " and " + EndAddress + "\n" + err.Error())
-		http.Error(w, err.Error(), http.StatusBadRequest)
+		log.Println("Unable to marshall all incidents" + "\n" + err.Error())
+		http.Error(w, utils.JsonMarshalErrorHandling(err).Error(), http.StatusInternalServerError)
 		return
 	}
+	fmt.Fprintf(w, "%v", string(output))
+}
+
+// routeIncidents The traffic incidents within the radius in meters of the route passing the filters of the query, in the
+// order they are passed departing at the time. Returns the status to answer with if they are unavailable
+func routeIncidents(roads structs.RouteStruct, departure time.Time, query utils.Query) ([]structs.OutIncident, int, error) {
+	radius := query.Float("radius")
+	if radius <= 0 {
+		radius = IncidentRadius
+	}
+	path := routePath(roads)
+	if len(path) == 0 {
+		return nil, http.StatusBadGateway, errors.New("The route has no points")
+	}
+
+	// The incidents are asked for in the bounding box of the route, widened by the radius
+	south, west, north, east := path[0].Latitude, path[0].Longitude, path[0].Latitude, path[0].Longitude
+	for _, point := range path {
+		south, north = minFloat(south, point.Latitude), maxFloat(north, point.Latitude)
+		west, east = minFloat(west, point.Longitude), maxFloat(east, point.Longitude)
+	}
+	latitude := radius / 111000.0
+	longitude := latitude / math.Cos(maxFloat(math.Abs(south), math.Abs(north))*math.Pi/180)
+	var corners []string
+	for _, value := range []float64{west - longitude, south - latitude, east + longitude, north + latitude} {
+		corners = append(corners, strconv.FormatFloat(value, 'f', 6, 64))
+	}
+	found, status, err := fetchIncidents(strings.Join(corners, ","))
+	if err != nil {
+		return nil, status, err
+	}
+	var matching []structs.Incident
+	now := time.Now()
+	for _, incident := range found {
+		if incidentMatches(incident, query, now) {
+			matching = append(matching, incident)
+		}
+	}
+	return incidentsOnRoute(matching, roads, departure, radius, query.Get("units")), http.StatusOK, nil
+}
 
-	var bbox structs.BboxStruct
-	if err = json.Unmarshal(bodyBox, &bbox); err != nil { //Unmarshalling bbox response into bbox struct
-		log.Println("Unable to unmarshall response into bbox struct" + "\n" + err.Error())
-		http.Error(w, err.Error(), http.StatusInternalServerError)
+// incidentMatches Checks if the incident passes the filters of the query at the time
+func incidentMatches(incident structs.Incident, query utils.Query, now time.Time) bool {
+	properties := incident.Properties
+	if query.Has("category") && !utils.Contains(query.All("category"), categoryOf(properties.IconCategory)) {
+		return false
+	}
+	if query.Has("severity") && properties.MagnitudeOfDelay != MagnitudeUndefined {
+		for magnitude, severity := range severities {
+			if severity == query.Get("severity") && properties.MagnitudeOfDelay < magnitude {
+				return false
+			}
+		}
+	}
+	if query.Has("road") {
+		found := false
+		for _, road := range properties.RoadNumbers {
+			for _, wanted := range query.All("road") {
+				found = found || roadNumber(road) == roadNumber(wanted)
+			}
+		}
+		if !found {
+			return false
+		}
 	}
+	switch query.Get("status") {
+	case IncidentActive:
+		return incidentActive(incident, now)
+	case IncidentPlanned:
+		return properties.StartTime.After(now)
+	}
+	return true
+}
 
-	var box string
-	for j := 0; j < len(bbox.Bbox); j++ { //Reformatting bbox
-		coordinate := strconv.FormatFloat(bbox.Bbox[j], 'f', 6, 64)
-		box += coordinate + ","
+// categoryOf The name of the icon category, categories without a name are unknown
+func categoryOf(iconCategory int) string {
+	if name, found := incidentCategories[iconCategory]; found {
+		return name
 	}
-	box = strings.TrimRight(box, ",")
+	return incidentCategories[0]
+}
 
+// incidentActive Checks if the incident has started and not ended at the time
+func incidentActive(incident structs.Incident, now time.Time) bool {
+	return !incident.Properties.StartTime.After(now) && (incident.Properties.EndTime.IsZero() || incident.Properties.EndTime.After(now))
+}
+
+// roadNumber The road number in upper case without spaces, so E 6 and e6 are the same road
+func roadNumber(road string) string {
+	return strings.ToUpper(strings.Replace(road, " ", "", -1))
+}
+
+// incidentsOnRoute The incidents within the radius in meters of the route, in the order they are passed departing at the
+// time, with where and when they are passed
+func incidentsOnRoute(found []structs.Incident, roads structs.RouteStruct, departure time.Time, radius float64, units string) []structs.OutIncident {
+	path, distances, times := routeTimeline(roads, departure)
+	var incidents []structs.OutIncident
+	var along []float64
+	for _, incident := range found {
+		deviation, distance, passage := math.Inf(1), math.Inf(1), time.Time{}
+
+		// The incident is met where the first of its points within the radius is passed
+		for _, point := range incidentPoints(incident) {
+			off, segment, t := utils.ClosestOnPath(point, path)
+			deviation = math.Min(deviation, off)
+			if off > radius {
+				continue
+			}
+			next := segment
+			if segment+1 < len(path) {
+				next = segment + 1
+			}
+			if at := distances[segment] + t*(distances[next]-distances[segment]); at < distance {
+				distance = at
+				passage = times[segment].Add(time.Duration(t * float64(times[next].Sub(times[segment]))))
+			}
+		}
+		if deviation > radius {
+			continue
+		}
+
+		out := outIncident(incident, units)
+		out.Distance, out.DistanceUnit = utils.Round(utils.Distance(distance, units), 1), unitsOf(units).Distance
+		out.Deviation = utils.ShortDistance(int(math.Round(deviation)), units)
+		out.Delay = incidentDelay(incident)
+		out.Passage = passage.Round(time.Second).Format(time.RFC3339)
+		out.DuringPassage = out.Start.Before(passage.Add(PassageMargin)) && (out.End.IsZero() || out.End.After(passage.Add(-PassageMargin)))
+		incidents = append(incidents, out)
+		along = append(along, distance)
+	}
+	sort.Sort(byDistance{incidents, along})
+	return incidents
+}
+
+// byDistance Sorts incidents by the meters along the route to them
+type byDistance struct {
+	incidents []structs.OutIncident
+	distances []float64
+}
+
+func (b byDistance) Len() int           { return len(b.incidents) }
+func (b byDistance) Less(i, j int) bool { return b.distances[i] < b.distances[j] }
+func (b byDistance) Swap(i, j int) {
+	b.incidents[i], b.incidents[j] = b.incidents[j], b.incidents[i]
+	b.distances[i], b.distances[j] = b.distances[j], b.distances[i]
+}
+
+// routeTimeline The points of the first route, with the meters from the start to each and the time it is passed. The
+// times are estimated from the travel time of each leg, without waiting for ferries
+func routeTimeline(roads structs.RouteStruct, departure time.Time) ([]utils.Coordinate, []float64, []time.Time) {
+	var path []utils.Coordinate
+	var distances []float64
+	var times []time.Time
+	at, distance := departure, 0.0
+	for _, leg := range roads.Routes[0].Legs {
+		if len(leg.Points) == 0 {
+			continue
+		}
+		along := make([]float64, len(leg.Points))
+		for j := 1; j < len(leg.Points); j++ {
+			along[j] = along[j-1] + utils.Haversine(leg.Points[j-1].Latitude, leg.Points[j-1].Longitude,
+				leg.Points[j].Latitude, leg.Points[j].Longitude)
+		}
+		length, travel := along[len(along)-1], time.Duration(leg.Summary.TravelTimeInSeconds)*time.Second
+		for j, point := range leg.Points {
+			path = append(path, point)
+			distances = append(distances, distance+along[j])
+			if length > 0 {
+				times = append(times, at.Add(time.Duration(float64(travel)*along[j]/length)))
+			} else {
+				times = append(times, at)
+			}
+		}
+		at, distance = at.Add(travel), distance+length
+	}
+	return path, distances, times
+}
+
+// incidentPoints The coordinates of the geometry of the incident, which is a point or a line
+func incidentPoints(incident structs.Incident) []utils.Coordinate {
+	var line [][]float64
+	if err := json.Unmarshal(incident.Geometry.Coordinates, &line); err != nil {
+		var point []float64
+		if err := json.Unmarshal(incident.Geometry.Coordinates, &point); err != nil {
+			return nil
+		}
+		line = [][]float64{point}
+	}
+	var points []utils.Coordinate
+	for _, position := range line {
+		if len(position) >= 2 {
+			points = append(points, utils.Coordinate{Latitude: position[1], Longitude: position[0]})
+		}
+	}
+	return points
+}
+
+// incidentDelay The minutes of delay of the incident, estimated from its magnitude if TomTom gives no delay
+func incidentDelay(incident structs.Incident) int {
+	if incident.Properties.Delay > 0 {
+		return minutes(time.Duration(incident.Properties.Delay) * time.Second)
+	}
+	return magnitudeDelays[incident.Properties.MagnitudeOfDelay]
+}
+
+//fetchIncidents Gets the traffic incidents in the bbox from TomTom, the bbox is given as min longitude, min latitude,
+//max longitude and max latitude. Returns the status to answer with if the incidents are unavailable
+func fetchIncidents(box string) ([]structs.Incident, int, error) {
 	//Gets traffic messages in bbox area
-	response, err := http.Get("https://api.tomtom.com/traffic/services/5/incidentDetails?bbox=" + url.QueryEscape(box) +
+	response, err := http.Get(utils.TomTomURL + "/traffic/services/5/incidentDetails?bbox=" + url.QueryEscape(box) +
 		"&fields=%7Bincidents%7Btype%2Cgeometry%7Btype%2Ccoordinates%7D%2Cproperties%7Bid%2CiconCategory%2CmagnitudeOfDelay%2Cevents%7Bdescription%2Ccode%7D%2CstartTime%2Cend" +
 		"Time%2Cfrom%2Cto%2Clength%2Cdelay%2CroadNumbers%2Caci%7BprobabilityOfOccurrence%2CnumberOfReports%2ClastReportTime%7D%7D%7D%7D&key=" + utils.TomtomKey)
+	if err != nil {
+		log.Println("Unable to get response for bbox: " + box + "\n" + err.Error())
+		return nil, http.StatusBadGateway, err
+	}
+	defer response.Body.Close()
 	err = utils.TomTomErrorHandling(response.StatusCode)
 	if err != nil {
 		log.Println("Unable to get response for bbox: " + "\n" + err.Error())
-		http.Error(w, err.Error(), response.StatusCode)
-		return
+		return nil, response.StatusCode, err
 	}
 
 	body, err := ioutil.ReadAll(response.Body) //Reads response
 	if err != nil {
 		log.Println("Unable to read response " + string(body) + "\n" + err.Error())
-		http.Error(w, err.Error(), http.StatusInternalServerError)
-		return
+		return nil, http.StatusInternalServerError, err
 	}
 
 	var messages structs.Incidents
 	if err = json.Unmarshal(body, &messages); err != nil { //Unmarshalls traffic incidents into incidents struct
 		log.Println("Unable to unmarshall response for body: " + string(body) + "\n" + err.Error())
-		jsonError := utils.JsonUnmarshalErrorHandling(err)
-		http.Error(w, jsonError.Error(), http.StatusInternalServerError)
-		return
+		return nil, http.StatusInternalServerError, utils.JsonUnmarshalErrorHandling(err)
 	}
 
-	var all []structs.OutIncident //Formats incidents
-	time := time.Now().Add(-60 * time.Minute)
+	// Incidents which ended more than an hour ago are left out
+	var all []structs.Incident
+	since := time.Now().Add(-60 * time.Minute)
 
 	for i := 0; i < len(messages.Incidents); i++ {
-		if messages.Incidents[i].Properties.EndTime.Before(time) {
-			startTime := messages.Incidents[i].Properties.StartTime
-			endTime := messages.Incidents[i].Properties.EndTime
-			FromAddress := messages.Incidents[i].Properties.From
-			toAddress := messages.Incidents[i].Properties.To
-			Event := messages.Incidents[i].Properties.Events[0].Description
-
-			incidents := structs.OutIncident{From: FromAddress, To: toAddress, Start: startTime, End: endTime, Event: Event}
-			all = append(all, incidents) //appends all information into incidents struct
+		if end := messages.Incidents[i].Properties.EndTime; end.IsZero() || end.After(since) {
+			all = append(all, messages.Incidents[i])
 		}
 	}
 
-	output, err := json.Marshal(all) //Marshalling the array to JSON
-	if err != nil {
-		jsonError := utils.JsonUnmarshalErrorHandling(err)
-		log.Println("Unable to marshall all incidents" + "\n" + err.Error())
-		http.Error(w, jsonError.Error(), http.StatusInternalServerError)
-		return
-	}
-
-	fmt.Fprintf(w, "%v", string(output)) //Outputs the chargers
-
+	return all, http.StatusOK, nil
 }
 
-//getBBox function for creating a BBox object used for getting traffic messages
-func getBBox(StartAddress string, endAddress string) ([]byte, error) {
-	//Defines request to get latitude for startlocation
-	startLat, startLong, err := database.LocationPresent(url.QueryEscape(StartAddress))
-	if err != nil {
-		log.Println("Unable to get response" + "\n" + err.Error())
-		return nil, err
+// outIncident Formats the incident, with its length in the unit system
+func outIncident(incident structs.Incident, units string) structs.OutIncident {
+	properties := incident.Properties
+	out := structs.OutIncident{ID: properties.ID, From: properties.From, To: properties.To, Start: properties.StartTime,
+		End: properties.EndTime, Events: []string{}, IconCategory: properties.IconCategory,
+		Category: categoryOf(properties.IconCategory), MagnitudeOfDelay: properties.MagnitudeOfDelay,
+		ReportedDelay: properties.Delay, Length: utils.ShortDistance(int(math.Round(properties.Length)), units),
+		RoadNumbers: properties.RoadNumbers, Aci: properties.Aci, Active: incidentActive(incident, time.Now()),
+		Geometry: incidentPoints(incident)}
+	for _, event := range properties.Events {
+		out.Events = append(out.Events, event.Description)
 	}
-
-	//Defines request to get latitude for endlocation
-	EndLat, endLong, err := database.LocationPresent(url.QueryEscape(endAddress))
-	if err != nil {
-		log.Println("Unable to get response" + "\n" + err.Error())
-		return nil, err
+	if len(out.Events) != 0 {
+		out.Event = out.Events[0]
 	}
-
-	//Defines request to get BBox using startlat and endlat
-	resp, err := http.Get("https://api.openrouteservice.org/v2/directions/driving-car?api_key=" + utils.OpenRouteServiceKey + "&start=" + startLong + "," + startLat + "&end=" + endLong + "," + EndLat)
-	if err != nil {
-		log.Println("Unable to get response" + "\n" + err.Error())
-		return nil, utils.OpenRouteError(resp.StatusCode)
+	if properties.MagnitudeOfDelay >= 0 && properties.MagnitudeOfDelay < len(severities) {
+		out.Severity = severities[properties.MagnitudeOfDelay]
 	}
-
-	//Reads body
-	body, err := ioutil.ReadAll(resp.Body)
-	if err != nil {
-		log.Println("Unable to read response" + "\n" + err.Error())
-		return nil, err
+	if out.RoadNumbers == nil {
+		out.RoadNumbers = []string{}
 	}
-
-	return body, err
+	if out.Geometry == nil {
+		out.Geometry = []utils.Coordinate{}
+	}
+	return out
 }
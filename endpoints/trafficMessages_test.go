@@ -0,0 +1,86 @@
+package endpoints
+
+import (
+	"cloudproject/structs"
+	"cloudproject/utils"
+	"encoding/json"
+	"testing"
+	"time"
+)
+
+func TestIncidentsOnRoute(t *testing.T) {
+	// A leg of 100 km north along the meridian at 10° east taking an hour
+	var roads structs.RouteStruct
+	content := `{"routes": [{"legs": [{"summary": {"travelTimeInSeconds": 3600},
+		"points": [{"latitude": 60, "longitude": 10}, {"latitude": 60.45, "longitude": 10}, {"latitude": 60.9, "longitude": 10}]}]}]}`
+	if err := json.Unmarshal([]byte(content), &roads); err != nil {
+		t.Fatal(err)
+	}
+	departure := time.Date(2021, 5, 17, 8, 0, 0, 0, time.UTC)
+
+	// Roadworks three quarters of the way, a closure on a line crossing the route a quarter of the way, and an accident
+	// a kilometer east of the route
+	var found []structs.Incident
+	content = `[
+		{"geometry": {"type": "Point", "coordinates": [10, 60.675]}, "properties": {"from": "C", "to": "D",
+			"startTime": "2021-05-17T00:00:00Z", "endTime": "2021-05-17T08:10:00Z", "magnitudeOfDelay": 1, "events": [{"description": "Roadworks"}]}},
+		{"geometry": {"type": "LineString", "coordinates": [[10.01, 60.2], [10, 60.225], [9.99, 60.25]]}, "properties": {"from": "A", "to": "B",
+			"startTime": "2021-05-17T08:00:00Z", "magnitudeOfDelay": 4, "delay": 600, "events": [{"description": "Closed"}]}},
+		{"geometry": {"type": "Point", "coordinates": [10.0185, 60.45]}, "properties": {"from": "E", "to": "F",
+			"startTime": "2021-05-17T07:00:00Z", "endTime": "2021-05-17T12:00:00Z", "events": [{"description": "Accident"}]}}
+	]`
+	if err := json.Unmarshal([]byte(content), &found); err != nil {
+		t.Fatal(err)
+	}
+
+	incidents := incidentsOnRoute(found, roads, departure, IncidentRadius, utils.Metric)
+	if len(incidents) != 2 || incidents[0].From != "A" || incidents[1].From != "C" {
+		t.Fatalf("Expected the closure and then the roadworks; got %+v", incidents)
+	}
+
+	// The closure is met where its line crosses the route, the roadworks are gone half an hour after they are passed
+	closure, roadworks := incidents[0], incidents[1]
+	if closure.Distance != 25 || closure.Deviation != 0 || closure.Delay != 10 ||
+		closure.Passage != "2021-05-17T08:15:00Z" || !closure.DuringPassage {
+		t.Errorf("Expected the closure 25 km along the route at 08:15; got %+v", closure)
+	}
+	if roadworks.Distance != 75.1 || roadworks.DistanceUnit != "km" || roadworks.Delay != 2 ||
+		roadworks.Passage != "2021-05-17T08:45:00Z" || roadworks.DuringPassage {
+		t.Errorf("Expected the roadworks 75.1 km along the route at 08:45, gone by then; got %+v", roadworks)
+	}
+
+	// The accident is on the route within two kilometers of it
+	incidents = incidentsOnRoute(found, roads, departure, 2000, utils.Imperial)
+	if len(incidents) != 3 || incidents[1].From != "E" || incidents[1].Deviation < 3000 || incidents[1].Deviation > 3400 ||
+		incidents[1].DistanceUnit != "mi" || incidents[1].Delay != 0 {
+		t.Errorf("Expected the accident about 1 km, in feet, east of the route halfway; got %+v", incidents)
+	}
+}
+
+func TestOutIncident(t *testing.T) {
+	// An incident without events in a category without a name
+	var incident structs.Incident
+	content := `{"geometry": {"type": "Point", "coordinates": [10.6, 60.9]}, "properties": {"iconCategory": 13,
+		"magnitudeOfDelay": 2, "startTime": "2021-05-17T10:00:00Z", "length": 100}}`
+	if err := json.Unmarshal([]byte(content), &incident); err != nil {
+		t.Fatal(err)
+	}
+	out := outIncident(incident, utils.Imperial)
+	if out.Event != "" || len(out.Events) != 0 || out.Category != "unknown" || out.Severity != "moderate" || out.Length != 328 ||
+		len(out.RoadNumbers) != 0 || len(out.Geometry) != 1 || out.Geometry[0].Latitude != 60.9 {
+		t.Errorf("Expected an unknown incident of moderate delay without events; got %+v", out)
+	}
+
+	// It has not ended, and is planned until it starts
+	query := utils.Query{"status": []string{IncidentPlanned}, "category": []string{"unknown"}}
+	if !incidentMatches(incident, query, time.Date(2021, 5, 17, 9, 0, 0, 0, time.UTC)) {
+		t.Errorf("Expected the incident to be planned before it starts")
+	}
+	if incidentMatches(incident, query, time.Date(2021, 5, 17, 11, 0, 0, 0, time.UTC)) {
+		t.Errorf("Expected the incident not to be planned once it has started")
+	}
+	query["status"] = []string{IncidentActive}
+	if !incidentMatches(incident, query, time.Date(2021, 5, 17, 11, 0, 0, 0, time.UTC)) {
+		t.Errorf("Expected the incident to be active once it has started")
+	}
+}
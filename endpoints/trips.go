@@ -0,0 +1,548 @@
+package endpoints
+
+import (
+	"cloudproject/database"
+	"cloudproject/i18n"
+	"cloudproject/router"
+	"cloudproject/structs"
+	"cloudproject/utils"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"log"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+)
+
+/**
+ * Class trips.go
+ * Saved trips: the stops in order, the vehicle profile, the route preferences and the planned departure or arrival
+ * The route, the weather along it, the traffic incidents on it and where to charge are planned for a saved trip by its
+ * id. Webhooks are subscriptions to a trip, the webhooks package keeps them up to date through TripChanged.
+ */
+
+// MaxStops The most stops a trip can have, the start and the destination included
+const MaxStops = 10
+
+// WeatherInterval How far apart, in meters, the weather is given between the stops of a trip
+const WeatherInterval = 50000
+
+// ChargeReserve The share of the range kept in reserve when planning where to charge
+const ChargeReserve = 0.2
+
+// TripChanged Called after a trip is replaced, or deleted with a nil trip. The webhooks package sets it to update the
+// subscriptions to the trip
+var TripChanged = func(id string, trip *structs.Trip) {}
+
+// TripRouteQuery The query parameters accepted by TripRoute
+var TripRouteQuery = append(utils.QuerySchema{utils.UnitsParam}, VehicleParams...)
+
+// TripWeatherQuery The query parameters accepted by TripWeather
+var TripWeatherQuery = utils.QuerySchema{i18n.LangParam, utils.UnitsParam}
+
+// TripChargeQuery The query parameters accepted by TripCharge
+var TripChargeQuery = utils.QuerySchema{
+	{Name: "radius", Type: utils.TypeInteger, Description: "Search radius in meters around each place to charge", Default: "5000",
+		Minimum: utils.Bound(1), Maximum: utils.Bound(50000)},
+	{Name: "power", Type: utils.TypeNumber, Description: "Minimum charging power in kW", Minimum: utils.Bound(0)},
+	utils.UnitsParam,
+}
+
+// routePoint A stop or a point between the stops of a route, with the time the trip passes it
+type routePoint struct {
+	place    string
+	location utils.Coordinate
+	distance float64 // Meters from the start
+	at       time.Time
+}
+
+// AddTrip Saves a new trip, and answers with it and its id
+func AddTrip(w http.ResponseWriter, r *http.Request) {
+	trip, err := readTrip(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	trip.ID, err = database.AddTrip(trip)
+	if err != nil {
+		log.Println("Unable to save the trip.\n" + err.Error())
+		http.Error(w, "Unable to save the trip, try again", http.StatusInternalServerError)
+		return
+	}
+	log.Println("Saved the trip with ID: " + trip.ID)
+	writeTrip(w, trip, http.StatusCreated)
+}
+
+// UpdateTrip Replaces the trip with the id in the path, and updates the subscriptions to it
+func UpdateTrip(w http.ResponseWriter, r *http.Request) {
+	id := router.Param(r, "id")
+	if _, err := database.GetTrip(id); err != nil {
+		tripError(w, id, err)
+		return
+	}
+	trip, err := readTrip(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	trip.ID = id
+	if err = database.SetTrip(id, trip); err != nil {
+		log.Println("Unable to update the trip with ID: " + id + "\n" + err.Error())
+		http.Error(w, "Unable to update the trip, try again", http.StatusInternalServerError)
+		return
+	}
+	TripChanged(id, &trip)
+	writeTrip(w, trip, http.StatusOK)
+}
+
+// GetTrip Displays the trip with the id in the path
+func GetTrip(w http.ResponseWriter, r *http.Request) {
+	trip, found := loadTrip(w, r)
+	if found {
+		writeTrip(w, trip, http.StatusOK)
+	}
+}
+
+// ListTrips Displays all saved trips
+func ListTrips(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	trips, err := database.GetTrips()
+	if err != nil {
+		log.Println("Unable to list the trips.\n" + err.Error())
+		http.Error(w, "Unable to list the trips, try again", http.StatusInternalServerError)
+		return
+	}
+	output, err := json.Marshal(trips)
+	if err != nil {
+		http.Error(w, utils.JsonMarshalErrorHandling(err).Error(), http.StatusInternalServerError)
+		return
+	}
+	fmt.Fprintf(w, "%v", string(output))
+}
+
+// DeleteTrip Deletes the trip with the id in the path, and the subscriptions to it
+func DeleteTrip(w http.ResponseWriter, r *http.Request) {
+	id := router.Param(r, "id")
+	if _, err := database.GetTrip(id); err != nil {
+		tripError(w, id, err)
+		return
+	}
+	if err := database.Client.Delete(database.TripCollection, id); err != nil {
+		log.Println("Unable to delete the trip with ID: " + id + "\n" + err.Error())
+		http.Error(w, "Unable to delete the trip, try again", http.StatusInternalServerError)
+		return
+	}
+	TripChanged(id, nil)
+	fmt.Fprintf(w, "Deleted the trip with ID: %v", id)
+}
+
+// TripRoute Responds with the route of the trip with the id in the path, through its stops
+func TripRoute(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	trip, found := loadTrip(w, r)
+	if !found {
+		return
+	}
+	query := router.Query(r)
+	request, status, err := tripRouteRequest(trip, query)
+	if err != nil {
+		http.Error(w, err.Error(), status)
+		return
+	}
+	writeRoute(w, r, request, query.Get("units"))
+}
+
+// TripWeather Responds with the forecast at the stops of the trip with the id in the path, and at points between them,
+// at the times the trip is expected to pass them
+func TripWeather(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	presentation := PresentationOf(r)
+	w.Header().Set("Content-Language", presentation.Locale)
+
+	trip, found := loadTrip(w, r)
+	if !found {
+		return
+	}
+	planned, status, err := planTrip(trip, router.Query(r))
+	if err != nil {
+		http.Error(w, err.Error(), status)
+		return
+	}
+
+	output := structs.TripWeather{Trip: trip.ID, DistanceUnit: unitsOf(presentation.Units).Distance, Weather: []structs.RouteWeather{}}
+	for _, point := range weatherPoints(planned.roads, planned.departure, trip.Stops) {
+		forecast, err := FetchForecast(forecastURL(point.location), presentation)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		output.Weather = append(output.Weather, structs.RouteWeather{Place: point.place, Latitude: point.location.Latitude,
+			Longitude: point.location.Longitude, Distance: utils.Distance(point.distance, presentation.Units),
+			Time: point.at.Format(time.RFC3339), Forecast: forecastAt(forecast, point.at)})
+	}
+
+	result, err := json.Marshal(output)
+	if err != nil {
+		http.Error(w, utils.JsonMarshalErrorHandling(err).Error(), http.StatusInternalServerError)
+		return
+	}
+	fmt.Fprintf(w, "%v", string(result))
+}
+
+// TripMessages Responds with the traffic incidents on the route of the trip with the id in the path, in the order they
+// are passed
+func TripMessages(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	trip, found := loadTrip(w, r)
+	if !found {
+		return
+	}
+	query := router.Query(r)
+	planned, status, err := planTrip(trip, query)
+	if err != nil {
+		http.Error(w, err.Error(), status)
+		return
+	}
+	incidents, status, err := routeIncidents(planned.roads, planned.departure, query)
+	if err != nil {
+		http.Error(w, err.Error(), status)
+		return
+	}
+	writeIncidents(w, r, incidents)
+}
+
+// TripCharge Responds with the places to charge along the route of the trip with the id in the path, planned from the
+// range of its electric or hybrid vehicle, and the charging stations with the connectors of the vehicle around them
+func TripCharge(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	trip, found := loadTrip(w, r)
+	if !found {
+		return
+	}
+	query := router.Query(r)
+	units := query.Get("units")
+
+	var profile structs.VehicleProfile
+	if trip.Profile != "" {
+		profile, _ = database.GetProfile(trip.Profile)
+	}
+	if (profile.Fuel != FuelElectric && profile.Fuel != FuelHybrid) || profile.Range == 0 {
+		http.Error(w, "Charging is planned from the range of the electric or hybrid vehicle profile of the trip", http.StatusBadRequest)
+		return
+	}
+	planned, status, err := planTrip(trip, query)
+	if err != nil {
+		http.Error(w, err.Error(), status)
+		return
+	}
+
+	filters := "&radius=" + query.Get("radius")
+	if len(profile.Connectors) != 0 {
+		filters += "&connectorSet=" + strings.Join(profile.Connectors, ",")
+	}
+	if query.Has("power") {
+		filters += "&minPowerKW=" + query.Get("power")
+	}
+
+	stops, status, err := chargingStops(planned, profile, filters, units)
+	if err != nil {
+		http.Error(w, err.Error(), status)
+		return
+	}
+
+	output, err := json.Marshal(stops)
+	if err != nil {
+		http.Error(w, utils.JsonMarshalErrorHandling(err).Error(), http.StatusInternalServerError)
+		return
+	}
+	fmt.Fprintf(w, "%v", string(output))
+}
+
+// TripIncidents The traffic incidents on the planned route of the trip, in the order they are passed
+func TripIncidents(trip structs.Trip) ([]structs.OutIncident, error) {
+	planned, _, err := planTrip(trip, utils.Query{})
+	if err != nil {
+		return nil, err
+	}
+	incidents, _, err := routeIncidents(planned.roads, planned.departure, utils.Query{})
+	return incidents, err
+}
+
+// chargingStops The places to charge along the planned route from the range of the vehicle, with the charging stations
+// found with the search filters around them. Returns the status to answer with if the stations are unavailable
+func chargingStops(planned plannedRoute, profile structs.VehicleProfile, filters string, units string) ([]structs.ChargingStop, int, error) {
+	stops := []structs.ChargingStop{}
+	reach := float64(profile.Range) * 1000 * (1 - ChargeReserve)
+	for _, point := range chargingPoints(routePath(planned.roads), reach) {
+		stations, status, err := fetchChargers(strconv.FormatFloat(point.location.Latitude, 'f', 6, 64),
+			strconv.FormatFloat(point.location.Longitude, 'f', 6, 64), filters, units)
+		if err != nil {
+			return nil, status, err
+		}
+		if stations == nil {
+			stations = []structs.OutputCharge{}
+		}
+		stops = append(stops, structs.ChargingStop{Latitude: point.location.Latitude, Longitude: point.location.Longitude,
+			Distance: utils.Distance(point.distance, units), DistanceUnit: unitsOf(units).Distance, Stations: stations})
+	}
+	return stops, http.StatusOK, nil
+}
+
+// readTrip Reads and validates the trip in the body of the request
+func readTrip(r *http.Request) (structs.Trip, error) {
+	body, err := ioutil.ReadAll(r.Body)
+	if err != nil {
+		return structs.Trip{}, err
+	}
+	var trip structs.Trip
+	if err = json.Unmarshal(body, &trip); err != nil {
+		return structs.Trip{}, utils.JsonUnmarshalErrorHandling(err)
+	}
+	return trip, ValidateTrip(&trip)
+}
+
+// ValidateTrip Checks that the trip has a name, that its stops can be found and that its profile exists, and fills in
+// the defaults of the preferences. The planned times may have passed, the trip then departs now
+func ValidateTrip(trip *structs.Trip) error {
+	trip.Name = strings.TrimSpace(trip.Name)
+	if trip.Name == "" {
+		return errors.New("The trip must have a name")
+	}
+	if len(trip.Stops) < 2 || len(trip.Stops) > MaxStops {
+		return errors.New("The trip must have from 2 to " + strconv.Itoa(MaxStops) + " stops, the start and the destination included")
+	}
+	for i, stop := range trip.Stops {
+		trip.Stops[i] = strings.TrimSpace(stop)
+		if trip.Stops[i] == "" {
+			return errors.New("The stops of the trip can not be empty")
+		}
+		if _, _, err := database.LocationPresent(url.QueryEscape(trip.Stops[i])); err != nil {
+			return errors.New("Unable to find the stop " + trip.Stops[i])
+		}
+	}
+	if trip.Profile != "" {
+		if _, err := database.GetProfile(trip.Profile); err != nil {
+			return errors.New("No vehicle profile with ID: " + trip.Profile)
+		}
+	}
+
+	if trip.Preferences.DepartAt != "" || trip.Preferences.ArriveAt != "" {
+		return errors.New("The departure and arrival of the trip are used instead of departAt and arriveAt")
+	}
+	if err := ValidatePreferences(&trip.Preferences); err != nil {
+		return err
+	}
+	if trip.Departure != "" && trip.Arrival != "" {
+		return errors.New("Only one of the departure and the arrival of the trip can be planned")
+	}
+	for name, value := range map[string]string{"departure": trip.Departure, "arrival": trip.Arrival} {
+		if _, err := time.Parse(time.RFC3339, value); value != "" && err != nil {
+			return errors.New("The " + name + " must be in RFC3339 format, such as 2021-05-17T12:10:00+02:00")
+		}
+	}
+	return nil
+}
+
+// writeTrip Answers with the trip
+func writeTrip(w http.ResponseWriter, trip structs.Trip, status int) {
+	output, err := json.Marshal(trip)
+	if err != nil {
+		http.Error(w, utils.JsonMarshalErrorHandling(err).Error(), http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	fmt.Fprintf(w, "%v", string(output))
+}
+
+// loadTrip Gets the trip with the id in the path, answers with the error and returns false if it can not be read
+func loadTrip(w http.ResponseWriter, r *http.Request) (structs.Trip, bool) {
+	id := router.Param(r, "id")
+	trip, err := database.GetTrip(id)
+	if err != nil {
+		tripError(w, id, err)
+		return structs.Trip{}, false
+	}
+	return trip, true
+}
+
+// tripError Answers that the trip could not be found, or could not be read
+func tripError(w http.ResponseWriter, id string, err error) {
+	if err == database.ErrNotFound {
+		router.Error(w, "No trip with ID: "+id, http.StatusNotFound)
+		return
+	}
+	log.Println("Unable to read the trip with ID: " + id + "\n" + err.Error())
+	http.Error(w, "Unable to read the trip, try again", http.StatusInternalServerError)
+}
+
+// TripCoordinates The coordinates of the places in the form TomTom routes through, from the first to the last
+func TripCoordinates(stops []string) (string, error) {
+	var coordinates []string
+	for _, stop := range stops {
+		latitude, longitude, err := database.LocationPresent(url.QueryEscape(stop))
+		if err != nil {
+			log.Println("Unable to get request for address: " + stop + "\n" + err.Error())
+			return "", err
+		}
+		coordinates = append(coordinates, latitude+"%2C"+longitude)
+	}
+	return strings.Join(coordinates, "%3A"), nil
+}
+
+// tripRouteRequest The route request of a trip, through its stops with its vehicle and preferences. The planned
+// departure or arrival is used while it is in the future. Returns the status to answer with if the route can not be
+// planned
+func tripRouteRequest(trip structs.Trip, query utils.Query) (routeRequest, int, error) {
+	coordinates, err := TripCoordinates(trip.Stops)
+	if err != nil {
+		return routeRequest{}, http.StatusBadRequest, err
+	}
+	request := routeRequest{coordinates: coordinates, preferences: trip.Preferences}
+	if trip.Profile != "" {
+		profile, err := database.GetProfile(trip.Profile)
+		if err == database.ErrNotFound {
+			return routeRequest{}, http.StatusBadRequest, errors.New("The vehicle profile of the trip no longer exists")
+		} else if err != nil {
+			log.Println("Unable to read the vehicle profile with ID: " + trip.Profile + "\n" + err.Error())
+			return routeRequest{}, http.StatusInternalServerError, errors.New("Unable to read the vehicle profile, try again")
+		}
+		request.profile = &profile
+	}
+	request.vehicle, request.consumption = vehicleOf(request.profile, query)
+
+	if departure, err := time.Parse(time.RFC3339, trip.Departure); err == nil && departure.After(time.Now()) {
+		request.preferences.DepartAt = trip.Departure
+	} else if arrival, err := time.Parse(time.RFC3339, trip.Arrival); err == nil && arrival.After(time.Now()) {
+		request.preferences.ArriveAt = trip.Arrival
+	}
+	return request, http.StatusOK, nil
+}
+
+// planTrip Gets and plans the route of the trip. Returns the status to answer with if the route is unavailable
+func planTrip(trip structs.Trip, query utils.Query) (plannedRoute, int, error) {
+	request, status, err := tripRouteRequest(trip, query)
+	if err != nil {
+		return plannedRoute{}, status, err
+	}
+	roads, status, err := FetchRoute(request.coordinates, RouteOptions(request.profile, request.preferences), nil)
+	if err != nil {
+		return plannedRoute{}, status, err
+	}
+	return request.plan(roads), http.StatusOK, nil
+}
+
+// TripTimetable The stops of the trip on its planned route, with the times they are passed and their distance from the
+// start. Stops between the start and the destination are left out if the route has no leg to them
+func TripTimetable(trip structs.Trip) ([]structs.StopTime, error) {
+	planned, _, err := planTrip(trip, utils.Query{})
+	if err != nil {
+		return nil, err
+	}
+	stops := []structs.StopTime{}
+	for _, point := range weatherPoints(planned.roads, planned.departure, trip.Stops) {
+		if point.place != "" {
+			stops = append(stops, structs.StopTime{Place: point.place, Distance: utils.Round(utils.Distance(point.distance, utils.Metric), 1),
+				Time: point.at.Format(time.RFC3339)})
+		}
+	}
+	if len(stops) < 2 {
+		return nil, errors.New("The route of the trip has no points")
+	}
+	return stops, nil
+}
+
+// weatherPoints The stops of the route, which has a leg from each stop to the next, and the points every
+// WeatherInterval between them. The times are estimated from the travel time of each leg, without waiting for ferries
+func weatherPoints(roads structs.RouteStruct, departure time.Time, stops []string) []routePoint {
+	var points []routePoint
+	at, distance := departure, 0.0
+	for i, leg := range roads.Routes[0].Legs {
+		if len(leg.Points) == 0 {
+			continue
+		}
+		// The distance along the leg to each of its points
+		along := make([]float64, len(leg.Points))
+		for j := 1; j < len(leg.Points); j++ {
+			along[j] = along[j-1] + utils.Haversine(leg.Points[j-1].Latitude, leg.Points[j-1].Longitude,
+				leg.Points[j].Latitude, leg.Points[j].Longitude)
+		}
+		length, travel := along[len(along)-1], time.Duration(leg.Summary.TravelTimeInSeconds)*time.Second
+
+		place := ""
+		if i < len(stops) {
+			place = stops[i]
+		}
+		points = append(points, routePoint{place: place, location: leg.Points[0], distance: distance, at: at})
+		next := WeatherInterval
+		for j := 1; j < len(leg.Points)-1; j++ {
+			if along[j] < float64(next) {
+				continue
+			}
+			points = append(points, routePoint{location: leg.Points[j], distance: distance + along[j],
+				at: at.Add(time.Duration(float64(travel) * along[j] / length))})
+			for float64(next) <= along[j] {
+				next += WeatherInterval
+			}
+		}
+		at, distance = at.Add(travel), distance+length
+
+		if i == len(roads.Routes[0].Legs)-1 {
+			points = append(points, routePoint{place: stops[len(stops)-1], location: leg.Points[len(leg.Points)-1],
+				distance: distance, at: at})
+		}
+	}
+	return points
+}
+
+// forecastAt The hourly forecast at the time, or else the daily forecast. nil if the time is beyond the forecast
+func forecastAt(forecast structs.OutputForecast, at time.Time) *structs.ForecastEntry {
+	if hours := window(forecast.Hourly, time.Hour, at, at.Add(time.Second)); len(hours) != 0 {
+		return &hours[0]
+	}
+	if days := window(forecast.Daily, 24*time.Hour, at, at.Add(time.Second)); len(days) != 0 {
+		return &days[0]
+	}
+	return nil
+}
+
+// chargingPoints The points of the path to charge at, starting fully charged, so the distance between two charges is
+// at most the reach in meters. The last point before the reach is used, or the next point if they are further apart
+func chargingPoints(path []utils.Coordinate, reach float64) []routePoint {
+	var points []routePoint
+	distances := make([]float64, len(path))
+	last := 0
+	for i := 1; i < len(path); i++ {
+		distances[i] = distances[i-1] + utils.Haversine(path[i-1].Latitude, path[i-1].Longitude, path[i].Latitude, path[i].Longitude)
+		if distances[i]-distances[last] <= reach {
+			continue
+		}
+		stop := i - 1
+		if stop == last {
+			stop = i
+		}
+		points = append(points, routePoint{location: path[stop], distance: distances[stop]})
+		last = stop
+	}
+	return points
+}
+
+// minFloat The smaller of the values
+func minFloat(a float64, b float64) float64 {
+	if a < b {
+		return a
+	}
+	return b
+}
+
+// maxFloat The larger of the values
+func maxFloat(a float64, b float64) float64 {
+	if a > b {
+		return a
+	}
+	return b
+}
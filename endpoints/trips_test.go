@@ -0,0 +1,101 @@
+package endpoints
+
+import (
+	"cloudproject/structs"
+	"cloudproject/utils"
+	"encoding/json"
+	"math"
+	"testing"
+	"time"
+)
+
+// meridian Points north along the meridian at 10° east, from 60° north in steps of the degrees of latitude
+func meridian(count int, step float64) []utils.Coordinate {
+	var points []utils.Coordinate
+	for i := 0; i < count; i++ {
+		points = append(points, utils.Coordinate{Latitude: 60 + float64(i)*step, Longitude: 10})
+	}
+	return points
+}
+
+func TestWeatherPoints(t *testing.T) {
+	// A leg of 100 km taking an hour, and a leg of 11 km taking ten minutes
+	var roads structs.RouteStruct
+	content := `{"routes": [{"legs": [
+		{"summary": {"travelTimeInSeconds": 3600}, "points": [{"latitude": 60, "longitude": 10}, {"latitude": 60.3, "longitude": 10},
+			{"latitude": 60.6, "longitude": 10}, {"latitude": 60.9, "longitude": 10}]},
+		{"summary": {"travelTimeInSeconds": 600}, "points": [{"latitude": 60.9, "longitude": 10}, {"latitude": 61, "longitude": 10}]}
+	]}]}`
+	if err := json.Unmarshal([]byte(content), &roads); err != nil {
+		t.Fatal(err)
+	}
+	departure := time.Date(2021, 5, 17, 8, 0, 0, 0, time.UTC)
+
+	points := weatherPoints(roads, departure, []string{"start", "via", "end"})
+	expected := []struct {
+		place    string
+		latitude float64
+		after    time.Duration
+	}{
+		{"start", 60, 0},
+		{"", 60.6, 40 * time.Minute},
+		{"via", 60.9, time.Hour},
+		{"end", 61, 70 * time.Minute},
+	}
+	if len(points) != len(expected) {
+		t.Fatalf("Expected %v points; got %+v", len(expected), points)
+	}
+	for i, want := range expected {
+		got := points[i]
+		if got.place != want.place || got.location.Latitude != want.latitude ||
+			got.at.Sub(departure).Round(time.Second) != want.after {
+			t.Errorf("Expected point %v to be %+v; got %+v", i, want, got)
+		}
+	}
+	if math.Abs(points[3].distance-utils.Haversine(60, 10, 61, 10)) > 1 {
+		t.Errorf("Expected the destination to be at the length of the route; got %v", points[3].distance)
+	}
+}
+
+func TestChargingPoints(t *testing.T) {
+	// Points 11 km apart, over 100 km
+	path := meridian(10, 0.1)
+	points := chargingPoints(path, 30000)
+
+	expected := []float64{60.2, 60.4, 60.6, 60.8}
+	if len(points) != len(expected) {
+		t.Fatalf("Expected %v places to charge; got %+v", len(expected), points)
+	}
+	for i, latitude := range expected {
+		if math.Abs(points[i].location.Latitude-latitude) > 1e-9 {
+			t.Errorf("Expected to charge at %v; got %v", latitude, points[i].location.Latitude)
+		}
+	}
+	if points := chargingPoints(path, 200000); len(points) != 0 {
+		t.Errorf("Expected no charging within the range; got %+v", points)
+	}
+}
+
+func TestForecastAt(t *testing.T) {
+	day := time.Date(2021, 5, 17, 12, 0, 0, 0, time.UTC)
+	forecast := structs.OutputForecast{
+		Hourly: []structs.ForecastEntry{{Time: day.Add(-2 * time.Hour)}, {Time: day.Add(-time.Hour)}},
+		Daily:  []structs.ForecastEntry{{Time: day}},
+	}
+	tests := []struct {
+		at       time.Time
+		expected time.Time
+	}{
+		{day.Add(-90 * time.Minute), day.Add(-2 * time.Hour)},
+		{day.Add(-time.Hour), day.Add(-time.Hour)},
+		{day.Add(5 * time.Hour), day},
+	}
+	for _, test := range tests {
+		if entry := forecastAt(forecast, test.at); entry == nil || !entry.Time.Equal(test.expected) {
+			t.Errorf("%v: expected the forecast for %v; got %+v", test.at, test.expected, entry)
+		}
+	}
+	if entry := forecastAt(forecast, day.Add(13*time.Hour)); entry != nil {
+		t.Errorf("Expected no forecast beyond the forecast; got %+v", entry)
+	}
+}
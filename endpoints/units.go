@@ -0,0 +1,97 @@
+package endpoints
+
+import (
+	"cloudproject/i18n"
+	"cloudproject/router"
+	"cloudproject/structs"
+	"cloudproject/utils"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+// Presentation The language and unit system a response is presented in
+type Presentation struct {
+	Locale string
+	Units  string
+}
+
+// MetricPresentation The presentation used where there is no request, in the given locale with metric units
+func MetricPresentation(locale string) Presentation {
+	return Presentation{Locale: locale, Units: utils.Metric}
+}
+
+// PresentationOf Chooses the presentation of a request from its lang and units parameters and Accept-Language header
+func PresentationOf(r *http.Request) Presentation {
+	units := router.Query(r).Get("units")
+	if units == "" {
+		units = utils.Metric
+	}
+	return Presentation{Locale: i18n.Negotiate(r), Units: units}
+}
+
+// unitsOf The names of the units in a unit system
+func unitsOf(system string) structs.Units {
+	if system == utils.Imperial {
+		return structs.Units{System: utils.Imperial, Temperature: "°F", Speed: "mph", Distance: "mi", Visibility: "ft", Precipitation: "in"}
+	}
+	return structs.Units{System: utils.Metric, Temperature: "°C", Speed: "m/s", Distance: "km", Visibility: "m", Precipitation: "mm"}
+}
+
+// convertWeather Converts the metric measurements of the weather to the unit system of the presentation
+func convertWeather(weather structs.OutputWeather, p Presentation) structs.OutputWeather {
+	weather.Units = unitsOf(p.Units)
+	weather.Temp.Temp = utils.Temperature(weather.Temp.Temp, p.Units)
+	weather.FeelsLike.FeelsLike = utils.Temperature(weather.FeelsLike.FeelsLike, p.Units)
+	weather.TempMin.TempMin = utils.Temperature(weather.TempMin.TempMin, p.Units)
+	weather.TempMax.TempMax = utils.Temperature(weather.TempMax.TempMax, p.Units)
+	weather.WindSpeed.WindSpeed = utils.Speed(weather.WindSpeed.WindSpeed, p.Units)
+	weather.WindGust = utils.Speed(weather.WindGust, p.Units)
+	weather.Visibility.Visibility = utils.ShortDistance(weather.Visibility.Visibility, p.Units)
+	weather.Rain1h = utils.Precipitation(weather.Rain1h, p.Units)
+	weather.Snow1h = utils.Precipitation(weather.Snow1h, p.Units)
+	return weather
+}
+
+// temperature The arguments of a message with a temperature in degrees Celsius, converted to the unit system
+func (p Presentation) temperature(celsius float64) i18n.Args {
+	return i18n.Args{"temp": utils.Temperature(celsius, p.Units), "unit": i18n.Text(p.Locale, "unit.temperature."+p.Units, nil)}
+}
+
+// speed The arguments of a message with a speed in meters per second, converted to the unit system
+func (p Presentation) speed(name string, metersPerSecond float64) i18n.Args {
+	return i18n.Args{name: utils.Speed(metersPerSecond, p.Units), "unit": i18n.Text(p.Locale, "unit.speed."+p.Units, nil)}
+}
+
+// visibility The arguments of a message with a visibility in meters, converted to the unit system
+func (p Presentation) visibility(meters int) i18n.Args {
+	return i18n.Args{"visibility": utils.ShortDistance(meters, p.Units), "unit": i18n.Text(p.Locale, "unit.visibility."+p.Units, nil)}
+}
+
+// zone The time zone of a location from its offset to UTC in seconds, named by the IANA name if it is known
+func zone(name string, offset int) *time.Location {
+	if name != "" {
+		if location, err := time.LoadLocation(name); err == nil {
+			return location
+		}
+	}
+	return time.FixedZone(utcOffsetName(offset), offset)
+}
+
+// utcOffsetName Names a fixed time zone by its offset, such as UTC+02:00
+func utcOffsetName(offset int) string {
+	sign := "+"
+	if offset < 0 {
+		sign, offset = "-", -offset
+	}
+	hours, minutes := offset/3600, offset%3600/60
+	return "UTC" + sign + pad(hours) + ":" + pad(minutes)
+}
+
+// pad Pads a number with a leading zero
+func pad(number int) string {
+	if number < 10 {
+		return "0" + strconv.Itoa(number)
+	}
+	return strconv.Itoa(number)
+}
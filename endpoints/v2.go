@@ -0,0 +1,107 @@
+package endpoints
+
+import (
+	"cloudproject/router"
+	"cloudproject/structs"
+	"net/http"
+	"path"
+)
+
+/**
+ * Class v2.go
+ * The responses of the v2 surface of the API
+ * The handlers of the endpoints mounted on both surfaces build the v1 structs, and the output is converted to the v2
+ * structs with the field names in camelCase when the request was made to v2.
+ */
+
+// APIVersion The version of the API surface the request was made to, for instance "v1" for /rtc/v1/diag
+func APIVersion(r *http.Request) string {
+	return path.Base(router.Mount(r))
+}
+
+// Versioned The output in the form of the API version of the request, v1 output is returned as it is
+func Versioned(r *http.Request, output interface{}) interface{} {
+	if APIVersion(r) != "v2" {
+		return output
+	}
+	switch value := output.(type) {
+	case structs.OutputWeather:
+		return weatherV2(value)
+	case []structs.OutputPoi:
+		places := make([]structs.OutputPoiV2, len(value))
+		for i, place := range value {
+			places[i] = structs.OutputPoiV2(place)
+		}
+		return places
+	case []structs.OutputCharge:
+		stations := make([]structs.OutputChargeV2, len(value))
+		for i, station := range value {
+			stations[i] = structs.OutputChargeV2(station)
+		}
+		return stations
+	case []structs.OutputPetrol:
+		stations := make([]structs.OutputPetrolV2, len(value))
+		for i, station := range value {
+			stations[i] = structs.OutputPetrolV2(station)
+		}
+		return stations
+	case []structs.OutIncident:
+		incidents := make([]structs.OutIncidentV2, len(value))
+		for i, incident := range value {
+			incidents[i] = structs.OutIncidentV2(incident)
+		}
+		return incidents
+	case structs.RoadInformation:
+		return roadInformationV2(value)
+	case structs.Webhook:
+		return structs.WebhookV2(value)
+	case []structs.Webhook:
+		hooks := make([]structs.WebhookV2, len(value))
+		for i, hook := range value {
+			hooks[i] = structs.WebhookV2(hook)
+		}
+		return hooks
+	}
+	return output
+}
+
+// weatherV2 Converts the current weather to v2
+func weatherV2(weather structs.OutputWeather) structs.OutputWeatherV2 {
+	return structs.OutputWeatherV2{
+		Main:       structs.MainStructV2(weather.Main),
+		Rain1h:     weather.Rain1h,
+		Snow1h:     weather.Snow1h,
+		Temp:       structs.TempStructV2(weather.Temp),
+		FeelsLike:  structs.FeelsLikeStructV2(weather.FeelsLike),
+		TempMin:    structs.TempMinStructV2(weather.TempMin),
+		TempMax:    structs.TempMaxStructV2(weather.TempMax),
+		Humidity:   structs.HumidityStructV2(weather.Humidity),
+		Visibility: structs.VisibilityStructV2(weather.Visibility),
+		WindSpeed:  structs.WindSpeedStructV2(weather.WindSpeed),
+		WindDeg:    structs.WindDegStructV2(weather.WindDeg),
+		WindGust:   weather.WindGust,
+		Sunrise:    structs.SunriseStructV2(weather.Sunrise),
+		Sunset:     structs.SunsetStructV2(weather.Sunset),
+		Conditions: weather.Conditions,
+		Units:      weather.Units,
+		Timezone:   weather.Timezone,
+	}
+}
+
+// roadInformationV2 Converts the route to v2
+func roadInformationV2(information structs.RoadInformation) structs.RoadInformationV2 {
+	route := make([]structs.RouteV2, len(information.Route))
+	for i, instruction := range information.Route {
+		route[i] = structs.RouteV2(instruction)
+	}
+	return structs.RoadInformationV2{
+		Departure:        information.Departure,
+		EstimatedArrival: information.EstimatedArrival,
+		Length:           information.Length,
+		DistanceUnit:     information.DistanceUnit,
+		Route:            route,
+		Closures:         information.Closures,
+		Ferries:          information.Ferries,
+		Cost:             information.Cost,
+	}
+}
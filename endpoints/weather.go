@@ -2,26 +2,31 @@ package endpoints
 
 import (
 	"cloudproject/database"
+	"cloudproject/i18n"
+	"cloudproject/router"
 	"cloudproject/structs"
 	"cloudproject/utils"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io/ioutil"
 	"log"
-	"math"
 	"net/http"
 	"net/url"
-	"strconv"
-	"strings"
 	"time"
 )
 
+// CurrentWeatherQuery The query parameters accepted by CurrentWeather
+var CurrentWeatherQuery = utils.QuerySchema{i18n.LangParam, utils.UnitsParam}
+
 // CurrentWeather Gets location and passes it to the openweathermap-API
 func CurrentWeather(rw http.ResponseWriter, request *http.Request) {
 	rw.Header().Set("Content-type", "application/json")
+	presentation := PresentationOf(request)
+	rw.Header().Set("Content-Language", presentation.Locale)
 
-	// Splits the URL to get the name of the city to be checked
-	address := strings.Split(request.URL.Path, `/`)[4]
+	// Gets the name of the city to be checked from the path
+	address := router.Param(request, "place")
 
 	//Receives the latitude and longitude of the place passed in the url
 	latitude, longitude, err := database.LocationPresent(url.QueryEscape(address))
@@ -35,7 +40,7 @@ func CurrentWeather(rw http.ResponseWriter, request *http.Request) {
 
 	if latitude != "" && longitude != "" {
 		// Defines the url to the openweathermap API with relevant latitude and longitude and apiKey
-		urlLoc = "https://api.openweathermap.org/data/2.5/weather?lat=" + latitude + "&lon=" + longitude + "&appid=" + utils.OpenweathermapKey
+		urlLoc = utils.OpenWeatherMapURL + "/data/2.5/weather?lat=" + latitude + "&lon=" + longitude + "&units=metric&appid=" + utils.OpenweathermapKey
 	} else {
 		_, err := fmt.Fprint(rw, "Check formatting of latitude and longitude.")
 		if err != nil {
@@ -45,9 +50,9 @@ func CurrentWeather(rw http.ResponseWriter, request *http.Request) {
 		}
 	}
 	// Calls the handler using the URL.
-	test := CurrentWeatherHandler(rw, urlLoc)
+	test := CurrentWeatherHandler(rw, urlLoc, presentation)
 	// Marshal the struct
-	output, err := json.Marshal(test) //Marshalling the array to JSON
+	output, err := json.Marshal(Versioned(request, test)) //Marshalling the array to JSON
 	if err != nil {
 		http.Error(rw, err.Error(), http.StatusInternalServerError)
 	}
@@ -60,22 +65,32 @@ func CurrentWeather(rw http.ResponseWriter, request *http.Request) {
 	} //Outputs the weather
 }
 
-// CurrentWeatherHandler Handling request with the url
-func CurrentWeatherHandler(rw http.ResponseWriter, url string) structs.OutputWeather {
+// CurrentWeatherHandler Handling request with the url, the messages and measurements are in the presentation
+func CurrentWeatherHandler(rw http.ResponseWriter, url string, p Presentation) structs.OutputWeather {
+	weather, err := FetchWeather(url, p)
+	if err != nil {
+		http.Error(rw, err.Error(), http.StatusInternalServerError)
+		return structs.OutputWeather{}
+	}
+	return weather
+}
+
+// FetchWeather Requests the current weather in metric units from the url and attaches the advisory messages, in the
+// presentation. Used where there is no response writer to report errors to (for instance the webhook workers)
+func FetchWeather(url string, p Presentation) (structs.OutputWeather, error) {
 	// Uses request URL
 	resp, err := http.Get(url)
 	if err != nil {
 		log.Println("Error: Encountered problem when requesting the url.\n" + err.Error())
-		http.Error(rw, "Error: "+err.Error(), http.StatusInternalServerError)
-		return structs.OutputWeather{}
+		return structs.OutputWeather{}, errors.New("Error: " + err.Error())
 	}
+	defer resp.Body.Close()
 
 	// Reads the data from the resp.Body
 	body, err := ioutil.ReadAll(resp.Body)
 	if err != nil {
 		log.Println("Error while reading response body.\n" + err.Error())
-		http.Error(rw, "Error: "+err.Error(), http.StatusInternalServerError)
-		return structs.OutputWeather{}
+		return structs.OutputWeather{}, errors.New("Error: " + err.Error())
 	}
 
 	// Defines struct instance
@@ -84,273 +99,184 @@ func CurrentWeatherHandler(rw http.ResponseWriter, url string) structs.OutputWea
 	// Unmarshalling the body into the weatherData struct/fields
 	if err = json.Unmarshal(body, &weather); err != nil {
 		log.Println("There was an error during unmarshalling.\n" + err.Error())
-		jsonError := utils.JsonUnmarshalErrorHandling(err)
-		http.Error(rw, jsonError.Error(), http.StatusInternalServerError)
-		return structs.OutputWeather{}
+		return structs.OutputWeather{}, utils.JsonUnmarshalErrorHandling(err)
+	}
+	if len(weather.Weather) == 0 {
+		log.Println("The weather response did not contain any weather conditions.")
+		return structs.OutputWeather{}, errors.New("Error: No weather conditions found for the location")
 	}
 
-	// Defines output struct instance
-	var data []structs.OutputWeather
+	// The times are shown in the time zone of the location
+	location := zone("", weather.Timezone)
 
 	// Defines various temporary variables with the data from the struct
-	main := weather.Weather[0].Main
-	rain1H := weather.Rain.OneH
-	snow1H := weather.Snow.OneH
-	tempActual := math.Round((weather.Main.Temp-273.15)*100) / 100
-	tempFeelsLike := math.Round((weather.Main.FeelsLike-273.15)*100) / 100
-	tempMin := math.Round((weather.Main.TempMin-273.15)*100) / 100
-	tempMax := math.Round((weather.Main.TempMax-273.15)*100) / 100
-	humidity := weather.Main.Humidity
-	visibility := weather.Visibility
-	windSpeed := weather.Wind.Speed
-	windDeg := weather.Wind.Deg
-	sunrise := weather.Sys.Sunrise
-	sunset := weather.Sys.Sunset
-
-	// Attaches the various temporary variables to the output struct
 	jsonStruct := structs.OutputWeather{
-		Main:       structs.MainStruct{Main: main},
-		Rain1h:     rain1H,
-		Snow1h:     snow1H,
-		Temp:       structs.TempStruct{Temp: tempActual},
-		FeelsLike:  structs.FeelsLikeStruct{FeelsLike: tempFeelsLike},
-		TempMin:    structs.TempMinStruct{TempMin: tempMin},
-		TempMax:    structs.TempMaxStruct{TempMax: tempMax},
-		Humidity:   structs.HumidityStruct{Humidity: humidity},
-		Visibility: structs.VisibilityStruct{Visibility: visibility},
-		WindSpeed:  structs.WindSpeedStruct{WindSpeed: windSpeed},
-		WindDeg:    structs.WindDegStruct{WindDeg: windDeg},
-		Sunrise:    structs.SunriseStruct{Sunrise: sunrise},
-		Sunset:     structs.SunsetStruct{Sunset: sunset}}
-
-	// Appends the struct to the array
-	data = append(data, jsonStruct)
+		Main:       structs.MainStruct{Main: weather.Weather[0].Main},
+		Rain1h:     weather.Rain.OneH,
+		Snow1h:     weather.Snow.OneH,
+		Temp:       structs.TempStruct{Temp: weather.Main.Temp},
+		FeelsLike:  structs.FeelsLikeStruct{FeelsLike: weather.Main.FeelsLike},
+		TempMin:    structs.TempMinStruct{TempMin: weather.Main.TempMin},
+		TempMax:    structs.TempMaxStruct{TempMax: weather.Main.TempMax},
+		Humidity:   structs.HumidityStruct{Humidity: weather.Main.Humidity},
+		Visibility: structs.VisibilityStruct{Visibility: weather.Visibility},
+		WindSpeed:  structs.WindSpeedStruct{WindSpeed: weather.Wind.Speed},
+		WindDeg:    structs.WindDegStruct{WindDeg: weather.Wind.Deg},
+		WindGust:   weather.Wind.Gust,
+		Sunrise:    structs.SunriseStruct{Sunrise: weather.Sys.Sunrise, Local: epochToHumanReadable(int64(weather.Sys.Sunrise)).In(location)},
+		Sunset:     structs.SunsetStruct{Sunset: weather.Sys.Sunset, Local: epochToHumanReadable(int64(weather.Sys.Sunset)).In(location)},
+		Timezone:   location.String()}
+
+	return advise(jsonStruct, time.Now(), p), nil
+}
+
+// advise Assesses the road conditions of the metric weather at the given time, and attaches them with the advisory
+// messages from response. The measurements are then converted to the unit system of the presentation
+func advise(weather structs.OutputWeather, at time.Time, p Presentation) structs.OutputWeather {
+	weather.Conditions = AssessConditions(weather, at, p)
 
 	// Calls method response which returns an array containing different return messages
-	responseArr := response(rw, data)
-
-	// Redefines jsonStruct to also contain the different, relevant messages
-	jsonStruct = structs.OutputWeather{
-		Main:       structs.MainStruct{Main: main, Message: responseArr[0]},
-		Rain1h:     rain1H,
-		Snow1h:     snow1H,
-		Temp:       structs.TempStruct{Temp: tempActual, Message: responseArr[1]},
-		FeelsLike:  structs.FeelsLikeStruct{FeelsLike: tempFeelsLike, Message: responseArr[2]},
-		TempMin:    structs.TempMinStruct{TempMin: tempMin, Message: responseArr[3]},
-		TempMax:    structs.TempMaxStruct{TempMax: tempMax, Message: responseArr[4]},
-		Humidity:   structs.HumidityStruct{Humidity: humidity, Message: responseArr[5]},
-		Visibility: structs.VisibilityStruct{Visibility: visibility, Message: responseArr[6]},
-		WindSpeed:  structs.WindSpeedStruct{WindSpeed: windSpeed, Message: responseArr[7]},
-		WindDeg:    structs.WindDegStruct{WindDeg: windDeg, Message: responseArr[8]},
-		Sunrise:    structs.SunriseStruct{Sunrise: sunrise, Message: responseArr[9]},
-		Sunset:     structs.SunsetStruct{Sunset: sunset, Message: responseArr[10]}}
-
-	return jsonStruct
+	responseArr := response([]structs.OutputWeather{weather}, p)
+
+	weather.Main.Message = responseArr[0]
+	weather.Temp.Message = responseArr[1]
+	weather.FeelsLike.Message = responseArr[2]
+	weather.TempMin.Message = responseArr[3]
+	weather.TempMax.Message = responseArr[4]
+	weather.Humidity.Message = responseArr[5]
+	weather.Visibility.Message = responseArr[6]
+	weather.WindSpeed.Message = responseArr[7]
+	weather.WindDeg.Message = responseArr[8]
+	weather.Sunrise.Message = responseArr[9]
+	weather.Sunset.Message = responseArr[10]
+	return convertWeather(weather, p)
 }
 
-// response Handles the different response-messages depending on the weather conditions
+// response Handles the different response-messages depending on the metric weather conditions, in the presentation
 // returns an array containing all the various return messages
-func response(rw http.ResponseWriter, data []structs.OutputWeather) []string {
+func response(data []structs.OutputWeather, p Presentation) []string {
+	locale := p.Locale
 
 	// Defines the different messages as string
 	var mainMessage string
 	var tempMessage string
 	var feelsLikeMessage string
-	var tempMinMessage string
-	var tempMaxMessage string
 	var humidityMessage string
-	var visibilityMessage string
-	var windSpeedMessage string
-	var windDegMessage string
-	var sunriseMessage string
-	var sunsetMessage string
 
 	// Defines the struct data passed to the method as dataTemp
 	dataTemp := data[0]
 
-	// Switch-cases handling different messages for different weather conditions
-	// Switch on Main weather condition
-	switch dataTemp.Main.Main {
+	// Messages for the main weather condition, from the precipitation class of the road conditions
+	conditions := dataTemp.Conditions
+	switch conditions.Precipitation {
 	// It is raining
-	case "Rain":
-		// Switch while true, to have a check for the different variables
-		switch true {
-		// It has rained less than or equal to 2 mm the last hour -> this is the message to be returned -> break out of switch true loop
-		case dataTemp.Rain1h <= 2:
-			mainMessage = "It has been light rain the last hour, consider bringing a umbrella."
-			break
-		// It has rained more than 2 mm and less than or equal to 7 mm the last hour -> this is the message to be returned -> break out of switch true loop
-		case dataTemp.Rain1h > 2 && dataTemp.Rain1h <= 7:
-			mainMessage = "It has been moderate rain the last hour, consider bringing rainwear."
-			break
-		case dataTemp.Rain1h > 7 && dataTemp.Rain1h <= 50:
-			mainMessage = "It has been heavy rain the last hour, you should bring rainwear."
-			break
-		case dataTemp.Rain1h > 50:
-			mainMessage = "It has been violent rain the last hour, you will most likely become wet if you go outside."
-			break
-		// None of the cases above is relevant : have a default return message
+	case PrecipitationRain:
+		switch conditions.PrecipitationIntensity {
+		case IntensityLight, IntensityModerate, IntensityHeavy, IntensityViolent:
+			mainMessage = i18n.Text(locale, "weather.main.rain."+conditions.PrecipitationIntensity, nil)
 		default:
-			mainMessage = "It is raining, bring appropriate clothing."
-			break
+			mainMessage = i18n.Text(locale, "weather.main.rain", nil)
 		}
-	// It is snowing
-	case "Snow":
-		// Switch while true, to have a check for the different variables
-		switch true {
-		// It has snowed less than or equal to 1 mm the last hour -> this is the message to be returned -> break out of switch true loop
-		case dataTemp.Snow1h <= 1:
-			mainMessage = "It has been snowing lightly the last hour, consider winter tires, " +
-				"wear appropriate clothing and set of a couple of minutes to clear the snow of your car."
-			break
-		// It has rained more than 1 mm and less than or equal to 3 mm the last hour -> this is the message to be returned -> break out of switch true loop
-		case dataTemp.Snow1h > 1 && dataTemp.Snow1h <= 3:
-			mainMessage = "It has been snowing moderately the last hour, make sure to have winter tires, " +
-				"wear appropriate clothing and expect at least 10 minutes to clear the snow of your car."
-			break
-		case dataTemp.Snow1h > 3:
-			mainMessage = "It has been snowing heavily the last hour, you must have winter tires, " +
-				"wear appropriate clothing and expect at least 20 minutes to clear the snow around- and of your car."
-			break
-		// None of the cases above is relevant : have a default return message
+	// It is snowing, the heavier the snow the more minutes are needed to clear the car
+	case PrecipitationSnow:
+		switch conditions.PrecipitationIntensity {
+		case IntensityLight:
+			mainMessage = i18n.Text(locale, "weather.main.snow.light", nil)
+		case IntensityModerate:
+			mainMessage = i18n.Text(locale, "weather.main.snow.moderate", i18n.Args{"count": 10})
+		case IntensityHeavy:
+			mainMessage = i18n.Text(locale, "weather.main.snow.heavy", i18n.Args{"count": 20})
 		default:
-			mainMessage = "It is snowing, drive carefully, bring appropriate clothing and turn on the heater."
-			break
+			mainMessage = i18n.Text(locale, "weather.main.snow", nil)
 		}
-	// It is clear sky outside
-	case "Clear":
-		// No need for switch true loop, because nothing more needs to be checked
-		// This is the return message
-		mainMessage = "The sky is clear, wear appropriate clothing with respect to terrain and temperature."
 	default:
-		mainMessage = "The weather of your destination is: " + dataTemp.Main.Main
+		switch dataTemp.Main.Main {
+		// It is clear sky outside
+		case "Clear":
+			mainMessage = i18n.Text(locale, "weather.main.clear", nil)
+		default:
+			mainMessage = i18n.Text(locale, "weather.main.other", i18n.Args{"main": condition(locale, dataTemp.Main.Main)})
+		}
 	}
 
-	// Reformatting temp to one decimal
-	s := fmt.Sprintf("%.1f", dataTemp.Temp.Temp)
+	temp := p.temperature(dataTemp.Temp.Temp)
 	switch true {
-	case dataTemp.Temp.Temp <= 0:
-		tempMessage = "The temperature is: " + s + " degrees Celsius. It is below the freezing point outside, drive carefully " +
-			"and we recommend you to use winter tires. Bring warm clothes and something warm to drink."
+	case conditions.FreezingRisk == FreezingLikely || dataTemp.Temp.Temp <= 0:
+		tempMessage = i18n.Text(locale, "weather.temp.freezing", temp)
+	case conditions.FreezingRisk == FreezingPossible:
+		tempMessage = i18n.Text(locale, "weather.temp.near_freezing", temp)
 	case dataTemp.Temp.Temp > 0 && dataTemp.Temp.Temp <= 10:
-		tempMessage = "The temperature is: " + s + " degrees Celsius. The temperature outside is moderate. Consider whether " +
-			"winter tires is needed. Most likely summer tires are recommended. Wear a moderate amount of clothing."
-		break
+		tempMessage = i18n.Text(locale, "weather.temp.cold", temp)
 	case dataTemp.Temp.Temp > 10 && dataTemp.Temp.Temp < 20:
-		tempMessage = "The temperature is: " + s + " degrees Celsius. The temperature outside is relatively high. Summer tires " +
-			"are needed. Wear a moderate amount of clothing."
-		break
+		tempMessage = i18n.Text(locale, "weather.temp.mild", temp)
 	case dataTemp.Temp.Temp >= 20:
-		// If the temp is above 20 degrees celsius -> Check whether it is clear sky or not
-		switch true {
-		// Sky is clear -> This is the return message -> break out of the switch true loop
-		case dataTemp.Main.Main == "Clear":
-			tempMessage = "The temperature is: " + s + " degrees Celsius. The temperature outside is high, and summer tires are highly " +
-				"recommended! Put on some sunscreen, some light clothing, blast your air condition at max and put a slush in the " +
-				"cup holder. Enjoy the temperature!"
-			break
-		// Break out of the loop if the weather is not clear
-		default:
-			break
-		}
-		// This is the message to be used
-		tempMessage = "The temperature is: " + s + " degrees Celsius. The temperature outside is high, and summer tires are highly " +
-			"recommended! Consider the need for sunscreen, appropriate clothing and drive after the conditions."
-		break
+		tempMessage = i18n.Text(locale, "weather.temp.warm", temp)
 	default:
-		tempMessage = "The temperature is: " + s + " degrees Celsius. Consider whether winter tires is needed and wear appropriate clothing."
-		break
+		tempMessage = i18n.Text(locale, "weather.temp", temp)
 	}
 
-	t := fmt.Sprintf("%.1f", dataTemp.FeelsLike.FeelsLike)
+	feelsLike := p.temperature(dataTemp.FeelsLike.FeelsLike)
 	switch true {
 	case dataTemp.FeelsLike.FeelsLike <= 10:
-		feelsLikeMessage = "It feels cold outside, with a feels like temperature of: " + t + " degrees Celsius."
-		break
+		feelsLikeMessage = i18n.Text(locale, "weather.feels_like.cold", feelsLike)
 	case dataTemp.FeelsLike.FeelsLike > 10 && dataTemp.FeelsLike.FeelsLike <= 20:
-		feelsLikeMessage = "It is a great temperature outside for jeans and jumper, with a feels like temperature of: " + t + " degrees Celsius."
-		break
+		feelsLikeMessage = i18n.Text(locale, "weather.feels_like.mild", feelsLike)
 	case dataTemp.FeelsLike.FeelsLike > 20:
-		switch true {
-		case dataTemp.Main.Main == "Clear":
-			feelsLikeMessage = "It is warm outside today, light clothes are strongly recommended. Take a trip to the beach " +
-				"and enjoy the day. The temperature feels like: " + t + " degrees Celsius."
-			break
-		default:
-			break
-		}
-		feelsLikeMessage = "It is warm outside today, consider to use light clothes unless it is raining. " +
-			"The temperature feels like: " + t + " degrees Celsius."
-		break
+		feelsLikeMessage = i18n.Text(locale, "weather.feels_like.warm", feelsLike)
 	default:
-		feelsLikeMessage = "The temperature outside feels like: " + t + " degrees Celsius. Dress accordingly."
-		break
+		feelsLikeMessage = i18n.Text(locale, "weather.feels_like", feelsLike)
 	}
 
-	u := fmt.Sprintf("%.1f", dataTemp.TempMin.TempMin)
-	tempMinMessage = "The minimum temperature for the day is expected to be: " + u + " degrees Celsius. Consider this both with regards " +
-		"to tires used and clothes you plan to wear."
-
-	v := fmt.Sprintf("%.1f", dataTemp.TempMax.TempMax)
-	tempMaxMessage = "The maximum temperature for the day is expected to be: " + v + " degrees Celsius. Consider this both with regards " +
-		"to tires used and clothes you plan to wear."
+	tempMinMessage := i18n.Text(locale, "weather.temp_min", p.temperature(dataTemp.TempMin.TempMin))
+	tempMaxMessage := i18n.Text(locale, "weather.temp_max", p.temperature(dataTemp.TempMax.TempMax))
 
+	humidity := i18n.Args{"humidity": dataTemp.Humidity.Humidity}
 	switch true {
 	case dataTemp.Humidity.Humidity <= 30:
-		humidityMessage = "The humidity value at the moment is:" + strconv.Itoa(dataTemp.Humidity.Humidity) + " percent. " +
-			"This is relatively low humidity which can result in health issues."
-		break
+		humidityMessage = i18n.Text(locale, "weather.humidity.low", humidity)
 	case dataTemp.Humidity.Humidity > 30 && dataTemp.Humidity.Humidity <= 75:
-		humidityMessage = "The humidity values at the moment is: " + strconv.Itoa(dataTemp.Humidity.Humidity) + " percent. " +
-			"This is an ideal humidity value and you should be comfortable going outside."
-		break
+		humidityMessage = i18n.Text(locale, "weather.humidity.ideal", humidity)
 	case dataTemp.Humidity.Humidity > 75:
-		humidityMessage = "The humidity values at the moment is: " + strconv.Itoa(dataTemp.Humidity.Humidity) + " percent. " +
-			"If it ain't raining already, there is a high change it will start raining soon."
-		break
+		humidityMessage = i18n.Text(locale, "weather.humidity.high", humidity)
 	default:
-		humidityMessage = "The humidity values at the moment is: " + strconv.Itoa(dataTemp.Humidity.Humidity) + " percent."
-		break
+		humidityMessage = i18n.Text(locale, "weather.humidity", humidity)
 	}
 
-	visibilityMessage = "The visibility outside is: " + strconv.Itoa(dataTemp.Visibility.Visibility) + " meters."
-
-	// Rounds of wind speed to 2 decimals
-	x := fmt.Sprintf("%.2f", dataTemp.WindSpeed.WindSpeed)
-	windSpeedMessage = "The wind speed today is " + x + " m/s."
+	visibilityMessage := i18n.Text(locale, "weather.visibility", p.visibility(dataTemp.Visibility.Visibility))
+	switch conditions.Visibility {
+	case VisibilityFog, VisibilityPoor:
+		visibilityMessage += " " + i18n.Text(locale, "weather.visibility."+conditions.Visibility, nil)
+	}
 
-	windDegMessage = "The wind degree direction is: " + strconv.Itoa(dataTemp.WindDeg.WindDeg)
+	windSpeedMessage := i18n.Text(locale, "weather.wind_speed", p.speed("speed", dataTemp.WindSpeed.WindSpeed))
+	switch conditions.WindRisk {
+	case WindSevere, WindHigh:
+		windSpeedMessage += " " + i18n.Text(locale, "weather.wind."+conditions.WindRisk, nil)
+	}
 
-	// Convert from epoch to human readable date
-	// Inspired by/taken from:
-	//	- https://play.golang.org/p/6h0A0WPxtq
-	//	- https://www.epochconverter.com
-	sunriseEpoch := dataTemp.Sunrise.Sunrise
-	sunsetEpoch := dataTemp.Sunset.Sunset
+	windDegMessage := i18n.Text(locale, "weather.wind_deg", i18n.Args{"deg": dataTemp.WindDeg.WindDeg})
 
-	// Returns message with the time for sunrise in a human readable format
-	sunriseMessage = "The time for sunrise is: " + epochToHumanReadable(int64(sunriseEpoch)).String()
+	// Returns message with the time for sunrise in a human readable format, in the time zone of the location
+	sunriseMessage := i18n.Text(locale, "weather.sunrise", i18n.Args{"time": dataTemp.Sunrise.Local})
 
-	// Returns message with the time for sunrise in a human readable format
-	sunsetMessage = "The time for sunset is: " + epochToHumanReadable(int64(sunsetEpoch)).String()
+	// Returns message with the time for sunset in a human readable format, in the time zone of the location
+	sunsetMessage := i18n.Text(locale, "weather.sunset", i18n.Args{"time": dataTemp.Sunset.Local})
+	if !conditions.Daylight {
+		sunsetMessage += " " + i18n.Text(locale, "weather.darkness", nil)
+	}
 
-	// Manually applies the different messages to an array ready to be returned
-	messages := []string{mainMessage, tempMessage, feelsLikeMessage, tempMinMessage, tempMaxMessage, humidityMessage,
+	// Return all the messages as an array of messages
+	return []string{mainMessage, tempMessage, feelsLikeMessage, tempMinMessage, tempMaxMessage, humidityMessage,
 		visibilityMessage, windSpeedMessage, windDegMessage, sunriseMessage, sunsetMessage}
+}
 
-	// Array to be returned
-	var returnedMessages []string
-
-	// Loop variable
-	var i int
-
-	// For each message in the messages array -> append them to the returnedMessages array
-	for i = 0; i < len(messages); i++ {
-		returnedMessages = append(returnedMessages, messages[i])
+// condition Translates the main weather condition from the API, conditions without a translation are kept as they are
+func condition(locale string, main string) string {
+	translated := i18n.Text(locale, "weather.condition."+main, nil)
+	if translated == "weather.condition."+main {
+		return main
 	}
-
-	// Return all the messages as an array of messages
-	return returnedMessages
+	return translated
 }
 
 // epochToHumanReadable Converts time from epoch format to a more human readable RFC822-format
@@ -364,41 +290,3 @@ func response(rw http.ResponseWriter, data []structs.OutputWeather) []string {
 func epochToHumanReadable(epoch int64) time.Time {
 	return time.Unix(epoch, 0)
 }
-
-// GetMessageWeight
-// Analyzes the weather message and calculates how much time needs to be added
-// Based on weather conditions
-func GetMessageWeight(message string) int {
-	base := 10.0
-	skyWeight := 1.0
-
-	// Changes the weight of extra time needed based on weather
-	switch true {
-	case strings.Contains(message, "snow"):
-		skyWeight = 1.6
-		if strings.Contains(message, "light") {
-			skyWeight = 1.2
-		} else if strings.Contains(message, "moderate") {
-			skyWeight = 1.6
-		} else if strings.Contains(message, "heavily") {
-			skyWeight = 2.0
-		}
-		base = base * skyWeight
-		break
-
-	case strings.Contains(message, "rain"):
-		skyWeight = 1.2
-		if strings.Contains(message, "light") {
-			skyWeight = 1
-		} else if strings.Contains(message, "moderate") {
-			skyWeight = 1.2
-		} else if strings.Contains(message, "heavy") {
-			skyWeight = 1.5
-		} else if strings.Contains(message, "violent") {
-			skyWeight = 1.8
-		}
-		base = base * skyWeight
-		break
-	}
-	return int(base)
-}
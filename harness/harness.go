@@ -0,0 +1,297 @@
+package harness
+
+import (
+	"bytes"
+	"cloudproject/database"
+	"cloudproject/utils"
+	"context"
+	"encoding/json"
+	"io/ioutil"
+	"log"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+/**
+ * Class harness.go
+ * Offline test harness for the endpoints and webhooks
+ * Starts local fake TomTom, MapQuest, OpenRouteService and OpenWeatherMap servers answering with recorded
+ * JSON fixtures from testdata, points the application at them, and replaces the database with an in-memory store.
+ * Webhook invocations are sent to a local receiver, and can be read from Deliveries. The background workers
+ * registered with RegisterWorkers run while the harness does, and are stopped before the application is restored.
+ *
+ * Record mode: running the tests with the environment variable RECORD_FIXTURES=1 forwards the requests to the
+ * real APIs and overwrites the fixtures with their responses.
+ */
+
+// Names of the faked services, also the names of their fixture directories
+const (
+	TomTom           = "tomtom"
+	MapQuest         = "mapquest"
+	OpenRouteService = "openrouteservice"
+	OpenWeatherMap   = "openweathermap"
+)
+
+// Fixture Maps requests to a recorded response
+type Fixture struct {
+	Service string            // The service answering the request
+	Method  string            // Method of the request, any method if it is empty
+	Path    string            // Prefix of the request path
+	Query   map[string]string // Query values the request must have, compared case-insensitively
+	File    string            // The recorded response, relative to the service directory in testdata
+}
+
+// Fixtures The recorded responses, the first fixture matching a request is used
+var Fixtures = []Fixture{
+	{Service: MapQuest, Path: "/geocoding/v1/address", Query: map[string]string{"location": "gjøvik"}, File: "gjovik.json"},
+	{Service: MapQuest, Path: "/geocoding/v1/address", Query: map[string]string{"location": "lillehammer"}, File: "lillehammer.json"},
+	{Service: MapQuest, Path: "/geocoding/v1/address", Query: map[string]string{"location": "oslo"}, File: "oslo.json"},
+	{Service: MapQuest, Path: "/geocoding/v1/address", Query: map[string]string{"location": "bergen"}, File: "bergen.json"},
+	{Service: MapQuest, Path: "/geocoding/v1/address", Query: map[string]string{"location": "stavanger"}, File: "stavanger.json"},
+	{Service: OpenWeatherMap, Path: "/data/2.5/weather", File: "weather.json"},
+	{Service: OpenWeatherMap, Path: "/data/2.5/onecall", File: "onecall.json"},
+	{Service: TomTom, Method: http.MethodGet, Path: "/routing/1/calculateRoute/60.394300,5.325900:58.970000,5.733100", File: "route_ferry.json"},
+	{Service: TomTom, Method: http.MethodGet, Path: "/routing/1/calculateRoute/", Query: map[string]string{"routeType": "shortest"}, File: "route_shortest.json"},
+	{Service: TomTom, Method: http.MethodPost, Path: "/routing/1/calculateRoute/", File: "route_avoiding.json"},
+	{Service: TomTom, Path: "/routing/1/calculateRoute/", File: "route.json"},
+	{Service: TomTom, Path: "/search/2/nearbySearch/", Query: map[string]string{"categorySet": "7309"}, File: "charge.json"},
+	{Service: TomTom, Path: "/search/2/nearbySearch/", Query: map[string]string{"categorySet": "7311"}, File: "petrol.json"},
+	{Service: TomTom, Path: "/search/2/poiSearch/", File: "poi.json"},
+	{Service: TomTom, Path: "/traffic/services/5/incidentDetails", File: "incidents.json"},
+	{Service: OpenRouteService, Path: "/v2/directions/driving-car", File: "directions.json"},
+}
+
+// Delivery A webhook invocation received by the harness
+type Delivery struct {
+	Signature string
+	Body      []byte
+}
+
+// Harness The running fake servers
+type Harness struct {
+	// WebhookURL Url of the receiver to register webhooks with
+	WebhookURL string
+
+	servers    map[string]*httptest.Server
+	receiver   *httptest.Server
+	deliveries chan Delivery
+	mutex      sync.Mutex
+	requests   map[string][]string
+}
+
+// realURLs The base urls of the real APIs, used in record mode
+var realURLs = map[string]string{
+	TomTom:           utils.TomTomURL,
+	MapQuest:         utils.MapQuestURL,
+	OpenRouteService: utils.OpenRouteServiceURL,
+	OpenWeatherMap:   utils.OpenWeatherMapURL,
+}
+
+// workers Start and stop the background workers of the application, set with RegisterWorkers
+var workers struct {
+	start    func(context.Context)
+	shutdown func(context.Context) error
+}
+
+// RegisterWorkers Sets how the background workers of the application are started and stopped, such as webhooks.Start
+// and webhooks.Shutdown. The harness can not start them itself, as their packages are tested with it
+func RegisterWorkers(start func(context.Context), shutdown func(context.Context) error) {
+	workers.start, workers.shutdown = start, shutdown
+}
+
+// Recording Checks if the fixtures should be refreshed from the real APIs
+func Recording() bool {
+	return os.Getenv("RECORD_FIXTURES") != ""
+}
+
+// Start Starts the fake servers and points the application at them, everything is restored when the test ends
+func Start(t testing.TB) *Harness {
+	h := &Harness{
+		servers:    map[string]*httptest.Server{},
+		deliveries: make(chan Delivery, 16),
+		requests:   map[string][]string{},
+	}
+	for service := range realURLs {
+		h.servers[service] = httptest.NewServer(h.fake(service))
+	}
+	h.receiver = httptest.NewServer(http.HandlerFunc(h.receive))
+	h.WebhookURL = h.receiver.URL + "/hook"
+
+	previousClient, previousCtx := database.Client, database.Ctx
+	previousURLs := []string{utils.TomTomURL, utils.MapQuestURL, utils.OpenRouteServiceURL, utils.OpenWeatherMapURL}
+
+	utils.TomTomURL = h.servers[TomTom].URL
+	utils.MapQuestURL = h.servers[MapQuest].URL
+	utils.OpenRouteServiceURL = h.servers[OpenRouteService].URL
+	utils.OpenWeatherMapURL = h.servers[OpenWeatherMap].URL
+	database.Client = database.NewMemory()
+
+	// The workers still running, and the notifications they sleep on, are stopped before anything is put back
+	ctx, stopWorkers := context.WithCancel(context.Background())
+	if workers.start != nil {
+		workers.start(ctx)
+	}
+
+	t.Cleanup(func() {
+		stopWorkers()
+		if workers.shutdown != nil {
+			shutdownCtx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+			if err := workers.shutdown(shutdownCtx); err != nil {
+				t.Error(err)
+			}
+			cancel()
+		}
+		for _, server := range h.servers {
+			server.Close()
+		}
+		h.receiver.Close()
+		database.Client, database.Ctx = previousClient, previousCtx
+		utils.TomTomURL, utils.MapQuestURL = previousURLs[0], previousURLs[1]
+		utils.OpenRouteServiceURL, utils.OpenWeatherMapURL = previousURLs[2], previousURLs[3]
+	})
+	return h
+}
+
+// Deliveries The webhook invocations received by the harness
+func (h *Harness) Deliveries() <-chan Delivery {
+	return h.deliveries
+}
+
+// WaitForDelivery Waits for the next webhook invocation, fails the test if none arrives within the timeout
+func (h *Harness) WaitForDelivery(t testing.TB, timeout time.Duration) Delivery {
+	select {
+	case delivery := <-h.deliveries:
+		return delivery
+	case <-time.After(timeout):
+		t.Fatalf("No webhook invocation received within %v", timeout)
+		return Delivery{}
+	}
+}
+
+// Requests Returns the paths and queries of the requests a fake service has received
+func (h *Harness) Requests(service string) []string {
+	h.mutex.Lock()
+	defer h.mutex.Unlock()
+	return append([]string{}, h.requests[service]...)
+}
+
+// fake Answers the requests of a service with the matching fixture
+func (h *Harness) fake(service string) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		h.mutex.Lock()
+		h.requests[service] = append(h.requests[service], r.URL.RequestURI())
+		h.mutex.Unlock()
+
+		fixture, found := match(service, r)
+		if !found {
+			log.Println("Harness: no fixture for " + service + " " + r.URL.Path + "?" + r.URL.RawQuery)
+			http.Error(w, `{"error": "no fixture for this request"}`, http.StatusNotFound)
+			return
+		}
+
+		var body []byte
+		var err error
+		if Recording() {
+			body, err = record(service, fixture, r)
+		} else {
+			body, err = ioutil.ReadFile(fixturePath(service, fixture.File))
+		}
+		if err != nil {
+			log.Println("Harness: unable to answer with fixture " + fixture.File + "\n" + err.Error())
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		w.Header().Set("Content-Type", contentType(fixture.File))
+		_, _ = w.Write(body)
+	}
+}
+
+// receive Receives webhook invocations
+func (h *Harness) receive(w http.ResponseWriter, r *http.Request) {
+	body, err := ioutil.ReadAll(r.Body)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	// Deliveries the test does not read are dropped once the buffer is full, so the webhook workers never wait for the
+	// test and can be shut down when it ends
+	select {
+	case h.deliveries <- Delivery{Signature: r.Header.Get("X-SIGNATURE"), Body: body}:
+	default:
+		log.Println("Harness: dropped a webhook delivery, the test has not read the earlier ones")
+	}
+	w.WriteHeader(http.StatusOK)
+}
+
+// match Finds the first fixture for the request
+func match(service string, r *http.Request) (Fixture, bool) {
+	query := r.URL.Query()
+	for _, fixture := range Fixtures {
+		if fixture.Service != service || !strings.HasPrefix(r.URL.Path, fixture.Path) ||
+			(fixture.Method != "" && fixture.Method != r.Method) {
+			continue
+		}
+		matches := true
+		for key, value := range fixture.Query {
+			if !strings.EqualFold(strings.TrimSpace(query.Get(key)), value) {
+				matches = false
+			}
+		}
+		if matches {
+			return fixture, true
+		}
+	}
+	return Fixture{}, false
+}
+
+// record Forwards the request to the real API and stores the response as the fixture
+func record(service string, fixture Fixture, r *http.Request) ([]byte, error) {
+	var request *http.Request
+	var err error
+	body, _ := ioutil.ReadAll(r.Body)
+	request, err = http.NewRequest(r.Method, realURLs[service]+r.URL.RequestURI(), bytes.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+	request.Header = r.Header.Clone()
+	response, err := http.DefaultClient.Do(request)
+	if err != nil {
+		return nil, err
+	}
+	defer response.Body.Close()
+	recorded, err := ioutil.ReadAll(response.Body)
+	if err != nil {
+		return nil, err
+	}
+
+	// Indents JSON, so the fixtures are readable and give small diffs when refreshed
+	if strings.HasSuffix(fixture.File, ".json") {
+		var indented bytes.Buffer
+		if json.Indent(&indented, recorded, "", "  ") == nil {
+			recorded = append(indented.Bytes(), '\n')
+		}
+	}
+	log.Println("Harness: recorded " + service + " " + r.URL.Path + " to " + fixture.File)
+	return recorded, ioutil.WriteFile(fixturePath(service, fixture.File), recorded, 0644)
+}
+
+// fixturePath Returns the path of a fixture, independent of the directory the tests run in
+func fixturePath(service string, file string) string {
+	_, source, _, _ := runtime.Caller(0)
+	return filepath.Join(filepath.Dir(source), "testdata", service, file)
+}
+
+// contentType The content type of a fixture, by its extension
+func contentType(file string) string {
+	if strings.HasSuffix(file, ".xml") {
+		return "application/xml"
+	}
+	return "application/json"
+}
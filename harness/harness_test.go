@@ -0,0 +1,28 @@
+package harness
+
+import (
+	"net/http"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestUnreadDeliveries(t *testing.T) {
+	h := Start(t)
+	client := http.Client{Timeout: 5 * time.Second}
+
+	// More deliveries than the test reads are answered without waiting for it
+	for i := 0; i < 20; i++ {
+		res, err := client.Post(h.WebhookURL, "application/json", strings.NewReader(`{"text": "`+strconv.Itoa(i)+`"}`))
+		if err != nil {
+			t.Fatalf("Expected delivery %v to be answered; got %v", i, err)
+		}
+		res.Body.Close()
+	}
+
+	// The first deliveries are kept in order
+	if delivery := h.WaitForDelivery(t, time.Second); string(delivery.Body) != `{"text": "0"}` {
+		t.Errorf("Expected the first delivery; got %v", string(delivery.Body))
+	}
+}
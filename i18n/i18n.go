@@ -0,0 +1,294 @@
+package i18n
+
+import (
+	"cloudproject/router"
+	"cloudproject/utils"
+	"encoding/json"
+	"errors"
+	"io/ioutil"
+	"log"
+	"math"
+	"net/http"
+	"os"
+	"path/filepath"
+	"runtime"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+/**
+ * Class i18n.go
+ * Message catalog for the texts shown to users
+ * The messages of each locale are read from locales/<locale>.json, where a message is either a string or an object
+ * with the plural forms "one" and "other". Messages contain placeholders such as {temp} or {temp:1}, where the number
+ * after the colon is the number of decimals. Numbers and times are formatted with the separators and layout of the
+ * locale, given by the format.* entries of the locale file.
+ * Messages missing from a locale are taken from English.
+ */
+
+// Supported locales
+const (
+	English   = "en"
+	Norwegian = "nb"
+	German    = "de"
+)
+
+// Fallback The locale used when none of the requested locales are supported
+const Fallback = English
+
+// Locales The supported locales, every locale has a file in the locales directory
+var Locales = []string{English, Norwegian, German}
+
+// aliases Language tags which are served by a supported locale
+var aliases = map[string]string{
+	"no": Norwegian,
+	"nn": Norwegian,
+}
+
+// LangParam The query parameter for choosing the language, to be added to the query schema of endpoints with texts
+var LangParam = utils.QueryParam{Name: "lang", Type: utils.TypeString, Enum: Locales, Aliases: aliases,
+	Description: "Language of the messages, overrides the Accept-Language header"}
+
+// Args The values of the placeholders in a message, a value named count chooses the plural form
+type Args map[string]interface{}
+
+// message A message with its plural forms, messages without plural forms only have Other
+type message struct {
+	One   string
+	Other string
+}
+
+var (
+	// Dir The directory of the locale files, LOCALES_DIR overrides the directory next to this file
+	Dir     = localesDir()
+	once    sync.Once
+	catalog map[string]map[string]message
+)
+
+// localesDir Finds the locale files, independent of the directory the application or the tests run in
+func localesDir() string {
+	if dir := os.Getenv("LOCALES_DIR"); dir != "" {
+		return dir
+	}
+	_, source, _, _ := runtime.Caller(0)
+	return filepath.Join(filepath.Dir(source), "locales")
+}
+
+// load Reads the locale files into the catalog, once
+func load() {
+	once.Do(func() {
+		catalog = map[string]map[string]message{}
+		for _, locale := range Locales {
+			messages, err := readLocale(filepath.Join(Dir, locale+".json"))
+			if err != nil {
+				log.Println("Unable to read the messages for locale: " + locale + "\n" + err.Error())
+				continue
+			}
+			catalog[locale] = messages
+		}
+	})
+}
+
+// readLocale Reads the messages of a locale file
+func readLocale(path string) (map[string]message, error) {
+	content, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var raw map[string]json.RawMessage
+	if err = json.Unmarshal(content, &raw); err != nil {
+		return nil, err
+	}
+	messages := map[string]message{}
+	for key, value := range raw {
+		var text string
+		if json.Unmarshal(value, &text) == nil {
+			messages[key] = message{Other: text}
+			continue
+		}
+		var forms struct {
+			One   string `json:"one"`
+			Other string `json:"other"`
+		}
+		if err := json.Unmarshal(value, &forms); err != nil || forms.Other == "" {
+			return nil, errors.New("The message " + key + " must be a string or an object with the plural forms one and other")
+		}
+		messages[key] = message{One: forms.One, Other: forms.Other}
+	}
+	return messages, nil
+}
+
+// Match Returns the supported locale serving a language tag such as nb-NO, and if there is one
+func Match(tag string) (string, bool) {
+	base := strings.ToLower(strings.TrimSpace(tag))
+	if i := strings.IndexAny(base, "-_"); i != -1 {
+		base = base[:i]
+	}
+	for _, locale := range Locales {
+		if base == locale {
+			return locale, true
+		}
+	}
+	locale, found := aliases[base]
+	return locale, found
+}
+
+// Negotiate Chooses the locale of a request, from the lang parameter or else the Accept-Language header
+func Negotiate(r *http.Request) string {
+	if lang := router.Query(r).Get("lang"); lang != "" {
+		return lang
+	}
+	return FromAcceptLanguage(r.Header.Get("Accept-Language"))
+}
+
+// FromAcceptLanguage Chooses the supported locale with the highest quality from an Accept-Language header
+func FromAcceptLanguage(header string) string {
+	type choice struct {
+		locale  string
+		quality float64
+	}
+	var choices []choice
+	for _, part := range strings.Split(header, ",") {
+		fields := strings.Split(part, ";")
+		locale, found := Match(fields[0])
+		if !found {
+			continue
+		}
+		quality := 1.0
+		for _, field := range fields[1:] {
+			field = strings.TrimSpace(field)
+			if strings.HasPrefix(field, "q=") {
+				if q, err := strconv.ParseFloat(strings.TrimPrefix(field, "q="), 64); err == nil {
+					quality = q
+				}
+			}
+		}
+		if quality > 0 {
+			choices = append(choices, choice{locale, quality})
+		}
+	}
+	sort.SliceStable(choices, func(i, j int) bool { return choices[i].quality > choices[j].quality })
+	if len(choices) == 0 {
+		return Fallback
+	}
+	return choices[0].locale
+}
+
+// Text Returns the message of the key in the locale, with the placeholders replaced by the arguments.
+// The key itself is returned if no locale has the message
+func Text(locale string, key string, args Args) string {
+	load()
+	msg, found := catalog[locale][key]
+	if !found {
+		locale = Fallback
+		if msg, found = catalog[Fallback][key]; !found {
+			log.Println("No message for the key: " + key)
+			return key
+		}
+	}
+
+	text := msg.Other
+	if count, hasCount := args["count"]; hasCount && msg.One != "" && pluralForm(locale, toFloat(count)) == "one" {
+		text = msg.One
+	}
+	return replace(locale, text, args)
+}
+
+// pluralForm The plural form of a count in the locale. English, Norwegian and German all use the singular for
+// exactly one, locales with other rules are to be added here
+func pluralForm(locale string, count float64) string {
+	if count == 1 {
+		return "one"
+	}
+	return "other"
+}
+
+// replace Replaces the placeholders of the text with the formatted arguments
+func replace(locale string, text string, args Args) string {
+	var result strings.Builder
+	for {
+		start := strings.Index(text, "{")
+		end := strings.Index(text, "}")
+		if start == -1 || end < start {
+			result.WriteString(text)
+			return result.String()
+		}
+		result.WriteString(text[:start])
+		name, decimals := text[start+1:end], -1
+		if i := strings.Index(name, ":"); i != -1 {
+			decimals, _ = strconv.Atoi(name[i+1:])
+			name = name[:i]
+		}
+		if value, found := args[name]; found {
+			result.WriteString(format(locale, value, decimals))
+		} else {
+			result.WriteString(text[start : end+1])
+		}
+		text = text[end+1:]
+	}
+}
+
+// format Formats a value of a placeholder for the locale
+func format(locale string, value interface{}, decimals int) string {
+	switch v := value.(type) {
+	case int, int64, float64:
+		if decimals == -1 {
+			decimals = 0
+			if _, isFloat := v.(float64); isFloat && toFloat(v) != math.Trunc(toFloat(v)) {
+				decimals = -1
+			}
+		}
+		return Number(locale, toFloat(v), decimals)
+	case time.Time:
+		return v.Format(Text(locale, "format.time", nil))
+	case string:
+		return v
+	default:
+		return ""
+	}
+}
+
+// Number Formats a number with the decimal and group separators of the locale, with the given number of decimals.
+// A negative number of decimals uses as many as needed
+func Number(locale string, value float64, decimals int) string {
+	formatted := strconv.FormatFloat(math.Abs(value), 'f', decimals, 64)
+	integer, fraction := formatted, ""
+	if i := strings.Index(formatted, "."); i != -1 {
+		integer, fraction = formatted[:i], formatted[i+1:]
+	}
+
+	// Groups the thousands
+	group := Text(locale, "format.group", nil)
+	var grouped strings.Builder
+	for i, digit := range integer {
+		if i != 0 && (len(integer)-i)%3 == 0 {
+			grouped.WriteString(group)
+		}
+		grouped.WriteRune(digit)
+	}
+
+	result := grouped.String()
+	if fraction != "" {
+		result += Text(locale, "format.decimal", nil) + fraction
+	}
+	if value < 0 && strings.Trim(formatted, "0.") != "" {
+		result = "-" + result
+	}
+	return result
+}
+
+// toFloat Converts the numeric argument types to float64
+func toFloat(value interface{}) float64 {
+	switch v := value.(type) {
+	case int:
+		return float64(v)
+	case int64:
+		return float64(v)
+	case float64:
+		return v
+	}
+	return 0
+}
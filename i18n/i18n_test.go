@@ -0,0 +1,83 @@
+package i18n
+
+import (
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+// TestLocalesComplete Checks that every locale has the same messages as English
+func TestLocalesComplete(t *testing.T) {
+	english, err := readLocale(filepath.Join(Dir, English+".json"))
+	if err != nil {
+		t.Fatalf("Could not read the English messages: %v", err)
+	}
+	for _, locale := range Locales {
+		messages, err := readLocale(filepath.Join(Dir, locale+".json"))
+		if err != nil {
+			t.Errorf("Could not read the messages of %v: %v", locale, err)
+			continue
+		}
+		for key, msg := range english {
+			translated, found := messages[key]
+			if !found {
+				t.Errorf("%v is missing the message %v", locale, key)
+			} else if (msg.One == "") != (translated.One == "") {
+				t.Errorf("%v has other plural forms than English for %v", locale, key)
+			}
+		}
+		for key := range messages {
+			if _, found := english[key]; !found {
+				t.Errorf("%v has the message %v, which English does not have", locale, key)
+			}
+		}
+	}
+}
+
+func TestFromAcceptLanguage(t *testing.T) {
+	tests := map[string]string{
+		"":                            English,
+		"nb-NO,nb;q=0.9,en;q=0.8":     Norwegian,
+		"no":                          Norwegian,
+		"fr-FR,de;q=0.5,en;q=0.7":     English,
+		"fr-FR, de-CH;q=0.9, *;q=0.1": German,
+		"en;q=0,nn":                   Norwegian,
+	}
+	for header, expected := range tests {
+		if locale := FromAcceptLanguage(header); locale != expected {
+			t.Errorf("%q: expected %v; got %v", header, expected, locale)
+		}
+	}
+}
+
+func TestText(t *testing.T) {
+	tests := []struct {
+		locale   string
+		key      string
+		args     Args
+		expected string
+	}{
+		{English, "weather.visibility", Args{"visibility": 10000, "unit": "meters"}, "The visibility outside is: 10,000 meters."},
+		{Norwegian, "weather.visibility", Args{"visibility": 32808, "unit": "fot"}, "Sikten ute er: 32\u00a0808 fot."},
+		{German, "weather.wind_speed", Args{"speed": 3.6, "unit": "m/s"}, "Die Windgeschwindigkeit beträgt heute 3,60 m/s."},
+		{Norwegian, "reason.freezing.likely", Args{"temp": -2.04, "unit": "grader Celsius"}, "sannsynlig is på veien ved -2,0 grader Celsius"},
+		{English, "weather.sunrise", Args{"time": time.Date(2021, 5, 10, 4, 2, 0, 0, time.UTC)},
+			"The time for sunrise is: 2021-05-10 04:02:00 +0000 UTC"},
+		{German, "weather.sunrise", Args{"time": time.Date(2021, 5, 10, 4, 2, 0, 0, time.UTC)},
+			"Sonnenaufgang: 10.05.2021 04:02 Uhr UTC"},
+		{"fr", "reason.darkness", nil, "driving in the dark"},
+		{English, "unknown.key", nil, "unknown.key"},
+	}
+	for _, test := range tests {
+		if text := Text(test.locale, test.key, test.args); text != test.expected {
+			t.Errorf("%v %v: expected %q; got %q", test.locale, test.key, test.expected, text)
+		}
+	}
+
+	one := Text(English, "weather.main.snow.heavy", Args{"count": 1})
+	other := Text(English, "weather.main.snow.heavy", Args{"count": 20})
+	if one == other || !strings.Contains(one, "1 minute to") || !strings.Contains(other, "20 minutes to") {
+		t.Errorf("Expected the plural forms to differ; got %q and %q", one, other)
+	}
+}
@@ -3,6 +3,10 @@ package main
 import (
 	"cloudproject/database"
 	"cloudproject/endpoints"
+	"cloudproject/openapi"
+	"cloudproject/router"
+	"cloudproject/structs"
+	"cloudproject/utils"
 	"cloudproject/webhooks"
 	"context"
 	firebase "firebase.google.com/go"
@@ -11,7 +15,11 @@ import (
 	"log"
 	"net/http"
 	"os"
+	"os/signal"
+	"strconv"
+	"syscall"
 	"time"
+	_ "time/tzdata" // The time zones of the locations, the container has no time zone database
 )
 
 //getPort sets the port to 8080
@@ -23,10 +31,68 @@ func getPort() string {
 	return ":" + port
 }
 
+// apiTitle The title of the API in the OpenAPI specification
+const apiTitle = "Road Trip Companion"
+
+// shutdownTimeout How long in-flight requests and webhook invocations are given to finish when shutting down
+const shutdownTimeout = 20 * time.Second
+
 //main Function to start application, initializes database and webhooks
 func main() {
-	// Creates instance of firebase
 	database.Ctx = context.Background()
+	database.Client = openDatabase()
+	importClimate()
+	importFerries()
+	importTolls()
+
+	// Starts uptime of program
+	endpoints.Uptime = time.Now()
+
+	//Webhook handling, the workers run until the context is cancelled on shutdown
+	workerCtx, stopWorkers := context.WithCancel(context.Background())
+	webhooks.AttachTrips()
+	webhooks.Start(workerCtx)
+
+	server := &http.Server{
+		Addr:              getPort(),
+		Handler:           handlers(),
+		ReadHeaderTimeout: 10 * time.Second,
+		ReadTimeout:       15 * time.Second,
+		WriteTimeout:      60 * time.Second, // Endpoints wait on several third party APIs before answering
+		IdleTimeout:       120 * time.Second,
+	}
+
+	// The event streams end when the server shuts down, instead of being waited for
+	server.RegisterOnShutdown(webhooks.CloseStreams)
+
+	serverErr := make(chan error, 1)
+	go func() {
+		log.Println("Listening on port: " + getPort())
+		serverErr <- server.ListenAndServe()
+	}()
+
+	// Waits for a termination signal, or for the server to fail
+	stop := make(chan os.Signal, 1)
+	signal.Notify(stop, syscall.SIGINT, syscall.SIGTERM)
+	select {
+	case sig := <-stop:
+		log.Println("Received " + sig.String() + ", shutting down.")
+	case err := <-serverErr:
+		log.Println("Server stopped unexpectedly: " + err.Error())
+	}
+
+	shutdown(server, stopWorkers)
+}
+
+// openDatabase Connects to Firestore, or creates an in-memory database if the environment variable
+// DATABASE is set to "memory" (for running without Firebase credentials)
+func openDatabase() database.Store {
+	if os.Getenv("DATABASE") == "memory" {
+		log.Println("Using an in-memory database, nothing is kept when the application stops.")
+		return database.NewMemory()
+	}
+
+	// Creates instance of firebase
 	sa := option.WithCredentialsFile("webhooks/cloudprojecttwo-firebase-adminsdk-uke12-6ed6b4ca4e.json") //Initializes database
 	app, err := firebase.NewApp(database.Ctx, nil, sa)
 	if err != nil {
@@ -34,35 +100,227 @@ func main() {
 		_ = fmt.Errorf("error initializing app: %v", err)
 	}
 
-	database.Client, err = app.Firestore(database.Ctx) //Connects to the database
+	client, err := app.Firestore(database.Ctx) //Connects to the database
 	if err != nil {
 		log.Fatalln(err)
 	}
+	return database.NewFirestore(client)
+}
 
-	// Starts uptime of program
-	endpoints.Uptime = time.Now()
-	//Webhook handling
-	go webhooks.InvokeAll()
-	go webhooks.DeleteExpiredWebhooks()
+// importClimate Imports the climate normals into the database, replacing the stored normals of the stations in the
+// file. CLIMATE_NORMALS points at another file
+func importClimate() {
+	stations, err := database.ImportClimateFile(database.ClimateFile)
+	if err != nil {
+		log.Println("Unable to import the climate normals from " + database.ClimateFile + "\n" + err.Error())
+		return
+	}
+	log.Println("Imported the climate normals of " + strconv.Itoa(stations) + " stations.")
+}
+
+// importFerries Imports the ferry timetable into the database, replacing the stored timetables of the ferry routes in
+// the feed. FERRY_TIMETABLE points at another GTFS feed
+func importFerries() {
+	routes, err := database.ImportTimetableFile(database.FerryTimetable)
+	if err != nil {
+		log.Println("Unable to import the ferry timetable from " + database.FerryTimetable + "\n" + err.Error())
+		return
+	}
+	log.Println("Imported the timetables of " + strconv.Itoa(routes) + " ferry routes.")
+}
+
+// importTolls Imports the toll points into the database, replacing the stored toll points with the same ids.
+// TOLL_POINTS points at another file
+func importTolls() {
+	points, err := database.ImportTollsFile(database.TollFile)
+	if err != nil {
+		log.Println("Unable to import the toll points from " + database.TollFile + "\n" + err.Error())
+		return
+	}
+	log.Println("Imported " + strconv.Itoa(points) + " toll points.")
+}
+
+// shutdown Stops the application in order: drains in-flight requests, stops the webhook workers,
+// flushes pending webhook invocations and finally closes the database client
+func shutdown(server *http.Server, stopWorkers context.CancelFunc) {
+	ctx, cancel := context.WithTimeout(context.Background(), shutdownTimeout)
+	defer cancel()
+
+	if err := server.Shutdown(ctx); err != nil {
+		log.Println("Error while draining in-flight requests: " + err.Error())
+	}
 
-	log.Println("Listening on port: " + getPort())
-	handlers()
+	stopWorkers()
+	if err := webhooks.Shutdown(ctx); err != nil {
+		log.Println("Error while stopping webhook workers: " + err.Error())
+	}
 
-	defer database.Client.Close()
+	if err := database.Client.Close(); err != nil {
+		log.Println("Error while closing the database client: " + err.Error())
+	}
+	log.Println("Shutdown complete.")
 }
 
 // handlers Function for redirecting endpoints
 // Error friendly for missing '/' at the end of endpoint
-func handlers() {
-	http.HandleFunc("/rtc/v1/weather/", endpoints.CurrentWeather)
-	http.HandleFunc("/rtc/v1/poi/", endpoints.PointOfInterest)
-	http.HandleFunc("/rtc/v1/diag/", endpoints.Diag)
-	http.HandleFunc("/rtc/v1/diag", endpoints.Diag)
-	http.HandleFunc("/rtc/v1/charge/", endpoints.EVStations)
-	http.HandleFunc("/rtc/v1/petrol/", endpoints.PetrolStation)
-	http.HandleFunc("/rtc/v1/messages/", endpoints.Messages)
-	http.HandleFunc("/rtc/v1/route/", endpoints.Route)
-	http.HandleFunc("/rtc/v1/notifyme/", webhooks.WebhookHandler)
-
-	log.Println(http.ListenAndServe(getPort(), nil))
+func handlers() *router.Router {
+	r := router.New()
+
+	v1 := r.Group("/rtc/v1")
+	v1.Get("/weather/{place}", endpoints.CurrentWeather).WithQuery(endpoints.CurrentWeatherQuery).
+		Describe("Current weather at a place, with advice for the trip").
+		Returns(http.StatusOK, structs.OutputWeather{})
+	v1.Get("/weather/{place}/forecast", endpoints.WeatherForecast).WithQuery(endpoints.WeatherForecastQuery).
+		Describe("Hourly and daily forecast at a place within an optional time window, with advice for the trip").
+		Returns(http.StatusOK, structs.OutputForecast{})
+	v1.Get("/climate/{place}", endpoints.Climate).WithQuery(endpoints.ClimateQuery).
+		Describe("Usual weather at a place in a date range, from the climate normals of the nearest station").
+		Returns(http.StatusOK, structs.OutputClimate{})
+	v1.Get("/poi/{place}/{category}", endpoints.PointOfInterest).WithQuery(endpoints.PointOfInterestQuery).
+		Describe("Points of interest of a category around a place").
+		Returns(http.StatusOK, []structs.OutputPoi{})
+	v1.Get("/diag", endpoints.Diag).WithQuery(nil).
+		Describe("Status of the third party APIs and uptime of the service").
+		Returns(http.StatusOK, structs.Diagnostics{})
+	v1.Get("/charge/{place}", endpoints.EVStations).WithQuery(endpoints.EVStationsQuery).
+		Describe("Charging stations for electric vehicles around a place").
+		Returns(http.StatusOK, []structs.OutputCharge{})
+	v1.Get("/petrol/{place}", endpoints.PetrolStation).WithQuery(endpoints.PetrolStationQuery).
+		Describe("Petrol stations around a place").
+		Returns(http.StatusOK, []structs.OutputPetrol{})
+	v1.Get("/messages/{start}/{destination}", endpoints.Messages).WithQuery(endpoints.MessagesQuery).
+		Describe("Traffic incidents on the route between two places, in the order they are passed").
+		Returns(http.StatusOK, []structs.OutIncident{})
+	v1.Get("/route/{start}/{destination}", endpoints.Route).WithQuery(endpoints.RouteQuery).
+		Describe("Driving route between two places").
+		Returns(http.StatusOK, structs.RoadInformation{})
+	v1.Get("/route/{start}/{destination}/compare", endpoints.CompareRoutes).WithQuery(endpoints.CompareRoutesQuery).
+		Describe("Routes of several route types between two places side by side, with their travel time, length and cost").
+		Returns(http.StatusOK, []structs.RouteAlternative{})
+	v1.Get("/route/{start}/{destination}/departure", endpoints.Departure).WithQuery(endpoints.DepartureQuery).
+		Describe("When to depart to arrive by a time, from the travel times predicted in the traffic and the weather for departures around it").
+		Returns(http.StatusOK, structs.DeparturePlan{})
+	v1.Get("/profiles", endpoints.ListProfiles).WithQuery(nil).
+		Describe("All vehicle profiles").
+		Returns(http.StatusOK, []structs.VehicleProfile{})
+	v1.Post("/profiles", endpoints.AddProfile).
+		Describe("Stores a vehicle profile, which routes, searches and webhooks can refer to").
+		Accepts(structs.VehicleProfile{}).Returns(http.StatusCreated, structs.VehicleProfile{})
+	v1.Get("/profiles/{id}", endpoints.GetProfile).WithQuery(nil).
+		Describe("A vehicle profile").
+		Returns(http.StatusOK, structs.VehicleProfile{})
+	v1.Put("/profiles/{id}", endpoints.UpdateProfile).
+		Describe("Replaces a vehicle profile").
+		Accepts(structs.VehicleProfile{}).Returns(http.StatusOK, structs.VehicleProfile{})
+	v1.Delete("/profiles/{id}", endpoints.DeleteProfile).
+		Describe("Deletes a vehicle profile").
+		Produces("text/plain").Returns(http.StatusOK, "")
+	v1.Get("/trips", endpoints.ListTrips).WithQuery(nil).
+		Describe("All saved trips").
+		Returns(http.StatusOK, []structs.Trip{})
+	v1.Post("/trips", endpoints.AddTrip).
+		Describe("Saves a trip, which routes, weather, incidents, charging plans and webhooks can refer to").
+		Accepts(structs.Trip{}).Returns(http.StatusCreated, structs.Trip{})
+	v1.Get("/trips/{id}", endpoints.GetTrip).WithQuery(nil).
+		Describe("A saved trip").
+		Returns(http.StatusOK, structs.Trip{})
+	v1.Put("/trips/{id}", endpoints.UpdateTrip).
+		Describe("Replaces a saved trip, and updates the webhooks subscribed to it").
+		Accepts(structs.Trip{}).Returns(http.StatusOK, structs.Trip{})
+	v1.Delete("/trips/{id}", endpoints.DeleteTrip).
+		Describe("Deletes a saved trip and the webhooks subscribed to it").
+		Produces("text/plain").Returns(http.StatusOK, "")
+	v1.Get("/trips/{id}/route", endpoints.TripRoute).WithQuery(endpoints.TripRouteQuery).
+		Describe("Driving route of a saved trip through its stops").
+		Returns(http.StatusOK, structs.RoadInformation{})
+	v1.Get("/trips/{id}/weather", endpoints.TripWeather).WithQuery(endpoints.TripWeatherQuery).
+		Describe("Forecast along the route of a saved trip, at the times it passes the stops and the points between them").
+		Returns(http.StatusOK, structs.TripWeather{})
+	v1.Get("/trips/{id}/departure", endpoints.TripDeparture).WithQuery(endpoints.TripDepartureQuery).
+		Describe("When to depart on a saved trip to arrive by its planned arrival").
+		Returns(http.StatusOK, structs.DeparturePlan{})
+	v1.Get("/trips/{id}/messages", endpoints.TripMessages).WithQuery(endpoints.TripMessagesQuery).
+		Describe("Traffic incidents on the route of a saved trip, in the order they are passed").
+		Returns(http.StatusOK, []structs.OutIncident{})
+	v1.Get("/trips/{id}/charge", endpoints.TripCharge).WithQuery(endpoints.TripChargeQuery).
+		Describe("Where to charge along the route of a saved trip, from the range of its vehicle, with the charging stations there").
+		Returns(http.StatusOK, []structs.ChargingStop{})
+	v1.Get("/trips/{id}/subscriptions", webhooks.TripSubscriptions).WithQuery(nil).
+		Describe("The webhooks subscribed to a saved trip").
+		Returns(http.StatusOK, []structs.Webhook{})
+	v1.Get("/trips/{id}/calendar.ics", webhooks.TripCalendar).WithQuery(webhooks.CalendarQuery).
+		Describe("iCalendar feed of a saved trip, with the departure and its weather advisory, the stops and the arrival").
+		Produces("text/calendar").Returns(http.StatusOK, "")
+	v1.Get("/trips/{id}/events", webhooks.TripEvents).WithQuery(webhooks.EventsQuery).
+		Describe("Live events of a saved trip as Server-Sent Events, or as JSON messages over a WebSocket when upgraded").
+		Produces("text/event-stream").Returns(http.StatusOK, "")
+	v1.Post("/trips/{id}/position", webhooks.CheckIn).
+		Describe("Checks in the position of the driver, and plans the remaining route and the estimated arrival from it").
+		Accepts(utils.Coordinate{}).Returns(http.StatusOK, structs.TripProgress{})
+	v1.Post("/trips/{id}/share", webhooks.ShareTrip).WithQuery(webhooks.ShareQuery).
+		Describe("Signed links to a read-only summary and the calendar of a saved trip, valid until they expire").
+		Returns(http.StatusCreated, structs.ShareLink{})
+	v1.Get("/shared/{id}", webhooks.SharedTrip).WithQuery(webhooks.SharedQuery).
+		Describe("Read-only summary of a trip shared through a signed link").
+		Returns(http.StatusOK, structs.TripSummary{})
+	v1.Get("/shared/{id}/calendar.ics", webhooks.SharedCalendar).WithQuery(webhooks.SharedQuery).
+		Describe("iCalendar feed of a trip shared through a signed link").
+		Produces("text/calendar").Returns(http.StatusOK, "")
+	v1.Get("/notifyme", webhooks.ListWebhooks).WithQuery(nil).
+		Describe("All registered webhooks").
+		Returns(http.StatusOK, []structs.Webhook{})
+	v1.Post("/notifyme", webhooks.AddWebhook).
+		Describe("Registers a webhook notifying when to depart to arrive in time").
+		Accepts(structs.Webhook{}).Produces("text/plain").Returns(http.StatusCreated, "")
+	v1.Post("/notifyme/preview", webhooks.PreviewTemplate).
+		Describe("Renders a notification template against sample data of its stage").
+		Accepts(structs.TemplatePreview{}).Returns(http.StatusOK, structs.RenderedTemplate{})
+	v1.Get("/notifyme/{id}", webhooks.GetWebhook).WithQuery(nil).
+		Describe("A registered webhook").
+		Returns(http.StatusOK, structs.Webhook{})
+	v1.Get("/notifyme/{id}/calendar.ics", webhooks.WebhookCalendar).WithQuery(webhooks.CalendarQuery).
+		Describe("iCalendar feed of the trip of a webhook, with the departure estimated for it").
+		Produces("text/calendar").Returns(http.StatusOK, "")
+	v1.Delete("/notifyme/{id}", webhooks.DeleteWebhook).
+		Describe("Deletes a registered webhook").
+		Produces("text/plain").Returns(http.StatusOK, "")
+	v1.Get("/openapi.json", openapi.Handler(r, "/rtc/v1", apiTitle)).WithQuery(nil).
+		Describe("This OpenAPI specification").
+		Returns(http.StatusOK, map[string]interface{}{})
+
+	// Endpoints with breaking changes are mounted on v2, next to the v1 surface. The v2 responses name the fields in
+	// camelCase, v1 keeps the names Go exports
+	v2 := r.Group("/rtc/v2")
+	v2.Get("/weather/{place}", endpoints.CurrentWeather).WithQuery(endpoints.CurrentWeatherQuery).
+		Describe("Current weather at a place, with advice for the trip").
+		Returns(http.StatusOK, structs.OutputWeatherV2{})
+	v2.Get("/poi/{place}/{category}", endpoints.PointOfInterest).WithQuery(endpoints.PointOfInterestQuery).
+		Describe("Points of interest of a category around a place").
+		Returns(http.StatusOK, []structs.OutputPoiV2{})
+	v2.Get("/diag", endpoints.Diag).WithQuery(nil).
+		Describe("Status of the third party APIs and uptime of the service").
+		Returns(http.StatusOK, structs.Diagnostics{})
+	v2.Get("/charge/{place}", endpoints.EVStations).WithQuery(endpoints.EVStationsQuery).
+		Describe("Charging stations for electric vehicles around a place").
+		Returns(http.StatusOK, []structs.OutputChargeV2{})
+	v2.Get("/petrol/{place}", endpoints.PetrolStation).WithQuery(endpoints.PetrolStationQuery).
+		Describe("Petrol stations around a place").
+		Returns(http.StatusOK, []structs.OutputPetrolV2{})
+	v2.Get("/messages/{start}/{destination}", endpoints.Messages).WithQuery(endpoints.MessagesQuery).
+		Describe("Traffic incidents on the route between two places, in the order they are passed").
+		Returns(http.StatusOK, []structs.OutIncidentV2{})
+	v2.Get("/route/{start}/{destination}", endpoints.Route).WithQuery(endpoints.RouteQuery).
+		Describe("Driving route between two places").
+		Returns(http.StatusOK, structs.RoadInformationV2{})
+	v2.Get("/notifyme", webhooks.ListWebhooks).WithQuery(nil).
+		Describe("All registered webhooks").
+		Returns(http.StatusOK, []structs.WebhookV2{})
+	v2.Get("/notifyme/{id}", webhooks.GetWebhook).WithQuery(nil).
+		Describe("A registered webhook").
+		Returns(http.StatusOK, structs.WebhookV2{})
+	v2.Get("/openapi.json", openapi.Handler(r, "/rtc/v2", apiTitle)).WithQuery(nil).
+		Describe("This OpenAPI specification").
+		Returns(http.StatusOK, map[string]interface{}{})
+
+	return r
 }
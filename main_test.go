@@ -0,0 +1,1215 @@
+package main
+
+import (
+	"bytes"
+	"cloudproject/closures"
+	"cloudproject/database"
+	"cloudproject/endpoints"
+	"cloudproject/harness"
+	"cloudproject/openapi"
+	"cloudproject/structs"
+	"cloudproject/webhooks"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"math"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"reflect"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+)
+
+// init Runs the webhook workers while the harness is started
+func init() {
+	harness.RegisterWorkers(webhooks.Start, webhooks.Shutdown)
+}
+
+// specification Gets the specification of the v1 surface served by the application
+func specification(t *testing.T, handler http.Handler) *openapi.Document {
+	return specificationOf(t, handler, "/rtc/v1")
+}
+
+// specificationOf Gets the specification of the surface mounted below mount served by the application
+func specificationOf(t *testing.T, handler http.Handler, mount string) *openapi.Document {
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, mount+"/openapi.json", nil))
+	if rec.Code != http.StatusOK {
+		t.Fatalf("Expected status Ok for the specification; got %v", rec.Code)
+	}
+	var doc openapi.Document
+	if err := json.Unmarshal(rec.Body.Bytes(), &doc); err != nil {
+		t.Fatalf("Could not unmarshal the specification: %v", err)
+	}
+	return &doc
+}
+
+// request Sends a request through the router and returns the response
+func request(handler http.Handler, method string, path string, body interface{}) *httptest.ResponseRecorder {
+	var reader *bytes.Reader
+	if body != nil {
+		content, _ := json.Marshal(body)
+		reader = bytes.NewReader(content)
+	} else {
+		reader = bytes.NewReader(nil)
+	}
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, httptest.NewRequest(method, path, reader))
+	return rec
+}
+
+func TestSpecificationCoversRoutes(t *testing.T) {
+	r := handlers()
+	docs := map[string]*openapi.Document{"/rtc/v1": specification(t, r), "/rtc/v2": specificationOf(t, r, "/rtc/v2")}
+
+	for _, route := range r.Routes() {
+		doc, found := docs[route.Mount]
+		if !found {
+			t.Errorf("Route %v %v is mounted outside the documented surfaces", route.Method, route.Pattern)
+			continue
+		}
+		path := strings.TrimPrefix(route.Pattern, route.Mount)
+		item, found := doc.Paths[path]
+		if !found {
+			t.Errorf("Route %v %v is missing from the specification", route.Method, path)
+			continue
+		}
+		if _, found := (*item)[strings.ToLower(route.Method)]; !found {
+			t.Errorf("Method %v of %v is missing from the specification", route.Method, path)
+		}
+		if route.Summary == "" || route.Status == 0 {
+			t.Errorf("Route %v %v is not documented", route.Method, path)
+		}
+	}
+}
+
+// useClosures Reads the road closures from the sample DATEX II publication during the test
+func useClosures(t *testing.T) {
+	previous := closures.Source
+	closures.Source = "closures/testdata/datex.xml"
+	t.Cleanup(func() { closures.Source = previous })
+}
+
+// TestEndpointsMatchSpecification Runs every GET endpoint against the recorded fixtures, and checks that the
+// output conforms to the specification
+func TestEndpointsMatchSpecification(t *testing.T) {
+	harness.Start(t)
+	importClimate()
+	importFerries()
+	importTolls()
+	useClosures(t)
+	r := handlers()
+	docs := map[string]*openapi.Document{"/rtc/v1": specification(t, r), "/rtc/v2": specificationOf(t, r, "/rtc/v2")}
+
+	tests := []struct {
+		path    string
+		pattern string
+	}{
+		{"/rtc/v1/weather/" + url.PathEscape("gjøvik"), "/weather/{place}"},
+		{"/rtc/v1/weather/" + url.PathEscape("gjøvik") + "?units=imperial", "/weather/{place}"},
+		{"/rtc/v1/weather/" + url.PathEscape("gjøvik") + "/forecast", "/weather/{place}/forecast"},
+		{"/rtc/v1/weather/" + url.PathEscape("gjøvik") + "/forecast?resolution=daily&from=2021-05-12T00:00:00Z", "/weather/{place}/forecast"},
+		{"/rtc/v1/climate/bergen?from=2021-12-20&to=2022-01-05&units=imperial", "/climate/{place}"},
+		{"/rtc/v1/poi/" + url.PathEscape("gjøvik") + "/cafe", "/poi/{place}/{category}"},
+		{"/rtc/v1/diag", "/diag"},
+		{"/rtc/v1/charge/" + url.PathEscape("gjøvik") + "?connector=type2,chademo&power=22", "/charge/{place}"},
+		{"/rtc/v1/petrol/" + url.PathEscape("gjøvik") + "?radius=2000", "/petrol/{place}"},
+		{"/rtc/v1/messages/" + url.PathEscape("gjøvik") + "/lillehammer", "/messages/{start}/{destination}"},
+		{"/rtc/v1/route/" + url.PathEscape("gjøvik") + "/lillehammer", "/route/{start}/{destination}"},
+		{"/rtc/v1/route/" + url.PathEscape("gjøvik") + "/lillehammer?units=imperial", "/route/{start}/{destination}"},
+		{"/rtc/v1/route/" + url.PathEscape("gjøvik") + "/lillehammer?avoid=closures", "/route/{start}/{destination}"},
+		{"/rtc/v1/route/bergen/stavanger", "/route/{start}/{destination}"},
+		{"/rtc/v1/route/bergen/stavanger?vehicle=ev&consumption=15.5", "/route/{start}/{destination}"},
+		{"/rtc/v1/route/" + url.PathEscape("gjøvik") + "/lillehammer/compare?alternatives=1", "/route/{start}/{destination}/compare"},
+		{"/rtc/v1/route/bergen/stavanger/compare?routeTypes=fastest,thrilling&units=imperial", "/route/{start}/{destination}/compare"},
+		{"/rtc/v1/notifyme", "/notifyme"},
+		{"/rtc/v1/openapi.json", "/openapi.json"},
+		{"/rtc/v2/weather/" + url.PathEscape("gjøvik"), "/weather/{place}"},
+		{"/rtc/v2/poi/" + url.PathEscape("gjøvik") + "/cafe", "/poi/{place}/{category}"},
+		{"/rtc/v2/diag", "/diag"},
+		{"/rtc/v2/charge/" + url.PathEscape("gjøvik") + "?connector=type2,chademo&power=22", "/charge/{place}"},
+		{"/rtc/v2/petrol/" + url.PathEscape("gjøvik") + "?radius=2000", "/petrol/{place}"},
+		{"/rtc/v2/messages/" + url.PathEscape("gjøvik") + "/lillehammer", "/messages/{start}/{destination}"},
+		{"/rtc/v2/route/" + url.PathEscape("gjøvik") + "/lillehammer", "/route/{start}/{destination}"},
+		{"/rtc/v2/notifyme", "/notifyme"},
+		{"/rtc/v2/openapi.json", "/openapi.json"},
+	}
+	for _, test := range tests {
+		rec := request(r, http.MethodGet, test.path, nil)
+		if rec.Code != http.StatusOK {
+			t.Errorf("GET %v: expected status Ok; got %v: %v", test.path, rec.Code, rec.Body.String())
+			continue
+		}
+		doc := docs[test.path[:len("/rtc/v1")]]
+		schema, err := doc.ResponseSchema(http.MethodGet, test.pattern)
+		if err != nil {
+			t.Error(err)
+			continue
+		}
+		if err := doc.ValidateJSON(schema, rec.Body.Bytes()); err != nil {
+			t.Errorf("GET %v drifts from the specification: %v", test.path, err)
+		}
+	}
+}
+
+// TestForecastWindow Checks that the forecast only contains the requested resolutions within the time window
+func TestForecastWindow(t *testing.T) {
+	harness.Start(t)
+	r := handlers()
+
+	tests := []struct {
+		query  string
+		hourly int
+		daily  int
+	}{
+		{"", 48, 8},
+		{"?resolution=hourly&from=2021-05-10T10:00:00Z&to=2021-05-10T14:00:00Z", 4, 0},
+		{"?resolution=daily&from=2021-05-11T00:00:00%2B02:00", 0, 7},
+		{"?to=2021-05-10T01:30:00Z", 2, 1},
+	}
+	for _, test := range tests {
+		rec := request(r, http.MethodGet, "/rtc/v1/weather/"+url.PathEscape("gjøvik")+"/forecast"+test.query, nil)
+		if rec.Code != http.StatusOK {
+			t.Errorf("%v: expected status Ok; got %v: %v", test.query, rec.Code, rec.Body.String())
+			continue
+		}
+		var forecast structs.OutputForecast
+		if err := json.Unmarshal(rec.Body.Bytes(), &forecast); err != nil {
+			t.Fatalf("%v: could not unmarshal the forecast: %v", test.query, err)
+		}
+		if len(forecast.Hourly) != test.hourly || len(forecast.Daily) != test.daily {
+			t.Errorf("%v: expected %v hourly and %v daily forecasts; got %v and %v", test.query, test.hourly, test.daily,
+				len(forecast.Hourly), len(forecast.Daily))
+		}
+		for _, entry := range append(forecast.Hourly, forecast.Daily...) {
+			if entry.Weather.Main.Message == "" || entry.Weather.Temp.Message == "" {
+				t.Errorf("%v: forecast for %v is missing the advice", test.query, entry.Time)
+			}
+		}
+	}
+
+	rec := request(r, http.MethodGet, "/rtc/v1/weather/"+url.PathEscape("gjøvik")+"/forecast?from=2021-05-11T00:00:00Z&to=2021-05-10T00:00:00Z", nil)
+	if rec.Code != http.StatusBadRequest {
+		t.Errorf("Expected status Bad Request for a reversed time window; got %v", rec.Code)
+	}
+}
+
+// TestLocalizedWeather Checks that the language is chosen from the lang parameter, or else the Accept-Language header
+func TestLocalizedWeather(t *testing.T) {
+	harness.Start(t)
+	r := handlers()
+
+	tests := []struct {
+		query          string
+		acceptLanguage string
+		locale         string
+		message        string
+	}{
+		{"", "", "en", "It has been snowing lightly"},
+		{"", "nb-NO,nb;q=0.9,en;q=0.8", "nb", "Det har snødd lett"},
+		{"?lang=de", "nb-NO", "de", "In der letzten Stunde hat es leicht geschneit"},
+		{"?lang=no", "", "nb", "Det har snødd lett"},
+	}
+	for _, test := range tests {
+		req := httptest.NewRequest(http.MethodGet, "/rtc/v1/weather/"+url.PathEscape("gjøvik")+test.query, nil)
+		req.Header.Set("Accept-Language", test.acceptLanguage)
+		rec := httptest.NewRecorder()
+		r.ServeHTTP(rec, req)
+		if rec.Code != http.StatusOK {
+			t.Errorf("%v: expected status Ok; got %v: %v", test.query, rec.Code, rec.Body.String())
+			continue
+		}
+		var weather structs.OutputWeather
+		if err := json.Unmarshal(rec.Body.Bytes(), &weather); err != nil {
+			t.Fatalf("%v: could not unmarshal the weather: %v", test.query, err)
+		}
+		if language := rec.Header().Get("Content-Language"); language != test.locale {
+			t.Errorf("%v %v: expected the locale %v; got %v", test.query, test.acceptLanguage, test.locale, language)
+		}
+		if !strings.HasPrefix(weather.Main.Message, test.message) {
+			t.Errorf("%v %v: expected the message to start with %q; got %q", test.query, test.acceptLanguage, test.message, weather.Main.Message)
+		}
+	}
+
+	if rec := request(r, http.MethodGet, "/rtc/v1/weather/"+url.PathEscape("gjøvik")+"?lang=fr", nil); rec.Code != http.StatusBadRequest {
+		t.Errorf("Expected status Bad Request for an unsupported language; got %v", rec.Code)
+	}
+}
+
+// TestUnitsAndTimeZones Checks that the measurements are converted to the requested unit system, and that the times
+// are in the time zone of the location
+func TestUnitsAndTimeZones(t *testing.T) {
+	harness.Start(t)
+	r := handlers()
+
+	var weather structs.OutputWeather
+	rec := request(r, http.MethodGet, "/rtc/v1/weather/"+url.PathEscape("gjøvik")+"?units=imperial", nil)
+	if err := json.Unmarshal(rec.Body.Bytes(), &weather); err != nil {
+		t.Fatalf("Could not unmarshal the weather: %v: %v", err, rec.Body.String())
+	}
+	if weather.Units.System != "imperial" || weather.Temp.Temp != 28.4 || weather.Visibility.Visibility != 13123 {
+		t.Errorf("Expected 28.4 °F and 13123 ft in imperial units; got %v %v and %v %v", weather.Temp.Temp,
+			weather.Units.Temperature, weather.Visibility.Visibility, weather.Units.Visibility)
+	}
+	if !strings.Contains(weather.Temp.Message, "28.4 degrees Fahrenheit") {
+		t.Errorf("Expected the temperature message in Fahrenheit; got %q", weather.Temp.Message)
+	}
+	if weather.Timezone != "UTC+02:00" || weather.Sunrise.Local.Format(time.RFC3339) != "2021-05-01T05:10:24+02:00" {
+		t.Errorf("Expected the sunrise at 05:10:24 in UTC+02:00; got %v in %v", weather.Sunrise.Local, weather.Timezone)
+	}
+
+	tests := []struct {
+		query  string
+		length float64
+		unit   string
+	}{
+		{"", 44.567, "km"},
+		{"?units=imperial", 44567 / 1609.344, "mi"},
+	}
+	for _, test := range tests {
+		var route structs.RoadInformation
+		rec = request(r, http.MethodGet, "/rtc/v1/route/"+url.PathEscape("gjøvik")+"/lillehammer"+test.query, nil)
+		if err := json.Unmarshal(rec.Body.Bytes(), &route); err != nil {
+			t.Fatalf("%v: could not unmarshal the route: %v: %v", test.query, err, rec.Body.String())
+		}
+		if math.Abs(route.Length-test.length) > 1e-9 || route.DistanceUnit != test.unit {
+			t.Errorf("%v: expected the length %v %v; got %v %v", test.query, test.length, test.unit, route.Length, route.DistanceUnit)
+		}
+		if route.EstimatedArrival != "2021-05-10T08:48:32+02:00" {
+			t.Errorf("%v: expected the arrival in the time zone of the destination; got %v", test.query, route.EstimatedArrival)
+		}
+	}
+
+	var forecast structs.OutputForecast
+	rec = request(r, http.MethodGet, "/rtc/v1/weather/"+url.PathEscape("gjøvik")+"/forecast?resolution=hourly", nil)
+	if err := json.Unmarshal(rec.Body.Bytes(), &forecast); err != nil {
+		t.Fatalf("Could not unmarshal the forecast: %v: %v", err, rec.Body.String())
+	}
+	if len(forecast.Hourly) == 0 {
+		t.Fatalf("Expected hourly forecasts; got %v", rec.Body.String())
+	}
+	if start := forecast.Hourly[0].Time.Format(time.RFC3339); forecast.Timezone != "Europe/Oslo" || start != "2021-05-10T02:00:00+02:00" {
+		t.Errorf("Expected the forecast to start at 02:00 in Europe/Oslo; got %v in %v", start, forecast.Timezone)
+	}
+}
+
+// TestClimate Checks the climate statistics from the imported climate normals of the nearest station
+func TestClimate(t *testing.T) {
+	harness.Start(t)
+	r := handlers()
+	if stations, err := database.ImportClimateFile(database.ClimateFile); err != nil || stations == 0 {
+		t.Fatalf("Could not import the climate normals: %v", err)
+	}
+
+	tests := []struct {
+		place    string
+		query    string
+		station  string
+		main     string
+		freezing string
+	}{
+		{"bergen", "?from=2021-01-10&to=2021-01-16", "SN50540", "Rain", endpoints.FreezingPossible},
+		{url.PathEscape("gjøvik"), "?from=2021-04-01&to=2021-04-10", "SN12550", "Clear", endpoints.FreezingPossible},
+		{"oslo", "?from=2021-07-01", "SN18700", "Clouds", endpoints.FreezingNone},
+	}
+	for _, test := range tests {
+		rec := request(r, http.MethodGet, "/rtc/v1/climate/"+test.place+test.query, nil)
+		if rec.Code != http.StatusOK {
+			t.Errorf("%v%v: expected status Ok; got %v: %v", test.place, test.query, rec.Code, rec.Body.String())
+			continue
+		}
+		var climate structs.OutputClimate
+		if err := json.Unmarshal(rec.Body.Bytes(), &climate); err != nil {
+			t.Fatalf("%v: could not unmarshal the climate: %v", test.place, err)
+		}
+		if climate.Station.ID != test.station || climate.Station.Distance > 100 {
+			t.Errorf("%v: expected the station %v nearby; got %v %v km away", test.place, test.station, climate.Station.ID,
+				climate.Station.Distance)
+		}
+		if climate.Weather.Main.Main != test.main || climate.Weather.Conditions.FreezingRisk != test.freezing {
+			t.Errorf("%v%v: expected %v with freezing risk %v; got %v with %v", test.place, test.query, test.main, test.freezing,
+				climate.Weather.Main.Main, climate.Weather.Conditions.FreezingRisk)
+		}
+	}
+
+	for _, query := range []string{"", "?from=2021-04-10&to=2021-04-01", "?from=2021-01-01&to=2022-06-01", "?from=01.04.2021"} {
+		if rec := request(r, http.MethodGet, "/rtc/v1/climate/oslo"+query, nil); rec.Code != http.StatusBadRequest {
+			t.Errorf("%v: expected status Bad Request; got %v", query, rec.Code)
+		}
+	}
+}
+
+// TestRouteClosures Checks that the closures on the route are flagged, and that the route avoids those asked for
+func TestRouteClosures(t *testing.T) {
+	harness.Start(t)
+	useClosures(t)
+	r := handlers()
+
+	tests := []struct {
+		query   string
+		length  float64
+		avoided map[string]bool
+	}{
+		{"", 44.567, map[string]bool{"NPRA_HBT_0101_1": false, "NPRA_HBT_0606_1": false}},
+		{"?avoid=closures", 51.234, map[string]bool{"NPRA_HBT_0101_1": true, "NPRA_HBT_0606_1": false}},
+		{"?avoid=convoys", 51.234, map[string]bool{"NPRA_HBT_0101_1": true, "NPRA_HBT_0606_1": false}},
+	}
+	for _, test := range tests {
+		var route structs.RoadInformation
+		rec := request(r, http.MethodGet, "/rtc/v1/route/"+url.PathEscape("gjøvik")+"/lillehammer"+test.query, nil)
+		if err := json.Unmarshal(rec.Body.Bytes(), &route); err != nil {
+			t.Fatalf("%v: could not unmarshal the route: %v: %v", test.query, err, rec.Body.String())
+		}
+		if route.Length != test.length {
+			t.Errorf("%v: expected the length %v; got %v", test.query, test.length, route.Length)
+		}
+		avoided := map[string]bool{}
+		for _, closure := range route.Closures {
+			avoided[closure.ID] = closure.Avoided
+		}
+		if !reflect.DeepEqual(avoided, test.avoided) {
+			t.Errorf("%v: expected the closures %v; got %v", test.query, test.avoided, avoided)
+		}
+	}
+
+	if rec := request(r, http.MethodGet, "/rtc/v1/route/"+url.PathEscape("gjøvik")+"/lillehammer?avoid=bridges", nil); rec.Code != http.StatusBadRequest {
+		t.Errorf("Expected status Bad Request for an unknown kind of road to avoid; got %v", rec.Code)
+	}
+}
+
+// TestRouteFerries Checks that the ferries on a route are matched to the imported timetable, and that the wait for
+// them is added to the estimated arrival
+func TestRouteFerries(t *testing.T) {
+	harness.Start(t)
+	if routes, err := database.ImportTimetableFile(database.FerryTimetable); err != nil || routes != 2 {
+		t.Fatalf("Could not import the ferry timetable: %v %v", routes, err)
+	}
+	r := handlers()
+
+	var route structs.RoadInformation
+	rec := request(r, http.MethodGet, "/rtc/v1/route/bergen/stavanger", nil)
+	if err := json.Unmarshal(rec.Body.Bytes(), &route); err != nil {
+		t.Fatalf("Could not unmarshal the route: %v: %v", err, rec.Body.String())
+	}
+	if len(route.Ferries) != 2 {
+		t.Fatalf("Expected two ferries on the route; got %v", route.Ferries)
+	}
+
+	quays := [][]string{{"Halhjem ferjekai", "Sandvikvåg ferjekai"}, {"Arsvågen ferjekai", "Mortavika ferjekai"}}
+	wait := 0
+	for i, ferry := range route.Ferries {
+		if ferry.From != quays[i][0] || ferry.To != quays[i][1] {
+			t.Errorf("Expected the ferry from %v to %v; got %v to %v", quays[i][0], quays[i][1], ferry.From, ferry.To)
+		}
+		if len(ferry.Departures) != 3 {
+			t.Fatalf("Expected three departures from %v; got %v", ferry.From, ferry.Departures)
+		}
+		quayArrival, _ := time.Parse(time.RFC3339, ferry.QuayArrival)
+		first, _ := time.Parse(time.RFC3339, ferry.Departures[0].Departure)
+		if first.Before(quayArrival) || int(first.Sub(quayArrival).Minutes()) != ferry.Wait || ferry.Wait > 60 {
+			t.Errorf("Expected the wait at %v to be the time until the first departure; got %v minutes from %v to %v",
+				ferry.From, ferry.Wait, ferry.QuayArrival, ferry.Departures[0].Departure)
+		}
+		wait += ferry.Wait
+	}
+
+	// The recorded route arrives at 11:32 without waiting, the wait is counted in seconds
+	arrival, _ := time.Parse(time.RFC3339, route.EstimatedArrival)
+	withoutWait := time.Date(2021, 5, 10, 11, 32, 0, 0, time.FixedZone("", 2*3600))
+	if delay := int(arrival.Sub(withoutWait).Minutes()); delay < wait || delay > wait+len(route.Ferries) {
+		t.Errorf("Expected the arrival to be delayed by the wait of %v minutes; got %v", wait, route.EstimatedArrival)
+	}
+
+	rec = request(r, http.MethodGet, "/rtc/v1/route/"+url.PathEscape("gjøvik")+"/lillehammer", nil)
+	if err := json.Unmarshal(rec.Body.Bytes(), &route); err != nil || len(route.Ferries) != 0 {
+		t.Errorf("Expected no ferries between Gjøvik and Lillehammer; got %v %v", route.Ferries, err)
+	}
+}
+
+// TestRouteCost Checks the tolls, ferry fares and energy of routes for the vehicle classes
+func TestRouteCost(t *testing.T) {
+	harness.Start(t)
+	importFerries()
+	if points, err := database.ImportTollsFile(database.TollFile); err != nil || points == 0 {
+		t.Fatalf("Could not import the toll points: %v %v", points, err)
+	}
+	useClosures(t)
+	r := handlers()
+
+	tests := []struct {
+		path    string
+		tolls   []string
+		ferries float64
+		energy  float64
+		total   float64
+	}{
+		{url.PathEscape("gjøvik") + "/lillehammer", []string{"Hunndalen", "Biri", "Vingrom"}, 0, 2.9, 159.35},
+		{url.PathEscape("gjøvik") + "/lillehammer?vehicle=ev", []string{"Hunndalen", "Biri", "Vingrom"}, 0, 8, 85},
+		{url.PathEscape("gjøvik") + "/lillehammer?vehicle=car&consumption=5", []string{"Hunndalen", "Biri", "Vingrom"}, 0, 2.2, 144.3},
+		{url.PathEscape("gjøvik") + "/lillehammer?vehicle=trailer&avoid=closures", []string{"Hunndalen", "Vingrom"}, 0, 4.9, 235.35},
+		// No energy is used on the 51 km of ferry crossings between Bergen and Stavanger
+		{"bergen/stavanger", []string{"Svegatjørn", "Sunnhordland", "Nord-Jæren"}, 452, 8.3, 773.45},
+		{"bergen/stavanger?vehicle=trailer", []string{"Svegatjørn", "Sunnhordland", "Nord-Jæren"}, 904, 12.2, 1452.3},
+	}
+	for _, test := range tests {
+		var route structs.RoadInformation
+		rec := request(r, http.MethodGet, "/rtc/v1/route/"+test.path, nil)
+		if err := json.Unmarshal(rec.Body.Bytes(), &route); err != nil {
+			t.Fatalf("%v: could not unmarshal the route: %v: %v", test.path, err, rec.Body.String())
+		}
+		tolls, ferries := []string{}, 0.0
+		for _, toll := range route.Cost.Tolls {
+			tolls = append(tolls, toll.Name)
+		}
+		for _, ferry := range route.Cost.Ferries {
+			ferries += ferry.Cost
+		}
+		if !reflect.DeepEqual(tolls, test.tolls) || ferries != test.ferries {
+			t.Errorf("%v: expected the tolls %v and ferries for %v; got %v and %v", test.path, test.tolls, test.ferries, tolls, ferries)
+		}
+		if route.Cost.Energy.Amount != test.energy || route.Cost.Total != test.total || route.Cost.Currency != "NOK" {
+			t.Errorf("%v: expected %v of energy and a total of %v; got %v and %v %v", test.path, test.energy, test.total,
+				route.Cost.Energy.Amount, route.Cost.Total, route.Cost.Currency)
+		}
+	}
+
+	if rec := request(r, http.MethodGet, "/rtc/v1/route/bergen/stavanger?vehicle=bus", nil); rec.Code != http.StatusBadRequest {
+		t.Errorf("Expected status Bad Request for an unknown vehicle class; got %v", rec.Code)
+	}
+}
+
+// requested Checks if a fake service has received a request containing all the parts
+func requested(h *harness.Harness, service string, parts ...string) bool {
+	for _, uri := range h.Requests(service) {
+		found := true
+		for _, part := range parts {
+			if !strings.Contains(uri, part) {
+				found = false
+			}
+		}
+		if found {
+			return true
+		}
+	}
+	return false
+}
+
+// TestVehicleProfiles Stores, changes and deletes a vehicle profile, and checks that routes, searches and webhooks
+// use it
+func TestVehicleProfiles(t *testing.T) {
+	h := harness.Start(t)
+	importTolls()
+	r := handlers()
+	doc := specification(t, r)
+
+	for _, invalid := range []map[string]interface{}{
+		{"name": "Tractor", "fuel": "coal"},
+		{"name": "Van", "fuel": "diesel", "connectors": []string{"type2"}},
+		{"name": "EV", "fuel": "electric", "connectors": []string{"type9"}},
+		{"name": "Truck", "fuel": "diesel", "height": 7},
+	} {
+		if rec := request(r, http.MethodPost, "/rtc/v1/profiles", invalid); rec.Code != http.StatusBadRequest {
+			t.Errorf("%v: expected status Bad Request; got %v", invalid, rec.Code)
+		}
+	}
+
+	rec := request(r, http.MethodPost, "/rtc/v1/profiles", map[string]interface{}{
+		"name": "Family EV", "fuel": "electric", "length": 4.7, "width": 1.9, "height": 1.6, "weight": 2100,
+		"connectors": []string{"type2", "IEC62196Type2CCS"}, "consumption": 16, "range": 450,
+	})
+	var profile structs.VehicleProfile
+	if rec.Code != http.StatusCreated || json.Unmarshal(rec.Body.Bytes(), &profile) != nil || profile.ID == "" {
+		t.Fatalf("Expected the profile to be created; got %v: %v", rec.Code, rec.Body.String())
+	}
+	if !reflect.DeepEqual(profile.Connectors, []string{"IEC62196Type2Outlet", "IEC62196Type2CCS"}) {
+		t.Errorf("Expected the connectors to be named as by TomTom; got %v", profile.Connectors)
+	}
+	for _, path := range []string{"/profiles/{id}", "/profiles"} {
+		rec = request(r, http.MethodGet, "/rtc/v1"+strings.Replace(path, "{id}", profile.ID, 1), nil)
+		schema, _ := doc.ResponseSchema(http.MethodGet, path)
+		if rec.Code != http.StatusOK {
+			t.Errorf("GET %v: expected status Ok; got %v", path, rec.Code)
+		} else if err := doc.ValidateJSON(schema, rec.Body.Bytes()); err != nil {
+			t.Errorf("GET %v drifts from the specification: %v", path, err)
+		}
+	}
+
+	// The route is restricted to the electric vehicle, and costs electricity at its consumption
+	var route structs.RoadInformation
+	rec = request(r, http.MethodGet, "/rtc/v1/route/"+url.PathEscape("gjøvik")+"/lillehammer?profile="+profile.ID, nil)
+	if err := json.Unmarshal(rec.Body.Bytes(), &route); err != nil {
+		t.Fatalf("Could not unmarshal the route: %v: %v", err, rec.Body.String())
+	}
+	if route.Cost.Vehicle != "ev" || route.Cost.Energy.Consumption != 16 || route.Cost.Energy.Unit != "kWh" {
+		t.Errorf("Expected the cost of the electric vehicle; got %+v", route.Cost)
+	}
+	if !requested(h, harness.TomTom, "/routing/1/calculateRoute/", "vehicleLength=4.7", "vehicleWeight=2100", "vehicleEngineType=electric") {
+		t.Errorf("Expected the route to be restricted to the vehicle; got %v", h.Requests(harness.TomTom))
+	}
+	if rec = request(r, http.MethodGet, "/rtc/v1/charge/"+url.PathEscape("gjøvik")+"?profile="+profile.ID, nil); rec.Code != http.StatusOK ||
+		!requested(h, harness.TomTom, "categorySet=7309", "connectorSet=IEC62196Type2Outlet,IEC62196Type2CCS") {
+		t.Errorf("Expected the charging stations to be searched for with the connectors of the vehicle; got %v", rec.Code)
+	}
+	if rec = request(r, http.MethodGet, "/rtc/v1/petrol/"+url.PathEscape("gjøvik")+"?profile="+profile.ID, nil); rec.Code != http.StatusBadRequest {
+		t.Errorf("Expected status Bad Request for petrol stations for an electric vehicle; got %v", rec.Code)
+	}
+
+	// A diesel car with a caravan pays the trailer prices, and fills diesel
+	rec = request(r, http.MethodPut, "/rtc/v1/profiles/"+profile.ID, map[string]interface{}{
+		"name": "Caravan", "fuel": "diesel", "length": 12.5, "weight": 3400, "trailer": true,
+	})
+	if rec.Code != http.StatusOK {
+		t.Fatalf("Expected the profile to be replaced; got %v: %v", rec.Code, rec.Body.String())
+	}
+	rec = request(r, http.MethodGet, "/rtc/v1/route/"+url.PathEscape("gjøvik")+"/lillehammer?profile="+profile.ID, nil)
+	if err := json.Unmarshal(rec.Body.Bytes(), &route); err != nil || route.Cost.Vehicle != "trailer" || route.Cost.Energy.Consumption != 9.5 {
+		t.Errorf("Expected the cost of a car with a trailer at the usual consumption; got %+v %v", route.Cost, err)
+	}
+	rec = request(r, http.MethodGet, "/rtc/v1/route/"+url.PathEscape("gjøvik")+"/lillehammer?profile="+profile.ID+"&vehicle=car", nil)
+	if err := json.Unmarshal(rec.Body.Bytes(), &route); err != nil || route.Cost.Vehicle != "car" {
+		t.Errorf("Expected the vehicle parameter to take precedence over the profile; got %+v %v", route.Cost, err)
+	}
+	if rec = request(r, http.MethodGet, "/rtc/v1/petrol/"+url.PathEscape("gjøvik")+"?profile="+profile.ID, nil); rec.Code != http.StatusOK ||
+		!requested(h, harness.TomTom, "categorySet=7311", "fuelSet=Diesel") {
+		t.Errorf("Expected the petrol stations to be searched for with the fuel of the vehicle; got %v", rec.Code)
+	}
+
+	// Webhooks can refer to the profile
+	rec = request(r, http.MethodPost, "/rtc/v1/notifyme", map[string]interface{}{
+		"url":                h.WebhookURL,
+		"DepartureLocation":  "gjøvik",
+		"ArrivalDestination": "lillehammer",
+		"ArrivalTime":        time.Now().Add(48 * time.Hour).UTC().Format(time.RFC822),
+		"profile":            profile.ID,
+	})
+	if rec.Code != http.StatusCreated {
+		t.Fatalf("Expected the webhook to be registered; got %v: %v", rec.Code, rec.Body.String())
+	}
+	id := strings.TrimSpace(strings.Split(rec.Body.String(), ":")[1])
+	var webhook structs.Webhook
+	rec = request(r, http.MethodGet, "/rtc/v1/notifyme/"+id, nil)
+	if err := json.Unmarshal(rec.Body.Bytes(), &webhook); err != nil || webhook.Profile != profile.ID {
+		t.Errorf("Expected the webhook to refer to the profile; got %v %v", webhook.Profile, err)
+	}
+	if !requested(h, harness.TomTom, "/routing/1/calculateRoute/", "vehicleLength=12.5", "vehicleEngineType=combustion") {
+		t.Errorf("Expected the departure to be calculated for the vehicle; got %v", h.Requests(harness.TomTom))
+	}
+
+	if rec = request(r, http.MethodDelete, "/rtc/v1/profiles/"+profile.ID, nil); rec.Code != http.StatusOK {
+		t.Errorf("Expected the profile to be deleted; got %v", rec.Code)
+	}
+	if rec = request(r, http.MethodGet, "/rtc/v1/profiles/"+profile.ID, nil); rec.Code != http.StatusNotFound {
+		t.Errorf("Expected status Not Found for a deleted profile; got %v", rec.Code)
+	}
+	if rec = request(r, http.MethodGet, "/rtc/v1/route/"+url.PathEscape("gjøvik")+"/lillehammer?profile="+profile.ID, nil); rec.Code != http.StatusBadRequest {
+		t.Errorf("Expected status Bad Request for a route with an unknown profile; got %v", rec.Code)
+	}
+}
+
+// TestRoutePreferences Checks that the route preferences are validated and passed on to TomTom, and that the route
+// departs and arrives at the times asked for
+func TestRoutePreferences(t *testing.T) {
+	h := harness.Start(t)
+	r := handlers()
+	route := "/rtc/v1/route/" + url.PathEscape("gjøvik") + "/lillehammer"
+
+	var information structs.RoadInformation
+	if rec := request(r, http.MethodGet, route, nil); rec.Code != http.StatusOK ||
+		!requested(h, harness.TomTom, "/routing/1/calculateRoute/", "avoid=unpavedRoads", "routeType=fastest", "traffic=false") {
+		t.Errorf("Expected the fastest route avoiding unpaved roads without traffic by default; got %v %v", rec.Code, h.Requests(harness.TomTom))
+	}
+
+	// The recorded route takes 2912 seconds
+	at := time.Now().Add(2 * time.Hour).UTC().Truncate(time.Second)
+	travel := 2912 * time.Second
+	rec := request(r, http.MethodGet, route+"?avoid=tolls,motorways&routeType=eco&traffic=true&departAt="+url.QueryEscape(at.Format(time.RFC3339)), nil)
+	if err := json.Unmarshal(rec.Body.Bytes(), &information); err != nil {
+		t.Fatalf("Could not unmarshal the route: %v: %v", err, rec.Body.String())
+	}
+	departure, _ := time.Parse(time.RFC3339, information.Departure)
+	arrival, _ := time.Parse(time.RFC3339, information.EstimatedArrival)
+	if !departure.Equal(at) || !arrival.Equal(at.Add(travel)) {
+		t.Errorf("Expected the route to depart at %v; got %v to %v", at, information.Departure, information.EstimatedArrival)
+	}
+	if !requested(h, harness.TomTom, "avoid=tollRoads&avoid=motorways&routeType=eco&traffic=true&departAt="+url.QueryEscape(at.Format(time.RFC3339))) ||
+		requested(h, harness.TomTom, "avoid=tollRoads&avoid=motorways&avoid=unpavedRoads") {
+		t.Errorf("Expected the preferences to be passed on to TomTom; got %v", h.Requests(harness.TomTom))
+	}
+
+	rec = request(r, http.MethodGet, route+"?arriveAt="+url.QueryEscape(at.Format(time.RFC3339)), nil)
+	if err := json.Unmarshal(rec.Body.Bytes(), &information); err != nil {
+		t.Fatalf("Could not unmarshal the route: %v: %v", err, rec.Body.String())
+	}
+	departure, _ = time.Parse(time.RFC3339, information.Departure)
+	arrival, _ = time.Parse(time.RFC3339, information.EstimatedArrival)
+	if !departure.Equal(at.Add(-travel)) || !arrival.Equal(at) || !requested(h, harness.TomTom, "arriveAt=") {
+		t.Errorf("Expected the route to arrive by %v; got %v to %v", at, information.Departure, information.EstimatedArrival)
+	}
+
+	past := time.Now().Add(-time.Hour).UTC().Format(time.RFC3339)
+	for _, query := range []string{
+		"?routeType=scenic",
+		"?traffic=sometimes",
+		"?departAt=" + url.QueryEscape(past),
+		"?departAt=" + url.QueryEscape(at.Format(time.RFC3339)) + "&arriveAt=" + url.QueryEscape(at.Format(time.RFC3339)),
+	} {
+		if rec := request(r, http.MethodGet, route+query, nil); rec.Code != http.StatusBadRequest {
+			t.Errorf("%v: expected status Bad Request; got %v", query, rec.Code)
+		}
+	}
+
+	// Webhooks are routed with their preferences in the traffic, to arrive at their arrival time
+	rec = request(r, http.MethodPost, "/rtc/v1/notifyme", map[string]interface{}{
+		"url":                h.WebhookURL,
+		"DepartureLocation":  "gjøvik",
+		"ArrivalDestination": "lillehammer",
+		"ArrivalTime":        time.Now().Add(48 * time.Hour).UTC().Format(time.RFC822),
+		"preferences":        map[string]interface{}{"avoid": []string{"Ferries"}, "routeType": "shortest"},
+	})
+	if rec.Code != http.StatusCreated {
+		t.Fatalf("Expected the webhook to be registered; got %v: %v", rec.Code, rec.Body.String())
+	}
+	id := strings.TrimSpace(strings.Split(rec.Body.String(), ":")[1])
+	var webhook structs.Webhook
+	rec = request(r, http.MethodGet, "/rtc/v1/notifyme/"+id, nil)
+	if err := json.Unmarshal(rec.Body.Bytes(), &webhook); err != nil || webhook.Preferences == nil ||
+		!reflect.DeepEqual(webhook.Preferences.Avoid, []string{"ferries"}) || webhook.Preferences.RouteType != "shortest" {
+		t.Errorf("Expected the webhook to have the preferences; got %+v %v", webhook.Preferences, err)
+	}
+	if !requested(h, harness.TomTom, "avoid=ferries&routeType=shortest&traffic=true&arriveAt=") {
+		t.Errorf("Expected the departure to be calculated with the preferences; got %v", h.Requests(harness.TomTom))
+	}
+	rec = request(r, http.MethodPost, "/rtc/v1/notifyme", map[string]interface{}{
+		"url":                h.WebhookURL,
+		"DepartureLocation":  "gjøvik",
+		"ArrivalDestination": "lillehammer",
+		"ArrivalTime":        time.Now().Add(48 * time.Hour).UTC().Format(time.RFC822),
+		"preferences":        map[string]interface{}{"departAt": at.Format(time.RFC3339)},
+	})
+	if rec.Code == http.StatusCreated {
+		t.Errorf("Expected a webhook with a departure time in its preferences to be rejected")
+	}
+}
+
+// TestCompareRoutes Checks that the routes of the route types and their alternatives are compared side by side
+func TestCompareRoutes(t *testing.T) {
+	h := harness.Start(t)
+	importTolls()
+	r := handlers()
+
+	var alternatives []structs.RouteAlternative
+	rec := request(r, http.MethodGet, "/rtc/v1/route/"+url.PathEscape("gjøvik")+"/lillehammer/compare?alternatives=1&avoid=tolls", nil)
+	if err := json.Unmarshal(rec.Body.Bytes(), &alternatives); err != nil {
+		t.Fatalf("Could not unmarshal the comparison: %v: %v", err, rec.Body.String())
+	}
+
+	// The recorded shortest route has one alternative
+	expected := []struct {
+		routeType   string
+		alternative int
+		length      float64
+		travelTime  int
+	}{
+		{"fastest", 0, 44.567, 48},
+		{"shortest", 0, 42.98, 51},
+		{"shortest", 1, 51.234, 56},
+		{"eco", 0, 44.567, 48},
+	}
+	if len(alternatives) != len(expected) {
+		t.Fatalf("Expected %v routes; got %+v", len(expected), alternatives)
+	}
+	for i, want := range expected {
+		got := alternatives[i]
+		if got.RouteType != want.routeType || got.Alternative != want.alternative || got.Length != want.length ||
+			got.TravelTime != want.travelTime {
+			t.Errorf("Expected route %v to be %+v; got %+v", i, want, got)
+		}
+		if got.Cost <= 0 || got.Currency != "NOK" || got.DistanceUnit != "km" {
+			t.Errorf("Expected route %v to have a cost; got %+v", i, got)
+		}
+	}
+	for _, routeType := range []string{"fastest", "shortest", "eco"} {
+		if !requested(h, harness.TomTom, "avoid=tollRoads&routeType="+routeType+"&traffic=false&maxAlternatives=1") {
+			t.Errorf("Expected the %v route and its alternative to be requested; got %v", routeType, h.Requests(harness.TomTom))
+		}
+	}
+
+	if rec := request(r, http.MethodGet, "/rtc/v1/route/"+url.PathEscape("gjøvik")+"/lillehammer/compare?alternatives=4", nil); rec.Code != http.StatusBadRequest {
+		t.Errorf("Expected status Bad Request for too many alternatives; got %v", rec.Code)
+	}
+}
+
+// TestTrafficMessages Lists the incidents on the route in the order they are passed. The recorded roadworks are planned
+// for 2098, the closure is active and the accident ended in 2021
+func TestTrafficMessages(t *testing.T) {
+	h := harness.Start(t)
+	r := handlers()
+	messages := func(query string) []structs.OutIncident {
+		var incidents []structs.OutIncident
+		rec := request(r, http.MethodGet, "/rtc/v1/messages/"+url.PathEscape("gjøvik")+"/lillehammer"+query, nil)
+		if err := json.Unmarshal(rec.Body.Bytes(), &incidents); err != nil {
+			t.Fatalf("%v: could not unmarshal the incidents: %v: %v", query, err, rec.Body.String())
+		}
+		return incidents
+	}
+
+	incidents := messages("?radius=50")
+	if len(incidents) != 2 || incidents[0].From != "Hunndalen" || incidents[1].From != "Biri" {
+		t.Fatalf("Expected the roadworks at Hunndalen and then the closure at Biri; got %+v", incidents)
+	}
+	for i, incident := range incidents {
+		passage, err := time.Parse(time.RFC3339, incident.Passage)
+		if err != nil || incident.Distance <= 0 || incident.Distance >= 44.6 || incident.DistanceUnit != "km" ||
+			incident.Deviation > 50 || (i > 0 && incident.Distance <= incidents[i-1].Distance) ||
+			passage.Before(time.Now().Add(-time.Minute)) {
+			t.Errorf("Expected incident %v along the route, passed from now on; got %+v", i, incident)
+		}
+	}
+
+	// The roadworks delay traffic 95 seconds, the closure has no delay and blocks the road while it is passed
+	roadworks, closure := incidents[0], incidents[1]
+	if roadworks.Delay != 1 || roadworks.ReportedDelay != 95 || roadworks.Category != "roadWorks" || roadworks.Severity != "minor" ||
+		roadworks.Length != 512 || roadworks.Active || roadworks.DuringPassage || roadworks.Aci != nil || len(roadworks.Geometry) != 1 {
+		t.Errorf("Expected the planned roadworks with a minor delay; got %+v", roadworks)
+	}
+	if closure.Delay != 0 || closure.IconCategory != 8 || closure.Category != "roadClosed" || closure.Severity != "undefined" ||
+		!reflect.DeepEqual(closure.RoadNumbers, []string{"E6"}) || !closure.Active || !closure.DuringPassage ||
+		closure.Aci == nil || closure.Aci.NumberOfReports != 3 || len(closure.Geometry) != 2 || closure.Geometry[0].Latitude != 61.01983 {
+		t.Errorf("Expected the active closure on E6 reported 3 times; got %+v", closure)
+	}
+
+	// The incidents are asked for around the route planned by TomTom
+	if len(h.Requests(harness.OpenRouteService)) != 0 ||
+		!requested(h, harness.TomTom, "/traffic/services/5/incidentDetails", "bbox=10.465267%2C60.794850%2C10.692633%2C61.115750") {
+		t.Errorf("Expected the incidents in the area of the route; got %v", h.Requests(harness.TomTom))
+	}
+
+	filters := []struct {
+		query    string
+		expected []string
+	}{
+		{"?status=active", []string{"Biri"}},
+		{"?status=planned", []string{"Hunndalen"}},
+		{"?category=roadWorks,accident", []string{"Hunndalen"}},
+		{"?road=e%206", []string{"Biri"}},
+		{"?road=4&road=E6", []string{"Hunndalen", "Biri"}},
+		// Closures are listed whatever the severity
+		{"?severity=moderate", []string{"Biri"}},
+		{"?severity=minor&units=imperial", []string{"Hunndalen", "Biri"}},
+	}
+	for _, filter := range filters {
+		var from []string
+		for _, incident := range messages(filter.query) {
+			from = append(from, incident.From)
+		}
+		if !reflect.DeepEqual(from, filter.expected) {
+			t.Errorf("%v: expected the incidents from %v; got %v", filter.query, filter.expected, from)
+		}
+	}
+	if incidents = messages("?units=imperial"); incidents[0].Length != 1680 || incidents[0].DistanceUnit != "mi" {
+		t.Errorf("Expected the length of the roadworks in feet; got %+v", incidents[0])
+	}
+
+	for _, query := range []string{"?radius=5", "?severity=extreme", "?status=ended", "?category=meteor"} {
+		if rec := request(r, http.MethodGet, "/rtc/v1/messages/"+url.PathEscape("gjøvik")+"/lillehammer"+query, nil); rec.Code != http.StatusBadRequest {
+			t.Errorf("%v: expected status Bad Request; got %v", query, rec.Code)
+		}
+	}
+}
+
+// TestDeparturePlanner Plans when to depart to arrive in time, from the travel times predicted around the departure
+func TestDeparturePlanner(t *testing.T) {
+	h := harness.Start(t)
+	r := handlers()
+	doc := specification(t, r)
+	arriveBy := time.Now().Add(48 * time.Hour).UTC().Truncate(time.Minute)
+
+	rec := request(r, http.MethodGet, "/rtc/v1/route/"+url.PathEscape("gjøvik")+"/lillehammer/departure?candidates=3&interval=10&arriveBy="+
+		url.QueryEscape(arriveBy.Format(time.RFC3339)), nil)
+	schema, _ := doc.ResponseSchema(http.MethodGet, "/route/{start}/{destination}/departure")
+	if rec.Code != http.StatusOK {
+		t.Fatalf("Expected status Ok; got %v: %v", rec.Code, rec.Body.String())
+	} else if err := doc.ValidateJSON(schema, rec.Body.Bytes()); err != nil {
+		t.Errorf("GET /route/{start}/{destination}/departure drifts from the specification: %v", err)
+	}
+	var plan structs.DeparturePlan
+	if err := json.Unmarshal(rec.Body.Bytes(), &plan); err != nil || len(plan.Candidates) != 3 {
+		t.Fatalf("Expected three candidate departures; got %v %v", rec.Body.String(), err)
+	}
+
+	// The recorded route takes 2912 seconds at every departure, the weather of the recorded forecast has passed, so
+	// the uncertainty is 5% and 10% of the travel time
+	departure := arriveBy.Add(-2912 * time.Second)
+	if plan.Departure != departure.Format(time.RFC3339) || plan.TravelTime != 48 || plan.Uncertainty != 7 ||
+		plan.Earliest != departure.Add(-437*time.Second).Format(time.RFC3339) {
+		t.Errorf("Expected to depart 2912 seconds before the arrival, give or take 7 minutes; got %+v", plan)
+	}
+	for i, candidate := range plan.Candidates {
+		at := departure.Add(time.Duration(i-1) * 10 * time.Minute)
+		if candidate.Departure != at.Format(time.RFC3339) || candidate.Recommended != (i == 1) || candidate.Forecast ||
+			candidate.WeatherDelay != 0 {
+			t.Errorf("Expected candidate %v to depart at %v; got %+v", i, at, candidate)
+		}
+		if !requested(h, harness.TomTom, "traffic=true&departAt="+url.QueryEscape(at.Format(time.RFC3339))) {
+			t.Errorf("Expected the travel time in the traffic to be predicted at %v; got %v", at, h.Requests(harness.TomTom))
+		}
+	}
+	if !requested(h, harness.TomTom, "traffic=true&arriveAt="+url.QueryEscape(arriveBy.Format(time.RFC3339))) {
+		t.Errorf("Expected the departure arriving in time to be planned in the traffic; got %v", h.Requests(harness.TomTom))
+	}
+
+	for _, query := range []string{"", "?arriveBy=2021-05-17T12:00:00Z", "?candidates=10&arriveBy=" + url.QueryEscape(arriveBy.Format(time.RFC3339))} {
+		if rec := request(r, http.MethodGet, "/rtc/v1/route/"+url.PathEscape("gjøvik")+"/lillehammer/departure"+query, nil); rec.Code != http.StatusBadRequest {
+			t.Errorf("%v: expected status Bad Request; got %v", query, rec.Code)
+		}
+	}
+
+	// Trips are planned to arrive by their arrival, those without one have no departure to plan
+	rec = request(r, http.MethodPost, "/rtc/v1/trips", map[string]interface{}{"name": "Later", "stops": []string{"gjøvik", "lillehammer"}})
+	var trip structs.Trip
+	if err := json.Unmarshal(rec.Body.Bytes(), &trip); err != nil {
+		t.Fatalf("Could not unmarshal the trip: %v: %v", err, rec.Body.String())
+	}
+	if rec := request(r, http.MethodGet, "/rtc/v1/trips/"+trip.ID+"/departure", nil); rec.Code != http.StatusBadRequest {
+		t.Errorf("Expected status Bad Request for a trip without an arrival; got %v", rec.Code)
+	}
+}
+
+// TestTrips Saves, changes and deletes a trip, plans it by its id, and checks that webhooks are subscriptions to trips
+func TestTrips(t *testing.T) {
+	h := harness.Start(t)
+	r := handlers()
+	doc := specification(t, r)
+	arrival := time.Now().Add(48 * time.Hour).UTC().Truncate(time.Minute)
+
+	rec := request(r, http.MethodPost, "/rtc/v1/profiles", map[string]interface{}{
+		"name": "City EV", "fuel": "electric", "connectors": []string{"type2"}, "range": 20,
+	})
+	var profile structs.VehicleProfile
+	if rec.Code != http.StatusCreated || json.Unmarshal(rec.Body.Bytes(), &profile) != nil {
+		t.Fatalf("Expected the profile to be created; got %v: %v", rec.Code, rec.Body.String())
+	}
+
+	for _, invalid := range []map[string]interface{}{
+		{"stops": []string{"gjøvik", "lillehammer"}},
+		{"name": "Alone", "stops": []string{"gjøvik"}},
+		{"name": "Lost", "stops": []string{"gjøvik", "atlantis"}},
+		{"name": "Both", "stops": []string{"gjøvik", "oslo"}, "departure": arrival.Format(time.RFC3339), "arrival": arrival.Format(time.RFC3339)},
+		{"name": "Early", "stops": []string{"gjøvik", "oslo"}, "preferences": map[string]interface{}{"departAt": arrival.Format(time.RFC3339)}},
+		{"name": "Unknown", "stops": []string{"gjøvik", "oslo"}, "profile": "missing"},
+		{"name": "Sloppy", "stops": []string{"gjøvik", "oslo"}, "arrival": "tomorrow"},
+	} {
+		if rec := request(r, http.MethodPost, "/rtc/v1/trips", invalid); rec.Code != http.StatusBadRequest {
+			t.Errorf("%v: expected status Bad Request; got %v", invalid, rec.Code)
+		}
+	}
+
+	rec = request(r, http.MethodPost, "/rtc/v1/trips", map[string]interface{}{
+		"name": "Inland", "stops": []string{"gjøvik", "oslo", "lillehammer"}, "profile": profile.ID,
+		"preferences": map[string]interface{}{"avoid": []string{"tolls"}}, "arrival": arrival.Format(time.RFC3339),
+	})
+	var trip structs.Trip
+	if rec.Code != http.StatusCreated || json.Unmarshal(rec.Body.Bytes(), &trip) != nil || trip.ID == "" {
+		t.Fatalf("Expected the trip to be saved; got %v: %v", rec.Code, rec.Body.String())
+	}
+	if trip.Preferences.RouteType != "fastest" || !reflect.DeepEqual(trip.Preferences.Avoid, []string{"tolls"}) {
+		t.Errorf("Expected the defaults of the preferences to be filled in; got %+v", trip.Preferences)
+	}
+
+	tests := []struct {
+		path    string
+		pattern string
+	}{
+		{"/trips", "/trips"},
+		{"/trips/{id}", "/trips/{id}"},
+		{"/trips/{id}/route", "/trips/{id}/route"},
+		{"/trips/{id}/route?units=imperial", "/trips/{id}/route"},
+		{"/trips/{id}/weather?lang=nb", "/trips/{id}/weather"},
+		{"/trips/{id}/departure?candidates=2", "/trips/{id}/departure"},
+		{"/trips/{id}/messages", "/trips/{id}/messages"},
+		{"/trips/{id}/charge", "/trips/{id}/charge"},
+	}
+	for _, test := range tests {
+		rec = request(r, http.MethodGet, "/rtc/v1"+strings.Replace(test.path, "{id}", trip.ID, 1), nil)
+		schema, _ := doc.ResponseSchema(http.MethodGet, test.pattern)
+		if rec.Code != http.StatusOK {
+			t.Errorf("GET %v: expected status Ok; got %v: %v", test.path, rec.Code, rec.Body.String())
+		} else if err := doc.ValidateJSON(schema, rec.Body.Bytes()); err != nil {
+			t.Errorf("GET %v drifts from the specification: %v", test.path, err)
+		}
+	}
+
+	// The route goes through the stops with the vehicle and preferences, to arrive at the arrival of the trip
+	if !requested(h, harness.TomTom, "/routing/1/calculateRoute/", "59.913300", "avoid=tollRoads", "vehicleEngineType=electric",
+		"arriveAt="+url.QueryEscape(arrival.Format(time.RFC3339))) {
+		t.Errorf("Expected the route through the stops of the trip; got %v", h.Requests(harness.TomTom))
+	}
+
+	// The recorded route has a single leg of 2912 seconds, the destination is reached at the end of it
+	var weather structs.TripWeather
+	rec = request(r, http.MethodGet, "/rtc/v1/trips/"+trip.ID+"/weather", nil)
+	if err := json.Unmarshal(rec.Body.Bytes(), &weather); err != nil || len(weather.Weather) != 2 {
+		t.Fatalf("Expected the weather at the start and the destination; got %v %v", rec.Body.String(), err)
+	}
+	first, _ := time.Parse(time.RFC3339, weather.Weather[0].Time)
+	last, _ := time.Parse(time.RFC3339, weather.Weather[1].Time)
+	if weather.Weather[0].Place != "gjøvik" || weather.Weather[1].Place != "lillehammer" || last.Sub(first) != 2912*time.Second ||
+		!last.Equal(arrival) {
+		t.Errorf("Expected the weather along the route at the times it is passed; got %+v", weather.Weather)
+	}
+
+	// The area of the route is widened by the 200 meters an incident can be from it
+	if !requested(h, harness.TomTom, "/traffic/services/5/incidentDetails", "bbox=10.462470%2C60.793498%2C10.695430%2C61.117102") {
+		t.Errorf("Expected the incidents in the area of the route; got %v", h.Requests(harness.TomTom))
+	}
+
+	// 16 km can be driven on a charge, the route is 44 km
+	var stops []structs.ChargingStop
+	rec = request(r, http.MethodGet, "/rtc/v1/trips/"+trip.ID+"/charge?power=50", nil)
+	if err := json.Unmarshal(rec.Body.Bytes(), &stops); err != nil || len(stops) < 2 {
+		t.Fatalf("Expected places to charge along the route; got %v %v", rec.Body.String(), err)
+	}
+	for i, stop := range stops {
+		if len(stop.Stations) == 0 || (i > 0 && stop.Distance-stops[i-1].Distance > 16) {
+			t.Errorf("Expected stations within the range of the previous charge; got %+v", stops)
+		}
+	}
+	if !requested(h, harness.TomTom, "categorySet=7309", "connectorSet=IEC62196Type2Outlet", "minPowerKW=50") {
+		t.Errorf("Expected the stations to be searched for with the connectors of the vehicle; got %v", h.Requests(harness.TomTom))
+	}
+
+	// A webhook subscribes to the trip, and follows it when it changes
+	rec = request(r, http.MethodPost, "/rtc/v1/notifyme", map[string]interface{}{"url": h.WebhookURL, "trip": trip.ID})
+	if rec.Code != http.StatusCreated {
+		t.Fatalf("Expected the webhook to subscribe to the trip; got %v: %v", rec.Code, rec.Body.String())
+	}
+	id := strings.TrimSpace(strings.Split(rec.Body.String(), ":")[1])
+	var webhook structs.Webhook
+	rec = request(r, http.MethodGet, "/rtc/v1/notifyme/"+id, nil)
+	if err := json.Unmarshal(rec.Body.Bytes(), &webhook); err != nil || webhook.Trip != trip.ID || webhook.DepartureLocation != "gjøvik" ||
+		webhook.ArrivalDestination != "lillehammer" || webhook.ArrivalTime != arrival.Format(time.RFC822) || webhook.Profile != profile.ID {
+		t.Errorf("Expected the webhook to have the places and arrival of the trip; got %+v %v", webhook, err)
+	}
+	var subscriptions []structs.Webhook
+	rec = request(r, http.MethodGet, "/rtc/v1/trips/"+trip.ID+"/subscriptions", nil)
+	if err := json.Unmarshal(rec.Body.Bytes(), &subscriptions); err != nil || len(subscriptions) != 1 || subscriptions[0].Id != id {
+		t.Errorf("Expected the webhook to be subscribed to the trip; got %v %v", rec.Body.String(), err)
+	}
+
+	moved := arrival.Add(24 * time.Hour)
+	rec = request(r, http.MethodPut, "/rtc/v1/trips/"+trip.ID, map[string]interface{}{
+		"name": "Inland", "stops": []string{"lillehammer", "gjøvik"}, "arrival": moved.Format(time.RFC3339),
+	})
+	if rec.Code != http.StatusOK {
+		t.Fatalf("Expected the trip to be replaced; got %v: %v", rec.Code, rec.Body.String())
+	}
+	var followed structs.Webhook
+	rec = request(r, http.MethodGet, "/rtc/v1/notifyme/"+id, nil)
+	if err := json.Unmarshal(rec.Body.Bytes(), &followed); err != nil || followed.DepartureLocation != "lillehammer" ||
+		followed.ArrivalTime != moved.Format(time.RFC822) || followed.Profile != "" {
+		t.Errorf("Expected the webhook to follow the trip; got %+v %v", followed, err)
+	}
+	if rec = request(r, http.MethodGet, "/rtc/v1/trips/"+trip.ID+"/charge", nil); rec.Code != http.StatusBadRequest {
+		t.Errorf("Expected status Bad Request for charging without an electric vehicle; got %v", rec.Code)
+	}
+
+	rec = request(r, http.MethodPost, "/rtc/v1/trips", map[string]interface{}{
+		"name": "Open", "stops": []string{"gjøvik", "oslo"}, "departure": arrival.Format(time.RFC3339),
+	})
+	var open structs.Trip
+	if err := json.Unmarshal(rec.Body.Bytes(), &open); err != nil {
+		t.Fatalf("Could not unmarshal the trip: %v: %v", err, rec.Body.String())
+	}
+	for _, invalid := range []string{open.ID, "missing"} {
+		rec = request(r, http.MethodPost, "/rtc/v1/notifyme", map[string]interface{}{"url": h.WebhookURL, "trip": invalid})
+		if rec.Code != http.StatusBadRequest {
+			t.Errorf("%v: expected status Bad Request for a trip without an arrival; got %v", invalid, rec.Code)
+		}
+	}
+
+	// Webhooks registered without a trip, now and before trips were added, get one
+	rec = request(r, http.MethodPost, "/rtc/v1/notifyme", map[string]interface{}{
+		"url":                h.WebhookURL,
+		"DepartureLocation":  "gjøvik",
+		"ArrivalDestination": "oslo",
+		"ArrivalTime":        arrival.Format(time.RFC822),
+	})
+	created := strings.TrimSpace(strings.Split(rec.Body.String(), ":")[1])
+	legacy, _ := database.Client.Add(database.Collection, map[string]interface{}{
+		"url":                h.WebhookURL,
+		"DepartureLocation":  "oslo",
+		"ArrivalDestination": "lillehammer",
+		"ArrivalTime":        arrival.Format(time.RFC822),
+	})
+	webhooks.AttachTrips()
+	for _, registered := range []string{created, legacy} {
+		var hook structs.Webhook
+		rec = request(r, http.MethodGet, "/rtc/v1/notifyme/"+registered, nil)
+		if err := json.Unmarshal(rec.Body.Bytes(), &hook); err != nil || hook.Trip == "" {
+			t.Fatalf("Expected the webhook to have a trip; got %v %v", rec.Body.String(), err)
+		}
+		var saved structs.Trip
+		rec = request(r, http.MethodGet, "/rtc/v1/trips/"+hook.Trip, nil)
+		if err := json.Unmarshal(rec.Body.Bytes(), &saved); err != nil ||
+			!reflect.DeepEqual(saved.Stops, []string{hook.DepartureLocation, hook.ArrivalDestination}) || saved.Arrival != arrival.Format(time.RFC3339) {
+			t.Errorf("Expected the trip of the webhook to be saved; got %v %v", rec.Body.String(), err)
+		}
+	}
+
+	// The subscriptions are deleted with the trip
+	if rec = request(r, http.MethodDelete, "/rtc/v1/trips/"+trip.ID, nil); rec.Code != http.StatusOK {
+		t.Errorf("Expected the trip to be deleted; got %v", rec.Code)
+	}
+	for _, path := range []string{"/rtc/v1/trips/" + trip.ID, "/rtc/v1/trips/" + trip.ID + "/route", "/rtc/v1/notifyme/" + id} {
+		if rec = request(r, http.MethodGet, path, nil); rec.Code != http.StatusNotFound {
+			t.Errorf("GET %v: expected status Not Found after deleting the trip; got %v", path, rec.Code)
+		}
+	}
+}
+
+// TestTripCalendar Checks the calendars of trips and webhooks, and that share links show the trip until they expire
+func TestTripCalendar(t *testing.T) {
+	h := harness.Start(t)
+	r := handlers()
+	doc := specification(t, r)
+	arrival := time.Now().Add(48 * time.Hour).UTC().Truncate(time.Minute)
+
+	rec := request(r, http.MethodPost, "/rtc/v1/trips", map[string]interface{}{
+		"name": "Cabin, weekend", "stops": []string{"gjøvik", "lillehammer"}, "arrival": arrival.Format(time.RFC3339),
+	})
+	var trip structs.Trip
+	if err := json.Unmarshal(rec.Body.Bytes(), &trip); err != nil {
+		t.Fatalf("Could not unmarshal the trip: %v: %v", err, rec.Body.String())
+	}
+
+	// Without a webhook the trip departs at the travel time of the recorded route, 2912 seconds, before the arrival
+	rec = request(r, http.MethodGet, "/rtc/v1/trips/"+trip.ID+"/calendar.ics?lang=nb", nil)
+	calendar := rec.Body.String()
+	if rec.Code != http.StatusOK || !strings.HasPrefix(rec.Header().Get("Content-Type"), "text/calendar") {
+		t.Fatalf("Expected a calendar; got %v %v: %v", rec.Code, rec.Header().Get("Content-Type"), calendar)
+	}
+	for _, line := range []string{
+		"BEGIN:VCALENDAR", "X-WR-CALNAME:Cabin\\, weekend", "UID:" + trip.ID + "-departure@roadtripcompanion",
+		"DTSTART:" + arrival.Add(-2912*time.Second).Format("20060102T150405Z"), "SUMMARY:Kjør til lillehammer",
+		"UID:" + trip.ID + "-arrival@roadtripcompanion", "DTSTART:" + arrival.Format("20060102T150405Z"), "END:VCALENDAR",
+	} {
+		if !strings.Contains(calendar, line+"\r\n") {
+			t.Errorf("Expected the calendar to have the line %v; got %v", line, calendar)
+		}
+	}
+	if strings.Count(calendar, "BEGIN:VEVENT") != 2 {
+		t.Errorf("Expected the departure and the arrival; got %v", calendar)
+	}
+
+	// The webhook estimates 48 minutes of travel, the calendar departs then with its weather advisory
+	rec = request(r, http.MethodPost, "/rtc/v1/notifyme", map[string]interface{}{"url": h.WebhookURL, "trip": trip.ID})
+	id := strings.TrimSpace(strings.Split(rec.Body.String(), ":")[1])
+	for _, path := range []string{"/rtc/v1/notifyme/" + id + "/calendar.ics", "/rtc/v1/trips/" + trip.ID + "/calendar.ics"} {
+		rec = request(r, http.MethodGet, path, nil)
+		if !strings.Contains(rec.Body.String(), "DTSTART:"+arrival.Add(-48*time.Minute).Format("20060102T150405Z")+"\r\n") ||
+			!strings.Contains(rec.Body.String(), "DESCRIPTION:It has been snowing lightly") {
+			t.Errorf("GET %v: expected the departure estimated for the webhook; got %v", path, rec.Body.String())
+		}
+	}
+	if rec = request(r, http.MethodGet, "/rtc/v1/notifyme/missing/calendar.ics", nil); rec.Code != http.StatusNotFound {
+		t.Errorf("Expected status Not Found for the calendar of an unknown webhook; got %v", rec.Code)
+	}
+
+	var link structs.ShareLink
+	rec = request(r, http.MethodPost, "/rtc/v1/trips/"+trip.ID+"/share?validFor=2", nil)
+	if err := json.Unmarshal(rec.Body.Bytes(), &link); err != nil || rec.Code != http.StatusCreated {
+		t.Fatalf("Expected a share link; got %v: %v", rec.Code, rec.Body.String())
+	}
+	expires, _ := time.Parse(time.RFC3339, link.Expires)
+	if expires.Sub(time.Now()) > 2*time.Hour || expires.Sub(time.Now()) < 119*time.Minute {
+		t.Errorf("Expected the link to expire in two hours; got %v", link.Expires)
+	}
+	shared, _ := url.Parse(link.Url)
+	rec = request(r, http.MethodGet, shared.RequestURI(), nil)
+	schema, _ := doc.ResponseSchema(http.MethodGet, "/shared/{id}")
+	var summary structs.TripSummary
+	if rec.Code != http.StatusOK {
+		t.Fatalf("Expected the shared trip; got %v: %v", rec.Code, rec.Body.String())
+	} else if err := doc.ValidateJSON(schema, rec.Body.Bytes()); err != nil {
+		t.Errorf("GET /shared/{id} drifts from the specification: %v", err)
+	}
+	if err := json.Unmarshal(rec.Body.Bytes(), &summary); err != nil || summary.Name != trip.Name || summary.TravelTime != 48 ||
+		summary.Arrival != arrival.Format(time.RFC3339) || len(summary.Stops) != 2 || summary.Weather == "" {
+		t.Errorf("Expected the summary of the trip with the estimate of the webhook; got %+v %v", summary, err)
+	}
+	if strings.Contains(rec.Body.String(), h.WebhookURL) || strings.Contains(rec.Body.String(), id) {
+		t.Errorf("Expected the summary to leave out the webhooks; got %v", rec.Body.String())
+	}
+	calendarLink, _ := url.Parse(link.Calendar)
+	if rec = request(r, http.MethodGet, calendarLink.RequestURI(), nil); rec.Code != http.StatusOK || !strings.Contains(rec.Body.String(), "BEGIN:VEVENT") {
+		t.Errorf("Expected the shared calendar; got %v: %v", rec.Code, rec.Body.String())
+	}
+
+	query := shared.Query()
+	tampered := "/rtc/v1/shared/" + trip.ID + "?expires=" + strconv.FormatInt(expires.Add(time.Hour).Unix(), 10) + "&signature=" + query.Get("signature")
+	expired := "/rtc/v1/shared/" + trip.ID + "?expires=" + strconv.FormatInt(time.Now().Add(-time.Hour).Unix(), 10) + "&signature=" +
+		hex.EncodeToString(hmacOf(webhooks.ShareSecret, trip.ID+":"+strconv.FormatInt(time.Now().Add(-time.Hour).Unix(), 10)))
+	for _, path := range []string{tampered, expired, "/rtc/v1/shared/other?" + shared.RawQuery} {
+		if rec = request(r, http.MethodGet, path, nil); rec.Code != http.StatusForbidden {
+			t.Errorf("GET %v: expected status Forbidden; got %v", path, rec.Code)
+		}
+	}
+}
+
+// hmacOf The HMAC-SHA256 of the message with the key
+func hmacOf(key []byte, message string) []byte {
+	mac := hmac.New(sha256.New, key)
+	mac.Write([]byte(message))
+	return mac.Sum(nil)
+}
+
+// TestWebhookLifecycle Registers, retrieves, lists and deletes a webhook through the router
+func TestWebhookLifecycle(t *testing.T) {
+	h := harness.Start(t)
+	r := handlers()
+	doc := specification(t, r)
+
+	rec := request(r, http.MethodPost, "/rtc/v1/notifyme", map[string]interface{}{
+		"url":                h.WebhookURL,
+		"DepartureLocation":  "gjøvik",
+		"ArrivalDestination": "lillehammer",
+		"ArrivalTime":        time.Now().Add(48 * time.Hour).UTC().Format(time.RFC822),
+	})
+	if rec.Code != http.StatusCreated {
+		t.Fatalf("Expected status Created; got %v: %v", rec.Code, rec.Body.String())
+	}
+	id := strings.TrimSpace(strings.Split(rec.Body.String(), ":")[1])
+
+	rec = request(r, http.MethodGet, "/rtc/v1/notifyme/"+id, nil)
+	schema, _ := doc.ResponseSchema(http.MethodGet, "/notifyme/{id}")
+	if rec.Code != http.StatusOK {
+		t.Fatalf("Expected status Ok; got %v", rec.Code)
+	} else if err := doc.ValidateJSON(schema, rec.Body.Bytes()); err != nil {
+		t.Errorf("GET /notifyme/{id} drifts from the specification: %v", err)
+	}
+
+	// v1 keeps the field names Go exports, v2 names them in camelCase
+	var fields map[string]interface{}
+	if err := json.Unmarshal(rec.Body.Bytes(), &fields); err != nil || fields["DepartureLocation"] != "gjøvik" {
+		t.Errorf("Expected the v1 webhook to have the DepartureLocation field; got %v", rec.Body.String())
+	}
+	rec = request(r, http.MethodGet, "/rtc/v2/notifyme/"+id, nil)
+	fields = nil
+	if err := json.Unmarshal(rec.Body.Bytes(), &fields); err != nil || fields["departureLocation"] != "gjøvik" ||
+		fields["DepartureLocation"] != nil {
+		t.Errorf("Expected the v2 webhook to have the departureLocation field; got %v", rec.Body.String())
+	}
+
+	rec = request(r, http.MethodDelete, "/rtc/v1/notifyme/"+id, nil)
+	if rec.Code != http.StatusOK {
+		t.Errorf("Expected status Ok when deleting; got %v", rec.Code)
+	}
+	rec = request(r, http.MethodGet, "/rtc/v1/notifyme/"+id, nil)
+	if rec.Code != http.StatusNotFound {
+		t.Errorf("Expected status Not Found after deleting; got %v", rec.Code)
+	}
+}
@@ -0,0 +1,349 @@
+package openapi
+
+import (
+	"cloudproject/router"
+	"cloudproject/utils"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+)
+
+/**
+ * Class openapi.go
+ * Generates an OpenAPI 3 specification from the routes registered on the router
+ * Paths and parameters come from the route patterns and query schemas, the response and request body schemas
+ * are generated from the Go types of the values passed to Route.Returns and Route.Accepts, using their json tags.
+ */
+
+// Version The version of the OpenAPI specification the document follows
+const Version = "3.0.3"
+
+// Document The root of an OpenAPI document
+type Document struct {
+	OpenAPI    string               `json:"openapi"`
+	Info       Info                 `json:"info"`
+	Servers    []Server             `json:"servers"`
+	Paths      map[string]*PathItem `json:"paths"`
+	Components Components           `json:"components"`
+}
+
+// Info Title and version of the API
+type Info struct {
+	Title       string `json:"title"`
+	Description string `json:"description,omitempty"`
+	Version     string `json:"version"`
+}
+
+// Server The base url of the documented paths
+type Server struct {
+	URL string `json:"url"`
+}
+
+// PathItem The operations of a path, keyed by lower case method
+type PathItem map[string]*Operation
+
+// Operation A method on a path
+type Operation struct {
+	Summary     string               `json:"summary,omitempty"`
+	OperationID string               `json:"operationId"`
+	Parameters  []Parameter          `json:"parameters,omitempty"`
+	RequestBody *RequestBody         `json:"requestBody,omitempty"`
+	Responses   map[string]*Response `json:"responses"`
+}
+
+// Parameter A path or query parameter
+type Parameter struct {
+	Name        string  `json:"name"`
+	In          string  `json:"in"`
+	Description string  `json:"description,omitempty"`
+	Required    bool    `json:"required"`
+	Style       string  `json:"style,omitempty"`
+	Explode     *bool   `json:"explode,omitempty"`
+	Schema      *Schema `json:"schema"`
+}
+
+// RequestBody The body an operation accepts
+type RequestBody struct {
+	Required bool                 `json:"required"`
+	Content  map[string]MediaType `json:"content"`
+}
+
+// Response A response of an operation
+type Response struct {
+	Description string               `json:"description"`
+	Content     map[string]MediaType `json:"content,omitempty"`
+}
+
+// MediaType The schema of a body of a given content type
+type MediaType struct {
+	Schema *Schema `json:"schema"`
+}
+
+// Components Schemas shared between operations, referenced by name
+type Components struct {
+	Schemas map[string]*Schema `json:"schemas"`
+}
+
+// Schema A JSON schema, as far as it is used in this API
+type Schema struct {
+	Ref                  string             `json:"$ref,omitempty"`
+	AllOf                []*Schema          `json:"allOf,omitempty"`
+	Type                 string             `json:"type,omitempty"`
+	Format               string             `json:"format,omitempty"`
+	Description          string             `json:"description,omitempty"`
+	Nullable             bool               `json:"nullable,omitempty"`
+	Properties           map[string]*Schema `json:"properties,omitempty"`
+	Required             []string           `json:"required,omitempty"`
+	AdditionalProperties interface{}        `json:"additionalProperties,omitempty"`
+	Items                *Schema            `json:"items,omitempty"`
+	Enum                 []string           `json:"enum,omitempty"`
+	Default              interface{}        `json:"default,omitempty"`
+	Minimum              *float64           `json:"minimum,omitempty"`
+	Maximum              *float64           `json:"maximum,omitempty"`
+}
+
+// refPrefix Prefix of references to the component schemas
+const refPrefix = "#/components/schemas/"
+
+// Generate Generates the specification of the routes mounted below mount, for instance "/rtc/v1"
+func Generate(routes []*router.Route, mount string, title string) *Document {
+	doc := &Document{
+		OpenAPI:    Version,
+		Info:       Info{Title: title, Version: strings.TrimPrefix(mount[strings.LastIndex(mount, "/")+1:], "v")},
+		Servers:    []Server{{URL: mount}},
+		Paths:      map[string]*PathItem{},
+		Components: Components{Schemas: map[string]*Schema{}},
+	}
+
+	for _, route := range routes {
+		if route.Mount != mount {
+			continue
+		}
+		path := strings.TrimPrefix(route.Pattern, mount)
+		if path == "" {
+			path = "/"
+		}
+		item, found := doc.Paths[path]
+		if !found {
+			item = &PathItem{}
+			doc.Paths[path] = item
+		}
+		(*item)[strings.ToLower(route.Method)] = doc.operation(route, path)
+	}
+	return doc
+}
+
+// operation Documents a single route
+func (doc *Document) operation(route *router.Route, path string) *Operation {
+	op := &Operation{
+		Summary:     route.Summary,
+		OperationID: operationID(route.Method, path),
+		Responses:   map[string]*Response{},
+	}
+
+	for _, segment := range strings.Split(path, "/") {
+		if strings.HasPrefix(segment, "{") && strings.HasSuffix(segment, "}") {
+			op.Parameters = append(op.Parameters, Parameter{
+				Name: strings.Trim(segment, "{}"), In: "path", Required: true, Schema: &Schema{Type: "string"},
+			})
+		}
+	}
+	for _, param := range route.Query {
+		op.Parameters = append(op.Parameters, queryParameter(param))
+	}
+
+	if route.Body != nil {
+		op.RequestBody = &RequestBody{Required: true, Content: map[string]MediaType{
+			"application/json": {Schema: doc.SchemaOf(reflect.TypeOf(route.Body))},
+		}}
+	}
+
+	status := route.Status
+	if status == 0 {
+		status = http.StatusOK
+	}
+	response := &Response{Description: http.StatusText(status)}
+	if route.Response != nil {
+		var schema *Schema
+		if route.ContentType == "application/json" {
+			schema = doc.SchemaOf(reflect.TypeOf(route.Response))
+		} else {
+			schema = &Schema{Type: "string"}
+		}
+		response.Content = map[string]MediaType{route.ContentType: {Schema: schema}}
+	}
+	op.Responses[strconv.Itoa(status)] = response
+	op.Responses["default"] = &Response{Description: "Error", Content: map[string]MediaType{
+		"application/json": {Schema: &Schema{Type: "object", Properties: map[string]*Schema{
+			"status": {Type: "integer"}, "error": {Type: "string"}, "message": {Type: "string"},
+		}}},
+		"text/plain": {Schema: &Schema{Type: "string"}},
+	}}
+	return op
+}
+
+// queryParameter Documents a query parameter from its schema
+func queryParameter(param utils.QueryParam) Parameter {
+	schema := &Schema{Type: param.Type, Enum: param.Enum, Minimum: param.Minimum, Maximum: param.Maximum}
+	if param.Type == utils.TypeDateTime || param.Type == utils.TypeDate {
+		schema.Type, schema.Format = "string", param.Type
+	}
+	if param.Default != "" {
+		schema.Default = typedDefault(param)
+	}
+	parameter := Parameter{Name: param.Name, In: "query", Description: param.Description, Required: param.Required, Schema: schema}
+	if param.Multiple {
+		explode := false
+		parameter.Style = "form"
+		parameter.Explode = &explode
+		parameter.Schema = &Schema{Type: "array", Items: schema}
+		if param.Default != "" {
+			schema.Default = nil
+			parameter.Schema.Default = strings.Split(param.Default, ",")
+		}
+	}
+	return parameter
+}
+
+// typedDefault Converts the default value of a parameter to its type, so an integer default is a JSON number
+func typedDefault(param utils.QueryParam) interface{} {
+	switch param.Type {
+	case utils.TypeInteger, utils.TypeNumber:
+		if number, err := strconv.ParseFloat(param.Default, 64); err == nil {
+			return number
+		}
+	case utils.TypeBoolean:
+		if boolean, err := strconv.ParseBool(param.Default); err == nil {
+			return boolean
+		}
+	}
+	return param.Default
+}
+
+// operationID Creates an id such as getWeatherPlace from the method and path
+func operationID(method string, path string) string {
+	id := strings.ToLower(method)
+	for _, segment := range strings.FieldsFunc(path, func(r rune) bool { return r == '/' || r == '{' || r == '}' || r == '-' || r == '.' }) {
+		id += strings.ToUpper(segment[:1]) + segment[1:]
+	}
+	return id
+}
+
+// timeType Time values are marshalled as RFC3339 strings
+var timeType = reflect.TypeOf(time.Time{})
+
+// SchemaOf Generates the schema of a Go type, named struct types are added to the components and referenced
+func (doc *Document) SchemaOf(t reflect.Type) *Schema {
+	switch t.Kind() {
+	case reflect.Ptr:
+		// Nil pointers are marshalled as null, a reference can not be nullable itself so it is wrapped
+		schema := doc.SchemaOf(t.Elem())
+		if schema.Ref != "" {
+			return &Schema{AllOf: []*Schema{schema}, Nullable: true}
+		}
+		schema.Nullable = true
+		return schema
+	case reflect.Bool:
+		return &Schema{Type: "boolean"}
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return &Schema{Type: "integer"}
+	case reflect.Float32, reflect.Float64:
+		return &Schema{Type: "number"}
+	case reflect.String:
+		return &Schema{Type: "string"}
+	case reflect.Slice, reflect.Array:
+		// Nil slices are marshalled as null
+		return &Schema{Type: "array", Items: doc.SchemaOf(t.Elem()), Nullable: t.Kind() == reflect.Slice}
+	case reflect.Map:
+		return &Schema{Type: "object", AdditionalProperties: doc.SchemaOf(t.Elem())}
+	case reflect.Struct:
+		if t == timeType {
+			return &Schema{Type: "string", Format: "date-time"}
+		}
+		if t.Name() == "" {
+			return doc.structSchema(t)
+		}
+		name := t.Name()
+		if _, found := doc.Components.Schemas[name]; !found {
+			// Reserves the name before generating, so recursive types terminate
+			doc.Components.Schemas[name] = &Schema{}
+			*doc.Components.Schemas[name] = *doc.structSchema(t)
+		}
+		return &Schema{Ref: refPrefix + name}
+	}
+	// Interfaces can hold any value
+	return &Schema{}
+}
+
+// structSchema Generates the object schema of a struct type from its exported fields and json tags
+func (doc *Document) structSchema(t reflect.Type) *Schema {
+	schema := &Schema{Type: "object", Properties: map[string]*Schema{}, AdditionalProperties: false}
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		if field.PkgPath != "" {
+			continue
+		}
+		name, omitEmpty, skip := jsonName(field)
+		if skip {
+			continue
+		}
+		if field.Anonymous && field.Type.Kind() == reflect.Struct && field.Tag.Get("json") == "" {
+			embedded := doc.structSchema(field.Type)
+			for property, propertySchema := range embedded.Properties {
+				schema.Properties[property] = propertySchema
+			}
+			schema.Required = append(schema.Required, embedded.Required...)
+			continue
+		}
+		property := doc.SchemaOf(field.Type)
+		if description := field.Tag.Get("description"); description != "" {
+			property.Description = description
+		}
+		schema.Properties[name] = property
+		if !omitEmpty {
+			schema.Required = append(schema.Required, name)
+		}
+	}
+	return schema
+}
+
+// jsonName Returns the name the field is marshalled as, if it is left out when empty and if it is skipped
+func jsonName(field reflect.StructField) (string, bool, bool) {
+	tag := field.Tag.Get("json")
+	if tag == "-" {
+		return "", false, true
+	}
+	parts := strings.Split(tag, ",")
+	name := parts[0]
+	if name == "" {
+		name = field.Name
+	}
+	omitEmpty := false
+	for _, option := range parts[1:] {
+		if option == "omitempty" {
+			omitEmpty = true
+		}
+	}
+	return name, omitEmpty, false
+}
+
+// Handler Serves the specification of the routes mounted below mount as JSON
+func Handler(r *router.Router, mount string, title string) http.HandlerFunc {
+	return func(w http.ResponseWriter, request *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		output, err := json.MarshalIndent(Generate(r.Routes(), mount, title), "", "  ")
+		if err != nil {
+			jsonError := utils.JsonMarshalErrorHandling(err)
+			log.Println("Unable to marshall the specification.\n" + err.Error())
+			http.Error(w, jsonError.Error(), http.StatusInternalServerError)
+			return
+		}
+		fmt.Fprintf(w, "%v", string(output))
+	}
+}
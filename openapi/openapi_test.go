@@ -0,0 +1,39 @@
+package openapi
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestNullableReference(t *testing.T) {
+	type Conditions struct {
+		Visibility string `json:"visibility"`
+	}
+	type Hook struct {
+		Conditions *Conditions `json:"conditions" description:"The road conditions"`
+	}
+	doc := &Document{Components: Components{Schemas: map[string]*Schema{}}}
+	schema := doc.SchemaOf(reflect.TypeOf(Hook{}))
+
+	// The pointer is a nullable wrapper around the reference, the referenced schema itself is not nullable
+	property := doc.Components.Schemas["Hook"].Properties["conditions"]
+	if len(property.AllOf) != 1 || property.AllOf[0].Ref != refPrefix+"Conditions" || !property.Nullable ||
+		property.Description != "The road conditions" || doc.Components.Schemas["Conditions"].Nullable {
+		t.Fatalf("Expected a nullable reference to Conditions; got %+v", property)
+	}
+
+	tests := []struct {
+		body  string
+		valid bool
+	}{
+		{`{"conditions": null}`, true},
+		{`{"conditions": {"visibility": "good"}}`, true},
+		{`{"conditions": {"Visibility": "good"}}`, false},
+		{`{"conditions": "good"}`, false},
+	}
+	for _, test := range tests {
+		if err := doc.ValidateJSON(schema, []byte(test.body)); (err == nil) != test.valid {
+			t.Errorf("Expected %v to be valid: %v; got %v", test.body, test.valid, err)
+		}
+	}
+}
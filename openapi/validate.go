@@ -0,0 +1,155 @@
+package openapi
+
+import (
+	"cloudproject/utils"
+	"encoding/json"
+	"errors"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// ResponseSchema Returns the schema of the successful response of the method on the path, for instance
+// ("get", "/weather/{place}")
+func (doc *Document) ResponseSchema(method string, path string) (*Schema, error) {
+	item, found := doc.Paths[path]
+	if !found {
+		return nil, errors.New("path " + path + " is not in the specification")
+	}
+	op, found := (*item)[strings.ToLower(method)]
+	if !found {
+		return nil, errors.New(method + " " + path + " is not in the specification")
+	}
+	for status, response := range op.Responses {
+		if status == "default" || status[0] != '2' {
+			continue
+		}
+		if media, found := response.Content["application/json"]; found {
+			return media.Schema, nil
+		}
+	}
+	return nil, errors.New(method + " " + path + " has no JSON response in the specification")
+}
+
+// ValidateJSON Checks that the JSON body conforms to the schema, the error tells where the body drifts from it
+func (doc *Document) ValidateJSON(schema *Schema, body []byte) error {
+	var value interface{}
+	decoder := json.NewDecoder(strings.NewReader(string(body)))
+	decoder.UseNumber()
+	if err := decoder.Decode(&value); err != nil {
+		return errors.New("body is not valid JSON: " + err.Error())
+	}
+	return doc.validate(schema, value, "$")
+}
+
+// validate Checks the decoded value against the schema, path is where in the body the value is
+func (doc *Document) validate(schema *Schema, value interface{}, path string) error {
+	if schema.Ref != "" {
+		referenced, found := doc.Components.Schemas[strings.TrimPrefix(schema.Ref, refPrefix)]
+		if !found {
+			return errors.New(path + ": unknown schema " + schema.Ref)
+		}
+		schema = referenced
+	}
+	if value == nil {
+		if schema.Nullable || (schema.Type == "" && len(schema.AllOf) == 0) {
+			return nil
+		}
+		return errors.New(path + ": null is not allowed, expected " + schema.Type)
+	}
+	for _, part := range schema.AllOf {
+		if err := doc.validate(part, value, path); err != nil {
+			return err
+		}
+	}
+
+	switch schema.Type {
+	case "object":
+		object, ok := value.(map[string]interface{})
+		if !ok {
+			return errors.New(path + ": expected an object")
+		}
+		if schema.Properties != nil {
+			return doc.validateProperties(schema, object, path)
+		}
+		if additional, ok := schema.AdditionalProperties.(*Schema); ok {
+			for _, key := range sortedKeys(object) {
+				if err := doc.validate(additional, object[key], path+"."+key); err != nil {
+					return err
+				}
+			}
+		}
+	case "array":
+		array, ok := value.([]interface{})
+		if !ok {
+			return errors.New(path + ": expected an array")
+		}
+		for i, item := range array {
+			if err := doc.validate(schema.Items, item, path+"["+strconv.Itoa(i)+"]"); err != nil {
+				return err
+			}
+		}
+	case "string":
+		text, ok := value.(string)
+		if !ok {
+			return errors.New(path + ": expected a string")
+		}
+		if schema.Format == "date-time" {
+			if _, err := time.Parse(time.RFC3339Nano, text); err != nil {
+				return errors.New(path + ": expected a date-time, got " + text)
+			}
+		}
+		if len(schema.Enum) != 0 && !utils.Contains(schema.Enum, text) {
+			return errors.New(path + ": " + text + " is not one of " + strings.Join(schema.Enum, ", "))
+		}
+	case "number", "integer":
+		number, ok := value.(json.Number)
+		if !ok {
+			return errors.New(path + ": expected " + schema.Type)
+		}
+		float, err := number.Float64()
+		if err != nil || (schema.Type == "integer" && float != math.Trunc(float)) {
+			return errors.New(path + ": expected " + schema.Type + ", got " + number.String())
+		}
+	case "boolean":
+		if _, ok := value.(bool); !ok {
+			return errors.New(path + ": expected a boolean")
+		}
+	}
+	return nil
+}
+
+// validateProperties Checks the properties of an object, properties missing from the schema and required
+// properties missing from the object are both drift
+func (doc *Document) validateProperties(schema *Schema, object map[string]interface{}, path string) error {
+	for _, required := range schema.Required {
+		if _, found := object[required]; !found {
+			return errors.New(path + ": required property " + required + " is missing")
+		}
+	}
+	for _, key := range sortedKeys(object) {
+		property, found := schema.Properties[key]
+		if !found {
+			if schema.AdditionalProperties == false {
+				return errors.New(path + ": property " + key + " is not in the specification")
+			}
+			continue
+		}
+		if err := doc.validate(property, object[key], path+"."+key); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// sortedKeys Returns the keys of the object in order, so errors are reported deterministically
+func sortedKeys(object map[string]interface{}) []string {
+	var keys []string
+	for key := range object {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
@@ -0,0 +1,318 @@
+package router
+
+import (
+	"cloudproject/utils"
+	"context"
+	"encoding/json"
+	"log"
+	"net/http"
+	"sort"
+	"strings"
+)
+
+/**
+ * Class router.go
+ * Routes requests to the endpoint handlers
+ * Patterns are written as paths where a segment in curly brackets is a named parameter, for instance:
+ *							/weather/{place}				matches /weather/oslo
+ *							/route/{start}/{destination}	matches /route/oslo/bergen
+ * A trailing '/' on the requested path is ignored.
+ * Requests matching a pattern, but not its method, are answered with 405 Method Not Allowed.
+ * Requests not matching any pattern are answered with 404 Not Found.
+ * Routes with a query schema get their query validated before the handler is called, and OPTIONS requests
+ * are answered with the methods and parameters the path accepts.
+ */
+
+// Route A registered method, pattern and handler
+type Route struct {
+	Method  string
+	Pattern string
+	Mount   string
+	Handler http.HandlerFunc
+	Query   utils.QuerySchema
+
+	// Documentation of the route, used to generate the API specification
+	Summary     string
+	Status      int
+	Response    interface{}
+	ContentType string
+	Body        interface{}
+
+	segments []string
+}
+
+// Router Holds the registered routes, groups of a router share the routes of the router they were made from
+type Router struct {
+	prefix string
+	routes *[]*Route
+}
+
+// contextKey Type for the values the router attaches to the request context
+type contextKey int
+
+const (
+	paramsKey contextKey = iota
+	mountKey
+	queryKey
+)
+
+// New Creates an empty router
+func New() *Router {
+	return &Router{routes: &[]*Route{}}
+}
+
+// Group Creates a router whose patterns are mounted below prefix, for instance "/rtc/v1"
+func (router *Router) Group(prefix string) *Router {
+	return &Router{prefix: router.prefix + "/" + strings.Trim(prefix, "/"), routes: router.routes}
+}
+
+// Handle Registers handler for the method and pattern
+func (router *Router) Handle(method string, pattern string, handler http.HandlerFunc) *Route {
+	full := router.prefix + "/" + strings.Trim(pattern, "/")
+	route := &Route{
+		Method:   method,
+		Pattern:  strings.TrimRight(full, "/"),
+		Mount:    router.prefix,
+		Handler:  handler,
+		segments: split(full),
+	}
+	*router.routes = append(*router.routes, route)
+	return route
+}
+
+// Get Registers handler for GET requests on pattern
+func (router *Router) Get(pattern string, handler http.HandlerFunc) *Route {
+	return router.Handle(http.MethodGet, pattern, handler)
+}
+
+// Post Registers handler for POST requests on pattern
+func (router *Router) Post(pattern string, handler http.HandlerFunc) *Route {
+	return router.Handle(http.MethodPost, pattern, handler)
+}
+
+// Put Registers handler for PUT requests on pattern
+func (router *Router) Put(pattern string, handler http.HandlerFunc) *Route {
+	return router.Handle(http.MethodPut, pattern, handler)
+}
+
+// Delete Registers handler for DELETE requests on pattern
+func (router *Router) Delete(pattern string, handler http.HandlerFunc) *Route {
+	return router.Handle(http.MethodDelete, pattern, handler)
+}
+
+// WithQuery Sets the query parameters the route accepts, requests with invalid parameters are answered with 400 Bad Request
+func (route *Route) WithQuery(schema utils.QuerySchema) *Route {
+	if schema == nil {
+		schema = utils.QuerySchema{}
+	}
+	route.Query = schema
+	return route
+}
+
+// Describe Sets the summary of the route
+func (route *Route) Describe(summary string) *Route {
+	route.Summary = summary
+	return route
+}
+
+// Returns Sets the status code and an example of the value the route responds with, the type of the value
+// documents the response
+func (route *Route) Returns(status int, response interface{}) *Route {
+	route.Status = status
+	route.Response = response
+	if route.ContentType == "" {
+		route.ContentType = "application/json"
+	}
+	return route
+}
+
+// Produces Sets the content type of the response, when it is not JSON
+func (route *Route) Produces(contentType string) *Route {
+	route.ContentType = contentType
+	return route
+}
+
+// Accepts Sets an example of the request body of the route, the type of the value documents the body
+func (route *Route) Accepts(body interface{}) *Route {
+	route.Body = body
+	return route
+}
+
+// Routes Returns all routes registered on the router and its groups
+func (router *Router) Routes() []*Route {
+	return *router.routes
+}
+
+// ServeHTTP Dispatches the request to the handler of the matching route
+func (router *Router) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	segments := split(r.URL.Path)
+
+	// Static segments take precedence over parameters from left to right, so /trips/shared is preferred to
+	// /trips/{id}, and /a/b/{y} to /a/{x}/c
+	var best *Route
+	var bestParams map[string]string
+	var allowed []string
+	var matched []*Route
+	for _, route := range *router.routes {
+		params, ok := match(route.segments, segments)
+		if !ok {
+			continue
+		}
+		matched = append(matched, route)
+		if route.Method == r.Method || (r.Method == http.MethodHead && route.Method == http.MethodGet) {
+			if best == nil || moreSpecific(route.segments, best.segments) {
+				best, bestParams = route, params
+			}
+			continue
+		}
+		allowed = append(allowed, route.Method)
+	}
+
+	if best != nil {
+		ctx := context.WithValue(r.Context(), paramsKey, bestParams)
+		ctx = context.WithValue(ctx, mountKey, best.Mount)
+		if best.Query != nil {
+			query, err := best.Query.Parse(r.URL)
+			if err != nil {
+				Error(w, err.Error(), http.StatusBadRequest)
+				return
+			}
+			ctx = context.WithValue(ctx, queryKey, query)
+		}
+		best.Handler(w, r.WithContext(ctx))
+		return
+	}
+
+	if r.Method == http.MethodOptions && len(matched) != 0 {
+		options(w, matched)
+		return
+	}
+
+	if len(allowed) != 0 {
+		w.Header().Set("Allow", allowHeader(allowed))
+		Error(w, "Method "+r.Method+" is not supported on "+r.URL.Path, http.StatusMethodNotAllowed)
+		return
+	}
+	Error(w, "No endpoint found at "+r.URL.Path, http.StatusNotFound)
+}
+
+// Param Returns the value of the named path parameter of the request, or "" if the route has no such parameter
+func Param(r *http.Request, name string) string {
+	params, _ := r.Context().Value(paramsKey).(map[string]string)
+	return params[name]
+}
+
+// Query Returns the validated query of the request, empty if the route has no query schema
+func Query(r *http.Request) utils.Query {
+	query, _ := r.Context().Value(queryKey).(utils.Query)
+	if query == nil {
+		return utils.Query{}
+	}
+	return query
+}
+
+// Mount Returns the prefix the matched route is mounted below, for instance "/rtc/v1"
+func Mount(r *http.Request) string {
+	mount, _ := r.Context().Value(mountKey).(string)
+	return mount
+}
+
+// errorBody The JSON body of error responses written by the router
+type errorBody struct {
+	Status  int    `json:"status"`
+	Error   string `json:"error"`
+	Message string `json:"message"`
+}
+
+// Error Replies to the request with the status code and message as a JSON error body
+func Error(w http.ResponseWriter, message string, status int) {
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("X-Content-Type-Options", "nosniff")
+	w.WriteHeader(status)
+	err := json.NewEncoder(w).Encode(errorBody{Status: status, Error: http.StatusText(status), Message: message})
+	if err != nil {
+		log.Println("Unable to write error response.\n" + err.Error())
+	}
+}
+
+// methodOptions Describes a method on a path and the query parameters it accepts
+type methodOptions struct {
+	Method     string             `json:"method"`
+	Parameters []utils.QueryParam `json:"parameters"`
+}
+
+// options Answers an OPTIONS request with the methods and query parameters of the matched routes
+func options(w http.ResponseWriter, routes []*Route) {
+	var allowed []string
+	var methods []methodOptions
+	for _, route := range routes {
+		allowed = append(allowed, route.Method)
+		parameters := []utils.QueryParam(route.Query)
+		if parameters == nil {
+			parameters = []utils.QueryParam{}
+		}
+		methods = append(methods, methodOptions{Method: route.Method, Parameters: parameters})
+	}
+	w.Header().Set("Allow", allowHeader(append(allowed, http.MethodOptions)))
+	w.Header().Set("Content-Type", "application/json")
+	err := json.NewEncoder(w).Encode(map[string]interface{}{"path": routes[0].Pattern, "methods": methods})
+	if err != nil {
+		log.Println("Unable to write options response.\n" + err.Error())
+	}
+}
+
+// allowHeader The methods sorted and listed once each, as the value of an Allow header. Several routes matching a path
+// can have the same method, such as a route with a static segment and one with a parameter in its place
+func allowHeader(methods []string) string {
+	var unique []string
+	for _, method := range methods {
+		if !utils.Contains(unique, method) {
+			unique = append(unique, method)
+		}
+	}
+	sort.Strings(unique)
+	return strings.Join(unique, ", ")
+}
+
+// split Splits a path into its non-empty segments
+func split(path string) []string {
+	var segments []string
+	for _, segment := range strings.Split(path, "/") {
+		if segment != "" {
+			segments = append(segments, segment)
+		}
+	}
+	return segments
+}
+
+// match Checks if the path segments match the pattern segments, and returns the named parameters if so
+func match(pattern []string, path []string) (map[string]string, bool) {
+	if len(pattern) != len(path) {
+		return nil, false
+	}
+	params := map[string]string{}
+	for i, segment := range pattern {
+		if isParam(segment) {
+			params[strings.Trim(segment, "{}")] = path[i]
+		} else if segment != path[i] {
+			return nil, false
+		}
+	}
+	return params, true
+}
+
+// moreSpecific Checks if pattern a is preferred to pattern b, which match the same path. At the first segment where
+// one of them has a parameter and the other a static segment, the static segment is preferred
+func moreSpecific(a []string, b []string) bool {
+	for i := range a {
+		if isParam(a[i]) != isParam(b[i]) {
+			return isParam(b[i])
+		}
+	}
+	return false
+}
+
+// isParam Checks if the pattern segment is a named parameter, such as {place}
+func isParam(segment string) bool {
+	return strings.HasPrefix(segment, "{") && strings.HasSuffix(segment, "}")
+}
@@ -0,0 +1,57 @@
+package router
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestRouter(t *testing.T) {
+	r := New()
+	v1 := r.Group("/rtc/v1")
+	v1.Get("/poi/{place}/{category}", func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, Param(r, "place")+","+Param(r, "category")+","+Mount(r))
+	})
+	v1.Get("/trips/{id}", func(w http.ResponseWriter, r *http.Request) { fmt.Fprint(w, "trip") })
+	v1.Get("/trips/shared", func(w http.ResponseWriter, r *http.Request) { fmt.Fprint(w, "shared") })
+	v1.Delete("/trips/{id}", func(w http.ResponseWriter, r *http.Request) {})
+	// Patterns with as many parameters are chosen by the first segment they differ in, whatever order they are registered in
+	v1.Get("/a/{x}/c", func(w http.ResponseWriter, r *http.Request) { fmt.Fprint(w, "x") })
+	v1.Get("/a/b/{y}", func(w http.ResponseWriter, r *http.Request) { fmt.Fprint(w, "y") })
+	v1.Get("/d/e/{y}", func(w http.ResponseWriter, r *http.Request) { fmt.Fprint(w, "y") })
+	v1.Get("/d/{x}/f", func(w http.ResponseWriter, r *http.Request) { fmt.Fprint(w, "x") })
+
+	tests := []struct {
+		method string
+		path   string
+		status int
+		body   string
+	}{
+		{http.MethodGet, "/rtc/v1/poi/oslo/cafe", http.StatusOK, "oslo,cafe,/rtc/v1"},
+		{http.MethodGet, "/rtc/v1/poi/oslo/cafe/", http.StatusOK, "oslo,cafe,/rtc/v1"},
+		{http.MethodGet, "/rtc/v1/poi/oslo", http.StatusNotFound, ""},
+		{http.MethodGet, "/rtc/v1/trips/shared", http.StatusOK, "shared"},
+		{http.MethodGet, "/rtc/v1/trips/abc", http.StatusOK, "trip"},
+		{http.MethodPost, "/rtc/v1/trips/abc", http.StatusMethodNotAllowed, ""},
+		// GET is allowed by both /trips/shared and /trips/{id}, and listed once
+		{http.MethodPost, "/rtc/v1/trips/shared", http.StatusMethodNotAllowed, ""},
+		{http.MethodGet, "/rtc/v1/a/b/c", http.StatusOK, "y"},
+		{http.MethodGet, "/rtc/v1/a/z/c", http.StatusOK, "x"},
+		{http.MethodGet, "/rtc/v1/d/e/f", http.StatusOK, "y"},
+		{http.MethodGet, "/rtc/v1/d/z/f", http.StatusOK, "x"},
+	}
+	for _, test := range tests {
+		rec := httptest.NewRecorder()
+		r.ServeHTTP(rec, httptest.NewRequest(test.method, test.path, nil))
+		if rec.Code != test.status {
+			t.Errorf("%v %v: expected status %v; got %v", test.method, test.path, test.status, rec.Code)
+		}
+		if test.body != "" && rec.Body.String() != test.body {
+			t.Errorf("%v %v: expected body %v; got %v", test.method, test.path, test.body, rec.Body.String())
+		}
+		if test.status == http.StatusMethodNotAllowed && rec.Header().Get("Allow") != "DELETE, GET" {
+			t.Errorf("%v %v: expected Allow header DELETE, GET; got %v", test.method, test.path, rec.Header().Get("Allow"))
+		}
+	}
+}
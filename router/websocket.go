@@ -0,0 +1,208 @@
+package router
+
+import (
+	"bufio"
+	"crypto/sha1"
+	"encoding/base64"
+	"encoding/binary"
+	"errors"
+	"io"
+	"net"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+)
+
+/**
+ * Class websocket.go
+ * The server side of the WebSocket protocol (RFC 6455), enough for streaming messages to clients
+ * The handshake is answered and the connection is taken over from the server, without the read and write timeouts
+ * of the server. Text messages are written to the client, pings are answered, and the messages of the client are read
+ * and discarded until it closes the connection.
+ */
+
+// websocketGUID Appended to the key of the client to make the accept key of the handshake
+const websocketGUID = "258EAFA5-E914-47DA-95CA-C5AB0DC85B11"
+
+// Opcodes of WebSocket frames
+const (
+	opText  = 0x1
+	opClose = 0x8
+	opPing  = 0x9
+	opPong  = 0xA
+)
+
+// maxFramePayload The largest frame accepted from a client, the client only sends control frames worth reading
+const maxFramePayload = 1 << 16
+
+// writeWait How long a frame may take to write before the client is considered gone
+const writeWait = 10 * time.Second
+
+// WebSocket A connection upgraded to the WebSocket protocol
+type WebSocket struct {
+	conn   net.Conn
+	reader *bufio.Reader
+	mutex  sync.Mutex // Frames are written whole by one writer at a time
+}
+
+// IsWebSocket Checks if the request asks to upgrade to the WebSocket protocol
+func IsWebSocket(r *http.Request) bool {
+	if !strings.EqualFold(r.Header.Get("Upgrade"), "websocket") {
+		return false
+	}
+	for _, option := range strings.Split(r.Header.Get("Connection"), ",") {
+		if strings.EqualFold(strings.TrimSpace(option), "upgrade") {
+			return true
+		}
+	}
+	return false
+}
+
+// Upgrade Answers the handshake of the request and takes over its connection. The request is answered with an error
+// if it is not a valid handshake
+func Upgrade(w http.ResponseWriter, r *http.Request) (*WebSocket, error) {
+	if r.Method != http.MethodGet || !IsWebSocket(r) {
+		Error(w, "Expected a WebSocket handshake", http.StatusBadRequest)
+		return nil, errors.New("the request is not a WebSocket handshake")
+	}
+	if r.Header.Get("Sec-WebSocket-Version") != "13" {
+		w.Header().Set("Sec-WebSocket-Version", "13")
+		Error(w, "Only version 13 of the WebSocket protocol is supported", http.StatusUpgradeRequired)
+		return nil, errors.New("unsupported WebSocket version " + r.Header.Get("Sec-WebSocket-Version"))
+	}
+	key := r.Header.Get("Sec-WebSocket-Key")
+	if key == "" {
+		Error(w, "The WebSocket handshake has no key", http.StatusBadRequest)
+		return nil, errors.New("the WebSocket handshake has no key")
+	}
+	hijacker, ok := w.(http.Hijacker)
+	if !ok {
+		Error(w, "WebSockets are not supported by this server", http.StatusInternalServerError)
+		return nil, errors.New("the response writer can not be hijacked")
+	}
+
+	conn, buffered, err := hijacker.Hijack()
+	if err != nil {
+		return nil, err
+	}
+	// The connection is no longer the server's, neither are its deadlines
+	_ = conn.SetDeadline(time.Time{})
+	accept := sha1.Sum([]byte(key + websocketGUID))
+	_, err = buffered.WriteString("HTTP/1.1 101 Switching Protocols\r\nUpgrade: websocket\r\nConnection: Upgrade\r\n" +
+		"Sec-WebSocket-Accept: " + base64.StdEncoding.EncodeToString(accept[:]) + "\r\n\r\n")
+	if err == nil {
+		err = buffered.Flush()
+	}
+	if err != nil {
+		conn.Close()
+		return nil, err
+	}
+	return &WebSocket{conn: conn, reader: buffered.Reader}, nil
+}
+
+// WriteText Writes the message to the client as a text frame
+func (ws *WebSocket) WriteText(message []byte) error {
+	return ws.writeFrame(opText, message)
+}
+
+// Ping Pings the client, which answers with a pong while it is connected
+func (ws *WebSocket) Ping() error {
+	return ws.writeFrame(opPing, nil)
+}
+
+// Close Closes the connection normally, the client is told it is closed if it is still connected
+func (ws *WebSocket) Close() error {
+	_ = ws.writeFrame(opClose, []byte{0x03, 0xE8})
+	return ws.conn.Close()
+}
+
+// Listen Reads the frames of the client until it closes the connection, answering its pings. Returns nil when the
+// client closes the connection, and the error otherwise
+func (ws *WebSocket) Listen() error {
+	for {
+		opcode, payload, err := ws.readFrame()
+		if err != nil {
+			return err
+		}
+		switch opcode {
+		case opClose:
+			if len(payload) > 2 {
+				payload = payload[:2]
+			}
+			_ = ws.writeFrame(opClose, payload)
+			return nil
+		case opPing:
+			if err := ws.writeFrame(opPong, payload); err != nil {
+				return err
+			}
+		}
+	}
+}
+
+// writeFrame Writes a single unmasked frame, as frames from servers are
+func (ws *WebSocket) writeFrame(opcode byte, payload []byte) error {
+	header := []byte{0x80 | opcode}
+	switch length := len(payload); {
+	case length < 126:
+		header = append(header, byte(length))
+	case length <= 0xFFFF:
+		header = append(header, 126, 0, 0)
+		binary.BigEndian.PutUint16(header[2:], uint16(length))
+	default:
+		header = append(header, 127, 0, 0, 0, 0, 0, 0, 0, 0)
+		binary.BigEndian.PutUint64(header[2:], uint64(length))
+	}
+
+	ws.mutex.Lock()
+	defer ws.mutex.Unlock()
+	_ = ws.conn.SetWriteDeadline(time.Now().Add(writeWait))
+	if _, err := ws.conn.Write(append(header, payload...)); err != nil {
+		return err
+	}
+	return nil
+}
+
+// readFrame Reads a frame of the client and unmasks its payload, frames from clients have to be masked
+func (ws *WebSocket) readFrame() (byte, []byte, error) {
+	header := make([]byte, 2)
+	if _, err := io.ReadFull(ws.reader, header); err != nil {
+		return 0, nil, err
+	}
+	opcode := header[0] & 0x0F
+	if header[1]&0x80 == 0 {
+		return 0, nil, errors.New("the client sent an unmasked frame")
+	}
+
+	length := uint64(header[1] & 0x7F)
+	switch length {
+	case 126:
+		extended := make([]byte, 2)
+		if _, err := io.ReadFull(ws.reader, extended); err != nil {
+			return 0, nil, err
+		}
+		length = uint64(binary.BigEndian.Uint16(extended))
+	case 127:
+		extended := make([]byte, 8)
+		if _, err := io.ReadFull(ws.reader, extended); err != nil {
+			return 0, nil, err
+		}
+		length = binary.BigEndian.Uint64(extended)
+	}
+	if length > maxFramePayload {
+		return 0, nil, errors.New("the client sent a frame larger than the limit")
+	}
+
+	mask := make([]byte, 4)
+	if _, err := io.ReadFull(ws.reader, mask); err != nil {
+		return 0, nil, err
+	}
+	payload := make([]byte, length)
+	if _, err := io.ReadFull(ws.reader, payload); err != nil {
+		return 0, nil, err
+	}
+	for i := range payload {
+		payload[i] ^= mask[i%4]
+	}
+	return opcode, payload, nil
+}
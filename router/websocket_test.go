@@ -0,0 +1,89 @@
+package router
+
+import (
+	"bufio"
+	"bytes"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+// clientFrame A masked frame as sent by clients
+func clientFrame(opcode byte, payload []byte) []byte {
+	mask := []byte{1, 2, 3, 4}
+	frame := []byte{0x80 | opcode, 0x80 | byte(len(payload))}
+	frame = append(frame, mask...)
+	for i, b := range payload {
+		frame = append(frame, b^mask[i%4])
+	}
+	return frame
+}
+
+// serverFrame Reads an unmasked frame with a short payload
+func serverFrame(t *testing.T, reader *bufio.Reader) (byte, []byte) {
+	header := make([]byte, 2)
+	if _, err := io.ReadFull(reader, header); err != nil {
+		t.Fatalf("Unable to read a frame: %v", err)
+	}
+	payload := make([]byte, header[1]&0x7F)
+	if _, err := io.ReadFull(reader, payload); err != nil {
+		t.Fatalf("Unable to read the payload of a frame: %v", err)
+	}
+	return header[0] & 0x0F, payload
+}
+
+func TestWebSocket(t *testing.T) {
+	listened := make(chan error, 1)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		ws, err := Upgrade(w, r)
+		if err != nil {
+			listened <- err
+			return
+		}
+		defer ws.Close()
+		_ = ws.WriteText([]byte("hello"))
+		listened <- ws.Listen()
+	}))
+	defer server.Close()
+
+	// Requests which are not handshakes are refused
+	res, err := http.Get(server.URL)
+	if err != nil || res.StatusCode != http.StatusBadRequest {
+		t.Fatalf("Expected a request without a handshake to be refused; got %v (%v)", res, err)
+	}
+	<-listened
+
+	conn, err := net.Dial("tcp", strings.TrimPrefix(server.URL, "http://"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+	_ = conn.SetDeadline(time.Now().Add(5 * time.Second))
+	_, _ = conn.Write([]byte("GET / HTTP/1.1\r\nHost: test\r\nUpgrade: websocket\r\nConnection: keep-alive, Upgrade\r\n" +
+		"Sec-WebSocket-Key: dGhlIHNhbXBsZSBub25jZQ==\r\nSec-WebSocket-Version: 13\r\n\r\n"))
+	reader := bufio.NewReader(conn)
+	handshake, err := http.ReadResponse(reader, nil)
+	if err != nil || handshake.StatusCode != http.StatusSwitchingProtocols ||
+		handshake.Header.Get("Sec-WebSocket-Accept") != "s3pPLMBiTxaQ9kYGzzhZRbK+xOo=" {
+		t.Fatalf("Expected the handshake to be accepted with the key from RFC 6455; got %v (%v)", handshake, err)
+	}
+
+	if opcode, payload := serverFrame(t, reader); opcode != opText || string(payload) != "hello" {
+		t.Errorf("Expected the text message hello; got opcode %v with %q", opcode, payload)
+	}
+	_, _ = conn.Write(clientFrame(opPing, []byte("are you there")))
+	if opcode, payload := serverFrame(t, reader); opcode != opPong || string(payload) != "are you there" {
+		t.Errorf("Expected the ping to be answered; got opcode %v with %q", opcode, payload)
+	}
+	_, _ = conn.Write(clientFrame(opClose, []byte{0x03, 0xE8}))
+	if opcode, payload := serverFrame(t, reader); opcode != opClose || !bytes.Equal(payload, []byte{0x03, 0xE8}) {
+		t.Errorf("Expected the close to be echoed; got opcode %v with %v", opcode, payload)
+	}
+	if err := <-listened; err != nil {
+		t.Errorf("Expected the client to close the connection normally; got %v", err)
+	}
+}
@@ -1,6 +1,8 @@
 package structs
 
 import (
+	"cloudproject/utils"
+	"encoding/json"
 	"time"
 )
 
@@ -17,7 +19,8 @@ type GeoLocation struct {
 
 type Charger struct {
 	Results []struct {
-		Poi struct {
+		Dist float64 `json:"dist"`
+		Poi  struct {
 			Name  string `json:"name"`
 			Phone string `json:"phone"`
 		} `json:"poi,omitempty"`
@@ -35,7 +38,8 @@ type Charger struct {
 
 type Petrol struct {
 	Results []struct {
-		Poi struct {
+		Dist float64 `json:"dist"`
+		Poi  struct {
 			Name   string `json:"name"`
 			Brands []struct {
 				Name string `json:"name"`
@@ -47,23 +51,40 @@ type Petrol struct {
 	} `json:"results"`
 }
 
-type BboxStruct struct {
-	Bbox []float64 `json:"bbox"`
+type Incidents struct {
+	Incidents []Incident `json:"incidents"`
 }
 
-type Incidents struct {
-	Incidents []struct {
-		Type       string `json:"type"`
-		Properties struct {
-			StartTime time.Time `json:"startTime"`
-			EndTime   time.Time `json:"endTime"`
-			From      string    `json:"from"`
-			To        string    `json:"to"`
-			Events    []struct {
-				Description string `json:"description"`
-			} `json:"events"`
-		} `json:"properties"`
-	} `json:"incidents"`
+// Incident A traffic incident from TomTom, its geometry is a point or a line of longitudes and latitudes
+type Incident struct {
+	Type     string `json:"type"`
+	Geometry struct {
+		Type        string          `json:"type"`
+		Coordinates json.RawMessage `json:"coordinates"`
+	} `json:"geometry"`
+	Properties struct {
+		ID               string           `json:"id"`
+		IconCategory     int              `json:"iconCategory"`
+		StartTime        time.Time        `json:"startTime"`
+		EndTime          time.Time        `json:"endTime"`
+		From             string           `json:"from"`
+		To               string           `json:"to"`
+		MagnitudeOfDelay int              `json:"magnitudeOfDelay"`
+		Delay            int              `json:"delay"`
+		Length           float64          `json:"length"`
+		RoadNumbers      []string         `json:"roadNumbers"`
+		Aci              *IncidentReports `json:"aci"`
+		Events           []struct {
+			Description string `json:"description"`
+		} `json:"events"`
+	} `json:"properties"`
+}
+
+// IncidentReports How probable a traffic incident is, from the reports of drivers (aci in TomTom)
+type IncidentReports struct {
+	ProbabilityOfOccurrence string    `json:"probabilityOfOccurrence" description:"certain, probable, risk_of or improbable"`
+	NumberOfReports         int       `json:"numberOfReports"`
+	LastReportTime          time.Time `json:"lastReportTime"`
 }
 
 // WeatherData Used to store weather related data from the API, in the format the API returns the data
@@ -85,6 +106,7 @@ type WeatherData struct {
 	Wind       struct {
 		Speed float64 `json:"speed"`
 		Deg   int     `json:"deg"`
+		Gust  float64 `json:"gust"`
 	} `json:"wind"`
 	Snow struct {
 		OneH   float64 `json:"1h"`
@@ -98,28 +120,93 @@ type WeatherData struct {
 		Sunrise int `json:"sunrise"`
 		Sunset  int `json:"sunset"`
 	} `json:"sys"`
+	Timezone int `json:"timezone"` // Offset to UTC in seconds
+}
+
+// ForecastData Used to store the hourly and daily forecasts from the One Call API, in the format the API returns the data
+type ForecastData struct {
+	Timezone       string `json:"timezone"`
+	TimezoneOffset int    `json:"timezone_offset"`
+	Hourly         []struct {
+		Dt         int64   `json:"dt"`
+		Temp       float64 `json:"temp"`
+		FeelsLike  float64 `json:"feels_like"`
+		Humidity   int     `json:"humidity"`
+		Visibility int     `json:"visibility"`
+		WindSpeed  float64 `json:"wind_speed"`
+		WindDeg    int     `json:"wind_deg"`
+		WindGust   float64 `json:"wind_gust"`
+		Weather    []struct {
+			ID          int    `json:"id"`
+			Main        string `json:"main"`
+			Description string `json:"description"`
+		} `json:"weather"`
+		Pop  float64 `json:"pop"`
+		Rain struct {
+			OneH float64 `json:"1h"`
+		} `json:"rain"`
+		Snow struct {
+			OneH float64 `json:"1h"`
+		} `json:"snow"`
+	} `json:"hourly"`
+	Daily []struct {
+		Dt      int64 `json:"dt"`
+		Sunrise int   `json:"sunrise"`
+		Sunset  int   `json:"sunset"`
+		Temp    struct {
+			Day float64 `json:"day"`
+			Min float64 `json:"min"`
+			Max float64 `json:"max"`
+		} `json:"temp"`
+		FeelsLike struct {
+			Day float64 `json:"day"`
+		} `json:"feels_like"`
+		Humidity  int     `json:"humidity"`
+		WindSpeed float64 `json:"wind_speed"`
+		WindDeg   int     `json:"wind_deg"`
+		WindGust  float64 `json:"wind_gust"`
+		Weather   []struct {
+			ID          int    `json:"id"`
+			Main        string `json:"main"`
+			Description string `json:"description"`
+		} `json:"weather"`
+		Pop  float64 `json:"pop"`
+		Rain float64 `json:"rain"` // Millimeters during the day
+		Snow float64 `json:"snow"` // Millimeters during the day
+	} `json:"daily"`
 }
 
 type RouteStruct struct {
 	FormatVersion string `json:"formatVersion"`
 	Routes        []struct {
 		Summary struct {
-			LengthInMeters      int       `json:"lengthInMeters"`
-			TravelTimeInSeconds int       `json:"travelTimeInSeconds"`
-			ArrivalTime         time.Time `json:"arrivalTime"`
+			LengthInMeters        int       `json:"lengthInMeters"`
+			TravelTimeInSeconds   int       `json:"travelTimeInSeconds"`
+			TrafficDelayInSeconds int       `json:"trafficDelayInSeconds"`
+			DepartureTime         time.Time `json:"departureTime"`
+			ArrivalTime           time.Time `json:"arrivalTime"`
 		} `json:"summary"`
 		Legs []struct {
 			Summary struct {
-				LengthInMeters int       `json:"lengthInMeters"`
-				ArrivalTime    time.Time `json:"arrivalTime"`
+				LengthInMeters      int       `json:"lengthInMeters"`
+				TravelTimeInSeconds int       `json:"travelTimeInSeconds"`
+				ArrivalTime         time.Time `json:"arrivalTime"`
 			} `json:"summary"`
+			Points []utils.Coordinate `json:"points"`
 		} `json:"legs"`
+		Sections []struct {
+			StartPointIndex int    `json:"startPointIndex"`
+			EndPointIndex   int    `json:"endPointIndex"`
+			SectionType     string `json:"sectionType"`
+		} `json:"sections"`
 		Guidance struct {
 			Instructions []struct {
-				Street       string   `json:"street,omitempty"`
-				Maneuver     string   `json:"maneuver"`
-				JunctionType string   `json:"junctionType,omitempty"`
-				RoadNumbers  []string `json:"roadNumbers,omitempty"`
+				Street              string   `json:"street,omitempty"`
+				Maneuver            string   `json:"maneuver"`
+				JunctionType        string   `json:"junctionType,omitempty"`
+				RoadNumbers         []string `json:"roadNumbers,omitempty"`
+				PointIndex          int      `json:"pointIndex"`
+				TravelTimeInSeconds int      `json:"travelTimeInSeconds"`
 			} `json:"instructions"`
 		} `json:"guidance"`
 	} `json:"routes"`
@@ -1,14 +1,17 @@
 package structs
 
 import (
+	"cloudproject/utils"
 	"time"
 )
 
 type OutputCharge struct {
-	Charger    string
-	Address    string
-	Phone      string
-	Connectors []Connectors
+	Charger      string       `json:"Charger"`
+	Address      string       `json:"Address"`
+	Phone        string       `json:"Phone"`
+	Connectors   []Connectors `json:"Connectors"`
+	Distance     float64      `json:"Distance"`
+	DistanceUnit string       `json:"DistanceUnit" description:"km or mi"`
 }
 
 type Connectors struct {
@@ -16,130 +19,601 @@ type Connectors struct {
 	RatedPowerKW  float64 `json:"ratedPowerKW"`
 }
 
+// Diagnostics Status codes of the third party APIs, the API version and the uptime in seconds
+type Diagnostics struct {
+	TomTom           string `json:"tomtom"`
+	OpenRouteService string `json:"openrouteservice"`
+	OpenWeatherMap   string `json:"openweathermap"`
+	MapQuest         string `json:"mapquest"`
+	Version          string `json:"version"`
+	Uptime           int    `json:"uptime"`
+}
+
 type OutputPetrol struct {
-	StationName  string
-	StationBrand string
-	Address      string
+	StationName  string  `json:"StationName"`
+	StationBrand string  `json:"StationBrand"`
+	Address      string  `json:"Address"`
+	Distance     float64 `json:"Distance"`
+	DistanceUnit string  `json:"DistanceUnit" description:"km or mi"`
 }
 
+// OutIncident A traffic incident, incidents on a route tell where and when the route passes them
 type OutIncident struct {
-	Start time.Time
-	End   time.Time
-	From  string
-	To    string
-	Event string
+	ID               string             `json:"ID"`
+	Start            time.Time          `json:"Start"`
+	End              time.Time          `json:"End"`
+	From             string             `json:"From"`
+	To               string             `json:"To"`
+	Event            string             `json:"Event" description:"The first event of the incident, empty if it has none"`
+	Events           []string           `json:"Events"`
+	IconCategory     int                `json:"IconCategory" description:"The TomTom category of the incident"`
+	Category         string             `json:"Category" description:"The name of the category, such as accident, roadClosed or roadWorks"`
+	MagnitudeOfDelay int                `json:"MagnitudeOfDelay" description:"0 unknown, 1 minor, 2 moderate, 3 major, 4 undefined, which is used for closures"`
+	Severity         string             `json:"Severity" description:"The name of the magnitude of delay"`
+	ReportedDelay    int                `json:"ReportedDelay" description:"Seconds of delay TomTom reports, 0 if unknown"`
+	Length           int                `json:"Length" description:"Meters, or feet, of road the incident covers"`
+	RoadNumbers      []string           `json:"RoadNumbers"`
+	Aci              *IncidentReports   `json:"Aci,omitempty"`
+	Active           bool               `json:"Active" description:"Whether the incident has started and not ended"`
+	Geometry         []utils.Coordinate `json:"Geometry" description:"The point or line of the incident"`
+	Distance         float64            `json:"Distance" description:"Distance along the route to the incident"`
+	DistanceUnit     string             `json:"DistanceUnit,omitempty" description:"km or mi"`
+	Deviation        int                `json:"Deviation" description:"Meters, or feet, from the route to the incident"`
+	Delay            int                `json:"Delay" description:"Estimated minutes of delay, from the delay of the incident or else its magnitude"`
+	Passage          string             `json:"Passage,omitempty" description:"When the route is estimated to pass the incident"`
+	DuringPassage    bool               `json:"DuringPassage" description:"Whether the incident is expected to be there within 30 minutes of the passage"`
 }
 
 // OutputWeather Used to easily store and access only the wanted weather data and to add messages to the data
 type OutputWeather struct {
-	Main       MainStruct
-	Rain1h     float64
-	Snow1h     float64
-	Temp       TempStruct
-	FeelsLike  FeelsLikeStruct
-	TempMin    TempMinStruct
-	TempMax    TempMaxStruct
-	Humidity   HumidityStruct
-	Visibility VisibilityStruct
-	WindSpeed  WindSpeedStruct
-	WindDeg    WindDegStruct
-	Sunrise    SunriseStruct
-	Sunset     SunsetStruct
+	Main       MainStruct       `json:"Main"`
+	Rain1h     float64          `json:"Rain1h"`
+	Snow1h     float64          `json:"Snow1h"`
+	Temp       TempStruct       `json:"Temp"`
+	FeelsLike  FeelsLikeStruct  `json:"FeelsLike"`
+	TempMin    TempMinStruct    `json:"TempMin"`
+	TempMax    TempMaxStruct    `json:"TempMax"`
+	Humidity   HumidityStruct   `json:"Humidity"`
+	Visibility VisibilityStruct `json:"Visibility"`
+	WindSpeed  WindSpeedStruct  `json:"WindSpeed"`
+	WindDeg    WindDegStruct    `json:"WindDeg"`
+	WindGust   float64          `json:"WindGust"`
+	Sunrise    SunriseStruct    `json:"Sunrise"`
+	Sunset     SunsetStruct     `json:"Sunset"`
+	Conditions RoadConditions   `json:"Conditions"`
+	Units      Units            `json:"Units"`
+	Timezone   string           `json:"Timezone" description:"Time zone of the location"`
+}
+
+// Units The units of the measurements in a response
+type Units struct {
+	System        string `json:"system" description:"metric or imperial"`
+	Temperature   string `json:"temperature"`
+	Speed         string `json:"speed"`
+	Distance      string `json:"distance"`
+	Visibility    string `json:"visibility"`
+	Precipitation string `json:"precipitation"`
+}
+
+// RoadConditions The driving conditions assessed from the weather, which the messages and the delay are derived from
+type RoadConditions struct {
+	Precipitation          string       `json:"precipitation" description:"none, rain or snow"`
+	PrecipitationIntensity string       `json:"precipitationIntensity" description:"none, light, moderate, heavy or violent"`
+	FreezingRisk           string       `json:"freezingRisk" description:"none, possible or likely"`
+	Visibility             string       `json:"visibility" description:"good, moderate, poor or fog"`
+	WindRisk               string       `json:"windRisk" description:"low, moderate, high or severe, from the wind gusts"`
+	Daylight               bool         `json:"daylight"`
+	RiskScore              int          `json:"riskScore" description:"From 0 (no risk) to 100"`
+	DelayFactor            float64      `json:"delayFactor" description:"Multiplier of the extra travel time caused by the conditions"`
+	Reasons                []RiskReason `json:"reasons"`
+}
+
+// RiskReason A condition adding to the risk score
+type RiskReason struct {
+	Factor      string `json:"factor"`
+	Points      int    `json:"points"`
+	Description string `json:"description"`
+}
+
+// OutputForecast Hourly and daily forecasts for a place, the resolutions which are not requested are left out
+type OutputForecast struct {
+	Timezone string          `json:"timezone" description:"Time zone of the location, the times of the forecasts are in it"`
+	Hourly   []ForecastEntry `json:"hourly,omitempty"`
+	Daily    []ForecastEntry `json:"daily,omitempty"`
+}
+
+// ForecastEntry The forecasted weather for an hour or a day, with the same messages as the current weather
+type ForecastEntry struct {
+	Time                     time.Time     `json:"time"`
+	PrecipitationProbability float64       `json:"precipitationProbability"`
+	Weather                  OutputWeather `json:"weather"`
+}
+
+// ClimateStation A weather station with its climate normals, as stored in the database
+type ClimateStation struct {
+	ID        string         `json:"id"`
+	Name      string         `json:"name"`
+	Latitude  float64        `json:"latitude"`
+	Longitude float64        `json:"longitude"`
+	Elevation int            `json:"elevation"`
+	Months    []ClimateMonth `json:"months"`
+}
+
+// ClimateMonth The climate normals of a month at a station, in metric units
+type ClimateMonth struct {
+	Month             int     `json:"month"`
+	TempMean          float64 `json:"tempMean"`
+	TempMin           float64 `json:"tempMin"`
+	TempMax           float64 `json:"tempMax"`
+	Precipitation     float64 `json:"precipitation"`     // Total for the month in mm
+	PrecipitationDays float64 `json:"precipitationDays"` // Days with at least 1 mm
+	SnowDays          float64 `json:"snowDays"`          // Days with snowfall
+	Humidity          int     `json:"humidity"`
+	WindSpeed         float64 `json:"windSpeed"`
+}
+
+// OutputClimate The usual weather at a place in a date range, from the climate normals of the nearest station
+type OutputClimate struct {
+	Station                  ClimateStationInfo `json:"station"`
+	From                     string             `json:"from" description:"First day of the range"`
+	To                       string             `json:"to" description:"Last day of the range"`
+	TempMean                 float64            `json:"tempMean" description:"Typical daily mean temperature"`
+	TempMin                  float64            `json:"tempMin" description:"Typical daily minimum temperature"`
+	TempMax                  float64            `json:"tempMax" description:"Typical daily maximum temperature"`
+	PrecipitationProbability float64            `json:"precipitationProbability" description:"Chance of at least 1 mm of precipitation on a day, from 0 to 1"`
+	Precipitation            float64            `json:"precipitation" description:"Expected total precipitation in the range"`
+	SnowDays                 float64            `json:"snowDays" description:"Expected number of days with snowfall in the range"`
+	Weather                  OutputWeather      `json:"weather" description:"The typical day, with the same messages and road conditions as the current weather"`
+}
+
+// ClimateStationInfo The station the climate normals are from
+type ClimateStationInfo struct {
+	ID           string  `json:"id"`
+	Name         string  `json:"name"`
+	Latitude     float64 `json:"latitude"`
+	Longitude    float64 `json:"longitude"`
+	Elevation    int     `json:"elevation" description:"Meters above sea level"`
+	Distance     float64 `json:"distance" description:"Distance from the place to the station"`
+	DistanceUnit string  `json:"distanceUnit" description:"km or mi"`
+}
+
+// FerryRoute A ferry route of the imported timetable, as stored in the database
+type FerryRoute struct {
+	ID       string         `json:"id"`
+	Name     string         `json:"name"`
+	Agency   string         `json:"agency"`
+	Timezone string         `json:"timezone"`
+	Stops    []FerryStop    `json:"stops"`
+	Trips    []FerryTrip    `json:"trips"`
+	Services []FerryService `json:"services"`
+	// Fares The price of the crossing for each vehicle class, such as car, ev and trailer
+	Fares map[string]float64 `json:"fares"`
+}
+
+// TollPoint A toll station, as stored in the database
+type TollPoint struct {
+	ID        string             `json:"id"`
+	Name      string             `json:"name"`
+	Road      string             `json:"road"`
+	Latitude  float64            `json:"latitude"`
+	Longitude float64            `json:"longitude"`
+	Prices    map[string]float64 `json:"prices"` // The price of passing for each vehicle class
+}
+
+// FerryStop A quay of a ferry route
+type FerryStop struct {
+	ID        string  `json:"id"`
+	Name      string  `json:"name"`
+	Latitude  float64 `json:"latitude"`
+	Longitude float64 `json:"longitude"`
+}
+
+// FerryTrip A trip of a ferry route on the days of its service
+type FerryTrip struct {
+	Service string      `json:"service"`
+	Calls   []FerryCall `json:"calls"`
+}
+
+// FerryCall A stop of a trip, the times are in seconds after midnight of the service day and can pass 24 hours
+type FerryCall struct {
+	Stop      string `json:"stop"`
+	Arrival   int    `json:"arrival"`
+	Departure int    `json:"departure"`
+}
+
+// FerryService The days a service runs, the dates are formatted as YYYYMMDD
+type FerryService struct {
+	ID      string   `json:"id"`
+	Days    []bool   `json:"days"` // Indexed by the weekday, starting on Sunday
+	Start   string   `json:"start"`
+	End     string   `json:"end"`
+	Added   []string `json:"added"`
+	Removed []string `json:"removed"`
 }
 
 // MainStruct Used to add a message regarding the Main weather condition,
 // which is bound to that condition
 type MainStruct struct {
-	Main    string
-	Message string
+	Main    string `json:"Main"`
+	Message string `json:"Message"`
 }
 
 // TempStruct Used to add a message regarding the temperature,
 // which is bound to that temperature and changes with the temperature
 type TempStruct struct {
-	Temp    float64
-	Message string
+	Temp    float64 `json:"Temp"`
+	Message string  `json:"Message"`
 }
 
 // FeelsLikeStruct Used to add a message regarding the feels-like temperature,
 // which floats and changes with the feels-like temperature
 type FeelsLikeStruct struct {
-	FeelsLike float64
-	Message   string
+	FeelsLike float64 `json:"FeelsLike"`
+	Message   string  `json:"Message"`
 }
 
 // TempMinStruct Used to add a message regarding the minimum temperature,
 // and floats and changes with the minimum temperature
 type TempMinStruct struct {
-	TempMin float64
-	Message string
+	TempMin float64 `json:"TempMin"`
+	Message string  `json:"Message"`
 }
 
 // TempMaxStruct Used to add a message regarding the maximum temperature,
 // and floats and changes with the maximum temperature
 type TempMaxStruct struct {
-	TempMax float64
-	Message string
+	TempMax float64 `json:"TempMax"`
+	Message string  `json:"Message"`
 }
 
 // HumidityStruct Used to add message to the humidity value, which changes if the humidity changes
 type HumidityStruct struct {
-	Humidity int
-	Message  string
+	Humidity int    `json:"Humidity"`
+	Message  string `json:"Message"`
 }
 
 // VisibilityStruct Used to add message to the visibility value and changes if the visibility changes.
 type VisibilityStruct struct {
-	Visibility int
-	Message    string
+	Visibility int    `json:"Visibility"`
+	Message    string `json:"Message"`
 }
 
 // WindSpeedStruct Used to add message to the wind speed, the message will change if the wind speed
 // increase or decrease.
 type WindSpeedStruct struct {
-	WindSpeed float64
-	Message   string
+	WindSpeed float64 `json:"WindSpeed"`
+	Message   string  `json:"Message"`
 }
 
 // WindDegStruct Used to add a "floating" message to the wind deg.
 type WindDegStruct struct {
-	WindDeg int
-	Message string
+	WindDeg int    `json:"WindDeg"`
+	Message string `json:"Message"`
 }
 
 // SunriseStruct Used to add message to the sunrise time, adds human readable sunrise value as message.
 type SunriseStruct struct {
-	Sunrise int
-	Message string
+	Sunrise int       `json:"Sunrise"`
+	Local   time.Time `json:"Local" description:"The sunrise in the time zone of the location"`
+	Message string    `json:"Message"`
 }
 
 // SunsetStruct Used to add message to the sunset time, adds human readable sunset value as message.
 type SunsetStruct struct {
-	Sunset  int
-	Message string
+	Sunset  int       `json:"Sunset"`
+	Local   time.Time `json:"Local" description:"The sunset in the time zone of the location"`
+	Message string    `json:"Message"`
 }
 
 type Route struct {
-	Street       string
-	Maneuver     string
-	RoadNumber   string
-	JunctionType string
+	Street       string `json:"Street"`
+	Maneuver     string `json:"Maneuver"`
+	RoadNumber   string `json:"RoadNumber"`
+	JunctionType string `json:"JunctionType"`
 }
 
 type RoadInformation struct {
-	EstimatedArrival string
-	LengthKM         int
-	Route            []Route
+	Departure        string         `json:"Departure" description:"RFC3339 time the route is planned to start"`
+	EstimatedArrival string         `json:"EstimatedArrival" description:"RFC3339 time in the time zone of the destination"`
+	Length           float64        `json:"Length"`
+	DistanceUnit     string         `json:"DistanceUnit" description:"km or mi"`
+	Route            []Route        `json:"Route"`
+	Closures         []RouteClosure `json:"Closures" description:"Closures and convoy driving on the route, and those the route was changed to avoid"`
+	Ferries          []FerryLeg     `json:"Ferries" description:"The ferries on the route, the wait for them is included in the estimated arrival"`
+	Cost             RouteCost      `json:"Cost"`
+}
+
+// RouteAlternative One of the routes compared side by side
+type RouteAlternative struct {
+	RouteType        string  `json:"routeType" description:"fastest, shortest, eco or thrilling"`
+	Alternative      int     `json:"alternative" description:"0 for the route of the route type, from 1 for the alternatives to it"`
+	Departure        string  `json:"departure" description:"RFC3339 time the route is planned to start"`
+	EstimatedArrival string  `json:"estimatedArrival" description:"RFC3339 time in the time zone of the destination"`
+	TravelTime       int     `json:"travelTime" description:"Minutes, including the wait for ferries"`
+	Length           float64 `json:"length"`
+	DistanceUnit     string  `json:"distanceUnit" description:"km or mi"`
+	Tolls            int     `json:"tolls" description:"Number of toll stations passed"`
+	Ferries          int     `json:"ferries" description:"Number of ferry crossings"`
+	Closures         int     `json:"closures" description:"Closures and convoy driving still on the route"`
+	Cost             float64 `json:"cost" description:"Tolls, ferry fares and energy"`
+	Currency         string  `json:"currency"`
+}
+
+// DeparturePlan When to depart to arrive by a time, from the travel times predicted for departures around it
+type DeparturePlan struct {
+	ArriveBy    string               `json:"arriveBy" description:"RFC3339 time to arrive by"`
+	Departure   string               `json:"departure" description:"RFC3339 time recommended to depart at"`
+	Earliest    string               `json:"earliest" description:"RFC3339 time, the departure less the uncertainty"`
+	Latest      string               `json:"latest" description:"RFC3339 time, the departure plus the uncertainty"`
+	TravelTime  int                  `json:"travelTime" description:"Minutes expected from the recommended departure to the arrival"`
+	Uncertainty int                  `json:"uncertainty" description:"Minutes the travel time may differ by"`
+	Candidates  []DepartureCandidate `json:"candidates" description:"The departures the travel time was predicted for"`
+}
+
+// DepartureCandidate A departure the travel time was predicted for, and what the travel time is made up of
+type DepartureCandidate struct {
+	Departure    string          `json:"departure" description:"RFC3339 time"`
+	Arrival      string          `json:"arrival" description:"RFC3339 time in the time zone of the destination, including the delays"`
+	TravelTime   int             `json:"travelTime" description:"Minutes predicted in the traffic, including the delays"`
+	TrafficDelay int             `json:"trafficDelay" description:"Minutes of the travel time caused by the traffic"`
+	WeatherDelay int             `json:"weatherDelay" description:"Minutes added for the road conditions"`
+	FerryWait    int             `json:"ferryWait" description:"Minutes waiting for ferries"`
+	Forecast     bool            `json:"forecast" description:"Whether the weather is forecast for the departure and the arrival"`
+	Conditions   *RoadConditions `json:"conditions,omitempty" description:"The worse of the road conditions at the start and at the destination"`
+	Recommended  bool            `json:"recommended"`
+}
+
+// RouteCost What driving a route costs the vehicle, in the currency
+type RouteCost struct {
+	Vehicle  string     `json:"vehicle" description:"car, ev or trailer"`
+	Currency string     `json:"currency"`
+	Tolls    []CostItem `json:"tolls" description:"The toll stations passed"`
+	Ferries  []CostItem `json:"ferries" description:"The fares of the ferries with a known price"`
+	Energy   EnergyCost `json:"energy"`
+	Total    float64    `json:"total" description:"Tolls, ferry fares and energy"`
+}
+
+// CostItem A toll station or ferry crossing and its price
+type CostItem struct {
+	Name string  `json:"name"`
+	Road string  `json:"road,omitempty"`
+	Cost float64 `json:"cost"`
+}
+
+// EnergyCost The fuel or electricity used on a route and its cost
+type EnergyCost struct {
+	Consumption float64 `json:"consumption" description:"Liters or kWh per 100 km"`
+	Amount      float64 `json:"amount" description:"Liters of fuel or kWh of electricity used"`
+	Unit        string  `json:"unit" description:"l or kWh"`
+	Price       float64 `json:"price" description:"Price per liter or kWh"`
+	Cost        float64 `json:"cost"`
+}
+
+// RouteClosure A road closure on a route
+type RouteClosure struct {
+	ID          string `json:"id"`
+	Type        string `json:"type" description:"closed or convoy"`
+	Winter      bool   `json:"winter" description:"Closed for the winter"`
+	Road        string `json:"road"`
+	Description string `json:"description"`
+	Start       string `json:"start,omitempty" description:"RFC3339 time the closure starts, left out if it has started"`
+	End         string `json:"end,omitempty" description:"RFC3339 time the closure is expected to end, left out if it is not known"`
+	Avoided     bool   `json:"avoided" description:"The route was changed to avoid the closure"`
+}
+
+// FerryLeg A ferry crossing on a route, with the next departures after the arrival at the quay
+type FerryLeg struct {
+	Route       string           `json:"route" description:"Name of the ferry route"`
+	From        string           `json:"from" description:"The quay the ferry leaves from, empty if the crossing is not in the timetable"`
+	To          string           `json:"to" description:"The quay the ferry arrives at, empty if the crossing is not in the timetable"`
+	QuayArrival string           `json:"quayArrival" description:"RFC3339 time of the expected arrival at the quay"`
+	Wait        int              `json:"wait" description:"Minutes of waiting for the first departure"`
+	Fare        float64          `json:"fare" description:"Price of the crossing for the vehicle, 0 if it is not known"`
+	Departures  []FerryDeparture `json:"departures"`
+}
+
+// FerryDeparture A departure of a ferry from the timetable
+type FerryDeparture struct {
+	Departure string `json:"departure" description:"RFC3339 time in the time zone of the timetable"`
+	Arrival   string `json:"arrival" description:"RFC3339 time of the arrival at the other quay"`
 }
 
 type Webhook struct {
-	Id                  string
-	Url                 string
-	DepartureLocation   string
-	ArrivalDestination  string
-	Weather             string
-	ArrivalTime         string
-	EstimatedTravelTime int
+	Id                  string            `json:"Id"`
+	Url                 string            `json:"Url"`
+	DepartureLocation   string            `json:"DepartureLocation"`
+	ArrivalDestination  string            `json:"ArrivalDestination"`
+	Weather             string            `json:"Weather"`
+	Conditions          *RoadConditions   `json:"Conditions,omitempty"`
+	ArrivalTime         string            `json:"ArrivalTime"`
+	EstimatedTravelTime int               `json:"EstimatedTravelTime"`
+	Uncertainty         int               `json:"Uncertainty" description:"Minutes the estimated travel time may differ by"`
+	Stages              []string          `json:"Stages,omitempty" description:"Notifications to send: briefing the evening before, leave when it is time to depart and enroute for incidents while driving. Only leave if left out"`
+	Notified            string            `json:"Notified,omitempty" description:"RFC3339 time the notification to depart was sent"`
+	Briefed             string            `json:"Briefed,omitempty" description:"RFC3339 time the briefing was sent"`
+	Alerted             []string          `json:"Alerted,omitempty" description:"The incidents on the route alerted while driving"`
+	ETA                 string            `json:"ETA,omitempty" description:"RFC3339 estimated arrival last sent while driving"`
+	Templates           map[string]string `json:"Templates,omitempty" description:"Go text/template of the message of each stage, update or title, rendered with a NotificationData. The default message where left out"`
+	Locale              string            `json:"Locale,omitempty" description:"Language of the notifications, such as nb, en or de"`
+	Profile             string            `json:"Profile,omitempty" description:"Id of the vehicle profile the trip is driven with"`
+	Preferences         *RoutePreferences `json:"Preferences,omitempty" description:"How the route is planned, the arrival time is used instead of departAt and arriveAt"`
+	Trip                string            `json:"Trip,omitempty" description:"Id of the trip the webhook is a subscription to"`
+}
+
+// Trip A saved trip, which routes, weather, incidents, charging plans and webhooks can refer to by id
+type Trip struct {
+	ID          string           `json:"id"`
+	Name        string           `json:"name"`
+	Stops       []string         `json:"stops" description:"The places of the trip in order, from the start to the destination"`
+	Profile     string           `json:"profile,omitempty" description:"Id of the vehicle profile the trip is driven with"`
+	Preferences RoutePreferences `json:"preferences" description:"How the route is planned, the departure and arrival of the trip are used instead of departAt and arriveAt"`
+	Departure   string           `json:"departure,omitempty" description:"RFC3339 time the trip is planned to depart"`
+	Arrival     string           `json:"arrival,omitempty" description:"RFC3339 time the trip is planned to arrive, if the departure is not planned"`
+}
+
+// TripWeather The weather along the route of a trip
+type TripWeather struct {
+	Trip         string         `json:"trip"`
+	DistanceUnit string         `json:"distanceUnit" description:"km or mi"`
+	Weather      []RouteWeather `json:"weather" description:"At the stops, and at points between them"`
+}
+
+// RouteWeather The forecast at a stop or a point along a route, at the time the trip is expected to pass it
+type RouteWeather struct {
+	Place     string         `json:"place,omitempty" description:"The stop, left out for points between the stops"`
+	Latitude  float64        `json:"latitude"`
+	Longitude float64        `json:"longitude"`
+	Distance  float64        `json:"distance" description:"Distance along the route from the start"`
+	Time      string         `json:"time" description:"RFC3339 time the trip is expected to pass, without waiting for ferries"`
+	Forecast  *ForecastEntry `json:"forecast,omitempty" description:"The hourly, or else daily, forecast at the time. Left out if the time is beyond the forecast"`
+}
+
+// ChargingStop A place along a route to charge, with the charging stations around it
+type ChargingStop struct {
+	Latitude     float64        `json:"latitude"`
+	Longitude    float64        `json:"longitude"`
+	Distance     float64        `json:"distance" description:"Distance along the route from the start"`
+	DistanceUnit string         `json:"distanceUnit" description:"km or mi"`
+	Stations     []OutputCharge `json:"stations"`
+}
+
+// StopTime A stop of a trip with the time the trip is expected to pass it
+type StopTime struct {
+	Place    string  `json:"place"`
+	Distance float64 `json:"distance" description:"Kilometers along the route from the start"`
+	Time     string  `json:"time" description:"RFC3339 time the stop is expected to be passed"`
+}
+
+// TripSummary The read-only summary of a trip, shown through a share link
+type TripSummary struct {
+	Name       string     `json:"name"`
+	Departure  string     `json:"departure" description:"RFC3339 time to depart, estimated with the weather and ferries if a webhook subscribes to the trip"`
+	Arrival    string     `json:"arrival" description:"RFC3339 time of arrival"`
+	TravelTime int        `json:"travelTime" description:"Estimated travel time in minutes"`
+	Weather    string     `json:"weather,omitempty" description:"The weather advisory at the start"`
+	Stops      []StopTime `json:"stops"`
+	Expires    string     `json:"expires,omitempty" description:"RFC3339 time the share link expires"`
+}
+
+// TripBriefing What to know about a trip the evening before it, for the departure it is planned for
+type TripBriefing struct {
+	Departure        string         `json:"departure" description:"RFC3339 time"`
+	Arrival          string         `json:"arrival" description:"RFC3339 time, including the wait for ferries"`
+	DepartureWeather string         `json:"departureWeather,omitempty" description:"The advisory of the forecast at the start when departing"`
+	ArrivalWeather   string         `json:"arrivalWeather,omitempty" description:"The advisory of the forecast at the destination when arriving"`
+	Closures         []RouteClosure `json:"closures" description:"Closures and convoy driving still on the route"`
+	ChargingStops    []ChargingStop `json:"chargingStops" description:"Where to charge an electric or hybrid vehicle"`
+}
+
+// NotificationData What the templates of the notifications of a webhook are rendered with
+type NotificationData struct {
+	Stage       string              `json:"stage" description:"briefing, leave, enroute, update, eta or the stage of the title. Empty for events which are not notifications"`
+	Trip        NotificationTrip    `json:"trip"`
+	Departure   time.Time           `json:"departure" description:"Estimated time to depart, {{.Departure.Format \"15:04\"}} formats it"`
+	Arrival     time.Time           `json:"arrival" description:"Time to arrive by"`
+	TravelTime  int                 `json:"travelTime" description:"Estimated minutes, with the delays of the traffic, the weather and ferries"`
+	Uncertainty int                 `json:"uncertainty" description:"Minutes the estimated travel time may differ by"`
+	Shift       int                 `json:"shift" description:"Minutes the departure, or the estimated arrival for eta, has moved, negative if earlier. Only for update and eta"`
+	Weather     NotificationWeather `json:"weather"`
+	Incidents   []OutIncident       `json:"incidents" description:"The incident alerted. Only for enroute"`
+	Briefing    *TripBriefing       `json:"briefing,omitempty" description:"Only for briefing"`
+	Progress    *TripProgress       `json:"progress,omitempty" description:"The remaining route from the latest position checked in. Only for eta and position"`
+	Links       NotificationLinks   `json:"links"`
+}
+
+// NotificationTrip The trip a notification is about
+type NotificationTrip struct {
+	ID          string   `json:"id"`
+	Name        string   `json:"name"`
+	Start       string   `json:"start"`
+	Destination string   `json:"destination"`
+	Stops       []string `json:"stops"`
+}
+
+// NotificationWeather The weather at the destination of a notification
+type NotificationWeather struct {
+	Message    string         `json:"message" description:"The weather message of the webhook, in its locale"`
+	Conditions RoadConditions `json:"conditions" description:"The road conditions, with no risk if they are not known"`
+}
+
+// NotificationLinks Links to more about the trip of a notification
+type NotificationLinks struct {
+	Weather  string `json:"weather" description:"The weather at the destination"`
+	Trip     string `json:"trip"`
+	Calendar string `json:"calendar" description:"iCalendar feed of the trip of the webhook"`
+}
+
+// TripProgress The remaining route of a trip being driven, from the latest position checked in
+type TripProgress struct {
+	Trip           string           `json:"trip"`
+	Position       utils.Coordinate `json:"position"`
+	Time           string           `json:"time" description:"RFC3339 time the position was checked in"`
+	NextStop       string           `json:"nextStop"`
+	RemainingStops []string         `json:"remainingStops" description:"The stops left, the destination included"`
+	Distance       int              `json:"distance" description:"Meters left"`
+	TravelTime     int              `json:"travelTime" description:"Minutes left in the current traffic, with the wait for ferries"`
+	TrafficDelay   int              `json:"trafficDelay" description:"Minutes of the travel time caused by the traffic"`
+	ETA            string           `json:"eta" description:"RFC3339 estimated time of arrival at the destination"`
+	PlannedArrival string           `json:"plannedArrival,omitempty" description:"RFC3339 time the trip is planned to arrive"`
+	Delay          *int             `json:"delay,omitempty" description:"Minutes the estimated arrival is after the planned arrival, negative if before. Left out without a planned arrival"`
+	Deviation      int              `json:"deviation" description:"Meters from the route given at the previous check-in"`
+	OffRoute       bool             `json:"offRoute" description:"The position is further than the deviation threshold from the route, the remaining route is planned from it"`
+	Arrived        bool             `json:"arrived" description:"The position is at the destination"`
+}
+
+// TripEvent A live update of a trip, streamed with the data the notifications of its webhooks are rendered with
+type TripEvent struct {
+	ID      int64            `json:"id" description:"Increases with each event, a client reconnecting from the last id it got receives the events it missed"`
+	Type    string           `json:"type" description:"weather, incident, departure, position or delivery"`
+	Trip    string           `json:"trip"`
+	Webhook string           `json:"webhook,omitempty" description:"The webhook of the trip the event is about, left out for incidents and positions"`
+	Time    string           `json:"time" description:"RFC3339 time of the event"`
+	Text    string           `json:"text,omitempty" description:"The message delivered to the webhook. Only for delivery"`
+	Status  int              `json:"status,omitempty" description:"Status code the webhook answered with, left out if it could not be reached. Only for delivery"`
+	Data    NotificationData `json:"data"`
+}
+
+// TemplatePreview A notification template to render against sample data
+type TemplatePreview struct {
+	Stage    string `json:"stage" description:"briefing, leave, enroute, update, eta or title, the sample data is of the stage"`
+	Template string `json:"template" description:"Go text/template rendered with a NotificationData"`
+}
+
+// RenderedTemplate A notification template rendered against sample data
+type RenderedTemplate struct {
+	Stage string `json:"stage"`
+	Text  string `json:"text"`
+}
+
+// ShareLink Signed links to the read-only summary and the calendar of a trip, valid until they expire
+type ShareLink struct {
+	Url      string `json:"url"`
+	Calendar string `json:"calendar" description:"iCalendar feed of the trip, with the same signature"`
+	Expires  string `json:"expires" description:"RFC3339 time the links expire"`
+}
+
+// RoutePreferences How a route is planned
+type RoutePreferences struct {
+	Avoid     []string `json:"avoid" description:"tolls, ferries, motorways, unpaved, closures or convoys. Unpaved roads if it is left out"`
+	RouteType string   `json:"routeType,omitempty" description:"fastest, shortest, eco or thrilling. fastest if it is left out"`
+	Traffic   bool     `json:"traffic" description:"Plan for the current traffic, not only the usual traffic"`
+	DepartAt  string   `json:"departAt,omitempty" description:"RFC3339 time to depart at"`
+	ArriveAt  string   `json:"arriveAt,omitempty" description:"RFC3339 time to arrive by"`
+}
+
+// VehicleProfile A stored vehicle, which the routes, searches and costs are adapted to
+type VehicleProfile struct {
+	ID          string   `json:"id"`
+	Name        string   `json:"name"`
+	Fuel        string   `json:"fuel" description:"petrol, diesel, electric or hybrid"`
+	Length      float64  `json:"length" description:"Meters, including the trailer, 0 if it is not known"`
+	Width       float64  `json:"width" description:"Meters, 0 if it is not known"`
+	Height      float64  `json:"height" description:"Meters, 0 if it is not known"`
+	Weight      int      `json:"weight" description:"Kilograms, including the trailer, 0 if it is not known"`
+	Trailer     bool     `json:"trailer" description:"Towing a trailer or caravan"`
+	Connectors  []string `json:"connectors" description:"Connector types the vehicle can charge with"`
+	Consumption float64  `json:"consumption" description:"Liters of fuel, or kWh for an electric vehicle, per 100 km. 0 for the usual consumption"`
+	Range       int      `json:"range" description:"Kilometers on a full tank or battery, 0 if it is not known"`
 }
 
 type NotificationInput struct {
@@ -151,9 +625,11 @@ type NotificationResponse struct {
 }
 
 type OutputPoi struct {
-	Name        string
-	PhoneNumber string
-	Address     string
+	Name         string  `json:"Name"`
+	PhoneNumber  string  `json:"PhoneNumber"`
+	Address      string  `json:"Address"`
+	Distance     float64 `json:"Distance"`
+	DistanceUnit string  `json:"DistanceUnit" description:"km or mi"`
 }
 
 type LocationLonLat struct {
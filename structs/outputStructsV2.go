@@ -0,0 +1,196 @@
+package structs
+
+import (
+	"cloudproject/utils"
+	"time"
+)
+
+// The v2 responses carry the same data as the v1 structs they are converted from, with the field names in camelCase.
+// The v1 structs keep the field names Go exports, which v1 clients read.
+
+// OutputWeatherV2 The current weather of OutputWeather, with the field names in camelCase
+type OutputWeatherV2 struct {
+	Main       MainStructV2       `json:"main"`
+	Rain1h     float64            `json:"rain1h"`
+	Snow1h     float64            `json:"snow1h"`
+	Temp       TempStructV2       `json:"temp"`
+	FeelsLike  FeelsLikeStructV2  `json:"feelsLike"`
+	TempMin    TempMinStructV2    `json:"tempMin"`
+	TempMax    TempMaxStructV2    `json:"tempMax"`
+	Humidity   HumidityStructV2   `json:"humidity"`
+	Visibility VisibilityStructV2 `json:"visibility"`
+	WindSpeed  WindSpeedStructV2  `json:"windSpeed"`
+	WindDeg    WindDegStructV2    `json:"windDeg"`
+	WindGust   float64            `json:"windGust"`
+	Sunrise    SunriseStructV2    `json:"sunrise"`
+	Sunset     SunsetStructV2     `json:"sunset"`
+	Conditions RoadConditions     `json:"conditions"`
+	Units      Units              `json:"units"`
+	Timezone   string             `json:"timezone" description:"Time zone of the location"`
+}
+
+// MainStructV2 The main weather condition of MainStruct
+type MainStructV2 struct {
+	Main    string `json:"main"`
+	Message string `json:"message"`
+}
+
+// TempStructV2 The temperature of TempStruct
+type TempStructV2 struct {
+	Temp    float64 `json:"temp"`
+	Message string  `json:"message"`
+}
+
+// FeelsLikeStructV2 The feels-like temperature of FeelsLikeStruct
+type FeelsLikeStructV2 struct {
+	FeelsLike float64 `json:"feelsLike"`
+	Message   string  `json:"message"`
+}
+
+// TempMinStructV2 The minimum temperature of TempMinStruct
+type TempMinStructV2 struct {
+	TempMin float64 `json:"tempMin"`
+	Message string  `json:"message"`
+}
+
+// TempMaxStructV2 The maximum temperature of TempMaxStruct
+type TempMaxStructV2 struct {
+	TempMax float64 `json:"tempMax"`
+	Message string  `json:"message"`
+}
+
+// HumidityStructV2 The humidity of HumidityStruct
+type HumidityStructV2 struct {
+	Humidity int    `json:"humidity"`
+	Message  string `json:"message"`
+}
+
+// VisibilityStructV2 The visibility of VisibilityStruct
+type VisibilityStructV2 struct {
+	Visibility int    `json:"visibility"`
+	Message    string `json:"message"`
+}
+
+// WindSpeedStructV2 The wind speed of WindSpeedStruct
+type WindSpeedStructV2 struct {
+	WindSpeed float64 `json:"windSpeed"`
+	Message   string  `json:"message"`
+}
+
+// WindDegStructV2 The wind direction of WindDegStruct
+type WindDegStructV2 struct {
+	WindDeg int    `json:"windDeg"`
+	Message string `json:"message"`
+}
+
+// SunriseStructV2 The sunrise of SunriseStruct
+type SunriseStructV2 struct {
+	Sunrise int       `json:"sunrise"`
+	Local   time.Time `json:"local" description:"The sunrise in the time zone of the location"`
+	Message string    `json:"message"`
+}
+
+// SunsetStructV2 The sunset of SunsetStruct
+type SunsetStructV2 struct {
+	Sunset  int       `json:"sunset"`
+	Local   time.Time `json:"local" description:"The sunset in the time zone of the location"`
+	Message string    `json:"message"`
+}
+
+// OutputPoiV2 A point of interest of OutputPoi, with the field names in camelCase
+type OutputPoiV2 struct {
+	Name         string  `json:"name"`
+	PhoneNumber  string  `json:"phoneNumber"`
+	Address      string  `json:"address"`
+	Distance     float64 `json:"distance"`
+	DistanceUnit string  `json:"distanceUnit" description:"km or mi"`
+}
+
+// OutputChargeV2 A charging station of OutputCharge, with the field names in camelCase
+type OutputChargeV2 struct {
+	Charger      string       `json:"charger"`
+	Address      string       `json:"address"`
+	Phone        string       `json:"phone"`
+	Connectors   []Connectors `json:"connectors"`
+	Distance     float64      `json:"distance"`
+	DistanceUnit string       `json:"distanceUnit" description:"km or mi"`
+}
+
+// OutputPetrolV2 A petrol station of OutputPetrol, with the field names in camelCase
+type OutputPetrolV2 struct {
+	StationName  string  `json:"stationName"`
+	StationBrand string  `json:"stationBrand"`
+	Address      string  `json:"address"`
+	Distance     float64 `json:"distance"`
+	DistanceUnit string  `json:"distanceUnit" description:"km or mi"`
+}
+
+// OutIncidentV2 A traffic incident of OutIncident, with the field names in camelCase
+type OutIncidentV2 struct {
+	ID               string             `json:"id"`
+	Start            time.Time          `json:"start"`
+	End              time.Time          `json:"end"`
+	From             string             `json:"from"`
+	To               string             `json:"to"`
+	Event            string             `json:"event" description:"The first event of the incident, empty if it has none"`
+	Events           []string           `json:"events"`
+	IconCategory     int                `json:"iconCategory" description:"The TomTom category of the incident"`
+	Category         string             `json:"category" description:"The name of the category, such as accident, roadClosed or roadWorks"`
+	MagnitudeOfDelay int                `json:"magnitudeOfDelay" description:"0 unknown, 1 minor, 2 moderate, 3 major, 4 undefined, which is used for closures"`
+	Severity         string             `json:"severity" description:"The name of the magnitude of delay"`
+	ReportedDelay    int                `json:"reportedDelay" description:"Seconds of delay TomTom reports, 0 if unknown"`
+	Length           int                `json:"length" description:"Meters, or feet, of road the incident covers"`
+	RoadNumbers      []string           `json:"roadNumbers"`
+	Aci              *IncidentReports   `json:"aci,omitempty"`
+	Active           bool               `json:"active" description:"Whether the incident has started and not ended"`
+	Geometry         []utils.Coordinate `json:"geometry" description:"The point or line of the incident"`
+	Distance         float64            `json:"distance" description:"Distance along the route to the incident"`
+	DistanceUnit     string             `json:"distanceUnit,omitempty" description:"km or mi"`
+	Deviation        int                `json:"deviation" description:"Meters, or feet, from the route to the incident"`
+	Delay            int                `json:"delay" description:"Estimated minutes of delay, from the delay of the incident or else its magnitude"`
+	Passage          string             `json:"passage,omitempty" description:"When the route is estimated to pass the incident"`
+	DuringPassage    bool               `json:"duringPassage" description:"Whether the incident is expected to be there within 30 minutes of the passage"`
+}
+
+// RoadInformationV2 The route of RoadInformation, with the field names in camelCase
+type RoadInformationV2 struct {
+	Departure        string         `json:"departure" description:"RFC3339 time the route is planned to start"`
+	EstimatedArrival string         `json:"estimatedArrival" description:"RFC3339 time in the time zone of the destination"`
+	Length           float64        `json:"length"`
+	DistanceUnit     string         `json:"distanceUnit" description:"km or mi"`
+	Route            []RouteV2      `json:"route"`
+	Closures         []RouteClosure `json:"closures" description:"Closures and convoy driving on the route, and those the route was changed to avoid"`
+	Ferries          []FerryLeg     `json:"ferries" description:"The ferries on the route, the wait for them is included in the estimated arrival"`
+	Cost             RouteCost      `json:"cost"`
+}
+
+// RouteV2 An instruction of Route, with the field names in camelCase
+type RouteV2 struct {
+	Street       string `json:"street"`
+	Maneuver     string `json:"maneuver"`
+	RoadNumber   string `json:"roadNumber"`
+	JunctionType string `json:"junctionType"`
+}
+
+// WebhookV2 A webhook of Webhook, with the field names in camelCase
+type WebhookV2 struct {
+	Id                  string            `json:"id"`
+	Url                 string            `json:"url"`
+	DepartureLocation   string            `json:"departureLocation"`
+	ArrivalDestination  string            `json:"arrivalDestination"`
+	Weather             string            `json:"weather"`
+	Conditions          *RoadConditions   `json:"conditions,omitempty"`
+	ArrivalTime         string            `json:"arrivalTime"`
+	EstimatedTravelTime int               `json:"estimatedTravelTime"`
+	Uncertainty         int               `json:"uncertainty" description:"Minutes the estimated travel time may differ by"`
+	Stages              []string          `json:"stages,omitempty" description:"Notifications to send: briefing the evening before, leave when it is time to depart and enroute for incidents while driving. Only leave if left out"`
+	Notified            string            `json:"notified,omitempty" description:"RFC3339 time the notification to depart was sent"`
+	Briefed             string            `json:"briefed,omitempty" description:"RFC3339 time the briefing was sent"`
+	Alerted             []string          `json:"alerted,omitempty" description:"The incidents on the route alerted while driving"`
+	ETA                 string            `json:"eta,omitempty" description:"RFC3339 estimated arrival last sent while driving"`
+	Templates           map[string]string `json:"templates,omitempty" description:"Go text/template of the message of each stage, update or title, rendered with a NotificationData. The default message where left out"`
+	Locale              string            `json:"locale,omitempty" description:"Language of the notifications, such as nb, en or de"`
+	Profile             string            `json:"profile,omitempty" description:"Id of the vehicle profile the trip is driven with"`
+	Preferences         *RoutePreferences `json:"preferences,omitempty" description:"How the route is planned, the arrival time is used instead of departAt and arriveAt"`
+	Trip                string            `json:"trip,omitempty" description:"Id of the trip the webhook is a subscription to"`
+}
@@ -0,0 +1,15 @@
+package utils
+
+// Base urls of the third party APIs, variables so they can be pointed at local fakes when testing
+
+// TomTomURL Base url of the TomTom API
+var TomTomURL = "https://api.tomtom.com"
+
+// MapQuestURL Base url of the Map Quest API
+var MapQuestURL = "https://www.mapquestapi.com"
+
+// OpenRouteServiceURL Base url of the Open Route Service API
+var OpenRouteServiceURL = "https://api.openrouteservice.org"
+
+// OpenWeatherMapURL Base url of the Openweathermap API
+var OpenWeatherMapURL = "https://api.openweathermap.org"
@@ -3,11 +3,19 @@ package utils
 import (
 	"errors"
 	"net/http"
-	"net/url"
 	"strconv"
-	"strings"
 )
 
+// Contains Checks if the value is in the list
+func Contains(list []string, value string) bool {
+	for _, item := range list {
+		if item == value {
+			return true
+		}
+	}
+	return false
+}
+
 // JsonUnmarshalErrorHandling Universal json unmarshalling error handler
 func JsonUnmarshalErrorHandling(err error) error {
 	errorString := "Unable to continue your request\n" +
@@ -51,23 +59,3 @@ func OpenRouteError(status int) error {
 	}
 	return errors.New("Error: " + strconv.Itoa(status) + "\n An unexpected error has occurred")
 }
-
-//Function to get all the filters from a url Query
-func GetOptionalFilter(url *url.URL) (map[string]string, error) {
-	var optionals = map[string]string{}
-	optional := strings.Split(url.RawQuery, "?") //Splits the url by '?'
-	if len(optional) != 0 && optional[0] != "" { //Checking if the user has passed a filter
-		for i := 0; i <= len(optional)-1; i++ {
-			nameOfFilter := strings.Split(optional[i], "=") //Separating the 'key' and 'value'
-			if len(nameOfFilter) == 2 {
-				valueName := nameOfFilter[1]   //Defining value
-				mapName := nameOfFilter[0]     //Defining key
-				optionals[mapName] = valueName //Adding in the map
-			} else {
-				return optionals, errors.New("Invalid format on filter\nMissing '=' in statement")
-			}
-		}
-		return optionals, nil
-	}
-	return nil, nil
-}
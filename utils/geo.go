@@ -0,0 +1,67 @@
+package utils
+
+import "math"
+
+// earthRadius Mean radius of the earth in meters
+const earthRadius = 6371000
+
+// Coordinate A position given in degrees
+type Coordinate struct {
+	Latitude  float64 `json:"latitude"`
+	Longitude float64 `json:"longitude"`
+}
+
+// Haversine The great-circle distance in meters between two coordinates given in degrees
+func Haversine(lat1 float64, lon1 float64, lat2 float64, lon2 float64) float64 {
+	dLat := toRadians(lat2 - lat1)
+	dLon := toRadians(lon2 - lon1)
+	a := math.Sin(dLat/2)*math.Sin(dLat/2) +
+		math.Cos(toRadians(lat1))*math.Cos(toRadians(lat2))*math.Sin(dLon/2)*math.Sin(dLon/2)
+	return 2 * earthRadius * math.Asin(math.Sqrt(a))
+}
+
+// DistanceToPath The shortest distance in meters from the point to a path of coordinates, such as the points of a route.
+// The segments are short enough to be treated as straight lines on a flat surface around the point
+func DistanceToPath(point Coordinate, path []Coordinate) float64 {
+	distance, _, _ := ClosestOnPath(point, path)
+	return distance
+}
+
+// ClosestOnPath The shortest distance in meters from the point to a path of coordinates, the index of the segment
+// starting at the closest position on the path, and how far along the segment from 0 to 1 the position is
+func ClosestOnPath(point Coordinate, path []Coordinate) (float64, int, float64) {
+	if len(path) == 0 {
+		return math.Inf(1), 0, 0
+	}
+	if len(path) == 1 {
+		return Haversine(point.Latitude, point.Longitude, path[0].Latitude, path[0].Longitude), 0, 0
+	}
+
+	// Projects the coordinates to meters east and north of the point
+	scale := math.Cos(toRadians(point.Latitude))
+	project := func(c Coordinate) (float64, float64) {
+		return toRadians(c.Longitude-point.Longitude) * scale * earthRadius, toRadians(c.Latitude-point.Latitude) * earthRadius
+	}
+
+	shortest, segment, along := math.Inf(1), 0, 0.0
+	for i := 1; i < len(path); i++ {
+		ax, ay := project(path[i-1])
+		bx, by := project(path[i])
+		dx, dy := bx-ax, by-ay
+
+		// The closest position on the segment to the point, which is at the origin
+		t := 0.0
+		if length := dx*dx + dy*dy; length > 0 {
+			t = math.Max(0, math.Min(1, -(ax*dx+ay*dy)/length))
+		}
+		if distance := math.Hypot(ax+t*dx, ay+t*dy); distance < shortest {
+			shortest, segment, along = distance, i-1, t
+		}
+	}
+	return shortest, segment, along
+}
+
+// toRadians Converts degrees to radians
+func toRadians(degrees float64) float64 {
+	return degrees * math.Pi / 180
+}
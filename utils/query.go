@@ -0,0 +1,258 @@
+package utils
+
+import (
+	"errors"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Parameter types supported in a QuerySchema
+const (
+	TypeString  = "string"
+	TypeInteger = "integer"
+	TypeNumber  = "number"
+	TypeBoolean = "boolean"
+	// TypeDateTime RFC3339 time, such as 2021-05-17T12:10:00+02:00
+	TypeDateTime = "date-time"
+	// TypeDate Calendar date, such as 2021-05-17
+	TypeDate = "date"
+)
+
+// DateLayout The layout of TypeDate values
+const DateLayout = "2006-01-02"
+
+// QueryParam Describes a query parameter an endpoint accepts
+type QueryParam struct {
+	Name        string            `json:"name"`
+	Type        string            `json:"type"`
+	Description string            `json:"description,omitempty"`
+	Default     string            `json:"default,omitempty"`
+	Minimum     *float64          `json:"minimum,omitempty"`
+	Maximum     *float64          `json:"maximum,omitempty"`
+	Enum        []string          `json:"enum,omitempty"`
+	Aliases     map[string]string `json:"aliases,omitempty"` // Alternative names for the enum values
+	Multiple    bool              `json:"multiple,omitempty"`
+	Required    bool              `json:"required,omitempty"`
+}
+
+// QuerySchema The query parameters an endpoint accepts, used both to validate requests and to list the parameters
+type QuerySchema []QueryParam
+
+// Query The validated values of a request, enum values are in their canonical form
+type Query map[string][]string
+
+// Bound Helper for setting the Minimum and Maximum of a QueryParam
+func Bound(value float64) *float64 {
+	return &value
+}
+
+// Parse Parses and validates the query of the url against the schema, and fills in the defaults.
+// Parameters may be repeated (?connector=type2&connector=chademo) or comma separated (?connector=type2,chademo)
+// if the parameter accepts multiple values
+func (schema QuerySchema) Parse(u *url.URL) (Query, error) {
+	values, err := url.ParseQuery(u.RawQuery)
+	if err != nil {
+		return nil, errors.New("Invalid format on query\n" + err.Error() + "\n" + schema.Describe())
+	}
+
+	for name := range values {
+		if _, found := schema.param(name); !found {
+			return nil, errors.New("error, Bad Request\nThe parameter '" + name + "' is not accepted\n" + schema.Describe())
+		}
+	}
+
+	query := Query{}
+	for _, param := range schema {
+		var raw []string
+		for _, value := range values[param.Name] {
+			if param.Multiple {
+				raw = append(raw, strings.Split(value, ",")...)
+			} else {
+				raw = append(raw, value)
+			}
+		}
+
+		if len(raw) == 0 || (len(raw) == 1 && raw[0] == "") {
+			if param.Required {
+				return nil, errors.New("error, Bad Request\nThe parameter '" + param.Name + "' is required\n" + schema.Describe())
+			}
+			if param.Default != "" && param.Multiple {
+				query[param.Name] = strings.Split(param.Default, ",")
+			} else if param.Default != "" {
+				query[param.Name] = []string{param.Default}
+			}
+			continue
+		}
+		if len(raw) > 1 && !param.Multiple {
+			return nil, errors.New("error, Bad Request\nThe parameter '" + param.Name + "' can only be given once")
+		}
+
+		for _, value := range raw {
+			value = strings.TrimSpace(value)
+			canonical, err := param.validate(value)
+			if err != nil {
+				return nil, err
+			}
+			query[param.Name] = append(query[param.Name], canonical)
+		}
+	}
+	return query, nil
+}
+
+// Describe Lists the accepted parameters in a human readable form, to be added to validation errors
+func (schema QuerySchema) Describe() string {
+	if len(schema) == 0 {
+		return "The endpoint does not accept any parameters"
+	}
+	var lines []string
+	for _, param := range schema {
+		line := param.Name + " (" + param.Type
+		if param.Minimum != nil || param.Maximum != nil {
+			line += ", " + formatBound(param.Minimum) + " to " + formatBound(param.Maximum)
+		}
+		if param.Default != "" {
+			line += ", default " + param.Default
+		}
+		if param.Multiple {
+			line += ", comma separated"
+		}
+		line += ")"
+		if len(param.Enum) != 0 {
+			line += ": one of " + strings.Join(param.Enum, ", ")
+		}
+		lines = append(lines, line)
+	}
+	return "Accepted parameters:\n" + strings.Join(lines, "\n")
+}
+
+// param Finds the parameter with the name in the schema
+func (schema QuerySchema) param(name string) (QueryParam, bool) {
+	for _, param := range schema {
+		if param.Name == name {
+			return param, true
+		}
+	}
+	return QueryParam{}, false
+}
+
+// validate Checks the value against the type, range and enum of the parameter, and returns its canonical form
+func (param QueryParam) validate(value string) (string, error) {
+	switch param.Type {
+	case TypeInteger, TypeNumber:
+		number, err := strconv.ParseFloat(value, 64)
+		if err != nil || (param.Type == TypeInteger && number != float64(int64(number))) {
+			return "", errors.New("Value of " + param.Name + " must be " + article(param.Type) + "\nTry again")
+		}
+		if (param.Minimum != nil && number < *param.Minimum) || (param.Maximum != nil && number > *param.Maximum) {
+			return "", errors.New("Value of " + param.Name + " must be between " + formatBound(param.Minimum) +
+				" and " + formatBound(param.Maximum) + "\nTry again")
+		}
+	case TypeBoolean:
+		boolean, err := strconv.ParseBool(value)
+		if err != nil {
+			return "", errors.New("Value of " + param.Name + " must be true or false\nTry again")
+		}
+		value = strconv.FormatBool(boolean)
+	case TypeDateTime:
+		if _, err := time.Parse(time.RFC3339, value); err != nil {
+			return "", errors.New("Value of " + param.Name + " must be a time in RFC3339 format, such as 2021-05-17T12:10:00+02:00\nTry again")
+		}
+	case TypeDate:
+		if _, err := time.Parse(DateLayout, value); err != nil {
+			return "", errors.New("Value of " + param.Name + " must be a date, such as 2021-05-17\nTry again")
+		}
+	}
+
+	if len(param.Enum) == 0 {
+		return value, nil
+	}
+	for _, allowed := range param.Enum {
+		if strings.EqualFold(value, allowed) {
+			return allowed, nil
+		}
+	}
+	for alias, allowed := range param.Aliases {
+		if strings.EqualFold(value, alias) {
+			return allowed, nil
+		}
+	}
+	return "", errors.New("Value '" + value + "' is not supported for " + param.Name + "\nSupported values: " + strings.Join(param.enumWithAliases(), ", "))
+}
+
+// enumWithAliases Lists the enum values and their aliases
+func (param QueryParam) enumWithAliases() []string {
+	values := append([]string{}, param.Enum...)
+	var aliases []string
+	for alias := range param.Aliases {
+		aliases = append(aliases, alias)
+	}
+	sort.Strings(aliases)
+	return append(values, aliases...)
+}
+
+// Get Returns the (first) value of the parameter, or "" if it is not set
+func (query Query) Get(name string) string {
+	if len(query[name]) == 0 {
+		return ""
+	}
+	return query[name][0]
+}
+
+// All Returns all values of the parameter
+func (query Query) All(name string) []string {
+	return query[name]
+}
+
+// Has Checks if the parameter is set, either by the request or by its default
+func (query Query) Has(name string) bool {
+	return len(query[name]) != 0
+}
+
+// Int Returns the value of an integer parameter, values are validated by Parse
+func (query Query) Int(name string) int {
+	number, _ := strconv.ParseFloat(query.Get(name), 64)
+	return int(number)
+}
+
+// Float Returns the value of a number parameter, values are validated by Parse
+func (query Query) Float(name string) float64 {
+	number, _ := strconv.ParseFloat(query.Get(name), 64)
+	return number
+}
+
+// Bool Returns the value of a boolean parameter, values are validated by Parse
+func (query Query) Bool(name string) bool {
+	boolean, _ := strconv.ParseBool(query.Get(name))
+	return boolean
+}
+
+// Time Returns the value of a date-time parameter, or the zero time if it is not set
+func (query Query) Time(name string) time.Time {
+	parsed, _ := time.Parse(time.RFC3339, query.Get(name))
+	return parsed
+}
+
+// Date Returns the value of a date parameter as midnight UTC, or the zero time if it is not set
+func (query Query) Date(name string) time.Time {
+	parsed, _ := time.Parse(DateLayout, query.Get(name))
+	return parsed
+}
+
+// formatBound Formats the minimum or maximum of a parameter
+func formatBound(bound *float64) string {
+	if bound == nil {
+		return "any"
+	}
+	return strconv.FormatFloat(*bound, 'f', -1, 64)
+}
+
+// article Puts 'a' or 'an' in front of the type name
+func article(typeName string) string {
+	if strings.IndexAny(typeName[:1], "aeiou") == 0 {
+		return "an " + typeName
+	}
+	return "a " + typeName
+}
@@ -0,0 +1,54 @@
+package utils
+
+import (
+	"net/url"
+	"reflect"
+	"testing"
+)
+
+func TestQuerySchemaParse(t *testing.T) {
+	schema := QuerySchema{
+		{Name: "radius", Type: TypeInteger, Default: "5000", Minimum: Bound(1), Maximum: Bound(50000)},
+		{Name: "power", Type: TypeNumber},
+		{Name: "connector", Type: TypeString, Enum: []string{"IEC62196Type2Outlet", "Chademo"},
+			Aliases: map[string]string{"type2": "IEC62196Type2Outlet"}, Multiple: true},
+		{Name: "from", Type: TypeDateTime},
+		{Name: "day", Type: TypeDate},
+	}
+
+	tests := []struct {
+		query    string
+		expected Query
+		fails    bool
+	}{
+		{"", Query{"radius": {"5000"}}, false},
+		{"radius=1000&power=50", Query{"radius": {"1000"}, "power": {"50"}}, false},
+		{"connector=type2,chademo&connector=Chademo", Query{"radius": {"5000"},
+			"connector": {"IEC62196Type2Outlet", "Chademo", "Chademo"}}, false},
+		{"connector=%63hademo", Query{"radius": {"5000"}, "connector": {"Chademo"}}, false},
+		{"connector=TYPE2,Type2", Query{"radius": {"5000"}, "connector": {"IEC62196Type2Outlet", "IEC62196Type2Outlet"}}, false},
+		{"from=2021-05-17T12:10:00%2B02:00", Query{"radius": {"5000"}, "from": {"2021-05-17T12:10:00+02:00"}}, false},
+		{"from=17 may 21 12:10 CEST", nil, true},
+		{"day=2021-04-01", Query{"radius": {"5000"}, "day": {"2021-04-01"}}, false},
+		{"day=2021-04-31", nil, true},
+		{"radius=100000", nil, true},
+		{"radius=1.5", nil, true},
+		{"radius=1&radius=2", nil, true},
+		{"connector=tesla", nil, true},
+		{"unknown=1", nil, true},
+	}
+	for _, test := range tests {
+		query, err := schema.Parse(&url.URL{RawQuery: test.query})
+		if test.fails {
+			if err == nil {
+				t.Errorf("%v: expected an error", test.query)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("%v: unexpected error %v", test.query, err)
+		} else if !reflect.DeepEqual(query, test.expected) {
+			t.Errorf("%v: expected %v; got %v", test.query, test.expected, query)
+		}
+	}
+}
@@ -0,0 +1,60 @@
+package utils
+
+import "math"
+
+// Unit systems of the responses, the values from the APIs are metric and converted when imperial units are requested
+const (
+	Metric   = "metric"
+	Imperial = "imperial"
+)
+
+// UnitsParam The query parameter for choosing the unit system, to be added to the query schema of endpoints with
+// measurements
+var UnitsParam = QueryParam{Name: "units", Type: TypeString, Enum: []string{Metric, Imperial}, Default: Metric,
+	Description: "Unit system of temperatures, speeds, distances and precipitation"}
+
+// Temperature Converts a temperature in degrees Celsius to the unit system
+func Temperature(celsius float64, system string) float64 {
+	if system == Imperial {
+		return Round(celsius*9/5+32, 2)
+	}
+	return celsius
+}
+
+// Speed Converts a speed in meters per second to the unit system, miles per hour for imperial
+func Speed(metersPerSecond float64, system string) float64 {
+	if system == Imperial {
+		return Round(metersPerSecond*3600/1609.344, 2)
+	}
+	return metersPerSecond
+}
+
+// Distance Converts a distance in meters to kilometers or miles, without rounding
+func Distance(meters float64, system string) float64 {
+	if system == Imperial {
+		return meters / 1609.344
+	}
+	return meters / 1000
+}
+
+// ShortDistance Converts a distance in meters to whole meters or feet, used for visibility
+func ShortDistance(meters int, system string) int {
+	if system == Imperial {
+		return int(math.Round(float64(meters) / 0.3048))
+	}
+	return meters
+}
+
+// Precipitation Converts an amount of precipitation in millimeters to the unit system, inches for imperial
+func Precipitation(millimeters float64, system string) float64 {
+	if system == Imperial {
+		return Round(millimeters/25.4, 3)
+	}
+	return millimeters
+}
+
+// Round Rounds the value to the number of decimals
+func Round(value float64, decimals int) float64 {
+	factor := math.Pow(10, float64(decimals))
+	return math.Round(value*factor) / factor
+}
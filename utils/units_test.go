@@ -0,0 +1,27 @@
+package utils
+
+import "testing"
+
+func TestUnits(t *testing.T) {
+	tests := []struct {
+		name     string
+		value    float64
+		expected float64
+	}{
+		{"metric temperature", Temperature(-2, Metric), -2},
+		{"imperial temperature", Temperature(-2, Imperial), 28.4},
+		{"metric speed", Speed(3.6, Metric), 3.6},
+		{"imperial speed", Speed(3.6, Imperial), 8.05},
+		{"metric distance", Distance(44567, Metric), 44.567},
+		{"imperial distance", Distance(1609.344, Imperial), 1},
+		{"metric visibility", float64(ShortDistance(4000, Metric)), 4000},
+		{"imperial visibility", float64(ShortDistance(4000, Imperial)), 13123},
+		{"metric precipitation", Precipitation(0.4, Metric), 0.4},
+		{"imperial precipitation", Precipitation(25.4, Imperial), 1},
+	}
+	for _, test := range tests {
+		if test.value != test.expected {
+			t.Errorf("%v: expected %v; got %v", test.name, test.expected, test.value)
+		}
+	}
+}
@@ -0,0 +1,166 @@
+package webhooks
+
+import (
+	"cloudproject/database"
+	"cloudproject/i18n"
+	"cloudproject/router"
+	"cloudproject/structs"
+	"cloudproject/utils"
+	"encoding/json"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+/**
+ * Class calendar.go
+ * iCalendar feeds of trips: the departure with the weather advisory, each stop between, and the arrival
+ * Calendar apps subscribing to a feed fetch it again every 30 minutes, the events keep their UID so they are updated
+ * when the estimated departure changes.
+ */
+
+// CalendarRefresh How often calendar apps are asked to fetch a feed again, as often as the webhooks are checked
+const CalendarRefresh = "PT30M"
+
+// calendarTime The format of the UTC times of a calendar
+const calendarTime = "20060102T150405Z"
+
+// CalendarQuery The query parameters accepted by the calendars of trips and webhooks
+var CalendarQuery = utils.QuerySchema{i18n.LangParam}
+
+// TripCalendar Responds with the calendar of the trip with the id in the path
+func TripCalendar(w http.ResponseWriter, r *http.Request) {
+	trip, found := tripOf(w, router.Param(r, "id"))
+	if !found {
+		return
+	}
+	writeCalendar(w, trip, nil, i18n.Negotiate(r))
+}
+
+// WebhookCalendar Responds with the calendar of the trip of the webhook with the id in the path, with the departure
+// estimated for the webhook
+func WebhookCalendar(w http.ResponseWriter, r *http.Request) {
+	id := router.Param(r, "id")
+	stored, err := database.Get(id)
+	if err != nil {
+		log.Println(err.Error())
+		router.Error(w, "No webhook registered with ID: "+id, http.StatusNotFound)
+		return
+	}
+	var hook structs.Webhook
+	if err = json.Unmarshal(stored, &hook); err != nil {
+		http.Error(w, utils.JsonUnmarshalErrorHandling(err).Error(), http.StatusInternalServerError)
+		return
+	}
+	trip, found := tripOf(w, hook.Trip)
+	if !found {
+		return
+	}
+	locale := i18n.Negotiate(r)
+	if !router.Query(r).Has("lang") {
+		locale = webhookLocale(hook)
+	}
+	writeCalendar(w, trip, &hook, locale)
+}
+
+// SharedCalendar Responds with the calendar of the trip of a share link
+func SharedCalendar(w http.ResponseWriter, r *http.Request) {
+	trip, _, valid := sharedTrip(w, r)
+	if !valid {
+		return
+	}
+	writeCalendar(w, trip, nil, i18n.Negotiate(r))
+}
+
+// writeCalendar Answers with the calendar of the trip, with the departure estimated for the webhook if it is given
+func writeCalendar(w http.ResponseWriter, trip structs.Trip, hook *structs.Webhook, locale string) {
+	summary, err := tripSummary(trip, hook, locale)
+	if err != nil {
+		log.Println("Unable to plan the calendar of the trip with ID: " + trip.ID + "\n" + err.Error())
+		http.Error(w, "Unable to plan the trip, try again", http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "text/calendar; charset=utf-8")
+	w.Header().Set("Content-Disposition", "inline; filename=\""+trip.ID+".ics\"")
+	w.Header().Set("Content-Language", locale)
+	w.Write([]byte(tripCalendar(trip.ID, summary, locale, time.Now())))
+}
+
+// tripCalendar The iCalendar feed of the summary of the trip, stamped at the time
+func tripCalendar(id string, summary structs.TripSummary, locale string, stamp time.Time) string {
+	lines := []string{
+		"BEGIN:VCALENDAR",
+		"VERSION:2.0",
+		"PRODID:-//The Road trip Companion//Trips//EN",
+		"CALSCALE:GREGORIAN",
+		"METHOD:PUBLISH",
+		"X-WR-CALNAME:" + calendarText(summary.Name),
+		"REFRESH-INTERVAL;VALUE=DURATION:" + CalendarRefresh,
+		"X-PUBLISHED-TTL:" + CalendarRefresh,
+	}
+
+	start, destination := summary.Stops[0], summary.Stops[len(summary.Stops)-1]
+	description := i18n.Text(locale, "calendar.travel", i18n.Args{"minutes": summary.TravelTime})
+	if summary.Weather != "" {
+		description = summary.Weather + "\n\n" + description
+	}
+	lines = append(lines, calendarEvent(id+"-departure", stamp, summary.Departure, summary.Arrival,
+		i18n.Text(locale, "calendar.departure", i18n.Args{"destination": destination.Place}), start.Place, description)...)
+	for i, stop := range summary.Stops[1 : len(summary.Stops)-1] {
+		lines = append(lines, calendarEvent(id+"-stop-"+strconv.Itoa(i+1), stamp, stop.Time, stop.Time,
+			i18n.Text(locale, "calendar.stop", i18n.Args{"place": stop.Place}), stop.Place, "")...)
+	}
+	lines = append(lines, calendarEvent(id+"-arrival", stamp, summary.Arrival, summary.Arrival,
+		i18n.Text(locale, "calendar.arrival", i18n.Args{"destination": destination.Place}), destination.Place, "")...)
+	lines = append(lines, "END:VCALENDAR")
+
+	var calendar strings.Builder
+	for _, line := range lines {
+		calendar.WriteString(foldLine(line) + "\r\n")
+	}
+	return calendar.String()
+}
+
+// calendarEvent The lines of an event from the start to the end, RFC3339 times. The uid is the same every time the
+// feed is fetched, so calendar apps update the event
+func calendarEvent(uid string, stamp time.Time, start string, end string, title string, location string, description string) []string {
+	from, _ := time.Parse(time.RFC3339, start)
+	to, _ := time.Parse(time.RFC3339, end)
+	lines := []string{
+		"BEGIN:VEVENT",
+		"UID:" + uid + "@roadtripcompanion",
+		"DTSTAMP:" + stamp.UTC().Format(calendarTime),
+		"DTSTART:" + from.UTC().Format(calendarTime),
+		"DTEND:" + to.UTC().Format(calendarTime),
+		"SUMMARY:" + calendarText(title),
+		"LOCATION:" + calendarText(location),
+	}
+	if description != "" {
+		lines = append(lines, "DESCRIPTION:"+calendarText(description))
+	}
+	return append(lines, "END:VEVENT")
+}
+
+// calendarText Escapes the text of a calendar value
+func calendarText(text string) string {
+	return strings.NewReplacer("\\", "\\\\", ";", "\\;", ",", "\\,", "\r\n", "\\n", "\n", "\\n").Replace(text)
+}
+
+// foldLine Folds a calendar line longer than 75 bytes onto continuation lines starting with a space, without
+// splitting characters
+func foldLine(line string) string {
+	var folded strings.Builder
+	length := 0
+	for _, character := range line {
+		size := len(string(character))
+		if length+size > 75 {
+			folded.WriteString("\r\n ")
+			length = 1
+		}
+		folded.WriteRune(character)
+		length += size
+	}
+	return folded.String()
+}
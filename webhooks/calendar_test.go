@@ -0,0 +1,66 @@
+package webhooks
+
+import (
+	"cloudproject/structs"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestCalendarText(t *testing.T) {
+	tests := map[string]string{
+		"Oslo":                   "Oslo",
+		"Cabin, weekend; family": "Cabin\\, weekend\\; family",
+		"Snow\n\nDrive slowly":   "Snow\\n\\nDrive slowly",
+		"C:\\trips":              "C:\\\\trips",
+	}
+	for text, expected := range tests {
+		if escaped := calendarText(text); escaped != expected {
+			t.Errorf("%q: expected %q; got %q", text, expected, escaped)
+		}
+	}
+}
+
+func TestFoldLine(t *testing.T) {
+	line := "DESCRIPTION:" + strings.Repeat("ø", 50)
+	folded := foldLine(line)
+	parts := strings.Split(folded, "\r\n")
+	if len(parts) != 2 {
+		t.Fatalf("Expected the line to be folded once; got %q", folded)
+	}
+	for _, part := range parts {
+		if len(part) > 75 || !strings.HasSuffix(part, "ø") {
+			t.Errorf("Expected lines of at most 75 bytes without split characters; got %q", part)
+		}
+	}
+	if !strings.HasPrefix(parts[1], " ") || strings.Replace(folded, "\r\n ", "", -1) != line {
+		t.Errorf("Expected the line to be unfolded to the original; got %q", folded)
+	}
+	if short := foldLine("SUMMARY:Oslo"); short != "SUMMARY:Oslo" {
+		t.Errorf("Expected a short line to be kept; got %q", short)
+	}
+}
+
+func TestTripCalendarStops(t *testing.T) {
+	summary := structs.TripSummary{Name: "Inland", Departure: "2021-05-17T08:00:00+02:00", Arrival: "2021-05-17T10:00:00+02:00",
+		TravelTime: 120, Stops: []structs.StopTime{
+			{Place: "gjøvik", Time: "2021-05-17T08:00:00+02:00"},
+			{Place: "oslo", Time: "2021-05-17T09:00:00+02:00"},
+			{Place: "lillehammer", Time: "2021-05-17T10:00:00+02:00"},
+		}}
+	calendar := tripCalendar("trip", summary, "en", time.Date(2021, 5, 10, 0, 0, 0, 0, time.UTC))
+
+	for _, line := range []string{
+		"UID:trip-departure@roadtripcompanion", "DTSTART:20210517T060000Z", "DTEND:20210517T080000Z",
+		"SUMMARY:Depart for lillehammer", "DESCRIPTION:Estimated travel time: 120 minutes",
+		"UID:trip-stop-1@roadtripcompanion", "DTSTART:20210517T070000Z", "SUMMARY:Stop at oslo",
+		"UID:trip-arrival@roadtripcompanion", "SUMMARY:Arrive at lillehammer", "DTSTAMP:20210510T000000Z",
+	} {
+		if !strings.Contains(calendar, "\r\n"+line+"\r\n") {
+			t.Errorf("Expected the calendar to have the line %v; got %v", line, calendar)
+		}
+	}
+	if strings.Count(calendar, "BEGIN:VEVENT") != 3 {
+		t.Errorf("Expected the departure, the stop and the arrival; got %v", calendar)
+	}
+}
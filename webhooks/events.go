@@ -0,0 +1,186 @@
+package webhooks
+
+import (
+	"cloudproject/database"
+	"cloudproject/endpoints"
+	"cloudproject/structs"
+	"log"
+	"strconv"
+	"sync"
+	"time"
+)
+
+/**
+ * Class events.go
+ * The live events of trips: changes to the weather, new traffic incidents, departures moving, positions checked in and
+ * deliveries to webhooks
+ * An event carries the same data the notifications of the webhooks are rendered with, and is published to the clients
+ * streaming its trip. The latest events are kept, so a client reconnecting with the id of the last event it got receives
+ * those it missed. The incidents on the route of a trip are checked while clients stream it.
+ */
+
+// Event types
+const (
+	EventWeather   = "weather"
+	EventIncident  = "incident"
+	EventDeparture = "departure"
+	EventPosition  = "position"
+	EventDelivery  = "delivery"
+)
+
+// EventHistory How many of the latest events are kept for clients reconnecting
+const EventHistory = 256
+
+// streamClient A client streaming the events of a trip
+type streamClient struct {
+	trip   string
+	events chan structs.TripEvent
+}
+
+// streamClients The clients streaming events
+var streamClients = map[*streamClient]bool{}
+
+// eventHistory The latest events, oldest first
+var eventHistory []structs.TripEvent
+
+// lastEventID The id of the latest event
+var lastEventID int64
+
+// incidentWatchers The trips whose incidents are being checked
+var incidentWatchers = map[string]bool{}
+
+// streamsMutex Guards streamClients, eventHistory, lastEventID and incidentWatchers
+var streamsMutex sync.Mutex
+
+// publish Sends the event to the clients streaming its trip and keeps it. Clients too slow to keep up miss it
+func publish(event structs.TripEvent) {
+	if event.Trip == "" {
+		return
+	}
+	streamsMutex.Lock()
+	defer streamsMutex.Unlock()
+
+	lastEventID++
+	event.ID = lastEventID
+	event.Time = time.Now().Format(time.RFC3339)
+	eventHistory = append(eventHistory, event)
+	if len(eventHistory) > EventHistory {
+		eventHistory = append([]structs.TripEvent{}, eventHistory[len(eventHistory)-EventHistory:]...)
+	}
+	for client := range streamClients {
+		if client.trip != event.Trip {
+			continue
+		}
+		select {
+		case client.events <- event:
+		default:
+			log.Println("A client streaming trip " + event.Trip + " is not keeping up, event " +
+				strconv.FormatInt(event.ID, 10) + " is dropped.")
+		}
+	}
+}
+
+// hookEvent The event of the type about the webhook, with the data its notifications are rendered with
+func hookEvent(eventType string, hook structs.Webhook, data structs.NotificationData) structs.TripEvent {
+	return structs.TripEvent{Type: eventType, Trip: hook.Trip, Webhook: hook.Id, Data: data}
+}
+
+// subscribeTrip Adds a client streaming the trip, and returns it with the kept events of the trip after the id. The
+// incidents on the route of the trip are checked while it is streamed
+func subscribeTrip(trip string, after int64) (*streamClient, []structs.TripEvent) {
+	client := &streamClient{trip: trip, events: make(chan structs.TripEvent, 64)}
+	var missed []structs.TripEvent
+
+	streamsMutex.Lock()
+	streamClients[client] = true
+	for _, event := range eventHistory {
+		if event.Trip == trip && event.ID > after {
+			missed = append(missed, event)
+		}
+	}
+	watching := incidentWatchers[trip]
+	incidentWatchers[trip] = true
+	streamsMutex.Unlock()
+
+	if !watching {
+		goWorker(func() { watchIncidents(trip) })
+	}
+	return client, missed
+}
+
+// unsubscribeTrip Removes the client
+func unsubscribeTrip(client *streamClient) {
+	streamsMutex.Lock()
+	delete(streamClients, client)
+	streamsMutex.Unlock()
+}
+
+// watchIncidents Publishes the traffic incidents on the route of the trip as they appear, until no client has streamed
+// it since the last check. Clients reconnecting in the meantime do not start it over
+func watchIncidents(trip string) {
+	alerted := map[string]bool{}
+	for {
+		checkIncidents(trip, alerted)
+		if !sleep(workerContext(), EnRouteInterval) || !streamed(trip) {
+			streamsMutex.Lock()
+			delete(incidentWatchers, trip)
+			streamsMutex.Unlock()
+			return
+		}
+	}
+}
+
+// streamed Checks if a client is streaming the trip
+func streamed(trip string) bool {
+	streamsMutex.Lock()
+	defer streamsMutex.Unlock()
+	for client := range streamClients {
+		if client.trip == trip {
+			return true
+		}
+	}
+	return false
+}
+
+// checkIncidents Publishes the incidents on the route of the trip which have not been published yet
+func checkIncidents(id string, published map[string]bool) {
+	trip, err := database.GetTrip(id)
+	if err != nil {
+		log.Println("Unable to read trip " + id + " for the incidents on its route.\n" + err.Error())
+		return
+	}
+	incidents, err := endpoints.TripIncidents(trip)
+	if err != nil {
+		log.Println("Unable to get the incidents on the route of trip " + id + "\n" + err.Error())
+		return
+	}
+	for _, incident := range incidents {
+		key := incidentKey(incident)
+		if published[key] {
+			continue
+		}
+		published[key] = true
+		data := tripData(trip)
+		data.Incidents = []structs.OutIncident{incident}
+		publish(structs.TripEvent{Type: EventIncident, Trip: id, Data: data})
+	}
+}
+
+// incidentKey Identifies the incident among the incidents of a route
+func incidentKey(incident structs.OutIncident) string {
+	return incident.From + "|" + incident.To + "|" + incident.Event + "|" + incident.Start.Format(time.RFC3339)
+}
+
+// tripData The data of the events of the trip which are not about one of its webhooks
+func tripData(trip structs.Trip) structs.NotificationData {
+	base := PublicURL + "/rtc/v1/trips/" + trip.ID
+	data := structs.NotificationData{Trip: structs.NotificationTrip{ID: trip.ID, Name: trip.Name, Stops: trip.Stops},
+		Incidents: []structs.OutIncident{}, Links: structs.NotificationLinks{Trip: base, Calendar: base + "/calendar.ics"}}
+	if len(trip.Stops) != 0 {
+		data.Trip.Start, data.Trip.Destination = trip.Stops[0], trip.Stops[len(trip.Stops)-1]
+		data.Links.Weather = notificationLinks(structs.Webhook{ArrivalDestination: data.Trip.Destination}).Weather
+	}
+	data.Departure, _ = time.Parse(time.RFC3339, trip.Departure)
+	data.Arrival, _ = time.Parse(time.RFC3339, trip.Arrival)
+	return data
+}
@@ -0,0 +1,101 @@
+package webhooks
+
+import (
+	"bufio"
+	"cloudproject/harness"
+	"cloudproject/router"
+	"cloudproject/structs"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+// readEvents Reads the Server-Sent Events of the stream until there are count of them, fails the test if they do not
+// arrive within the timeout
+func readEvents(t *testing.T, scanner *bufio.Scanner, count int, timeout time.Duration) []structs.TripEvent {
+	read := make(chan []structs.TripEvent, 1)
+	go func() {
+		var events []structs.TripEvent
+		name := ""
+		for len(events) < count && scanner.Scan() {
+			line := scanner.Text()
+			switch {
+			case strings.HasPrefix(line, "event: "):
+				name = strings.TrimPrefix(line, "event: ")
+			case strings.HasPrefix(line, "data: "):
+				var event structs.TripEvent
+				if err := json.Unmarshal([]byte(strings.TrimPrefix(line, "data: ")), &event); err != nil || event.Type != name {
+					t.Errorf("Expected an event named by its type; got %v %v (%v)", name, line, err)
+				}
+				events = append(events, event)
+			}
+		}
+		read <- events
+	}()
+	select {
+	case events := <-read:
+		return events
+	case <-time.After(timeout):
+		t.Fatalf("Expected %v events within %v", count, timeout)
+		return nil
+	}
+}
+
+func TestTripEvents(t *testing.T) {
+	h := harness.Start(t)
+	id := register(t, map[string]interface{}{
+		"url":                h.WebhookURL,
+		"DepartureLocation":  "gjøvik",
+		"ArrivalDestination": "lillehammer",
+		"ArrivalTime":        time.Now().Add(48 * time.Hour).UTC().Format(time.RFC822),
+	})
+	hook, _ := webhookOf(id)
+
+	r := router.New()
+	r.Group("/rtc/v1").Get("/trips/{id}/events", TripEvents).WithQuery(EventsQuery)
+	server := httptest.NewServer(r)
+	defer server.Close()
+
+	res, err := http.Get(server.URL + "/rtc/v1/trips/" + hook.Trip + "/events")
+	if err != nil || res.StatusCode != http.StatusOK || res.Header.Get("Content-Type") != "text/event-stream" {
+		t.Fatalf("Expected an event stream; got %v (%v)", res, err)
+	}
+	defer res.Body.Close()
+	scanner := bufio.NewScanner(res.Body)
+
+	// The weather checked when the webhook was registered is sent first, then the incidents on the route
+	events := readEvents(t, scanner, 3, 10*time.Second)
+	if events[0].Type != EventWeather || events[0].Webhook != id || events[0].Data.Weather.Message == "" {
+		t.Errorf("Expected the weather of the webhook; got %+v", events[0])
+	}
+	for _, event := range events[1:] {
+		if event.Type != EventIncident || len(event.Data.Incidents) != 1 || event.Data.Trip.Destination != "lillehammer" {
+			t.Errorf("Expected an incident on the route; got %+v", event)
+		}
+	}
+
+	// A notification is streamed with the status the webhook answered with
+	data := notificationData(hook, TemplateUpdate)
+	send(hook, data, "Leave earlier")
+	h.WaitForDelivery(t, 10*time.Second)
+	delivery := readEvents(t, scanner, 1, 10*time.Second)[0]
+	if delivery.Type != EventDelivery || delivery.Text != "Leave earlier" || delivery.Status != http.StatusOK ||
+		delivery.Data.Stage != TemplateUpdate || delivery.ID <= events[2].ID {
+		t.Errorf("Expected the delivery of the update; got %+v", delivery)
+	}
+
+	// A client reconnecting gets the events it missed
+	client, missed := subscribeTrip(hook.Trip, events[2].ID)
+	unsubscribeTrip(client)
+	if len(missed) != 1 || missed[0].ID != delivery.ID {
+		t.Errorf("Expected the delivery to be missed after the incidents; got %+v", missed)
+	}
+
+	// Unknown trips have no events
+	if res, err := http.Get(server.URL + "/rtc/v1/trips/unknown/events"); err != nil || res.StatusCode != http.StatusNotFound {
+		t.Errorf("Expected status Not Found for an unknown trip; got %v (%v)", res, err)
+	}
+}
@@ -2,11 +2,12 @@ package webhooks
 
 import (
 	"bytes"
-	"cloud.google.com/go/firestore"
 	"cloudproject/database"
 	"cloudproject/endpoints"
+	"cloudproject/i18n"
 	"cloudproject/structs"
 	"cloudproject/utils"
+	"context"
 	"crypto/hmac"
 	"crypto/sha256"
 	"encoding/hex"
@@ -16,7 +17,6 @@ import (
 	"io/ioutil"
 	"log"
 	"net/http"
-	"net/url"
 	"strconv"
 	"time"
 )
@@ -27,12 +27,24 @@ var SignatureKey = "X-SIGNATURE"
 // Secret byte array
 var Secret []byte
 
-// CalculateDeparture Calculates the time of departure based on weather conditions and traffic messages
-// (The traffic messages is considered by the API it self, but has an impact on the time it takes from one
-// destination to another)
+// NotificationLead Minutes the notification is sent before the earliest departure
+const NotificationLead = 30
+
+// CalculateDeparture Calculates the time of departure with the departure planner, from the travel times predicted in
+// the traffic and the road conditions forecast along the trip
 func CalculateDeparture(id string) error {
+	return planDeparture(id, endpoints.DefaultCandidates)
+}
+
+// planDeparture Calculates the time of departure of the webhook from the travel times predicted for the number of
+// departures around it
+func planDeparture(id string, candidates int) error {
 	// Retrieves the webhook and its information from the database
-	webhookInformation, _ := database.Client.Collection(database.Collection).Doc(id).Get(database.Ctx)
+	webhookInformation, err := database.Client.Get(database.Collection, id)
+	if err != nil {
+		log.Println(err.Error())
+		return errors.New("internal error, could not calculate time, try again")
+	}
 
 	// Defines instance of Webhook-struct
 	var message structs.Webhook
@@ -42,75 +54,86 @@ func CalculateDeparture(id string) error {
 		return errors.New("internal error, could not calculate time, try again")
 	}
 
-	// Retrieves the latitude and longitude for the departure location
-	startLat, startLong, err := database.LocationPresent(url.QueryEscape(message.DepartureLocation))
-	if err != nil {
-		log.Println("There was an error retrieving the departure locations latitude and longitude." +
-			"\n" + err.Error())
-		return errors.New("internal error, could not calculate time, try again")
-
+	// The route goes through the stops of the trip the webhook subscribes to, or else from the departure location to
+	// the arrival destination
+	stops := []string{message.DepartureLocation, message.ArrivalDestination}
+	if message.Trip != "" {
+		trip, err := database.GetTrip(message.Trip)
+		if err != nil {
+			log.Println("There was an error retrieving the trip " + message.Trip + "\n" + err.Error())
+			return errors.New("internal error, could not calculate time, try again")
+		}
+		stops = trip.Stops
 	}
 
-	endLat, endLong, err := database.LocationPresent(url.QueryEscape(message.ArrivalDestination))
+	// Stores the latitudes and longitudes for the stops to be used in the call to the API.
+	coordinates, err := endpoints.TripCoordinates(stops)
 	if err != nil {
-		log.Println("There was an error retrieving the arrival destinations latitude and longitude." +
+		log.Println("There was an error retrieving the latitudes and longitudes of the stops." +
 			"\n" + err.Error())
 		return errors.New("internal error, could not calculate time, try again")
-
 	}
 
-	// Stores the latitudes and longitudes for the start- and end location to be used in the call to the API.
-	// Have to use '%2C' for ',' and '%3A' for ':'
-	coordinates := startLat + "%2C" + startLong + "%3A" + endLat + "%2C" + endLong
-
-	// Sends a Get-request to the API to call for route data such as travel time (as we need in this instance)
-	resp, err := http.Get("https://api.tomtom.com/routing/1/calculateRoute/" + coordinates + "/json?instructionsType=coded&traffic=false&avoid=unpavedRoads&travelMode=car&key=" + utils.TomtomKey)
-	if err != nil {
-		log.Println("There was an error retrieving travel data from the TomTom API, Status Code: " + strconv.Itoa(http.StatusInternalServerError) +
-			"\n" + err.Error())
-		err = utils.TomTomErrorHandling(resp.StatusCode)
-		return errors.New("internal error, could not calculate time, try again")
-
+	// The route is restricted to the vehicle of the profile the webhook refers to
+	var profile *structs.VehicleProfile
+	if message.Profile != "" {
+		stored, err := database.GetProfile(message.Profile)
+		if err != nil {
+			log.Println("There was an error retrieving the vehicle profile " + message.Profile + "\n" + err.Error())
+			return errors.New("internal error, could not calculate time, try again")
+		}
+		profile = &stored
 	}
 
-	body, err := ioutil.ReadAll(resp.Body)
-	if err != nil {
-		log.Println("There was an error reading the response body, Status Code: " + strconv.Itoa(http.StatusInternalServerError) +
-			"\n" + err.Error())
+	// The route is planned with the preferences of the webhook, to arrive by its arrival time
+	var preferences structs.RoutePreferences
+	if message.Preferences != nil {
+		preferences = *message.Preferences
+	}
+	if err := endpoints.ValidatePreferences(&preferences); err != nil {
+		log.Println("The route preferences of the webhook are invalid.\n" + err.Error())
 		return errors.New("internal error, could not calculate time, try again")
-
+	}
+	arrival, err := time.Parse(time.RFC822, message.ArrivalTime)
+	if err != nil || !arrival.After(time.Now()) {
+		log.Println("The arrival of webhook with ID: " + id + " is not in the future, there is no departure to calculate.")
+		return nil
 	}
 
-	var roads structs.RouteStruct
-	if err = json.Unmarshal(body, &roads); err != nil {
-		unmarshalErr := utils.JsonUnmarshalErrorHandling(err)
-		log.Println(unmarshalErr)
+	// Plans the departure from the travel times predicted in the traffic around it, with the delay of the road
+	// conditions forecast at the departure location and the arrival destination, and the wait for ferries
+	plan, _, err := endpoints.PlanDeparture(endpoints.DepartureRequest{Coordinates: coordinates, Profile: profile,
+		Preferences: preferences, ArriveBy: arrival, Candidates: candidates, Locale: webhookLocale(message)})
+	if err != nil {
+		log.Println("There was an error planning the departure of webhook with ID: " + id + "\n" + err.Error())
 		return errors.New("internal error, could not calculate time, try again")
 	}
-
-	estimatedTravelTime := roads.Routes[0].Summary.TravelTimeInSeconds
-
-	// Estimates the travel time based on the actual travel time provided by the API, and adds the weighted time which
-	// is calculated using weather conditions.
-	estimatedTravelTimeMinutes := (estimatedTravelTime + endpoints.GetMessageWeight(message.Weather)) / 60
+	departure, _ := time.Parse(time.RFC3339, plan.Departure)
+	estimatedTravelTimeMinutes := int(arrival.Sub(departure).Minutes())
 
 	// Updates the estimated travel time for the webhook in the database by setting the newly calculated travel time
 	// as the travel time.
-	_, err = database.Client.Collection(database.Collection).Doc(id).Set(database.Ctx, map[string]interface{}{
+	err = database.Update(id, map[string]interface{}{
 		"EstimatedTravelTime": estimatedTravelTimeMinutes,
-	}, firestore.MergeAll)
+		"Uncertainty":         plan.Uncertainty,
+	})
+	if err != nil {
+		log.Println(err.Error())
+		return errors.New("internal error, could not calculate time, try again")
+	}
 
 	return nil
 }
 
-// CallUrl Calls the URL provided in the webhook on invocation
-func CallUrl(url string, content string) {
+// CallUrl Calls the URL provided in the webhook on invocation, and returns the status code it answered with, or 0 if
+// it could not be called
+func CallUrl(url string, content string) int {
 
 	// Creates a POST request with the content
 	req, err := http.NewRequest(http.MethodPost, url, bytes.NewBuffer([]byte(content)))
 	if err != nil {
 		log.Println("Error during request creation - unable to notify on URL: " + url)
-		return
+		return 0
 	}
 
 	// Hash content using sha256
@@ -119,36 +142,40 @@ func CallUrl(url string, content string) {
 	if errHash != nil {
 		log.Println("Error during content hash.")
 		_ = fmt.Errorf("%v", "Error during content hashing.")
-		return
+		return 0
 	}
 	// Convert to string & add to header
 	req.Header.Add(SignatureKey, hex.EncodeToString(mac.Sum(nil)))
 
-	client := http.Client{}
+	client := http.Client{Timeout: 30 * time.Second}
 
 	// Send the request
 	res, err := client.Do(req)
 	if err != nil {
 		log.Println("Error in HTTP request: " + err.Error())
-		return
+		return 0
 	}
 
+	defer res.Body.Close()
+
 	// Reading response
 	response, err := ioutil.ReadAll(res.Body)
 	if err != nil {
 		log.Println("Something is wrong with invocation response, Status Code: " + strconv.Itoa(res.StatusCode) +
 			"\n" + err.Error())
-		return
+		return res.StatusCode
 	}
 
 	log.Println("Webhook invoked. Received Status Code: " + strconv.Itoa(res.StatusCode) +
 		" and body: " + string(response))
+	return res.StatusCode
 }
 
-// SendNotification Creates POST body which is supported by Slack and controls when to invoke the webhooks
-func SendNotification(notificationId string) {
+// SendNotification Creates POST body which is supported by Slack and controls when to invoke the webhooks.
+// Returns without notifying if ctx is cancelled while waiting for the time of invocation
+func SendNotification(ctx context.Context, notificationId string) {
 	// Checks through all entries in collection "messages" for a webhook with id: notificationId
-	doc, err := database.Client.Collection(database.Collection).Doc(notificationId).Get(database.Ctx)
+	doc, err := database.Client.Get(database.Collection, notificationId)
 	if err != nil {
 		log.Println("Unable to find webhook with ID: " + notificationId + " in the " + database.Collection + " collection")
 		_ = errors.New("The notification ID is not in our system")
@@ -156,7 +183,6 @@ func SendNotification(notificationId string) {
 	}
 	var firebase structs.Webhook
 	var arrivalTime string
-	var notificationUrl string
 	var timeUntilInvocation float64
 
 	// Tries to add the data from firebase to the Webhook-struct
@@ -165,15 +191,18 @@ func SendNotification(notificationId string) {
 		return
 	}
 
-	notificationUrl = firebase.Url
+	// The notification is sent once if the webhook is notified when to leave, updates to the departure are sent by replan
+	if !stageEnabled(firebase, StageLeave) || firebase.Notified != "" {
+		return
+	}
+
 	arrivalTime = firebase.ArrivalTime
-	destination := firebase.ArrivalDestination
 
 	isValid := utils.IsValidInput(arrivalTime)
 	var newTime time.Time
 	if isValid == true {
 		timeS, _ := time.Parse(time.RFC822, firebase.ArrivalTime)
-		newTime = timeS.Add(time.Duration(-firebase.EstimatedTravelTime-30) * time.Minute)
+		newTime = timeS.Add(time.Duration(-firebase.EstimatedTravelTime-firebase.Uncertainty-NotificationLead) * time.Minute)
 		timeUntilInvocation = time.Until(newTime).Minutes()
 		if timeUntilInvocation < 0 {
 			return
@@ -183,11 +212,19 @@ func SendNotification(notificationId string) {
 		return
 	}
 
-	// Sleeps the go-routine for a given time
-	time.Sleep(time.Duration(timeUntilInvocation) * time.Minute)
+	// Sleeps the go-routine for a given time, the webhook is picked up again by InvokeAll on the next start up
+	// if the application shuts down in the meantime. The notification is cancelled when it is scheduled again
+	if !sleep(ctx, time.Duration(timeUntilInvocation)*time.Minute) {
+		if workerContext().Err() == nil {
+			log.Println("The notification for webhook with ID: " + notificationId + " has been rescheduled.")
+			return
+		}
+		log.Println("Shutting down, notification for webhook with ID: " + notificationId + " is left for the next start up.")
+		return
+	}
 
 	//Getting the updated weather
-	doc, err = database.Client.Collection(database.Collection).Doc(notificationId).Get(database.Ctx)
+	doc, err = database.Client.Get(database.Collection, notificationId)
 	if err != nil {
 		log.Println("Unable to find webhook with ID: " + notificationId + " in the " + database.Collection + " collection")
 		_ = errors.New("The notification ID is not in our system")
@@ -200,34 +237,25 @@ func SendNotification(notificationId string) {
 		return
 	}
 
-	var message string
-	message = firebase.Weather
-
-	jsonMessage := structs.NotificationResponse{
-		Text: message,
-	}
-
-	//Updating the new time, from weather conditions
-	timeS, err := time.Parse(time.RFC822, firebase.ArrivalTime)
-	if err != nil {
-		log.Println("Error when parsing time in send notification " + err.Error())
+	// The arrival has been moved while sleeping, the notification for the new arrival is sent instead
+	if firebase.ArrivalTime != arrivalTime {
+		log.Println("The arrival of webhook with ID: " + notificationId + " has changed, the notification is rescheduled.")
+		return
 	}
-	newTime = timeS.Add(time.Duration(-firebase.EstimatedTravelTime) * time.Minute)
-	timeString := newTime.Add(time.Minute).String()
 
-	text := "Your registered trip is about to begin. To be there in time, consider departure " + timeString + "\n\n\n" +
-		jsonMessage.Text + ". For more information go to our website:"
+	// The notification is in the locale of the webhook, as is the stored weather message, unless it has templates
+	locale := webhookLocale(firebase)
+	data := notificationData(firebase, StageLeave)
+	text := notificationText(firebase, StageLeave, data,
+		i18n.Text(locale, "notification.text", i18n.Args{"departure": data.Departure, "weather": firebase.Weather}))
 
-	//link for the weather endpoint
-	link := "http://10.212.141.222:80/rtc/v1/weather/" + destination
-
-	// Formats output to be accepted by Slack
+	// Formats output to be accepted by Slack, colored by the risk of the road conditions
 	jsonData := structs.JsonMessage{Text: text, Attachment: []structs.Attachments{{
-		Color:      "#2eb886",
+		Color:      riskColor(data.Weather.Conditions),
 		AuthorName: "Roadtrip Planner",
-		Title:      "Weather",
-		TitleLink:  link,
-		Text:       "The Weather Forecast for you destination",
+		Title:      notificationText(firebase, TemplateTitle, data, i18n.Text(locale, "notification.title", nil)),
+		TitleLink:  data.Links.Weather,
+		Text:       i18n.Text(locale, "notification.attachment", nil),
 		Footer:     "The Road trip Companion",
 	}}}
 
@@ -247,19 +275,23 @@ func SendNotification(notificationId string) {
 	}
 
 	// Creates a go routine of the invocation
-	go CallUrl(notificationUrl, string(output))
+	deliver(firebase, data, text, string(output))
+	err = database.Update(notificationId, map[string]interface{}{"notified": time.Now().Format(time.RFC3339)})
+	if err != nil {
+		log.Println("Unable to mark webhook with ID: " + notificationId + " as notified.\n" + err.Error())
+	}
 }
 
 // InvokeAll Invokes all webhooks
-func InvokeAll() {
+func InvokeAll(ctx context.Context) {
 	webhook, err := database.GetAll()
 	if err != nil {
 		log.Println("There has been an error retrieving the webhooks from the database.\n" + err.Error())
-		log.Fatalf(err.Error())
 		return
 	}
 	// For each webhook, create a go routine for it
 	for i := 0; i < len(webhook); i++ {
-		go SendNotification(webhook[i].Ref.ID)
+		id := webhook[i].ID
+		schedule(id)
 	}
 }
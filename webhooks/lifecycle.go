@@ -0,0 +1,96 @@
+package webhooks
+
+import (
+	"cloudproject/structs"
+	"context"
+	"errors"
+	"log"
+	"sync"
+	"time"
+)
+
+// workerCtx The context the background workers and sleeping notifications run under,
+// cancelled by the application when it shuts down. Read it with workerContext
+var workerCtx = context.Background()
+
+// lifecycleMutex Guards workerCtx, which Start replaces while requests schedule notifications
+var lifecycleMutex sync.Mutex
+
+// workers Keeps track of the background go routines (checks, clean up and sleeping notifications)
+var workers sync.WaitGroup
+
+// deliveries Keeps track of webhook invocations that have been sent but not yet answered
+var deliveries sync.WaitGroup
+
+// Start Starts the background webhook workers, they keep running until ctx is cancelled
+func Start(ctx context.Context) {
+	lifecycleMutex.Lock()
+	workerCtx = ctx
+	lifecycleMutex.Unlock()
+	goWorker(func() { InvokeAll(ctx) })
+	goWorker(func() { DeleteExpiredWebhooks(ctx) })
+	goWorker(func() { Check(ctx) })
+}
+
+// Shutdown Waits for the background workers to return and for in-flight webhook invocations to be delivered,
+// so no pending webhook state is lost. Returns an error if ctx expires before everything is flushed
+func Shutdown(ctx context.Context) error {
+	done := make(chan struct{})
+	go func() {
+		workers.Wait()
+		deliveries.Wait()
+		close(done)
+	}()
+
+	select {
+	case <-done:
+		// The notifications left waiting are scheduled again on the next start
+		pendingMutex.Lock()
+		pending = map[string]*pendingNotification{}
+		pendingMutex.Unlock()
+		log.Println("All webhook workers stopped and pending invocations delivered.")
+		return nil
+	case <-ctx.Done():
+		return errors.New("gave up waiting for webhook workers: " + ctx.Err().Error())
+	}
+}
+
+// workerContext The context the background workers run under
+func workerContext() context.Context {
+	lifecycleMutex.Lock()
+	defer lifecycleMutex.Unlock()
+	return workerCtx
+}
+
+// goWorker Runs fn in a go routine which Shutdown will wait for
+func goWorker(fn func()) {
+	workers.Add(1)
+	go func() {
+		defer workers.Done()
+		fn()
+	}()
+}
+
+// deliver Invokes the url of the webhook with the content in a go routine which Shutdown will wait for, and publishes
+// the delivery of the text with the data it was rendered with to the clients streaming the trip of the webhook
+func deliver(hook structs.Webhook, data structs.NotificationData, text string, content string) {
+	deliveries.Add(1)
+	go func() {
+		defer deliveries.Done()
+		event := hookEvent(EventDelivery, hook, data)
+		event.Text, event.Status = text, CallUrl(hook.Url, content)
+		publish(event)
+	}()
+}
+
+// sleep Sleeps for the given duration, returns false if ctx got cancelled before the time was up
+func sleep(ctx context.Context, duration time.Duration) bool {
+	timer := time.NewTimer(duration)
+	defer timer.Stop()
+	select {
+	case <-timer.C:
+		return true
+	case <-ctx.Done():
+		return false
+	}
+}
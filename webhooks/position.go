@@ -0,0 +1,207 @@
+package webhooks
+
+import (
+	"cloudproject/database"
+	"cloudproject/endpoints"
+	"cloudproject/i18n"
+	"cloudproject/router"
+	"cloudproject/structs"
+	"cloudproject/utils"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"log"
+	"math"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+)
+
+/**
+ * Class position.go
+ * Check-ins of the position of the driver while a trip is driven
+ * Each check-in plans the remaining route and the estimated arrival from the position, which is streamed to the clients
+ * of the trip. Webhooks alerted en route are sent the estimated arrival when it has moved REPLAN_THRESHOLD minutes or
+ * more from the one they were last given. A trip is checked in at most every CHECKIN_INTERVAL minutes (2 by default),
+ * so the routing API is called at that pace however often the position is posted.
+ */
+
+// CheckInInterval Minutes between the check-ins of a trip
+var CheckInInterval = minutesFromEnv("CHECKIN_INTERVAL", 2)
+
+// drive A trip being driven
+type drive struct {
+	route     endpoints.DriveRoute // Given at the latest check-in, empty before the first
+	checkedIn time.Time
+}
+
+// drives The trips being driven, by id
+var drives = map[string]*drive{}
+
+// drivesMutex Guards drives
+var drivesMutex sync.Mutex
+
+// CheckIn Checks in the position in the body for the trip with the id in the path, and answers with the remaining
+// route from it
+func CheckIn(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	trip, found := tripOf(w, router.Param(r, "id"))
+	if !found {
+		return
+	}
+
+	input, err := ioutil.ReadAll(r.Body)
+	if err != nil {
+		log.Println("There was an error during read of response body.\n" + err.Error())
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	var position utils.Coordinate
+	if err = json.Unmarshal(input, &position); err != nil {
+		http.Error(w, utils.JsonUnmarshalErrorHandling(err).Error(), http.StatusBadRequest)
+		return
+	}
+	if math.Abs(position.Latitude) > 90 || math.Abs(position.Longitude) > 180 {
+		http.Error(w, "error, the position needs a latitude from -90 to 90 and a longitude from -180 to 180", http.StatusBadRequest)
+		return
+	}
+
+	previous, wait := reserveCheckIn(trip.ID, time.Now())
+	if wait > 0 {
+		seconds := int(math.Ceil(wait.Seconds()))
+		w.Header().Set("Retry-After", strconv.Itoa(seconds))
+		http.Error(w, "error, trip "+trip.ID+" can be checked in every "+strconv.Itoa(CheckInInterval)+
+			" minutes, try again in "+strconv.Itoa(seconds)+" seconds", http.StatusTooManyRequests)
+		return
+	}
+	progress, err := checkIn(trip, previous.route, position)
+	if err != nil {
+		releaseCheckIn(trip.ID, previous.checkedIn)
+		log.Println("Unable to check in trip " + trip.ID + "\n" + err.Error())
+		http.Error(w, "Unable to plan the remaining route of the trip, try again", http.StatusBadGateway)
+		return
+	}
+
+	output, err := json.Marshal(progress)
+	if err != nil {
+		http.Error(w, utils.JsonMarshalErrorHandling(err).Error(), http.StatusInternalServerError)
+		return
+	}
+	fmt.Fprintf(w, "%v", string(output))
+}
+
+// reserveCheckIn Reserves the check-in of the trip at the time, and returns the trip as it was driven before. Returns
+// how long until the trip can be checked in instead if it was checked in less than CheckInInterval ago
+func reserveCheckIn(id string, at time.Time) (drive, time.Duration) {
+	drivesMutex.Lock()
+	defer drivesMutex.Unlock()
+	current, found := drives[id]
+	if !found {
+		current = &drive{}
+		drives[id] = current
+	}
+	if !current.checkedIn.IsZero() {
+		if wait := current.checkedIn.Add(time.Duration(CheckInInterval) * time.Minute).Sub(at); wait > 0 {
+			return drive{}, wait
+		}
+	}
+	previous := *current
+	current.checkedIn = at
+	return previous, 0
+}
+
+// releaseCheckIn Gives the check-in of the trip back when it failed, so it can be checked in again right away
+func releaseCheckIn(id string, checkedIn time.Time) {
+	drivesMutex.Lock()
+	defer drivesMutex.Unlock()
+	if current, found := drives[id]; found {
+		current.checkedIn = checkedIn
+	}
+}
+
+// forgetDrive Forgets the route of the trip, which is planned anew from the next check-in
+func forgetDrive(id string) {
+	drivesMutex.Lock()
+	defer drivesMutex.Unlock()
+	delete(drives, id)
+}
+
+// checkIn Plans the remaining route of the trip from the position, keeps it for the next check-in, and feeds the
+// estimated arrival to the clients and webhooks of the trip. The whole route of the trip is planned on the first
+func checkIn(trip structs.Trip, route endpoints.DriveRoute, position utils.Coordinate) (structs.TripProgress, error) {
+	if len(route.Legs) == 0 {
+		planned, err := endpoints.TripDriveRoute(trip)
+		if err != nil {
+			return structs.TripProgress{}, err
+		}
+		route = planned
+	}
+	progress, next, err := endpoints.RemainingRoute(trip, route, position)
+	if err != nil {
+		return structs.TripProgress{}, err
+	}
+
+	// The route is kept at the destination, so positions checked in after arriving are compared with it
+	if !progress.Arrived {
+		drivesMutex.Lock()
+		if current, found := drives[trip.ID]; found {
+			current.route = next
+		}
+		drivesMutex.Unlock()
+	}
+
+	data := tripData(trip)
+	data.Progress = &progress
+	publish(structs.TripEvent{Type: EventPosition, Trip: trip.ID, Data: data})
+	if !progress.Arrived {
+		sendETA(trip.ID, progress)
+	}
+	return progress, nil
+}
+
+// sendETA Sends the estimated arrival to the webhooks of the trip alerted en route, if it has moved by the threshold
+// or more from the one they were last given, or else from their arrival time
+func sendETA(id string, progress structs.TripProgress) {
+	eta, err := time.Parse(time.RFC3339, progress.ETA)
+	if err != nil {
+		return
+	}
+	hooks, err := subscriptions(id)
+	if err != nil {
+		log.Println("Unable to read the webhooks of trip " + id + " for the estimated arrival.\n" + err.Error())
+		return
+	}
+	for _, hook := range hooks {
+		if !stageEnabled(hook, StageEnRoute) {
+			continue
+		}
+		given, err := time.Parse(time.RFC3339, hook.ETA)
+		if err != nil {
+			if given, err = time.Parse(time.RFC822, hook.ArrivalTime); err != nil {
+				continue
+			}
+		}
+		shift := int(math.Round(eta.Sub(given).Minutes()))
+		if shift < ReplanThreshold && -shift < ReplanThreshold {
+			continue
+		}
+
+		key, count := "notification.eta.later", shift
+		if shift < 0 {
+			key, count = "notification.eta.earlier", -shift
+		}
+		data := notificationData(hook, TemplateETA)
+		data.Shift, data.Progress = shift, &progress
+		text := notificationText(hook, TemplateETA, data, i18n.Text(webhookLocale(hook), key, i18n.Args{"count": count,
+			"destination": hook.ArrivalDestination, "eta": eta, "travel": progress.TravelTime}))
+
+		log.Println("Sending the estimated arrival to webhook with ID: " + hook.Id)
+		if !send(hook, data, text) {
+			continue
+		}
+		if err := database.Update(hook.Id, map[string]interface{}{"eta": progress.ETA}); err != nil {
+			log.Println("Unable to store the estimated arrival sent to webhook with ID: " + hook.Id + "\n" + err.Error())
+		}
+	}
+}
@@ -0,0 +1,112 @@
+package webhooks
+
+import (
+	"bytes"
+	"cloudproject/harness"
+	"cloudproject/router"
+	"cloudproject/structs"
+	"cloudproject/utils"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestReserveCheckIn(t *testing.T) {
+	id := "reserved"
+	defer forgetDrive(id)
+	at := time.Date(2021, 5, 10, 8, 0, 0, 0, time.UTC)
+
+	if _, wait := reserveCheckIn(id, at); wait != 0 {
+		t.Errorf("Expected the first check-in to be let through; got a wait of %v", wait)
+	}
+	expected := time.Duration(CheckInInterval)*time.Minute - 30*time.Second
+	if _, wait := reserveCheckIn(id, at.Add(30*time.Second)); wait != expected {
+		t.Errorf("Expected to wait %v for the next check-in; got %v", expected, wait)
+	}
+	releaseCheckIn(id, time.Time{})
+	if _, wait := reserveCheckIn(id, at.Add(time.Minute)); wait != 0 {
+		t.Errorf("Expected a failed check-in to be given back; got a wait of %v", wait)
+	}
+}
+
+func TestCheckIn(t *testing.T) {
+	h := harness.Start(t)
+	id := register(t, map[string]interface{}{
+		"url":                h.WebhookURL,
+		"DepartureLocation":  "gjøvik",
+		"ArrivalDestination": "lillehammer",
+		"ArrivalTime":        time.Now().Add(48 * time.Hour).UTC().Format(time.RFC822),
+		"locale":             "en",
+		"stages":             []string{"enroute"},
+	})
+	hook, _ := webhookOf(id)
+
+	r := router.New()
+	r.Group("/rtc/v1").Post("/trips/{id}/position", CheckIn)
+	checkIn := func(trip string, position utils.Coordinate) (*httptest.ResponseRecorder, structs.TripProgress) {
+		content, _ := json.Marshal(position)
+		rec := httptest.NewRecorder()
+		r.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/rtc/v1/trips/"+trip+"/position", bytes.NewReader(content)))
+		var progress structs.TripProgress
+		_ = json.Unmarshal(rec.Body.Bytes(), &progress)
+		return rec, progress
+	}
+
+	// Halfway along the recorded route, which arrives long before the webhook
+	rec, progress := checkIn(hook.Trip, utils.Coordinate{Latitude: 60.97355, Longitude: 10.57342})
+	if rec.Code != http.StatusOK {
+		t.Fatalf("Expected status Ok; got %v: %v", rec.Code, rec.Body.String())
+	}
+	if progress.NextStop != "lillehammer" || progress.Distance != 44567 || progress.TravelTime != 48 || progress.OffRoute ||
+		progress.Deviation > 50 || progress.Arrived || progress.ETA != "2021-05-10T08:48:32+02:00" || progress.Delay == nil {
+		t.Errorf("Expected the remaining route to lillehammer on the route; got %+v", progress)
+	}
+	var message structs.JsonMessage
+	delivery := h.WaitForDelivery(t, 10*time.Second)
+	if err := json.Unmarshal(delivery.Body, &message); err != nil || !strings.HasPrefix(message.Text, "You are now expected at lillehammer ") {
+		t.Errorf("Expected the estimated arrival to be sent; got %v", string(delivery.Body))
+	}
+	if hook, _ = webhookOf(id); hook.ETA != progress.ETA {
+		t.Errorf("Expected the estimated arrival sent to be stored; got %q", hook.ETA)
+	}
+
+	// Checking in again right away is refused
+	if rec, _ = checkIn(hook.Trip, utils.Coordinate{Latitude: 60.97355, Longitude: 10.57342}); rec.Code != http.StatusTooManyRequests ||
+		rec.Header().Get("Retry-After") == "" {
+		t.Errorf("Expected status Too Many Requests with the time to retry after; got %v %v", rec.Code, rec.Header())
+	}
+
+	// Off the route once the interval has passed, the estimated arrival has not moved and is not sent again
+	releaseCheckIn(hook.Trip, time.Time{})
+	if rec, progress = checkIn(hook.Trip, utils.Coordinate{Latitude: 60.3943, Longitude: 5.3259}); rec.Code != http.StatusOK || !progress.OffRoute {
+		t.Errorf("Expected the position to be off the route; got %v: %v", rec.Code, rec.Body.String())
+	}
+	select {
+	case delivery := <-h.Deliveries():
+		t.Errorf("Expected the unchanged estimated arrival not to be sent; got %v", string(delivery.Body))
+	case <-time.After(200 * time.Millisecond):
+	}
+
+	// The check-ins are streamed to the clients of the trip
+	client, events := subscribeTrip(hook.Trip, 0)
+	unsubscribeTrip(client)
+	positions := 0
+	for _, event := range events {
+		if event.Type == EventPosition && event.Data.Progress != nil {
+			positions++
+		}
+	}
+	if positions != 2 {
+		t.Errorf("Expected the two check-ins to be streamed; got %+v", events)
+	}
+
+	if rec, _ = checkIn(hook.Trip, utils.Coordinate{Latitude: 91}); rec.Code != http.StatusBadRequest {
+		t.Errorf("Expected status Bad Request for an invalid position; got %v", rec.Code)
+	}
+	if rec, _ = checkIn("unknown", utils.Coordinate{}); rec.Code != http.StatusNotFound {
+		t.Errorf("Expected status Not Found for an unknown trip; got %v", rec.Code)
+	}
+}
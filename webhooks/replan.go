@@ -0,0 +1,149 @@
+package webhooks
+
+import (
+	"cloudproject/endpoints"
+	"cloudproject/i18n"
+	"cloudproject/structs"
+	"context"
+	"log"
+	"os"
+	"strconv"
+	"sync"
+	"time"
+)
+
+/**
+ * Class replan.go
+ * Re-planning of the departures of webhooks as the weather and the traffic change
+ * Every check plans the departure of each webhook again. The travel time is predicted for the departures around it only
+ * when the weather at the departure location has changed, otherwise for the planned departure alone. When the
+ * departure moves, the notifications waiting for the old departure are cancelled and scheduled for the new one, and
+ * when it moves by REPLAN_THRESHOLD minutes or more (10 by default) an update telling how much earlier or later to
+ * leave is sent.
+ */
+
+// ReplanThreshold Minutes the departure of a webhook has to move for an update to be sent
+var ReplanThreshold = minutesFromEnv("REPLAN_THRESHOLD", 10)
+
+// ReplanCandidates Departures the travel time is predicted for when re-planning while the weather is unchanged
+const ReplanCandidates = 1
+
+// pendingNotification The notifications of a webhook waiting for their time, cancelled when they are scheduled again
+type pendingNotification struct {
+	cancel context.CancelFunc
+}
+
+// pending The notifications waiting for their time, by the id of their webhook
+var pending = map[string]*pendingNotification{}
+
+// pendingMutex Guards pending
+var pendingMutex sync.Mutex
+
+// minutesFromEnv Reads a number of minutes from an environment variable, or uses the default if it is not valid
+func minutesFromEnv(name string, fallback int) int {
+	value := os.Getenv(name)
+	if value == "" {
+		return fallback
+	}
+	minutes, err := strconv.Atoi(value)
+	if err != nil || minutes < 0 {
+		log.Println(name + " is not a valid number of minutes, " + strconv.Itoa(fallback) + " is used instead.")
+		return fallback
+	}
+	return minutes
+}
+
+// schedule Schedules the notifications of the webhook in each of its stages, in place of those already waiting for it
+func schedule(id string) {
+	ctx, cancel := context.WithCancel(workerContext())
+	notification := &pendingNotification{cancel: cancel}
+
+	pendingMutex.Lock()
+	if waiting, found := pending[id]; found {
+		waiting.cancel()
+	}
+	pending[id] = notification
+	pendingMutex.Unlock()
+
+	goWorker(func() {
+		var stages sync.WaitGroup
+		for _, stage := range []func(context.Context, string){sendBriefing, SendNotification, alertEnRoute} {
+			stage := stage
+			stages.Add(1)
+			goWorker(func() {
+				defer stages.Done()
+				stage(ctx, id)
+			})
+		}
+		stages.Wait()
+		pendingMutex.Lock()
+		if pending[id] == notification {
+			delete(pending, id)
+		}
+		pendingMutex.Unlock()
+		cancel()
+	})
+}
+
+// replan Plans the departure of the webhook again, reschedules its notifications if the departure has moved, and sends
+// an update if it has moved by the threshold or more. The departures around it are only predicted if the weather has
+// changed. Webhooks whose departure has passed are left as they are
+func replan(id string, hook structs.Webhook, weatherChanged bool) {
+	before, planned := departureOf(hook)
+	if !planned || !before.After(time.Now()) {
+		return
+	}
+	candidates := ReplanCandidates
+	if weatherChanged {
+		candidates = endpoints.DefaultCandidates
+	}
+	if err := planDeparture(id, candidates); err != nil {
+		log.Println("Unable to recalculate the departure of webhook with ID: " + id + "\n" + err.Error())
+		return
+	}
+	updated, err := webhookOf(id)
+	if err != nil {
+		log.Println("Unable to read webhook with ID: " + id + " after planning its departure.\n" + err.Error())
+		return
+	}
+	after, _ := departureOf(updated)
+	if after.Equal(before) {
+		return
+	}
+
+	log.Println("The departure of webhook with ID: " + id + " has moved from " + before.Format(time.RFC3339) + " to " +
+		after.Format(time.RFC3339) + ".")
+	schedule(id)
+	shift := int(after.Sub(before).Minutes())
+	data := notificationData(updated, TemplateUpdate)
+	data.Shift = shift
+	publish(hookEvent(EventDeparture, updated, data))
+	if shift >= ReplanThreshold || -shift >= ReplanThreshold {
+		sendUpdate(updated, after, shift)
+	}
+}
+
+// sendUpdate Tells the webhook how many minutes earlier or later than before to leave, a negative shift is earlier
+func sendUpdate(hook structs.Webhook, departure time.Time, shift int) {
+	locale := webhookLocale(hook)
+	key, count := "notification.update.later", shift
+	if shift < 0 {
+		key, count = "notification.update.earlier", -shift
+	}
+	data := notificationData(hook, TemplateUpdate)
+	data.Shift = shift
+	text := notificationText(hook, TemplateUpdate, data, i18n.Text(locale, key, i18n.Args{"count": count,
+		"departure": departure, "travel": hook.EstimatedTravelTime, "weather": hook.Weather}))
+
+	log.Println("Sending the updated departure to webhook with ID: " + hook.Id)
+	send(hook, data, text)
+}
+
+// departureOf The departure estimated for the webhook, and whether it has been estimated
+func departureOf(hook structs.Webhook) (time.Time, bool) {
+	arrival, estimated := estimateOf(&hook)
+	if !estimated {
+		return time.Time{}, false
+	}
+	return arrival.Add(-time.Duration(hook.EstimatedTravelTime) * time.Minute), true
+}
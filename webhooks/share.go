@@ -0,0 +1,215 @@
+package webhooks
+
+import (
+	"cloudproject/database"
+	"cloudproject/endpoints"
+	"cloudproject/i18n"
+	"cloudproject/router"
+	"cloudproject/structs"
+	"cloudproject/utils"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"os"
+	"strconv"
+	"time"
+)
+
+/**
+ * Class share.go
+ * Signed, expiring share links to the read-only summary and the calendar of a trip
+ * A link is signed with SHARE_SECRET, or with a secret made at startup if it is not set, in which case the links stop
+ * working when the application restarts. The summary uses the estimate of a webhook subscribed to the trip if any.
+ */
+
+// ShareSecret The key share links are signed with, SHARE_SECRET sets it
+var ShareSecret = secretFromEnv("SHARE_SECRET")
+
+// MaxShareHours The longest a share link can be valid, 30 days
+const MaxShareHours = 720
+
+// ShareQuery The query parameters accepted by ShareTrip
+var ShareQuery = utils.QuerySchema{
+	{Name: "validFor", Type: utils.TypeInteger, Description: "Hours the links are valid", Default: "168",
+		Minimum: utils.Bound(1), Maximum: utils.Bound(MaxShareHours)},
+}
+
+// SharedQuery The query parameters of a share link
+var SharedQuery = utils.QuerySchema{
+	{Name: "expires", Type: utils.TypeInteger, Description: "Unix time the link expires", Required: true},
+	{Name: "signature", Type: utils.TypeString, Description: "Signature of the trip and the expiry", Required: true},
+	i18n.LangParam,
+}
+
+// secretFromEnv Reads a secret from an environment variable, or makes a random one if it is not set
+func secretFromEnv(name string) []byte {
+	if value := os.Getenv(name); value != "" {
+		return []byte(value)
+	}
+	secret := make([]byte, 32)
+	if _, err := rand.Read(secret); err != nil {
+		log.Println("Unable to make a secret for " + name + ".\n" + err.Error())
+	}
+	return secret
+}
+
+// ShareTrip Makes signed links to the read-only summary and the calendar of the trip with the id in the path
+func ShareTrip(w http.ResponseWriter, r *http.Request) {
+	trip, found := tripOf(w, router.Param(r, "id"))
+	if !found {
+		return
+	}
+	hours := router.Query(r).Int("validFor")
+	expires := time.Now().Add(time.Duration(hours) * time.Hour).Truncate(time.Second)
+
+	query := "?expires=" + strconv.FormatInt(expires.Unix(), 10) + "&signature=" + shareSignature(trip.ID, expires)
+	link := structs.ShareLink{
+		Url:      baseURL(r) + "/rtc/v1/shared/" + trip.ID + query,
+		Calendar: baseURL(r) + "/rtc/v1/shared/" + trip.ID + "/calendar.ics" + query,
+		Expires:  expires.UTC().Format(time.RFC3339),
+	}
+	output, err := json.Marshal(link)
+	if err != nil {
+		http.Error(w, utils.JsonMarshalErrorHandling(err).Error(), http.StatusInternalServerError)
+		return
+	}
+	log.Println("Shared the trip with ID: " + trip.ID + " until " + link.Expires)
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	fmt.Fprintf(w, "%v", string(output))
+}
+
+// SharedTrip Displays the read-only summary of the trip of a share link
+func SharedTrip(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	trip, expires, valid := sharedTrip(w, r)
+	if !valid {
+		return
+	}
+	summary, err := tripSummary(trip, nil, i18n.Negotiate(r))
+	if err != nil {
+		log.Println("Unable to summarize the trip with ID: " + trip.ID + "\n" + err.Error())
+		http.Error(w, "Unable to plan the trip, try again", http.StatusInternalServerError)
+		return
+	}
+	summary.Expires = expires.UTC().Format(time.RFC3339)
+
+	output, err := json.Marshal(summary)
+	if err != nil {
+		http.Error(w, utils.JsonMarshalErrorHandling(err).Error(), http.StatusInternalServerError)
+		return
+	}
+	fmt.Fprintf(w, "%v", string(output))
+}
+
+// sharedTrip Checks the signature and expiry of a share link, and gets its trip. Answers with the error and returns
+// false if the link is not valid
+func sharedTrip(w http.ResponseWriter, r *http.Request) (structs.Trip, time.Time, bool) {
+	id := router.Param(r, "id")
+	query := router.Query(r)
+	unix := query.Int("expires")
+	expires := time.Unix(int64(unix), 0)
+	expected := shareSignature(id, expires)
+	if !hmac.Equal([]byte(query.Get("signature")), []byte(expected)) || expires.Before(time.Now()) {
+		http.Error(w, "The share link is invalid or has expired", http.StatusForbidden)
+		return structs.Trip{}, time.Time{}, false
+	}
+	trip, found := tripOf(w, id)
+	return trip, expires, found
+}
+
+// shareSignature The signature of a share link to the trip, valid until it expires
+func shareSignature(id string, expires time.Time) string {
+	mac := hmac.New(sha256.New, ShareSecret)
+	mac.Write([]byte(id + ":" + strconv.FormatInt(expires.Unix(), 10)))
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+// baseURL The scheme and host the request was sent to, which links are made from
+func baseURL(r *http.Request) string {
+	if r.TLS != nil {
+		return "https://" + r.Host
+	}
+	return "http://" + r.Host
+}
+
+// tripSummary The summary of the trip, with the times of the route planned for it. The estimate of the webhook, or
+// else of a webhook subscribed to the trip, is used if there is one, as it includes the weather and the ferries
+func tripSummary(trip structs.Trip, hook *structs.Webhook, locale string) (structs.TripSummary, error) {
+	stops, err := endpoints.TripTimetable(trip)
+	if err != nil {
+		return structs.TripSummary{}, err
+	}
+	departure, _ := time.Parse(time.RFC3339, stops[0].Time)
+	arrival, _ := time.Parse(time.RFC3339, stops[len(stops)-1].Time)
+	summary := structs.TripSummary{Name: trip.Name, Stops: stops}
+
+	if hook == nil {
+		hooks, err := subscriptions(trip.ID)
+		if err != nil {
+			log.Println("Unable to read the subscriptions to the trip with ID: " + trip.ID + "\n" + err.Error())
+		}
+		for i := range hooks {
+			if _, estimated := estimateOf(&hooks[i]); estimated {
+				hook = &hooks[i]
+				break
+			}
+		}
+	}
+
+	if expected, estimated := estimateOf(hook); estimated {
+		// The stops are passed at the same share of the estimated travel time as of the planned travel time
+		planned := arrival.Sub(departure)
+		travel := time.Duration(hook.EstimatedTravelTime) * time.Minute
+		start := expected.Add(-travel)
+		for i := range stops {
+			at, _ := time.Parse(time.RFC3339, stops[i].Time)
+			share := 1.0
+			if planned > 0 {
+				share = float64(at.Sub(departure)) / float64(planned)
+			}
+			stops[i].Time = start.Add(time.Duration(share * float64(travel))).Format(time.RFC3339)
+		}
+		departure, arrival = start, expected
+		summary.Weather = hook.Weather
+	} else {
+		weather, err := weatherAt(trip.Stops[0], locale)
+		if err != nil {
+			log.Println("Unable to get the weather at the start of the trip with ID: " + trip.ID + "\n" + err.Error())
+		}
+		summary.Weather = weather.Main.Message
+	}
+
+	summary.Departure = departure.Format(time.RFC3339)
+	summary.Arrival = arrival.Format(time.RFC3339)
+	summary.TravelTime = int(arrival.Sub(departure).Minutes())
+	return summary, nil
+}
+
+// estimateOf The arrival of the webhook, and whether its travel time has been estimated
+func estimateOf(hook *structs.Webhook) (time.Time, bool) {
+	if hook == nil || hook.EstimatedTravelTime <= 0 {
+		return time.Time{}, false
+	}
+	arrival, err := time.Parse(time.RFC822, hook.ArrivalTime)
+	return arrival, err == nil
+}
+
+// tripOf Gets the trip with the id, answers with the error and returns false if it can not be read
+func tripOf(w http.ResponseWriter, id string) (structs.Trip, bool) {
+	trip, err := database.GetTrip(id)
+	if err == database.ErrNotFound {
+		router.Error(w, "No trip with ID: "+id, http.StatusNotFound)
+		return structs.Trip{}, false
+	} else if err != nil {
+		log.Println("Unable to read the trip with ID: " + id + "\n" + err.Error())
+		http.Error(w, "Unable to read the trip, try again", http.StatusInternalServerError)
+		return structs.Trip{}, false
+	}
+	return trip, true
+}
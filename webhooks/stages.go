@@ -0,0 +1,240 @@
+package webhooks
+
+import (
+	"cloudproject/database"
+	"cloudproject/endpoints"
+	"cloudproject/i18n"
+	"cloudproject/structs"
+	"cloudproject/utils"
+	"context"
+	"encoding/json"
+	"errors"
+	"log"
+	"strings"
+	"time"
+)
+
+/**
+ * Class stages.go
+ * The stages of the notifications of a webhook: a briefing the evening before the trip, the alert to leave, and alerts
+ * of the traffic incidents on the route while the trip is driven
+ * Each stage has its own message, and the stages are chosen per webhook with stages, only the alert to leave is sent
+ * if it is left out. The briefing and the alert to leave are sent once, each incident is alerted once.
+ */
+
+// Notification stages
+const (
+	StageBriefing = "briefing"
+	StageLeave    = "leave"
+	StageEnRoute  = "enroute"
+)
+
+// Stages The notification stages a webhook can choose
+var Stages = []string{StageBriefing, StageLeave, StageEnRoute}
+
+// BriefingHour The hour of the evening before the departure the briefing is sent, in the time zone of the trip
+const BriefingHour = 18
+
+// EnRouteInterval How often the incidents on the route are checked while the trip is driven
+const EnRouteInterval = 10 * time.Minute
+
+// validateStages Checks the stages of the webhook, and stores them in lower case without duplicates
+func validateStages(hook *structs.Webhook) error {
+	var stages []string
+	for _, stage := range hook.Stages {
+		stage = strings.ToLower(strings.TrimSpace(stage))
+		if !utils.Contains(Stages, stage) {
+			return errors.New("error, the notification stage " + stage + " is not supported, supported stages: " + strings.Join(Stages, ", "))
+		}
+		if !utils.Contains(stages, stage) {
+			stages = append(stages, stage)
+		}
+	}
+	hook.Stages = stages
+	return nil
+}
+
+// stageEnabled Checks if the webhook is notified in the stage
+func stageEnabled(hook structs.Webhook, stage string) bool {
+	if len(hook.Stages) == 0 {
+		return stage == StageLeave
+	}
+	return utils.Contains(hook.Stages, stage)
+}
+
+// webhookOf Reads the webhook with the id
+func webhookOf(id string) (structs.Webhook, error) {
+	var hook structs.Webhook
+	stored, err := database.Get(id)
+	if err != nil {
+		return hook, err
+	}
+	err = json.Unmarshal(stored, &hook)
+	return hook, err
+}
+
+// sendBriefing Sends the briefing of the trip of the webhook the evening before its departure. Returns without sending
+// it if ctx is cancelled while waiting, or if the evening before has passed
+func sendBriefing(ctx context.Context, id string) {
+	hook, err := webhookOf(id)
+	if err != nil || !stageEnabled(hook, StageBriefing) || hook.Briefed != "" {
+		return
+	}
+	departure, planned := departureOf(hook)
+	if !planned {
+		return
+	}
+	trip, err := database.GetTrip(hook.Trip)
+	if err != nil {
+		log.Println("Unable to read the trip of webhook with ID: " + id + " for the briefing.\n" + err.Error())
+		return
+	}
+	due := briefingTime(departure, trip)
+	if !due.After(time.Now()) {
+		log.Println("The evening before the departure of webhook with ID: " + id + " has passed, the briefing is not sent.")
+		return
+	}
+	if !sleep(ctx, time.Until(due)) {
+		return
+	}
+
+	// The webhook may have been deleted or briefed while waiting
+	hook, err = webhookOf(id)
+	if err != nil || hook.Briefed != "" {
+		return
+	}
+	brief(hook, departure, trip)
+}
+
+// brief Sends the briefing of the trip departing at the departure to the webhook
+func brief(hook structs.Webhook, departure time.Time, trip structs.Trip) {
+	locale := webhookLocale(hook)
+	briefing, err := endpoints.TripBriefing(trip, departure, locale)
+	if err != nil {
+		log.Println("Unable to make the briefing of webhook with ID: " + hook.Id + "\n" + err.Error())
+		return
+	}
+	data := notificationData(hook, StageBriefing)
+	data.Briefing = &briefing
+	if !send(hook, data, notificationText(hook, StageBriefing, data, briefingText(locale, hook.ArrivalDestination, briefing))) {
+		return
+	}
+	if err := database.Update(hook.Id, map[string]interface{}{"briefed": time.Now().Format(time.RFC3339)}); err != nil {
+		log.Println("Unable to mark webhook with ID: " + hook.Id + " as briefed.\n" + err.Error())
+	}
+}
+
+// briefingTime The evening before the departure, in the time zone the departure or arrival of the trip is given in
+func briefingTime(departure time.Time, trip structs.Trip) time.Time {
+	location := time.UTC
+	for _, planned := range []string{trip.Departure, trip.Arrival} {
+		if at, err := time.Parse(time.RFC3339, planned); err == nil {
+			location = at.Location()
+			break
+		}
+	}
+	local := departure.In(location)
+	return time.Date(local.Year(), local.Month(), local.Day()-1, BriefingHour, 0, 0, 0, location)
+}
+
+// briefingText The message of the briefing in the locale, a paragraph for each part of it
+func briefingText(locale string, destination string, briefing structs.TripBriefing) string {
+	departure, _ := time.Parse(time.RFC3339, briefing.Departure)
+	arrival, _ := time.Parse(time.RFC3339, briefing.Arrival)
+	paragraphs := []string{i18n.Text(locale, "notification.briefing.text",
+		i18n.Args{"destination": destination, "departure": departure, "arrival": arrival})}
+	if briefing.DepartureWeather != "" {
+		paragraphs = append(paragraphs, i18n.Text(locale, "notification.briefing.departure", i18n.Args{"weather": briefing.DepartureWeather}))
+	}
+	if briefing.ArrivalWeather != "" {
+		paragraphs = append(paragraphs, i18n.Text(locale, "notification.briefing.arrival",
+			i18n.Args{"destination": destination, "weather": briefing.ArrivalWeather}))
+	}
+	if len(briefing.Closures) != 0 {
+		var roads []string
+		for _, closure := range briefing.Closures {
+			roads = append(roads, closure.Road+" ("+closure.Description+")")
+		}
+		paragraphs = append(paragraphs, i18n.Text(locale, "notification.briefing.closures",
+			i18n.Args{"count": len(briefing.Closures), "closures": strings.Join(roads, ", ")}))
+	}
+	if len(briefing.ChargingStops) != 0 {
+		paragraphs = append(paragraphs, i18n.Text(locale, "notification.briefing.charging", i18n.Args{"count": len(briefing.ChargingStops)}))
+	}
+	return strings.Join(paragraphs, "\n\n")
+}
+
+// alertEnRoute Alerts the webhook of the traffic incidents on the route of its trip, from the departure until the
+// arrival. Returns when the trip has arrived, or if ctx is cancelled
+func alertEnRoute(ctx context.Context, id string) {
+	hook, err := webhookOf(id)
+	if err != nil || !stageEnabled(hook, StageEnRoute) {
+		return
+	}
+	departure, planned := departureOf(hook)
+	arrival, _ := estimateOf(&hook)
+	if !planned || !arrival.After(time.Now()) {
+		return
+	}
+	if !sleep(ctx, time.Until(departure)) {
+		return
+	}
+
+	for time.Now().Before(arrival) {
+		// The webhook may have been deleted while waiting
+		hook, err = webhookOf(id)
+		if err != nil {
+			return
+		}
+		alertIncidents(hook)
+		if !sleep(ctx, EnRouteInterval) {
+			return
+		}
+	}
+}
+
+// alertIncidents Alerts the webhook of the incidents on the route of its trip it has not been alerted of
+func alertIncidents(hook structs.Webhook) {
+	trip, err := database.GetTrip(hook.Trip)
+	if err != nil {
+		log.Println("Unable to read the trip of webhook with ID: " + hook.Id + " for the incidents.\n" + err.Error())
+		return
+	}
+	incidents, err := endpoints.TripIncidents(trip)
+	if err != nil {
+		log.Println("Unable to get the incidents on the route of webhook with ID: " + hook.Id + "\n" + err.Error())
+		return
+	}
+
+	locale := webhookLocale(hook)
+	alerted := hook.Alerted
+	for _, incident := range incidents {
+		key := incidentKey(incident)
+		if utils.Contains(alerted, key) {
+			continue
+		}
+		data := notificationData(hook, StageEnRoute)
+		data.Incidents = []structs.OutIncident{incident}
+		text := notificationText(hook, StageEnRoute, data,
+			i18n.Text(locale, "notification.enroute", i18n.Args{"from": incident.From, "to": incident.To, "event": incident.Event}))
+		if send(hook, data, text) {
+			alerted = append(alerted, key)
+		}
+	}
+	if len(alerted) != len(hook.Alerted) {
+		if err := database.Update(hook.Id, map[string]interface{}{"alerted": alerted}); err != nil {
+			log.Println("Unable to store the incidents alerted to webhook with ID: " + hook.Id + "\n" + err.Error())
+		}
+	}
+}
+
+// send Sends the text rendered with the data to the webhook as a Slack message, returns false if it could not be made
+func send(hook structs.Webhook, data structs.NotificationData, text string) bool {
+	output, err := json.Marshal(structs.JsonMessage{Text: text})
+	if err != nil {
+		log.Println(utils.JsonMarshalErrorHandling(err))
+		return false
+	}
+	deliver(hook, data, text, string(output))
+	return true
+}
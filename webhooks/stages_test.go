@@ -0,0 +1,123 @@
+package webhooks
+
+import (
+	"bytes"
+	"cloudproject/database"
+	"cloudproject/harness"
+	"cloudproject/structs"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestBriefingTime(t *testing.T) {
+	departure := time.Date(2021, 5, 17, 8, 0, 0, 0, time.UTC)
+	tests := map[string]struct {
+		trip     structs.Trip
+		expected time.Time
+	}{
+		"arrival in CEST": {structs.Trip{Arrival: "2021-05-17T12:00:00+02:00"}, time.Date(2021, 5, 16, 16, 0, 0, 0, time.UTC)},
+		"departure first": {structs.Trip{Departure: "2021-05-17T03:00:00-05:00", Arrival: "2021-05-17T12:00:00+02:00"},
+			time.Date(2021, 5, 16, 23, 0, 0, 0, time.UTC)},
+		"no time zone": {structs.Trip{}, time.Date(2021, 5, 16, 18, 0, 0, 0, time.UTC)},
+	}
+	for name, test := range tests {
+		if due := briefingTime(departure, test.trip); !due.Equal(test.expected) {
+			t.Errorf("%v: expected the briefing at %v; got %v", name, test.expected, due)
+		}
+	}
+}
+
+func TestBriefingText(t *testing.T) {
+	briefing := structs.TripBriefing{Departure: "2021-05-17T08:00:00Z", Arrival: "2021-05-17T09:00:00Z",
+		DepartureWeather: "Light snow", Closures: []structs.RouteClosure{
+			{Road: "Fv 51", Description: "Closed for the winter"}, {Road: "E6", Description: "Convoy driving"}},
+		ChargingStops: []structs.ChargingStop{{}}}
+	text := briefingText("en", "lillehammer", briefing)
+	paragraphs := strings.Split(text, "\n\n")
+	expected := []string{
+		"Your trip to lillehammer is tomorrow. Leave at 2021-05-17 08:00:00 +0000 UTC to arrive at 2021-05-17 09:00:00 +0000 UTC.",
+		"Forecast when you leave: Light snow",
+		"2 closures on the route: Fv 51 (Closed for the winter), E6 (Convoy driving)",
+		"Plan 1 charging stop on the way.",
+	}
+	if len(paragraphs) != len(expected) {
+		t.Fatalf("Expected %v paragraphs; got %q", len(expected), text)
+	}
+	for i := range expected {
+		if paragraphs[i] != expected[i] {
+			t.Errorf("Expected %q; got %q", expected[i], paragraphs[i])
+		}
+	}
+}
+
+func TestNotificationStages(t *testing.T) {
+	h := harness.Start(t)
+
+	// Unknown stages are rejected
+	content, _ := json.Marshal(map[string]interface{}{"url": h.WebhookURL, "DepartureLocation": "gjøvik",
+		"ArrivalDestination": "lillehammer", "ArrivalTime": time.Now().Add(48 * time.Hour).UTC().Format(time.RFC822),
+		"stages": []string{"leave", "teleport"}})
+	rec := httptest.NewRecorder()
+	AddWebhook(rec, httptest.NewRequest(http.MethodPost, "/rtc/v1/notifyme", bytes.NewReader(content)))
+	if rec.Code != http.StatusBadRequest {
+		t.Errorf("Expected status Bad Request for an unknown stage; got %v", rec.Code)
+	}
+
+	// The trip is being driven, the incidents on the route are alerted once each and the alert to leave is not sent
+	id := register(t, map[string]interface{}{
+		"url":                h.WebhookURL,
+		"DepartureLocation":  "gjøvik",
+		"ArrivalDestination": "lillehammer",
+		"ArrivalTime":        time.Now().Add(30 * time.Minute).UTC().Format(time.RFC822),
+		"stages":             []string{"EnRoute"},
+	})
+	for i := 0; i < 2; i++ {
+		var message structs.JsonMessage
+		delivery := h.WaitForDelivery(t, 10*time.Second)
+		if err := json.Unmarshal(delivery.Body, &message); err != nil || !strings.HasPrefix(message.Text, "Traffic incident on your route from ") {
+			t.Errorf("Expected an alert of an incident on the route; got %v", string(delivery.Body))
+		}
+	}
+	var hook structs.Webhook
+	for wait := 0; wait < 50 && len(hook.Alerted) != 2; wait++ {
+		time.Sleep(20 * time.Millisecond)
+		hook, _ = webhookOf(id)
+	}
+	if len(hook.Alerted) != 2 || !strings.HasPrefix(hook.Alerted[0], "Hunndalen|Redalen|Roadworks|") || hook.Notified != "" {
+		t.Errorf("Expected the two incidents to be alerted in the order they are passed, and not the alert to leave; got %+v", hook)
+	}
+	alertIncidents(hook)
+	select {
+	case delivery := <-h.Deliveries():
+		t.Errorf("Expected the incidents to be alerted once; got %v", string(delivery.Body))
+	case <-time.After(200 * time.Millisecond):
+	}
+
+	// The briefing of a trip tomorrow
+	id = register(t, map[string]interface{}{
+		"url":                h.WebhookURL,
+		"DepartureLocation":  "gjøvik",
+		"ArrivalDestination": "lillehammer",
+		"ArrivalTime":        time.Now().Add(48 * time.Hour).UTC().Format(time.RFC822),
+		"stages":             []string{"briefing", "leave"},
+	})
+	hook, _ = webhookOf(id)
+	trip, err := database.GetTrip(hook.Trip)
+	if err != nil {
+		t.Fatalf("Could not read the trip of the webhook: %v", err)
+	}
+	departure, _ := departureOf(hook)
+	brief(hook, departure, trip)
+	var message structs.JsonMessage
+	delivery := h.WaitForDelivery(t, 10*time.Second)
+	if err := json.Unmarshal(delivery.Body, &message); err != nil || !strings.HasPrefix(message.Text, "Your trip to lillehammer is tomorrow.") {
+		t.Errorf("Expected the briefing; got %v", string(delivery.Body))
+	}
+	if hook, _ = webhookOf(id); hook.Briefed == "" || !stageEnabled(hook, StageLeave) || stageEnabled(hook, StageEnRoute) {
+		t.Errorf("Expected the webhook to be briefed, and notified to leave but not en route; got %+v", hook)
+	}
+}
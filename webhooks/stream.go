@@ -0,0 +1,180 @@
+package webhooks
+
+import (
+	"cloudproject/router"
+	"cloudproject/structs"
+	"cloudproject/utils"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+)
+
+/**
+ * Class stream.go
+ * Streams the events of a trip to clients, as Server-Sent Events or over a WebSocket
+ * A Server-Sent Events stream ends before the write timeout of the server, and the client reconnects with the id of the
+ * last event it got in Last-Event-ID, as browsers do by themselves. A WebSocket is kept open until either side closes
+ * it, and a client reconnecting gives the id of the last event it got in after.
+ */
+
+// StreamDuration How long a Server-Sent Events stream lasts before the client reconnects, within the write timeout of
+// the server
+const StreamDuration = 50 * time.Second
+
+// KeepAliveInterval How often idle streams are written to, so proxies keep them open and gone clients are noticed
+const KeepAliveInterval = 20 * time.Second
+
+// EventsQuery The query parameters accepted by TripEvents
+var EventsQuery = utils.QuerySchema{
+	{Name: "after", Type: utils.TypeInteger, Description: "Id of the last event received, the kept events after it are sent first. Last-Event-ID takes precedence",
+		Minimum: utils.Bound(0)},
+}
+
+// streamsDone Closed when the streams are to end, for the server to shut down
+var streamsDone = make(chan struct{})
+
+// closeStreams Closes streamsDone once
+var closeStreams sync.Once
+
+// CloseStreams Ends the event streams, so the server does not wait for them when shutting down
+func CloseStreams() {
+	closeStreams.Do(func() { close(streamsDone) })
+}
+
+// TripEvents Streams the events of the trip with the id in the path, over a WebSocket if the request asks to upgrade
+// and as Server-Sent Events otherwise
+func TripEvents(w http.ResponseWriter, r *http.Request) {
+	trip, found := tripOf(w, router.Param(r, "id"))
+	if !found {
+		return
+	}
+	after := int64(router.Query(r).Int("after"))
+	if last, err := strconv.ParseInt(r.Header.Get("Last-Event-ID"), 10, 64); err == nil {
+		after = last
+	}
+
+	if router.IsWebSocket(r) {
+		streamWebSocket(w, r, trip.ID, after)
+		return
+	}
+	streamEvents(w, r, trip.ID, after)
+}
+
+// streamEvents Writes the events of the trip after the id as Server-Sent Events, until the stream has lasted for
+// StreamDuration or the client leaves
+func streamEvents(w http.ResponseWriter, r *http.Request, trip string, after int64) {
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		http.Error(w, "Streaming is not supported by this server", http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("X-Accel-Buffering", "no")
+
+	client, missed := subscribeTrip(trip, after)
+	defer unsubscribeTrip(client)
+
+	// The client reconnects right away when the stream ends
+	fmt.Fprint(w, "retry: 1000\n\n")
+	for _, event := range missed {
+		if err := writeEvent(w, event); err != nil {
+			return
+		}
+	}
+	flusher.Flush()
+
+	end := time.NewTimer(StreamDuration)
+	defer end.Stop()
+	keepAlive := time.NewTicker(KeepAliveInterval)
+	defer keepAlive.Stop()
+	for {
+		select {
+		case event := <-client.events:
+			if err := writeEvent(w, event); err != nil {
+				return
+			}
+		case <-keepAlive.C:
+			if _, err := fmt.Fprint(w, ": keep-alive\n\n"); err != nil {
+				return
+			}
+		case <-end.C:
+			return
+		case <-r.Context().Done():
+			return
+		case <-streamsDone:
+			return
+		}
+		flusher.Flush()
+	}
+}
+
+// writeEvent Writes the event as a Server-Sent Event named by its type
+func writeEvent(w io.Writer, event structs.TripEvent) error {
+	output, err := json.Marshal(event)
+	if err != nil {
+		log.Println(utils.JsonMarshalErrorHandling(err))
+		return nil
+	}
+	_, err = fmt.Fprintf(w, "id: %d\nevent: %s\ndata: %s\n\n", event.ID, event.Type, output)
+	return err
+}
+
+// streamWebSocket Upgrades the request to a WebSocket and sends the events of the trip after the id as JSON text
+// messages, until either side closes it
+func streamWebSocket(w http.ResponseWriter, r *http.Request, trip string, after int64) {
+	ws, err := router.Upgrade(w, r)
+	if err != nil {
+		log.Println("Unable to upgrade the stream of trip " + trip + " to a WebSocket.\n" + err.Error())
+		return
+	}
+	defer ws.Close()
+
+	client, missed := subscribeTrip(trip, after)
+	defer unsubscribeTrip(client)
+
+	closed := make(chan struct{})
+	go func() {
+		defer close(closed)
+		_ = ws.Listen()
+	}()
+
+	for _, event := range missed {
+		if err := writeMessage(ws, event); err != nil {
+			return
+		}
+	}
+	keepAlive := time.NewTicker(KeepAliveInterval)
+	defer keepAlive.Stop()
+	for {
+		select {
+		case event := <-client.events:
+			if err := writeMessage(ws, event); err != nil {
+				return
+			}
+		case <-keepAlive.C:
+			if err := ws.Ping(); err != nil {
+				return
+			}
+		case <-closed:
+			return
+		case <-streamsDone:
+			return
+		}
+	}
+}
+
+// writeMessage Writes the event as a JSON text message
+func writeMessage(ws *router.WebSocket, event structs.TripEvent) error {
+	output, err := json.Marshal(event)
+	if err != nil {
+		log.Println(utils.JsonMarshalErrorHandling(err))
+		return nil
+	}
+	return ws.WriteText(output)
+}
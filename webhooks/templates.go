@@ -0,0 +1,278 @@
+package webhooks
+
+import (
+	"cloudproject/database"
+	"cloudproject/structs"
+	"cloudproject/utils"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"log"
+	"net/http"
+	"net/url"
+	"os"
+	"strconv"
+	"strings"
+	"text/template"
+	"text/template/parse"
+	"time"
+)
+
+/**
+ * Class templates.go
+ * Templates of the notifications of a webhook
+ * The message of each stage, of the updates to the departure and to the estimated arrival while driving, and the title
+ * of the alert to leave can be replaced by a Go text/template rendered with a structs.NotificationData. The templates
+ * are rendered against sample data when the webhook is registered, and the default message is sent instead of one
+ * failing when notifying.
+ */
+
+// Notification templates besides those of the stages
+const (
+	TemplateUpdate = "update"
+	TemplateETA    = "eta"
+	TemplateTitle  = "title"
+)
+
+// TemplateKeys The notification templates a webhook can give
+var TemplateKeys = []string{StageBriefing, StageLeave, StageEnRoute, TemplateUpdate, TemplateETA, TemplateTitle}
+
+// MaxTemplateLength The longest template accepted, in bytes
+const MaxTemplateLength = 4000
+
+// MaxMessageLength The longest message a template can render, in bytes
+const MaxMessageLength = 8000
+
+// errMessageTooLong Stops the rendering of a template once the message is longer than MaxMessageLength
+var errMessageTooLong = errors.New("the template renders a message longer than " + strconv.Itoa(MaxMessageLength) + " bytes")
+
+// cappedWriter Collects a rendered message, and fails once it grows past MaxMessageLength
+type cappedWriter struct {
+	strings.Builder
+}
+
+// Write Adds to the message, unless it would grow past MaxMessageLength
+func (w *cappedWriter) Write(p []byte) (int, error) {
+	if w.Len()+len(p) > MaxMessageLength {
+		return 0, errMessageTooLong
+	}
+	return w.Builder.Write(p)
+}
+
+// PublicURL The scheme and host the links of the notifications are made from, set with PUBLIC_URL
+var PublicURL = publicURL()
+
+// publicURL Reads PUBLIC_URL, or uses the address the service has been deployed at
+func publicURL() string {
+	if value := os.Getenv("PUBLIC_URL"); value != "" {
+		return strings.TrimRight(value, "/")
+	}
+	return "http://10.212.141.222:80"
+}
+
+// validateTemplates Checks that each template of the webhook renders against the sample data of its stage, and stores
+// them by their key in lower case
+func validateTemplates(hook *structs.Webhook) error {
+	var templates map[string]string
+	for key, text := range hook.Templates {
+		key = strings.ToLower(strings.TrimSpace(key))
+		if !utils.Contains(TemplateKeys, key) {
+			return errors.New("error, there is no notification template " + key + ", supported templates: " + strings.Join(TemplateKeys, ", "))
+		}
+		if _, err := renderTemplate(key, text, sampleData(key)); err != nil {
+			return errors.New("error, the " + key + " template is invalid: " + err.Error())
+		}
+		if templates == nil {
+			templates = map[string]string{}
+		}
+		templates[key] = text
+	}
+	hook.Templates = templates
+	return nil
+}
+
+// renderTemplate Renders the template with the data, the template has to give a message which is not blank. Templates
+// can not define or call other templates, which would let a short template render without bounds
+func renderTemplate(key string, text string, data structs.NotificationData) (string, error) {
+	if len(text) > MaxTemplateLength {
+		return "", errors.New("the template is longer than " + strconv.Itoa(MaxTemplateLength) + " bytes")
+	}
+	parsed, err := template.New(key).Parse(text)
+	if err != nil {
+		return "", err
+	}
+	if len(parsed.Templates()) > 1 || (parsed.Tree != nil && callsTemplate(parsed.Tree.Root)) {
+		return "", errors.New("the template can not use define, template or block")
+	}
+	var rendered cappedWriter
+	if err := parsed.Execute(&rendered, data); err != nil {
+		return "", err
+	}
+	if strings.TrimSpace(rendered.String()) == "" {
+		return "", errors.New("the template renders an empty message")
+	}
+	return rendered.String(), nil
+}
+
+// callsTemplate Checks if the node or any node within it calls a template
+func callsTemplate(node parse.Node) bool {
+	switch node := node.(type) {
+	case *parse.TemplateNode:
+		return true
+	case *parse.ListNode:
+		if node == nil {
+			return false
+		}
+		for _, child := range node.Nodes {
+			if callsTemplate(child) {
+				return true
+			}
+		}
+	case *parse.IfNode:
+		return callsTemplate(node.List) || callsTemplate(node.ElseList)
+	case *parse.RangeNode:
+		return callsTemplate(node.List) || callsTemplate(node.ElseList)
+	case *parse.WithNode:
+		return callsTemplate(node.List) || callsTemplate(node.ElseList)
+	}
+	return false
+}
+
+// notificationText Renders the template of the webhook with the key, or returns the default message if the webhook has
+// no such template or it fails
+func notificationText(hook structs.Webhook, key string, data structs.NotificationData, fallback string) string {
+	text, found := hook.Templates[key]
+	if !found {
+		return fallback
+	}
+	rendered, err := renderTemplate(key, text, data)
+	if err != nil {
+		log.Println("Unable to render the " + key + " template of webhook with ID: " + hook.Id +
+			", the default message is sent instead.\n" + err.Error())
+		return fallback
+	}
+	return rendered
+}
+
+// notificationData The data the templates of the webhook are rendered with in the stage, from its estimate and trip
+func notificationData(hook structs.Webhook, stage string) structs.NotificationData {
+	data := structs.NotificationData{Stage: stage, TravelTime: hook.EstimatedTravelTime, Uncertainty: hook.Uncertainty,
+		Trip: structs.NotificationTrip{ID: hook.Trip, Start: hook.DepartureLocation, Destination: hook.ArrivalDestination,
+			Stops: []string{hook.DepartureLocation, hook.ArrivalDestination}},
+		Weather: structs.NotificationWeather{Message: hook.Weather}, Incidents: []structs.OutIncident{},
+		Links: notificationLinks(hook)}
+	if hook.Conditions != nil {
+		data.Weather.Conditions = *hook.Conditions
+	}
+	if hook.Trip != "" {
+		if trip, err := database.GetTrip(hook.Trip); err == nil {
+			data.Trip.Name = trip.Name
+			data.Trip.Stops = trip.Stops
+		}
+	}
+	data.Arrival, _ = estimateOf(&hook)
+	data.Departure, _ = departureOf(hook)
+	return data
+}
+
+// notificationLinks The links to the weather at the destination, the trip and the calendar of the webhook
+func notificationLinks(hook structs.Webhook) structs.NotificationLinks {
+	base := PublicURL + "/rtc/v1"
+	links := structs.NotificationLinks{Weather: base + "/weather/" + url.PathEscape(hook.ArrivalDestination),
+		Calendar: base + "/notifyme/" + hook.Id + "/calendar.ics"}
+	if hook.Trip != "" {
+		links.Trip = base + "/trips/" + hook.Trip
+	}
+	return links
+}
+
+// riskColor The color of the attachment of the alert to leave, from green to red as the risk of the road conditions grows
+func riskColor(conditions structs.RoadConditions) string {
+	switch {
+	case conditions.RiskScore < 30:
+		return "#2eb886"
+	case conditions.RiskScore < 60:
+		return "#daa038"
+	default:
+		return "#a30200"
+	}
+}
+
+// sampleData The data of a trip from gjøvik to lillehammer the templates with the key are checked and previewed with
+func sampleData(key string) structs.NotificationData {
+	stage := key
+	if key == TemplateTitle {
+		stage = StageLeave
+	}
+	arrival := time.Date(2021, 5, 17, 10, 0, 0, 0, time.UTC)
+	departure := arrival.Add(-55 * time.Minute)
+	hook := structs.Webhook{Id: "sample", Trip: "sample", ArrivalDestination: "lillehammer"}
+	data := structs.NotificationData{Stage: stage, Departure: departure, Arrival: arrival, TravelTime: 55, Uncertainty: 7,
+		Trip: structs.NotificationTrip{ID: "sample", Name: "Weekend at the cabin", Start: "gjøvik", Destination: "lillehammer",
+			Stops: []string{"gjøvik", "lillehammer"}},
+		Weather: structs.NotificationWeather{Message: "It is raining lightly, the roads may be slippery",
+			Conditions: structs.RoadConditions{Precipitation: "rain", PrecipitationIntensity: "light", FreezingRisk: "none",
+				Visibility: "good", WindRisk: "low", Daylight: true, RiskScore: 20, DelayFactor: 1.1,
+				Reasons: []structs.RiskReason{{Factor: "precipitation", Points: 20, Description: "light rain"}}}},
+		Incidents: []structs.OutIncident{}, Links: notificationLinks(hook)}
+
+	switch stage {
+	case StageBriefing:
+		data.Briefing = &structs.TripBriefing{Departure: departure.Format(time.RFC3339), Arrival: arrival.Format(time.RFC3339),
+			DepartureWeather: "Light rain", ArrivalWeather: "Overcast",
+			Closures:      []structs.RouteClosure{{ID: "sample", Type: "closed", Road: "Fv 51", Description: "Closed for the winter", Winter: true}},
+			ChargingStops: []structs.ChargingStop{}}
+	case StageEnRoute:
+		data.Incidents = []structs.OutIncident{{Start: departure.Add(-time.Hour), End: arrival.Add(time.Hour), From: "Biri",
+			To: "Vingrom", Event: "Closed"}}
+	case TemplateUpdate:
+		data.Shift = -15
+	case TemplateETA:
+		delay := 12
+		data.Shift = 12
+		data.Progress = &structs.TripProgress{Trip: "sample", Position: utils.Coordinate{Latitude: 60.9, Longitude: 10.6},
+			Time: departure.Add(20 * time.Minute).Format(time.RFC3339), NextStop: "lillehammer",
+			RemainingStops: []string{"lillehammer"}, Distance: 31000, TravelTime: 47, TrafficDelay: 12,
+			ETA: arrival.Add(12 * time.Minute).Format(time.RFC3339), PlannedArrival: arrival.Format(time.RFC3339), Delay: &delay}
+	}
+	return data
+}
+
+// PreviewTemplate Renders the notification template in the body against the sample data of its stage
+func PreviewTemplate(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	input, err := ioutil.ReadAll(r.Body)
+	if err != nil {
+		log.Println("There was an error during read of response body.\n" + err.Error())
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	var preview structs.TemplatePreview
+	if err = json.Unmarshal(input, &preview); err != nil {
+		http.Error(w, utils.JsonUnmarshalErrorHandling(err).Error(), http.StatusBadRequest)
+		return
+	}
+	key := strings.ToLower(strings.TrimSpace(preview.Stage))
+	if key == "" {
+		key = StageLeave
+	}
+	if !utils.Contains(TemplateKeys, key) {
+		http.Error(w, "error, there is no notification template "+key+", supported templates: "+strings.Join(TemplateKeys, ", "),
+			http.StatusBadRequest)
+		return
+	}
+
+	text, err := renderTemplate(key, preview.Template, sampleData(key))
+	if err != nil {
+		http.Error(w, "error, the "+key+" template is invalid: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+	output, err := json.Marshal(structs.RenderedTemplate{Stage: key, Text: text})
+	if err != nil {
+		http.Error(w, utils.JsonMarshalErrorHandling(err).Error(), http.StatusInternalServerError)
+		return
+	}
+	fmt.Fprintf(w, "%v", string(output))
+}
@@ -0,0 +1,113 @@
+package webhooks
+
+import (
+	"bytes"
+	"cloudproject/harness"
+	"cloudproject/structs"
+	"cloudproject/utils"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestValidateTemplates(t *testing.T) {
+	tests := []struct {
+		name      string
+		templates map[string]string
+		valid     bool
+	}{
+		{"none", nil, true},
+		{"every key", map[string]string{"Briefing": "{{len .Briefing.Closures}} closures", " leave ": "Leave at {{.Departure.Format \"15:04\"}}",
+			"enroute": "{{range .Incidents}}{{.Event}}{{end}}", "update": "{{.Shift}}", "title": "{{.Trip.Name}}"}, true},
+		{"unknown key", map[string]string{"arrival": "Arrived"}, false},
+		{"syntax", map[string]string{"leave": "{{.Departure"}, false},
+		{"unknown field", map[string]string{"leave": "{{.Traffic}}"}, false},
+		{"briefing outside its stage", map[string]string{"leave": "{{.Briefing.Departure}}"}, false},
+		{"empty message", map[string]string{"update": "{{if .Incidents}}{{.Shift}}{{end}}"}, false},
+		{"too long", map[string]string{"leave": strings.Repeat("a", MaxTemplateLength+1)}, false},
+		{"define", map[string]string{"leave": `{{define "a"}}aaaa{{end}}Go`}, false},
+		{"template", map[string]string{"leave": `{{if .Trip}}{{template "leave" .}}{{end}}`}, false},
+		{"block", map[string]string{"leave": `{{block "a" .}}Go{{end}}`}, false},
+		{"message too long", map[string]string{"leave": `{{range .Trip.Stops}}{{range $.Trip.Stops}}` + strings.Repeat("a", 3000) + `{{end}}{{end}}`}, false},
+	}
+	for _, test := range tests {
+		hook := structs.Webhook{Templates: test.templates}
+		if err := validateTemplates(&hook); (err == nil) != test.valid {
+			t.Errorf("%v: expected valid %v; got %v", test.name, test.valid, err)
+		}
+	}
+
+	hook := structs.Webhook{Templates: map[string]string{" Leave ": "Go"}}
+	if err := validateTemplates(&hook); err != nil || hook.Templates["leave"] != "Go" {
+		t.Errorf("Expected the template to be stored as leave; got %v (%v)", hook.Templates, err)
+	}
+}
+
+func TestPreviewTemplate(t *testing.T) {
+	tests := []struct {
+		body     structs.TemplatePreview
+		status   int
+		expected string
+	}{
+		{structs.TemplatePreview{Template: "Leave {{.Trip.Start}} at {{.Departure.Format \"15:04\"}}, {{.Weather.Conditions.RiskScore}}% risk"},
+			http.StatusOK, "Leave gjøvik at 09:05, 20% risk"},
+		{structs.TemplatePreview{Stage: "Update", Template: "Moved {{.Shift}} minutes, see {{.Links.Trip}}"},
+			http.StatusOK, "Moved -15 minutes, see " + PublicURL + "/rtc/v1/trips/sample"},
+		{structs.TemplatePreview{Stage: "enroute", Template: "{{(index .Incidents 0).Event}} from {{(index .Incidents 0).From}}"},
+			http.StatusOK, "Closed from Biri"},
+		{structs.TemplatePreview{Stage: "enroute", Template: "{{.Briefing.Departure}}"}, http.StatusBadRequest, ""},
+		{structs.TemplatePreview{Stage: "arrival", Template: "Arrived"}, http.StatusBadRequest, ""},
+	}
+	for _, test := range tests {
+		content, _ := json.Marshal(test.body)
+		rec := httptest.NewRecorder()
+		PreviewTemplate(rec, httptest.NewRequest(http.MethodPost, "/rtc/v1/notifyme/preview", bytes.NewReader(content)))
+		if rec.Code != test.status {
+			t.Errorf("%q: expected status %v; got %v: %v", test.body.Template, test.status, rec.Code, rec.Body.String())
+			continue
+		}
+		var rendered structs.RenderedTemplate
+		if test.status == http.StatusOK && (json.Unmarshal(rec.Body.Bytes(), &rendered) != nil || rendered.Text != test.expected) {
+			t.Errorf("Expected %q; got %v", test.expected, rec.Body.String())
+		}
+	}
+}
+
+func TestNotificationTemplates(t *testing.T) {
+	h := harness.Start(t)
+
+	// Invalid templates are rejected at registration
+	content, _ := json.Marshal(map[string]interface{}{"url": h.WebhookURL, "DepartureLocation": "gjøvik",
+		"ArrivalDestination": "lillehammer", "ArrivalTime": time.Now().Add(48 * time.Hour).UTC().Format(time.RFC822),
+		"templates": map[string]string{"leave": "{{.Departure"}})
+	rec := httptest.NewRecorder()
+	AddWebhook(rec, httptest.NewRequest(http.MethodPost, "/rtc/v1/notifyme", bytes.NewReader(content)))
+	if rec.Code != http.StatusBadRequest {
+		t.Errorf("Expected status Bad Request for an invalid template; got %v", rec.Code)
+	}
+
+	// The incidents alerted while driving are rendered with the template of the stage
+	register(t, map[string]interface{}{
+		"url":                h.WebhookURL,
+		"DepartureLocation":  "gjøvik",
+		"ArrivalDestination": "lillehammer",
+		"ArrivalTime":        time.Now().Add(30 * time.Minute).UTC().Format(time.RFC822),
+		"stages":             []string{"enroute"},
+		"templates":          map[string]string{"enroute": "{{range .Incidents}}{{.Event}}: {{.From}} - {{.To}}{{end}} on the way to {{.Trip.Destination}}"},
+	})
+	var texts []string
+	for i := 0; i < 2; i++ {
+		var message structs.JsonMessage
+		delivery := h.WaitForDelivery(t, 10*time.Second)
+		if err := json.Unmarshal(delivery.Body, &message); err != nil {
+			t.Fatalf("The invocation is not a Slack message: %v", err)
+		}
+		texts = append(texts, message.Text)
+	}
+	if !utils.Contains(texts, "Closed: Biri - Vingrom on the way to lillehammer") {
+		t.Errorf("Expected the closure to be alerted with the template; got %q", texts)
+	}
+}
@@ -0,0 +1,207 @@
+package webhooks
+
+import (
+	"cloudproject/database"
+	"cloudproject/endpoints"
+	"cloudproject/router"
+	"cloudproject/structs"
+	"cloudproject/utils"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"time"
+)
+
+/**
+ * Class trips.go
+ * Webhooks as subscriptions to saved trips
+ * A webhook notifies when to depart on its trip to arrive at the arrival of the trip. The places, profile and
+ * preferences of the trip are copied to the webhook, and updated when the trip is replaced.
+ */
+
+func init() {
+	endpoints.TripChanged = updateSubscriptions
+}
+
+// TripSubscriptions Displays the webhooks subscribed to the trip with the id in the path
+func TripSubscriptions(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-type", "application/json")
+	id := router.Param(r, "id")
+	if _, found := tripOf(w, id); !found {
+		return
+	}
+	subscriptions, err := subscriptions(id)
+	if err != nil {
+		http.Error(w, "Error occurred when listing webhooks from database", http.StatusInternalServerError)
+		return
+	}
+
+	output, err := json.Marshal(subscriptions)
+	if err != nil {
+		http.Error(w, utils.JsonMarshalErrorHandling(err).Error(), http.StatusInternalServerError)
+		return
+	}
+	fmt.Fprintf(w, "%v", string(output))
+}
+
+// subscriptions The webhooks subscribed to the trip
+func subscriptions(id string) ([]structs.Webhook, error) {
+	docs, err := database.GetAll()
+	if err != nil {
+		return nil, err
+	}
+	hooks := []structs.Webhook{}
+	for _, doc := range docs {
+		var hook structs.Webhook
+		if err := doc.DataTo(&hook); err != nil {
+			log.Println("There was an error while adding data to the struct.\n" + err.Error())
+			continue
+		}
+		if hook.Trip == id {
+			hooks = append(hooks, hook)
+		}
+	}
+	return hooks, nil
+}
+
+// subscribe Makes the webhook a subscription to its trip. Returns an error if the trip does not exist, or has no
+// planned arrival to notify for
+func subscribe(hook *structs.Webhook) error {
+	trip, err := database.GetTrip(hook.Trip)
+	if err != nil {
+		log.Println("Unable to read the trip with ID: " + hook.Trip + "\n" + err.Error())
+		return errors.New("error, there is no trip with ID: " + hook.Trip)
+	}
+	if trip.Arrival == "" {
+		return errors.New("error, only trips with a planned arrival can be subscribed to")
+	}
+	applyTrip(hook, trip)
+	return nil
+}
+
+// applyTrip Copies the start, destination, arrival, profile and preferences of the trip to the webhook
+func applyTrip(hook *structs.Webhook, trip structs.Trip) {
+	hook.Trip = trip.ID
+	hook.DepartureLocation = trip.Stops[0]
+	hook.ArrivalDestination = trip.Stops[len(trip.Stops)-1]
+	if arrival, err := time.Parse(time.RFC3339, trip.Arrival); err == nil {
+		hook.ArrivalTime = arrival.UTC().Format(time.RFC822)
+	}
+	hook.Profile = trip.Profile
+	preferences := trip.Preferences
+	hook.Preferences = &preferences
+}
+
+// saveTrip Saves the trip of a webhook registered without one, from its departure location to its arrival destination
+func saveTrip(hook *structs.Webhook) error {
+	trip := structs.Trip{Name: hook.DepartureLocation + " - " + hook.ArrivalDestination,
+		Stops: []string{hook.DepartureLocation, hook.ArrivalDestination}, Profile: hook.Profile}
+	if hook.Preferences != nil {
+		trip.Preferences = *hook.Preferences
+	}
+	if arrival, err := time.Parse(time.RFC822, hook.ArrivalTime); err == nil {
+		trip.Arrival = arrival.Format(time.RFC3339)
+	}
+	if err := endpoints.ValidateTrip(&trip); err != nil {
+		return err
+	}
+	id, err := database.AddTrip(trip)
+	if err != nil {
+		return err
+	}
+	hook.Trip = id
+	return nil
+}
+
+// discardTrip Deletes the trip saved for a webhook which could not be registered
+func discardTrip(id string) {
+	if err := database.DeleteTrip(id); err != nil {
+		log.Println("Unable to delete the trip with ID: " + id + " of a webhook which was not registered.\n" + err.Error())
+	}
+}
+
+// AttachTrips Saves the trips of the webhooks registered before trips were added, so every webhook is a subscription
+// to a trip
+func AttachTrips() {
+	docs, err := database.GetAll()
+	if err != nil {
+		log.Println("There was an error while iterating through the webhooks.\n" + err.Error())
+		return
+	}
+	for _, doc := range docs {
+		var hook structs.Webhook
+		if err := doc.DataTo(&hook); err != nil {
+			log.Println("There was an error while adding data to the struct.\n" + err.Error())
+			continue
+		}
+		if hook.Trip != "" {
+			continue
+		}
+		if err := saveTrip(&hook); err != nil {
+			log.Println("Unable to save the trip of webhook with ID: " + doc.ID + "\n" + err.Error())
+			continue
+		}
+		if err := database.Update(doc.ID, map[string]interface{}{"trip": hook.Trip}); err != nil {
+			log.Println("Unable to attach the trip to webhook with ID: " + doc.ID + "\n" + err.Error())
+			continue
+		}
+		log.Println("Attached the trip with ID: " + hook.Trip + " to webhook with ID: " + doc.ID)
+	}
+}
+
+// updateSubscriptions Updates the webhooks subscribed to the trip after it is replaced, and recalculates their
+// departure. The webhooks are deleted with the trip, a nil trip
+func updateSubscriptions(id string, trip *structs.Trip) {
+	// The route of the trip being driven is planned again from the next check-in
+	forgetDrive(id)
+
+	docs, err := database.GetAll()
+	if err != nil {
+		log.Println("There was an error while iterating through the webhooks.\n" + err.Error())
+		return
+	}
+	for _, doc := range docs {
+		var hook structs.Webhook
+		if err := doc.DataTo(&hook); err != nil {
+			log.Println("There was an error while adding data to the struct.\n" + err.Error())
+			continue
+		}
+		if hook.Trip != id {
+			continue
+		}
+		if trip == nil {
+			if _, err := database.Delete(doc.ID); err != nil {
+				log.Println("Deletion of webhook with ID: " + doc.ID + " FAILED.\n" + err.Error())
+			}
+			continue
+		}
+
+		arrival := hook.ArrivalTime
+		applyTrip(&hook, *trip)
+		fields := map[string]interface{}{
+			"DepartureLocation":  hook.DepartureLocation,
+			"ArrivalDestination": hook.ArrivalDestination,
+			"ArrivalTime":        hook.ArrivalTime,
+			"profile":            hook.Profile,
+			"preferences":        hook.Preferences,
+		}
+		// The webhook is notified again for the new arrival
+		if hook.ArrivalTime != arrival {
+			fields["notified"], fields["briefed"], fields["alerted"], fields["eta"] = "", "", []string{}, ""
+		}
+		err := database.Update(doc.ID, fields)
+		if err != nil {
+			log.Println("Unable to update webhook with ID: " + doc.ID + " to its trip.\n" + err.Error())
+			continue
+		}
+		checkWebhook(doc.ID, hook)
+		if err := CalculateDeparture(doc.ID); err != nil {
+			log.Println("Unable to recalculate the departure of webhook with ID: " + doc.ID + "\n" + err.Error())
+			continue
+		}
+		// The notification waiting for the previous trip gives way to this one
+		schedule(doc.ID)
+	}
+}
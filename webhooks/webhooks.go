@@ -1,108 +1,171 @@
 package webhooks
 
 import (
-	"cloud.google.com/go/firestore"
 	"cloudproject/database"
 	"cloudproject/endpoints"
+	"cloudproject/i18n"
+	"cloudproject/router"
 	"cloudproject/structs"
 	"cloudproject/utils"
+	"context"
 	"encoding/json"
 	"errors"
 	"fmt"
 	_ "fmt"
-	"google.golang.org/api/iterator"
 	"io/ioutil"
 	"log"
 	"net/http"
 	url2 "net/url"
 	"strings"
-	"sync"
 	"time"
 	_ "time"
 )
 
-// Check Checks for updates in weather conditions and traffic incidents
-func Check(w http.ResponseWriter) {
-	// Loop through all entries in collection "messages"
-	iter := database.Client.Collection(database.Collection).Documents(database.Ctx)
-	var hook structs.Webhook
-
-	// While loop
+// Check Checks for updates in weather conditions every 30 minutes, and plans the departures again in the current
+// traffic and weather, until ctx is cancelled
+func Check(ctx context.Context) {
 	for {
-		doc, err := iter.Next()
-		if err == iterator.Done {
-			break
+		// Loop through all entries in collection "messages"
+		docs, err := database.GetAll()
+		if err != nil {
+			log.Println("There was an error while iterating through the webhooks.\n" + err.Error())
 		}
 
-		// Adds the data to the Webhook-struct
-		if err := doc.DataTo(&hook); err != nil {
-			log.Println("There was an error while adding data to the struct.\n" + err.Error())
-			http.Error(w, err.Error(), http.StatusBadRequest)
+		for _, doc := range docs {
+			// Adds the data to the Webhook-struct
+			var hook structs.Webhook
+			if err := doc.DataTo(&hook); err != nil {
+				log.Println("There was an error while adding data to the struct.\n" + err.Error())
+				continue
+			}
+			replan(doc.ID, hook, checkWebhook(doc.ID, hook))
 		}
 
-		weatherMessage := hook.Weather
-
-		// Receives the latitude and longitude of the place passed in to the url
-		latitude, longitude, err := database.LocationPresent(url2.QueryEscape(hook.DepartureLocation))
-		if err != nil {
-			log.Println("There was an error while retrieving GeoCode for location: " + hook.DepartureLocation + "\n" + err.Error())
-			http.Error(w, err.Error(), http.StatusBadRequest)
-		}
-		url := ""
-		if latitude != "" && longitude != "" {
-			// Defines the url to the openweathermap API with relevant latitude and longitude and apiKey
-			url = "https://api.openweathermap.org/data/2.5/weather?lat=" + latitude + "&lon=" + longitude + "&appid=" + utils.OpenweathermapKey
-		} else {
-			log.Println("Wrong formatting or no input for latitude and/or longitude.")
-			http.Error(w, "Check formatting of latitude and longitude.", http.StatusBadRequest)
+		if !sleep(ctx, time.Minute*30) {
+			return
 		}
+	}
+}
 
-		// Gets the current weather
-		newMessage := endpoints.CurrentWeatherHandler(w, url).Main.Message
-		if !(newMessage == weatherMessage) {
-			hook.Weather = newMessage
-			database.Update(doc.Ref.ID, hook)
-		}
+// checkWebhook Updates the stored weather message and road conditions of a webhook if the weather at the departure
+// location has changed, and returns whether it has changed
+func checkWebhook(id string, hook structs.Webhook) bool {
+	weatherMessage := hook.Weather
+
+	// Gets the current weather at the departure location
+	weather, err := weatherAt(hook.DepartureLocation, webhookLocale(hook))
+	if err != nil {
+		log.Println("There was an error while checking the weather for webhook with ID: " + id + "\n" + err.Error())
+		return false
 	}
-	time.Sleep(time.Minute * 30)
-	Check(w)
+	newMessage := weather.Main.Message
+	if newMessage == weatherMessage && hook.Conditions != nil && sameConditions(*hook.Conditions, weather.Conditions) {
+		return false
+	}
+	conditions, err := database.ToData(weather.Conditions)
+	if err != nil {
+		log.Println("Unable to convert the road conditions for webhook with ID: " + id + "\n" + err.Error())
+		return true
+	}
+	err = database.Update(id, map[string]interface{}{
+		"Weather":    newMessage,
+		"Conditions": conditions,
+	})
+	if err != nil {
+		log.Println("Unable to update the weather for webhook with ID: " + id + "\n" + err.Error())
+		return true
+	}
+	hook.Id, hook.Weather, hook.Conditions = id, newMessage, &weather.Conditions
+	publish(hookEvent(EventWeather, hook, notificationData(hook, "")))
+	return true
 }
 
-func WebhookHandler(w http.ResponseWriter, r *http.Request) {
-	id := strings.Split(r.URL.Path, "/")[4]
-	switch r.Method {
-	case http.MethodDelete:
-		message, err := database.Delete(id)
-		if err != nil {
-			http.Error(w, err.Error(), http.StatusInternalServerError)
-		} else {
-			_, err := fmt.Fprintf(w, message)
-			if err != nil {
-				log.Println(err.Error())
-			}
+// weatherAt The current weather at the place, with the advisory in the locale
+func weatherAt(place string, locale string) (structs.OutputWeather, error) {
+	// Receives the latitude and longitude of the place passed in to the url
+	latitude, longitude, err := database.LocationPresent(url2.QueryEscape(place))
+	if err != nil {
+		log.Println("There was an error while retrieving GeoCode for location: " + place + "\n" + err.Error())
+		return structs.OutputWeather{}, err
+	}
+	if latitude == "" || longitude == "" {
+		log.Println("Wrong formatting or no input for latitude and/or longitude.")
+		return structs.OutputWeather{}, errors.New("no coordinates for location: " + place)
+	}
+	// Defines the url to the openweathermap API with relevant latitude and longitude and apiKey
+	url := utils.OpenWeatherMapURL + "/data/2.5/weather?lat=" + latitude + "&lon=" + longitude + "&units=metric&appid=" + utils.OpenweathermapKey
+	return endpoints.FetchWeather(url, endpoints.MetricPresentation(locale))
+}
 
-		}
-	case http.MethodPost:
-		AddWebhook(w, r)
-	case http.MethodGet:
-		w.Header().Set("Content-type", "application/json")
-		if id != "" {
-			output, _ := database.Get(id)
-			_, err := fmt.Fprintf(w, "%v", string(output))
-			if err != nil {
-				log.Println(err.Error())
-			}
-		} else {
-			GetAllWebhooks(w)
-		}
+// sameConditions Checks if the road conditions have the same classes and score, the reasons follow from them
+func sameConditions(a structs.RoadConditions, b structs.RoadConditions) bool {
+	return a.Precipitation == b.Precipitation && a.PrecipitationIntensity == b.PrecipitationIntensity &&
+		a.FreezingRisk == b.FreezingRisk && a.Visibility == b.Visibility && a.WindRisk == b.WindRisk &&
+		a.Daylight == b.Daylight && a.RiskScore == b.RiskScore
+}
+
+// webhookLocale The locale to notify the webhook in, webhooks registered before locales were added are notified in English
+func webhookLocale(hook structs.Webhook) string {
+	if locale, supported := i18n.Match(hook.Locale); supported {
+		return locale
+	}
+	return i18n.Fallback
+}
+
+// GetWebhook Displays the webhook with the id in the path
+func GetWebhook(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-type", "application/json")
+	id := router.Param(r, "id")
+
+	stored, err := database.Get(id)
+	if err != nil {
+		log.Println(err.Error())
+		router.Error(w, "No webhook registered with ID: "+id, http.StatusNotFound)
+		return
+	}
+
+	// Goes through the struct, so the output has the same form as the list of webhooks
+	var webhook structs.Webhook
+	if err = json.Unmarshal(stored, &webhook); err != nil {
+		log.Println("There was an error during unmarshalling.\n" + err.Error())
+		jsonError := utils.JsonUnmarshalErrorHandling(err)
+		http.Error(w, jsonError.Error(), http.StatusInternalServerError)
+		return
+	}
+	output, err := json.Marshal(endpoints.Versioned(r, webhook))
+	if err != nil {
+		log.Println("There was an error while marshalling the data.\n" + err.Error())
+		jsonError := utils.JsonMarshalErrorHandling(err)
+		http.Error(w, jsonError.Error(), http.StatusInternalServerError)
+		return
+	}
+	_, err = fmt.Fprintf(w, "%v", string(output))
+	if err != nil {
+		log.Println(err.Error())
+	}
+}
+
+// ListWebhooks Displays all registered webhooks
+func ListWebhooks(w http.ResponseWriter, r *http.Request) {
+	GetAllWebhooks(w, r)
+}
+
+// DeleteWebhook Deletes the webhook with the id in the path
+func DeleteWebhook(w http.ResponseWriter, r *http.Request) {
+	message, err := database.Delete(router.Param(r, "id"))
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	_, err = fmt.Fprintf(w, message)
+	if err != nil {
+		log.Println(err.Error())
 	}
 }
 
 // AddWebhook Add new webhook
 func AddWebhook(w http.ResponseWriter, r *http.Request) {
 
-	// Need to wait before starting new go routine
-	wg := new(sync.WaitGroup)
 	// Read response body
 	input, err := ioutil.ReadAll(r.Body)
 	if err != nil {
@@ -124,6 +187,20 @@ func AddWebhook(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	// The notifications are sent in the locale of the webhook, or else in the language of the request
+	if notification.Locale == "" {
+		notification.Locale = i18n.Negotiate(r)
+	}
+
+	// A webhook subscribing to a trip gets its places, arrival, profile and preferences from the trip
+	if notification.Trip != "" {
+		if err = subscribe(&notification); err != nil {
+			log.Println("Error: Unable to subscribe to the trip.\n" + err.Error())
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+	}
+
 	// Checks the webhook format
 	err = webhookFormat(notification)
 	if err != nil {
@@ -131,39 +208,72 @@ func AddWebhook(w http.ResponseWriter, r *http.Request) {
 		http.Error(w, err.Error(), http.StatusNoContent)
 		return
 	}
+	notification.Locale, _ = i18n.Match(notification.Locale)
+	if err = validateStages(&notification); err != nil {
+		log.Println("Error: Invalid notification stages.\n" + err.Error())
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	if err = validateTemplates(&notification); err != nil {
+		log.Println("Error: Invalid notification templates.\n" + err.Error())
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	// Every webhook is a subscription to a trip, the trip is saved for a webhook registered without one, and deleted
+	// again if the webhook can not be registered
+	savedTrip := notification.Trip == ""
+	if savedTrip {
+		if err = saveTrip(&notification); err != nil {
+			log.Println("Error: Unable to save the trip of the webhook.\n" + err.Error())
+			http.Error(w, "error, "+err.Error(), http.StatusBadRequest)
+			return
+		}
+	}
 
 	// Adds data to the database
-	id, _, err := database.Client.Collection(database.Collection).Add(database.Ctx,
+	id, err := database.Client.Add(database.Collection,
 		map[string]interface{}{
 			"url":                notification.Url,
 			"ArrivalDestination": notification.ArrivalDestination,
 			"ArrivalTime":        notification.ArrivalTime,
 			"Weather":            notification.Weather,
 			"DepartureLocation":  notification.DepartureLocation,
+			"locale":             notification.Locale,
+			"profile":            notification.Profile,
+			"preferences":        notification.Preferences,
+			"trip":               notification.Trip,
+			"stages":             notification.Stages,
+			"templates":          notification.Templates,
 		})
 	if err != nil {
 		log.Println("Error: Unable to add data to database.\n" + err.Error())
+		if savedTrip {
+			discardTrip(notification.Trip)
+		}
 		http.Error(w, err.Error(), http.StatusBadRequest)
 		return
 	} else {
-		trimmedId := strings.TrimLeft(id.ID, "/") //Trimming the id
+		trimmedId := strings.TrimLeft(id, "/") //Trimming the id
 		//Adding the Id to a field, for easier access
-		_, err = database.Client.Collection(database.Collection).Doc(trimmedId).Set(database.Ctx, map[string]interface{}{
+		err = database.Update(trimmedId, map[string]interface{}{
 			"id": trimmedId,
-		}, firestore.MergeAll)
-		log.Println("Successfully registered webhook with ID: " + id.ID)
-		http.Error(w, "Registered with ID: "+id.ID, http.StatusCreated)
-		go Check(w)
-
-		// Waits for Check to complete before moving on
-		wg.Wait()
-		err := CalculateDeparture(id.ID)
+		})
+		log.Println("Successfully registered webhook with ID: " + id)
+		http.Error(w, "Registered with ID: "+id, http.StatusCreated)
+
+		// Checks the weather before calculating the departure, as the weather affects the travel time
+		checkWebhook(id, notification)
+		err := CalculateDeparture(id)
 		if err != nil {
-			database.Delete(id.ID)
+			database.Delete(id)
+			if savedTrip {
+				discardTrip(notification.Trip)
+			}
 			http.Error(w, err.Error(), http.StatusInternalServerError)
 			return
 		}
-		go SendNotification(id.ID)
+		schedule(id)
 	}
 }
 
@@ -180,6 +290,27 @@ func webhookFormat(web structs.Webhook) error {
 		log.Println("Arrival time cannot be empty.")
 		return errors.New("error, arrival time cannot be empty")
 	}
+	if _, supported := i18n.Match(web.Locale); !supported {
+		log.Println("Unsupported locale: " + web.Locale)
+		return errors.New("error, the locale " + web.Locale + " is not supported, supported locales: " + strings.Join(i18n.Locales, ", "))
+	}
+	if web.Profile != "" {
+		if _, err := database.GetProfile(web.Profile); err != nil {
+			log.Println("Unknown vehicle profile: " + web.Profile)
+			return errors.New("error, there is no vehicle profile with ID: " + web.Profile)
+		}
+	}
+	if web.Preferences != nil {
+		if web.Preferences.DepartAt != "" || web.Preferences.ArriveAt != "" {
+			log.Println("The route of a webhook can not have a departure or arrival time.")
+			return errors.New("error, the route is planned to arrive at the arrival time, departAt and arriveAt can not be given")
+		}
+		// Validated through the pointer, so the preferences are stored with their defaults
+		if err := endpoints.ValidatePreferences(web.Preferences); err != nil {
+			log.Println("Invalid route preferences.\n" + err.Error())
+			return errors.New("error, " + err.Error())
+		}
+	}
 	err := utils.IsValidInput(web.ArrivalTime)
 	if !err {
 		log.Println("Error: Invalid time format. Example of expected format: 17 may 21 12:10 CEST")
@@ -188,44 +319,51 @@ func webhookFormat(web structs.Webhook) error {
 	return nil
 }
 
-// DeleteExpiredWebhooks Deletes webhooks which are older than 24 hours
-func DeleteExpiredWebhooks() {
-	// Retrieves all entries in collection "messages"
-	iter := database.Client.Collection(database.Collection).Documents(database.Ctx)
-
-	var firebase structs.Webhook
-
-	// Iterates through all instances
+// DeleteExpiredWebhooks Deletes webhooks which are older than 24 hours, runs once a day until ctx is cancelled
+func DeleteExpiredWebhooks(ctx context.Context) {
 	for {
-		doc, err := iter.Next()
-		if err == iterator.Done {
-			break
+		deleteExpired()
+		if !sleep(ctx, time.Hour*24) {
+			return
 		}
+	}
+}
 
+// deleteExpired Goes through all webhooks once and deletes the ones which are older than 24 hours
+func deleteExpired() {
+	// Retrieves all entries in collection "messages"
+	docs, err := database.GetAll()
+	if err != nil {
+		log.Println("There was an error while iterating through the webhooks.\n" + err.Error())
+		return
+	}
+
+	// Iterates through all instances
+	for _, doc := range docs {
+		var firebase structs.Webhook
 		if err := doc.DataTo(&firebase); err != nil {
 			log.Println("Unable to append data to firebase.")
+			continue
 		}
 
 		arrival, err := time.Parse(time.RFC822, firebase.ArrivalTime)
 		if err != nil {
-			log.Println("Unable to parse time to format: RFC822.")
-			log.Fatalf(err.Error())
+			log.Println("Unable to parse time to format: RFC822.\n" + err.Error())
+			continue
 		}
 
 		if arrival.Before(time.Now().AddDate(0, 0, -1)) {
-			_, err := database.Delete(doc.Ref.ID)
+			_, err := database.Delete(doc.ID)
 			if err != nil {
-				log.Println("Deletion of webhook with ID: " + doc.Ref.ID + " FAILED.")
-				log.Fatalf(err.Error())
+				log.Println("Deletion of webhook with ID: " + doc.ID + " FAILED.\n" + err.Error())
+				continue
 			}
 			log.Println("Webhook got SUCCESSFULLY deleted.")
 		}
 	}
-	time.Sleep(time.Hour * 24)
-	DeleteExpiredWebhooks()
 }
 
-func GetAllWebhooks(w http.ResponseWriter) {
+func GetAllWebhooks(w http.ResponseWriter, r *http.Request) {
 	w.Header().Set("Content-type", "application/json")
 
 	list, err := database.GetAll()
@@ -247,14 +385,26 @@ func GetAllWebhooks(w http.ResponseWriter) {
 			DepartureLocation:   webhook.DepartureLocation,
 			ArrivalDestination:  webhook.ArrivalDestination,
 			Weather:             webhook.Weather,
+			Conditions:          webhook.Conditions,
 			ArrivalTime:         webhook.ArrivalTime,
 			EstimatedTravelTime: webhook.EstimatedTravelTime,
+			Uncertainty:         webhook.Uncertainty,
+			Stages:              webhook.Stages,
+			Notified:            webhook.Notified,
+			Briefed:             webhook.Briefed,
+			Alerted:             webhook.Alerted,
+			ETA:                 webhook.ETA,
+			Templates:           webhook.Templates,
+			Locale:              webhook.Locale,
+			Profile:             webhook.Profile,
+			Preferences:         webhook.Preferences,
+			Trip:                webhook.Trip,
 		}
 		allWebhooks = append(allWebhooks, webStruct)
 	}
 
 	// Marshalling the array to JSON
-	output, err := json.Marshal(allWebhooks)
+	output, err := json.Marshal(endpoints.Versioned(r, allWebhooks))
 	if err != nil {
 		log.Println("There was an error while marshalling the data.\n" + err.Error())
 		jsonError := utils.JsonMarshalErrorHandling(err)
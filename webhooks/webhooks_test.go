@@ -3,48 +3,35 @@ package webhooks
 import (
 	"bytes"
 	"cloudproject/database"
+	"cloudproject/endpoints"
+	"cloudproject/harness"
 	"cloudproject/structs"
-	"context"
+	"cloudproject/utils"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
 	"encoding/json"
-	firebase "firebase.google.com/go"
-	"fmt"
-	"google.golang.org/api/option"
 	"io/ioutil"
-	"log"
 	"net/http"
 	"net/http/httptest"
 	"strings"
 	"testing"
+	"time"
 )
 
-func TestCreateWebhook(t *testing.T) {
-	database.Ctx = context.Background()
-	sa := option.WithCredentialsFile("cloudprojecttwo-firebase-adminsdk-uke12-6ed6b4ca4e.json")
-	app, err := firebase.NewApp(database.Ctx, nil, sa)
-	if err != nil {
-		_ = fmt.Errorf("error initializing app: %v", err)
-	}
-
-	database.Client, err = app.Firestore(database.Ctx)
-	if err != nil {
-		log.Fatalln(err)
-	}
-
-	mcPostBody := map[string]interface{}{
-		"url":                "https://discord.com/api/webhooks/842330664279998474/YpO-9WUDl9qwl29ka9wvlm90ijN_gZeYkWwIfJl41IXRNUWYH3EMDH6hWBeZbbHKwDSz",
-		"ArrivalDestination": "lillehammer",
-		"DepartureLocation":  "gjøvik",
-		"ArrivalTime":        "10 aug 21 12:10 CEST",
-	}
-	body, _ := json.Marshal(mcPostBody)
-
-	req, err := http.NewRequest("POST", "http://localhost:8080/hook/", bytes.NewReader(body))
-
-	req.Header.Set("Content-Type", "application/json")
+// init Runs the webhook workers while the harness is started
+func init() {
+	harness.RegisterWorkers(Start, Shutdown)
+}
 
+// register Registers a webhook through AddWebhook and returns its id
+func register(t *testing.T, body map[string]interface{}) string {
+	content, _ := json.Marshal(body)
+	req, err := http.NewRequest("POST", "http://localhost:8080/hook/", bytes.NewReader(content))
 	if err != nil {
 		t.Fatalf("could not create request %v", err)
 	}
+	req.Header.Set("Content-Type", "application/json")
 
 	rec := httptest.NewRecorder()
 	AddWebhook(rec, req)
@@ -53,19 +40,32 @@ func TestCreateWebhook(t *testing.T) {
 	defer response.Body.Close()
 
 	if response.StatusCode != http.StatusCreated {
-		t.Errorf("Expected status Ok; got %v", response.StatusCode)
+		t.Fatalf("Expected status Created; got %v", response.StatusCode)
 	}
 
 	read, err := ioutil.ReadAll(response.Body)
 	if err != nil {
 		t.Fatalf("Got an error in reading")
 	}
-
 	output := string(read)
 	outputarr := strings.Split(output, "{")
-	id := strings.Split(outputarr[0], ":")[1]
+	return strings.TrimSpace(strings.Split(outputarr[0], ":")[1])
+}
+
+func TestCreateWebhook(t *testing.T) {
+	h := harness.Start(t)
+
+	id := register(t, map[string]interface{}{
+		"url":                h.WebhookURL,
+		"ArrivalDestination": "lillehammer",
+		"DepartureLocation":  "gjøvik",
+		"ArrivalTime":        time.Now().Add(48 * time.Hour).UTC().Format(time.RFC822),
+	})
 
-	webhook, err := database.Get(strings.TrimSpace(id))
+	webhook, err := database.Get(id)
+	if err != nil {
+		t.Fatalf("The webhook was not stored: %v", err)
+	}
 
 	var hook structs.Webhook
 	err = json.Unmarshal(webhook, &hook)
@@ -77,9 +77,137 @@ func TestCreateWebhook(t *testing.T) {
 		t.Fatalf("Expected lillehammer; got %v", hook.ArrivalDestination)
 	} else if "gjøvik" != hook.DepartureLocation {
 		t.Fatalf("Expected gjøvik; got %v", hook.DepartureLocation)
+	} else if hook.Weather == "" {
+		t.Fatalf("Expected the weather at the departure location to be stored")
+	} else if hook.Conditions == nil || hook.Conditions.Precipitation != "snow" {
+		t.Fatalf("Expected the road conditions at the departure location to be stored; got %v", hook.Conditions)
+	} else if hook.EstimatedTravelTime == 0 {
+		t.Fatalf("Expected the travel time to be calculated")
 	}
-	_, err = database.Client.Collection(database.Collection).Doc(strings.TrimSpace(id)).Delete(database.Ctx)
+	_, err = database.Delete(id)
 	if err != nil {
 		t.Fatalf("Error when deleting")
 	}
 }
+
+func TestFailedRegistration(t *testing.T) {
+	h := harness.Start(t)
+	before, _ := database.GetTrips()
+
+	// The departure can not be calculated while TomTom can not be reached, the harness restores the url
+	utils.TomTomURL = "http://127.0.0.1:1"
+	id := register(t, map[string]interface{}{
+		"url":                h.WebhookURL,
+		"ArrivalDestination": "lillehammer",
+		"DepartureLocation":  "gjøvik",
+		"ArrivalTime":        time.Now().Add(48 * time.Hour).UTC().Format(time.RFC822),
+	})
+
+	if _, err := database.Get(id); err == nil {
+		t.Errorf("Expected the webhook to be deleted")
+	}
+	if trips, _ := database.GetTrips(); len(trips) != len(before) {
+		t.Errorf("Expected the trip saved for the webhook to be deleted; got %+v", trips)
+	}
+}
+
+func TestSendNotification(t *testing.T) {
+	h := harness.Start(t)
+
+	// The recorded route takes 2912 seconds, which is 48 minutes, give or take 7 minutes as the weather of the recorded
+	// forecast has passed. The arrival is set so the notification, 30 minutes before the earliest departure, is due
+	// within the next minute (the arrival time has minute precision)
+	arrival := time.Now().Add(48*time.Minute + 7*time.Minute + 30*time.Minute + time.Minute).UTC()
+	register(t, map[string]interface{}{
+		"url":                h.WebhookURL,
+		"ArrivalDestination": "lillehammer",
+		"DepartureLocation":  "gjøvik",
+		"ArrivalTime":        arrival.Format(time.RFC822),
+		"locale":             "nb-NO",
+	})
+
+	delivery := h.WaitForDelivery(t, 10*time.Second)
+
+	mac := hmac.New(sha256.New, Secret)
+	mac.Write(delivery.Body)
+	if delivery.Signature != hex.EncodeToString(mac.Sum(nil)) {
+		t.Errorf("The signature of the invocation does not match its content")
+	}
+	var message structs.JsonMessage
+	if err := json.Unmarshal(delivery.Body, &message); err != nil {
+		t.Fatalf("The invocation is not a Slack message: %v", err)
+	}
+	if !strings.Contains(message.Text, "Turen din begynner snart") || !strings.Contains(message.Text, "Det har snødd lett") {
+		t.Errorf("Unexpected notification text: %v", message.Text)
+	}
+}
+
+func TestReplan(t *testing.T) {
+	h := harness.Start(t)
+	arrival := time.Now().Add(48 * time.Hour).UTC()
+	id := register(t, map[string]interface{}{
+		"url":                h.WebhookURL,
+		"ArrivalDestination": "lillehammer",
+		"DepartureLocation":  "gjøvik",
+		"ArrivalTime":        arrival.Format(time.RFC822),
+		"locale":             "en",
+	})
+	stored := func() structs.Webhook {
+		content, err := database.Get(id)
+		var hook structs.Webhook
+		if err != nil || json.Unmarshal(content, &hook) != nil {
+			t.Fatalf("Could not read the webhook: %v", err)
+		}
+		return hook
+	}
+	predictions := func() int {
+		count := 0
+		for _, uri := range h.Requests(harness.TomTom) {
+			if strings.Contains(uri, "departAt=") {
+				count++
+			}
+		}
+		return count
+	}
+
+	// The departure was planned for a travel time of 20 minutes, the recorded route now takes 48 minutes in the changed
+	// weather, which predicts the travel time for the departures around it
+	if err := database.Update(id, map[string]interface{}{"EstimatedTravelTime": 20}); err != nil {
+		t.Fatalf("Could not update the webhook: %v", err)
+	}
+	before := predictions()
+	replan(id, stored(), true)
+	if predicted := predictions() - before; predicted != endpoints.DefaultCandidates {
+		t.Errorf("Expected the travel time to be predicted for %v departures; got %v", endpoints.DefaultCandidates, predicted)
+	}
+	delivery := h.WaitForDelivery(t, 10*time.Second)
+	var message structs.JsonMessage
+	if err := json.Unmarshal(delivery.Body, &message); err != nil || !strings.Contains(message.Text, "Updated: leave 28 minutes earlier") {
+		t.Errorf("Expected an update to leave 28 minutes earlier; got %v %v", string(delivery.Body), err)
+	}
+	if hook := stored(); hook.EstimatedTravelTime != 48 {
+		t.Errorf("Expected the travel time to be estimated again; got %v", hook.EstimatedTravelTime)
+	}
+	pendingMutex.Lock()
+	_, scheduled := pending[id]
+	pendingMutex.Unlock()
+	if !scheduled {
+		t.Errorf("Expected the notification to be scheduled for the new departure")
+	}
+
+	// A move of less than the threshold only reschedules the notification, in the unchanged weather the travel time is
+	// predicted for the planned departure alone
+	if err := database.Update(id, map[string]interface{}{"EstimatedTravelTime": 45}); err != nil {
+		t.Fatalf("Could not update the webhook: %v", err)
+	}
+	before = predictions()
+	replan(id, stored(), false)
+	if predicted := predictions() - before; predicted != ReplanCandidates {
+		t.Errorf("Expected the travel time to be predicted for %v departure; got %v", ReplanCandidates, predicted)
+	}
+	select {
+	case delivery := <-h.Deliveries():
+		t.Errorf("Expected no update for a move of 3 minutes; got %v", string(delivery.Body))
+	case <-time.After(200 * time.Millisecond):
+	}
+}